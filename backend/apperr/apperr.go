@@ -0,0 +1,163 @@
+// Package apperr defines the errors handlers return and the Fiber error handler that turns
+// them into responses. Every error response has the same envelope:
+//
+//	{"error": {"code": "batch_not_found", "message": "Batch tidak ditemukan", "details": ..., "requestId": "..."}}
+//
+// The code is a stable key of the i18n catalog; the message is that key in the request's language.
+package apperr
+
+import (
+	"academic-suite-backend/i18n"
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Error is an API error: the HTTP status, a catalog code with its message arguments, optional
+// details for the client (e.g. the fields that failed validation) and the underlying cause,
+// which is logged but never sent.
+type Error struct {
+	Status  int
+	Code    string
+	Args    []interface{}
+	Details interface{}
+	Err     error
+}
+
+func New(status int, code string, args ...interface{}) *Error {
+	return &Error{Status: status, Code: code, Args: args}
+}
+
+func BadRequest(code string, args ...interface{}) *Error {
+	return New(fiber.StatusBadRequest, code, args...)
+}
+
+func Unauthorized(code string, args ...interface{}) *Error {
+	return New(fiber.StatusUnauthorized, code, args...)
+}
+
+func Forbidden(code string, args ...interface{}) *Error {
+	return New(fiber.StatusForbidden, code, args...)
+}
+
+func NotFound(code string, args ...interface{}) *Error {
+	return New(fiber.StatusNotFound, code, args...)
+}
+
+func Conflict(code string, args ...interface{}) *Error {
+	return New(fiber.StatusConflict, code, args...)
+}
+
+// Internal reports a server-side failure; cause is logged with the request ID
+func Internal(code string, cause error) *Error {
+	return &Error{Status: fiber.StatusInternalServerError, Code: code, Err: cause}
+}
+
+// Error gives the English message (and the cause), for logs
+func (e *Error) Error() string {
+	msg := i18n.English.T(e.Code, e.Args...)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is matches errors by code, so sentinel errors work with errors.Is
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Message is the error text in locale l
+func (e *Error) Message(l i18n.Locale) string {
+	return l.T(e.Code, e.Args...)
+}
+
+// WithDetails returns a copy of e carrying details, leaving sentinel errors untouched
+func (e *Error) WithDetails(details interface{}) *Error {
+	copied := *e
+	copied.Details = details
+	return &copied
+}
+
+// Wrap returns a copy of e with cause attached
+func (e *Error) Wrap(cause error) *Error {
+	copied := *e
+	copied.Err = cause
+	return &copied
+}
+
+// LocalizedDetails is implemented by details with text of their own (e.g. field errors),
+// rendered in the request's language like the message
+type LocalizedDetails interface {
+	Localize(l i18n.Locale) interface{}
+}
+
+// Body is the content of the "error" field of a response
+type Body struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"requestId,omitempty"`
+}
+
+// Response is the envelope of every error response
+type Response struct {
+	Error Body `json:"error"`
+}
+
+// RequestIDKey is where the requestid middleware stores the request ID
+const RequestIDKey = "requestid"
+
+// Handler is the app's fiber.Config.ErrorHandler. *Error values are rendered as is, fiber's own
+// errors (unknown route, body too large, ...) get a generic code, and anything else is a 500
+// whose cause is logged rather than sent.
+func Handler(c *fiber.Ctx, err error) error {
+	var e *Error
+	if !errors.As(err, &e) {
+		var fe *fiber.Error
+		if errors.As(err, &fe) {
+			e = fromFiber(fe)
+		} else {
+			e = Internal("internal_error", err)
+		}
+	}
+
+	requestID, _ := c.Locals(RequestIDKey).(string)
+	if e.Status >= fiber.StatusInternalServerError {
+		log.Printf("[%s] %s %s: %v", requestID, c.Method(), c.Path(), e)
+	}
+
+	l := i18n.FromRequest(c)
+	details := e.Details
+	if ld, ok := details.(LocalizedDetails); ok {
+		details = ld.Localize(l)
+	}
+
+	// Exports set attachment headers before writing; the error is not a file
+	c.Response().Header.Del(fiber.HeaderContentDisposition)
+	return c.Status(e.Status).JSON(Response{Error: Body{
+		Code:      e.Code,
+		Message:   e.Message(l),
+		Details:   details,
+		RequestID: requestID,
+	}})
+}
+
+func fromFiber(fe *fiber.Error) *Error {
+	switch fe.Code {
+	case fiber.StatusNotFound:
+		return NotFound("route_not_found")
+	case fiber.StatusMethodNotAllowed:
+		return New(fe.Code, "method_not_allowed")
+	case fiber.StatusRequestEntityTooLarge:
+		return New(fe.Code, "request_too_large")
+	}
+	if fe.Code >= fiber.StatusInternalServerError {
+		return &Error{Status: fe.Code, Code: "internal_error", Err: fe}
+	}
+	return New(fe.Code, "invalid_request")
+}
@@ -0,0 +1,98 @@
+package apperr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+)
+
+var errMissing = NotFound("batch_not_found")
+
+func newApp() *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: Handler})
+	app.Use(requestid.New())
+	app.Get("/missing", func(c *fiber.Ctx) error {
+		return fmt.Errorf("loading report: %w", errMissing)
+	})
+	app.Get("/invalid", func(c *fiber.Ctx) error {
+		return BadRequest("invalid_page_limit", 1000).WithDetails(map[string]string{"limit": "0"})
+	})
+	app.Get("/broken", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentDisposition, "attachment; filename=report.xlsx")
+		return errors.New("pq: connection refused")
+	})
+	return app
+}
+
+func request(t *testing.T, app *fiber.App, path string, headers ...string) (int, Response, http.Header) {
+	t.Helper()
+	req := httptest.NewRequest("GET", path, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	var body Response
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("%s: decode %q: %v", path, data, err)
+	}
+	return resp.StatusCode, body, resp.Header
+}
+
+func TestHandlerEnvelope(t *testing.T) {
+	app := newApp()
+
+	status, body, header := request(t, app, "/missing", "Accept-Language", "en")
+	if status != 404 || body.Error.Code != "batch_not_found" || body.Error.Message != "Batch not found" {
+		t.Errorf("wrapped sentinel: %d %+v", status, body)
+	}
+	if body.Error.RequestID == "" || body.Error.RequestID != header.Get("X-Request-ID") {
+		t.Errorf("request ID %q, header %q", body.Error.RequestID, header.Get("X-Request-ID"))
+	}
+
+	status, body, _ = request(t, app, "/invalid")
+	if status != 400 || body.Error.Message != "limit harus antara 1 dan 1000" {
+		t.Errorf("bad request: %d %+v", status, body)
+	}
+	if details, _ := body.Error.Details.(map[string]interface{}); details["limit"] != "0" {
+		t.Errorf("details = %#v", body.Error.Details)
+	}
+
+	// Unknown errors are not leaked to the client
+	status, body, header = request(t, app, "/broken", "Accept-Language", "en")
+	if status != 500 || body.Error.Code != "internal_error" || body.Error.Message != "Something went wrong, please try again" {
+		t.Errorf("internal: %d %+v", status, body)
+	}
+	if cd := header.Get(fiber.HeaderContentDisposition); cd != "" {
+		t.Errorf("Content-Disposition kept: %q", cd)
+	}
+
+	status, body, _ = request(t, app, "/nowhere")
+	if status != 404 || body.Error.Code != "route_not_found" {
+		t.Errorf("unknown route: %d %+v", status, body)
+	}
+}
+
+func TestErrorIdentity(t *testing.T) {
+	wrapped := Internal("export_failed", errors.New("disk full"))
+	if wrapped.Error() != "Could not generate the file: disk full" {
+		t.Errorf("Error() = %q", wrapped.Error())
+	}
+	if !errors.Is(NotFound("batch_not_found"), errMissing) || errors.Is(NotFound("quiz_not_found"), errMissing) {
+		t.Error("errors.Is should match by code")
+	}
+	if errMissing.WithDetails("x"); errMissing.Details != nil {
+		t.Error("WithDetails modified the sentinel")
+	}
+}
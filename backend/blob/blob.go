@@ -0,0 +1,65 @@
+// Package blob stores uploaded files (question images, listening audio) by key. The Store
+// interface is all handlers see, so the local disk store can later be swapped for an
+// S3-compatible one (MinIO) without touching them.
+package blob
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ErrNotFound is returned by Open and Delete for keys that hold nothing
+var ErrNotFound = errors.New("blob: not found")
+
+// ErrInvalidKey is returned for keys that are empty or try to leave the store
+var ErrInvalidKey = errors.New("blob: invalid key")
+
+// Store keeps blobs by key. Keys are slash-separated relative paths ("media/inst-1/abc").
+type Store interface {
+	Put(key string, r io.Reader) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+const DriverLocal = "local"
+
+// Config selects the store. Driver is "local" (default), keeping blobs under Dir.
+type Config struct {
+	Driver string
+	Dir    string
+}
+
+// LoadConfig reads the media section of the config (media.driver, media.dir) already loaded
+// by database.LoadConfig, with the same environment overrides (MEDIA_DIR, ...)
+func LoadConfig() Config {
+	viper.SetDefault("media.driver", DriverLocal)
+	viper.SetDefault("media.dir", "uploads")
+	return Config{
+		Driver: viper.GetString("media.driver"),
+		Dir:    viper.GetString("media.dir"),
+	}
+}
+
+// Open returns the store cfg selects
+func Open(cfg Config) (Store, error) {
+	switch cfg.Driver {
+	case DriverLocal, "":
+		return NewLocal(cfg.Dir)
+	default:
+		return nil, fmt.Errorf("blob: unsupported driver %q", cfg.Driver)
+	}
+}
+
+// cleanKey rejects keys that are empty, absolute or climb out with ".."
+func cleanKey(key string) (string, error) {
+	cleaned := path.Clean(key)
+	if key == "" || path.IsAbs(key) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
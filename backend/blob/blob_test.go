@@ -0,0 +1,47 @@
+package blob
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	s, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put("media/inst-1/a", strings.NewReader("hello")); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	s.Put("media/inst-1/a", strings.NewReader("replaced"))
+
+	r, err := s.Open("media/inst-1/a")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "replaced" {
+		t.Errorf("data = %q", data)
+	}
+
+	if err := s.Delete("media/inst-1/a"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := s.Open("media/inst-1/a"); err != ErrNotFound {
+		t.Errorf("open deleted: %v", err)
+	}
+	if err := s.Delete("media/inst-1/a"); err != ErrNotFound {
+		t.Errorf("delete twice: %v", err)
+	}
+}
+
+func TestLocalRejectsEscapingKeys(t *testing.T) {
+	s, _ := NewLocal(t.TempDir())
+	for _, key := range []string{"", ".", "../x", "a/../../x", "/etc/passwd", `a\..\x`} {
+		if err := s.Put(key, strings.NewReader("x")); err != ErrInvalidKey {
+			t.Errorf("put %q: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"academic-suite-backend/database"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+)
+
+const usage = `Usage:
+  main [serve]              start the API server (schema must be migrated)
+  main migrate up [n]       apply all (or the next n) pending migrations
+  main migrate down [n]     revert the last (or last n) applied migrations
+  main migrate status       list migrations and their state
+  main migrate verify       exit non-zero on pending or drifted migrations
+  main seed [--reset] [dataset...]
+                            seed the default (or named) datasets; refused when APP_ENV=prod,
+                            --reset truncates all data first (APP_ENV dev or test only)
+  main seed list            list available datasets
+`
+
+// runCommand handles CLI subcommands. Returns false when the server should start.
+func runCommand(args []string) bool {
+	if len(args) == 0 || args[0] == "serve" {
+		return false
+	}
+
+	switch args[0] {
+	case "migrate":
+		runMigrate(args[1:])
+	case "seed":
+		runSeed(args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], usage)
+		os.Exit(2)
+	}
+	return true
+}
+
+func stepsArg(args []string) int {
+	if len(args) < 2 {
+		return 0
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil || n < 1 {
+		fmt.Fprintf(os.Stderr, "invalid step count %q\n", args[1])
+		os.Exit(2)
+	}
+	return n
+}
+
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	database.Open()
+
+	switch args[0] {
+	case "up":
+		if err := database.MigrateUp(stepsArg(args)); err != nil {
+			log.Fatalf("migrate up: %v", err)
+		}
+	case "down":
+		if err := database.MigrateDown(stepsArg(args)); err != nil {
+			log.Fatalf("migrate down: %v", err)
+		}
+	case "status":
+		statuses, err := database.GetMigrationStatus()
+		if err != nil {
+			log.Fatalf("migrate status: %v", err)
+		}
+		for _, s := range statuses {
+			appliedAt := "-"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-32s  %-8s  %s\n", s.Version, s.Name, s.State, appliedAt)
+		}
+	case "verify":
+		if err := database.VerifySchema(); err != nil {
+			log.Fatalf("migrate verify: %v", err)
+		}
+		fmt.Println("Schema is up to date")
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate command %q\n\n%s", args[0], usage)
+		os.Exit(2)
+	}
+}
+
+func runSeed(args []string) {
+	if len(args) > 0 && args[0] == "list" {
+		for _, d := range database.Datasets {
+			marker := " "
+			if d.Default {
+				marker = "*"
+			}
+			fmt.Printf("%s %-18s %s\n", marker, d.Name, d.Description)
+		}
+		fmt.Println("\n* seeded when no dataset is given")
+		return
+	}
+
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	reset := fs.Bool("reset", false, "truncate all application tables before seeding (dev/test only)")
+	fs.Parse(args)
+
+	database.Connect()
+	if err := database.Seed(fs.Args(), database.SeedOptions{Reset: *reset}); err != nil {
+		log.Fatalf("seed: %v", err)
+	}
+	log.Println("Seeding finished")
+}
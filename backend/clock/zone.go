@@ -0,0 +1,105 @@
+package clock
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	// Embed the zone database so Asia/Jakarta etc. resolve on images without tzdata
+	_ "time/tzdata"
+)
+
+// DefaultTimezone is used for institutions (and batches) that never set one
+const DefaultTimezone = "Asia/Jakarta"
+
+// ErrNonexistentLocalTime is returned for wall times skipped by a DST jump (e.g. 02:30 on spring-forward day)
+var ErrNonexistentLocalTime = errors.New("local time does not exist in this timezone (DST gap)")
+
+// LoadLocation resolves an IANA zone name, empty meaning DefaultTimezone.
+// Abbreviations like "WIB" are not zones; "Local" is refused because it depends on the server.
+func LoadLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = DefaultTimezone
+	}
+	if name == "Local" {
+		return nil, fmt.Errorf("timezone %q depends on the server, use an IANA name such as %s", name, DefaultTimezone)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return loc, nil
+}
+
+// Formats accepted without an offset; these are wall-clock times in the given zone
+var wallTimeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// ParseInZone parses a schedule time.
+// A value with an explicit offset or Z (RFC 3339) is an absolute instant and loc is ignored.
+// A value without one ("2025-12-22T14:50", as sent by datetime-local inputs) is a wall time in loc:
+//   - wall times inside a DST gap return ErrNonexistentLocalTime instead of silently shifting
+//   - wall times that occur twice (DST fall-back) resolve to the earlier instant
+//
+// The result is always expressed in loc.
+func ParseInZone(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, errors.New("time is required")
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t.In(loc), nil
+	}
+
+	for _, layout := range wallTimeLayouts {
+		wall, err := time.Parse(layout, value) // parsed as UTC, only the fields matter
+		if err != nil {
+			continue
+		}
+		return resolveWallTime(wall, loc)
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339 or YYYY-MM-DDTHH:MM", value)
+}
+
+// resolveWallTime finds every instant whose wall clock in loc reads wall and picks the earliest.
+// time.Date alone normalizes gap times unpredictably, so the offsets in effect around the
+// wall time are tried one by one.
+func resolveWallTime(wall time.Time, loc *time.Location) (time.Time, error) {
+	guess := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), loc)
+
+	offsets := map[int]bool{}
+	for _, probe := range []time.Time{guess.Add(-24 * time.Hour), guess, guess.Add(24 * time.Hour)} {
+		_, offset := probe.Zone()
+		offsets[offset] = true
+	}
+
+	var candidates []time.Time
+	for offset := range offsets {
+		t := wall.Add(-time.Duration(offset) * time.Second).In(loc)
+		if sameWallClock(t, wall) {
+			candidates = append(candidates, t)
+		}
+	}
+	if len(candidates) == 0 {
+		return time.Time{}, fmt.Errorf("%s in %s: %w", wall.Format("2006-01-02 15:04"), loc, ErrNonexistentLocalTime)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+	return candidates[0], nil
+}
+
+func sameWallClock(t, wall time.Time) bool {
+	y1, m1, d1 := t.Date()
+	y2, m2, d2 := wall.Date()
+	return y1 == y2 && m1 == m2 && d1 == d2 &&
+		t.Hour() == wall.Hour() && t.Minute() == wall.Minute() && t.Second() == wall.Second() &&
+		t.Nanosecond() == wall.Nanosecond()
+}
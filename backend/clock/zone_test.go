@@ -0,0 +1,134 @@
+package clock
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestLoadLocation(t *testing.T) {
+	if loc := mustLoad(t, ""); loc.String() != DefaultTimezone {
+		t.Fatalf("empty zone = %s, want %s", loc, DefaultTimezone)
+	}
+	for _, bad := range []string{"WIB", "Asia/Nowhere", "Local"} {
+		if _, err := LoadLocation(bad); err == nil {
+			t.Errorf("LoadLocation(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestParseWallTimeInJakarta(t *testing.T) {
+	jakarta := mustLoad(t, "Asia/Jakarta")
+
+	// The case behind the old repro script: 14:50 typed in Surabaya is 07:50 UTC, not 14:50 UTC
+	for _, value := range []string{"2025-12-22T14:50", "2025-12-22T14:50:00", "2025-12-22 14:50"} {
+		got, err := ParseInZone(value, jakarta)
+		if err != nil {
+			t.Fatalf("%s: %v", value, err)
+		}
+		want := time.Date(2025, 12, 22, 7, 50, 0, 0, time.UTC)
+		if !got.Equal(want) {
+			t.Errorf("%s = %s, want %s", value, got.UTC(), want)
+		}
+		if got.Format(time.RFC3339) != "2025-12-22T14:50:00+07:00" {
+			t.Errorf("%s renders as %s", value, got.Format(time.RFC3339))
+		}
+	}
+
+	// Just before midnight WIB is still the previous day in UTC
+	got, _ := ParseInZone("2026-01-01T06:30", jakarta)
+	if want := time.Date(2025, 12, 31, 23, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("06:30 WIB on Jan 1 = %s, want %s", got.UTC(), want)
+	}
+}
+
+func TestParseWithOffsetIgnoresZone(t *testing.T) {
+	jakarta := mustLoad(t, "Asia/Jakarta")
+
+	// What the frontend used to send: an absolute UTC instant
+	got, err := ParseInZone("2025-12-22T07:50:00.000Z", jakarta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Format(time.RFC3339) != "2025-12-22T14:50:00+07:00" {
+		t.Errorf("got %s", got.Format(time.RFC3339))
+	}
+
+	got, _ = ParseInZone("2025-12-22T14:50:00+07:00", mustLoad(t, "America/New_York"))
+	if want := time.Date(2025, 12, 22, 7, 50, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("explicit offset moved: %s", got.UTC())
+	}
+}
+
+func TestParseRejectsDSTGap(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+
+	// Clocks jump from 02:00 to 03:00 on 2025-03-09
+	_, err := ParseInZone("2025-03-09T02:30", newYork)
+	if !errors.Is(err, ErrNonexistentLocalTime) {
+		t.Fatalf("02:30 on spring-forward day: err = %v, want ErrNonexistentLocalTime", err)
+	}
+
+	// Either side of the gap is fine
+	before, err := ParseInZone("2025-03-09T01:59", newYork)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := ParseInZone("2025-03-09T03:00", newYork)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := after.Sub(before); d != time.Minute {
+		t.Errorf("01:59 EST to 03:00 EDT = %s, want 1m", d)
+	}
+}
+
+func TestParseResolvesDSTOverlapToEarlier(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+
+	// 01:30 happens twice on 2025-11-02 (EDT, then EST); the first one is used
+	got, err := ParseInZone("2025-11-02T01:30", newYork)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2025, 11, 2, 5, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("01:30 on fall-back day = %s, want %s (EDT)", got.UTC(), want)
+	}
+
+	// An explicit offset still selects the second occurrence
+	got, _ = ParseInZone("2025-11-02T01:30:00-05:00", newYork)
+	if want := time.Date(2025, 11, 2, 6, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("01:30-05:00 = %s, want %s", got.UTC(), want)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	jakarta := mustLoad(t, "Asia/Jakarta")
+	for _, value := range []string{"", "22/12/2025 14:50", "2025-12-22", "tomorrow"} {
+		if _, err := ParseInZone(value, jakarta); err == nil {
+			t.Errorf("ParseInZone(%q) succeeded, want error", value)
+		}
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+	f.Advance(90 * time.Second)
+	if got := f.Now(); !got.Equal(start.Add(90 * time.Second)) {
+		t.Errorf("after Advance: %s", got)
+	}
+	f.Set(start)
+	if got := f.Now(); !got.Equal(start) {
+		t.Errorf("after Set: %s", got)
+	}
+}
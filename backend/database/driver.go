@@ -0,0 +1,98 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// openPostgres connects to the target database, creating it first if it does not exist yet
+func openPostgres(cfg Config) (*gorm.DB, error) {
+	// Connect to default 'postgres' database to check/create target DB
+	dsnDefault := fmt.Sprintf("host=%s user=%s password=%s dbname=postgres port=%s sslmode=%s",
+		cfg.Host, cfg.User, cfg.Password, cfg.Port, cfg.SSLMode)
+
+	db, err := gorm.Open(postgres.Open(dsnDefault), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("connect to postgres default DB: %w", err)
+	}
+
+	// Check if database exists
+	var count int
+	db.Raw("SELECT count(*) FROM pg_database WHERE datname = ?", cfg.DBName).Scan(&count)
+	if count == 0 {
+		log.Printf("Database %s does not exist. Creating...", cfg.DBName)
+		if err := db.Exec(fmt.Sprintf("CREATE DATABASE %s", cfg.DBName)).Error; err != nil {
+			return nil, fmt.Errorf("create database: %w", err)
+		}
+		log.Println("Database created successfully")
+	}
+
+	// Close connection to default DB
+	sqlDB, _ := db.DB()
+	sqlDB.Close()
+
+	// Connect to Target Database
+	dsnTarget := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
+		cfg.Host, cfg.User, cfg.Password, cfg.DBName, cfg.Port, cfg.SSLMode)
+
+	target, err := gorm.Open(postgres.Open(dsnTarget), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Optimize Connection Pool
+	targetSQLDB, err := target.DB()
+	if err != nil {
+		return nil, err
+	}
+	// Postgre Default Max Connections is usually 100. Set slightly lower to leave room for other apps.
+	targetSQLDB.SetMaxOpenConns(90)
+	targetSQLDB.SetMaxIdleConns(10)
+	targetSQLDB.SetConnMaxLifetime(time.Hour)
+	return target, nil
+}
+
+// openSQLite opens (or creates) a SQLite file, for tests and small offline installs.
+// Path ":memory:" gives a private in-memory database.
+func openSQLite(cfg Config) (*gorm.DB, error) {
+	path := cfg.Path
+	if path == "" {
+		path = "academic_suite.db"
+	}
+	memory := path == ":memory:"
+
+	// Foreign keys are off by default in SQLite; WAL lets readers run next to the single writer
+	pragmas := []string{"foreign_keys(1)", "busy_timeout(5000)"}
+	if !memory {
+		pragmas = append(pragmas, "journal_mode(WAL)")
+	}
+	dsn := path + "?_pragma=" + strings.Join(pragmas, "&_pragma=")
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if memory {
+		// Every connection to :memory: is a separate database, so keep exactly one
+		sqlDB.SetMaxOpenConns(1)
+	} else {
+		sqlDB.SetMaxOpenConns(8)
+	}
+	return db, nil
+}
@@ -0,0 +1,314 @@
+package database
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Versioned SQL migrations. Files live in database/migrations/<dialect> as
+// NNNN_name.up.sql / NNNN_name.down.sql and are embedded in the binary.
+// Each dialect (postgres, sqlite) has its own sequence.
+// Applied versions are recorded in schema_migrations together with the checksum
+// of the up file, so an edited migration is detected as drift.
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// migrationLockID is an arbitrary key for pg_advisory_lock so two migrate runs never overlap
+const migrationLockID int64 = 31_0001
+
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// SchemaMigration is a row of the schema_migrations table
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	Checksum  string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (SchemaMigration) TableName() string { return "schema_migrations" }
+
+type MigrationState string
+
+const (
+	MigrationApplied MigrationState = "applied"
+	MigrationPending MigrationState = "pending"
+	MigrationDrifted MigrationState = "drifted" // applied, but the file changed since
+	MigrationUnknown MigrationState = "unknown" // applied, but no file for it in this binary
+)
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	State     MigrationState
+	AppliedAt *time.Time
+}
+
+// LoadMigrations reads and validates the embedded migration files for the connected dialect, sorted by version
+func LoadMigrations() ([]Migration, error) {
+	return loadMigrations(DB.Dialector.Name())
+}
+
+func loadMigrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for dialect %s: %w", dialect, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		m := migrationFileRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", e.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		content, err := migrationFiles.ReadFile(path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %04d has two names: %s and %s", version, mig.Name, m[2])
+		}
+
+		if m[3] == "up" {
+			mig.Up = string(content)
+			sum := sha256.Sum256(content)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func ensureMigrationsTable() error {
+	timestampType := "timestamptz"
+	if DB.Dialector.Name() == "sqlite" {
+		timestampType = "datetime"
+	}
+	return DB.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    bigint PRIMARY KEY,
+		name       text NOT NULL,
+		checksum   text NOT NULL,
+		applied_at ` + timestampType + ` NOT NULL
+	)`).Error
+}
+
+func appliedMigrations() (map[int]SchemaMigration, error) {
+	var rows []SchemaMigration
+	if err := DB.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int]SchemaMigration, len(rows))
+	for _, r := range rows {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// GetMigrationStatus compares the embedded migrations with schema_migrations
+func GetMigrationStatus() ([]MigrationStatus, error) {
+	if err := ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []MigrationStatus{}
+	known := make(map[int]bool)
+	for _, m := range migrations {
+		known[m.Version] = true
+		st := MigrationStatus{Version: m.Version, Name: m.Name, State: MigrationPending}
+		if row, ok := applied[m.Version]; ok {
+			appliedAt := row.AppliedAt
+			st.AppliedAt = &appliedAt
+			st.State = MigrationApplied
+			if row.Checksum != m.Checksum {
+				st.State = MigrationDrifted
+			}
+		}
+		statuses = append(statuses, st)
+	}
+	for v, row := range applied {
+		if !known[v] {
+			appliedAt := row.AppliedAt
+			statuses = append(statuses, MigrationStatus{Version: v, Name: row.Name, State: MigrationUnknown, AppliedAt: &appliedAt})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// VerifySchema returns an error unless every migration is applied and unchanged
+func VerifySchema() error {
+	statuses, err := GetMigrationStatus()
+	if err != nil {
+		return err
+	}
+	var pending, drifted, unknown []string
+	for _, s := range statuses {
+		label := fmt.Sprintf("%04d_%s", s.Version, s.Name)
+		switch s.State {
+		case MigrationPending:
+			pending = append(pending, label)
+		case MigrationDrifted:
+			drifted = append(drifted, label)
+		case MigrationUnknown:
+			unknown = append(unknown, label)
+		}
+	}
+	if len(drifted) > 0 {
+		return fmt.Errorf("schema drift: applied migrations changed since they ran: %v", drifted)
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("database has migrations this binary does not know (newer release?): %v", unknown)
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("pending migrations: %v (run `migrate up`)", pending)
+	}
+	return nil
+}
+
+// withMigrationLock serializes migrate runs across processes (Postgres only)
+func withMigrationLock(fn func() error) error {
+	if DB.Dialector.Name() != "postgres" {
+		return fn()
+	}
+	// Session-level lock, so it must be taken and released on one pinned connection
+	return DB.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockID).Error; err != nil {
+			return err
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockID)
+		return fn()
+	})
+}
+
+// MigrateUp applies up to steps pending migrations (steps <= 0 = all).
+// Refuses to run while any applied migration has drifted.
+func MigrateUp(steps int) error {
+	return withMigrationLock(func() error {
+		statuses, err := GetMigrationStatus()
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			if s.State == MigrationDrifted {
+				return fmt.Errorf("migration %04d_%s has drifted; fix it before migrating", s.Version, s.Name)
+			}
+		}
+
+		migrations, _ := LoadMigrations()
+		applied, err := appliedMigrations()
+		if err != nil {
+			return err
+		}
+
+		count := 0
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			if steps > 0 && count >= steps {
+				break
+			}
+			log.Printf("Applying migration %04d_%s...", m.Version, m.Name)
+			err := DB.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(m.Up).Error; err != nil {
+					return err
+				}
+				return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, Checksum: m.Checksum, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s failed: %w", m.Version, m.Name, err)
+			}
+			count++
+		}
+		log.Printf("Applied %d migration(s)", count)
+		return nil
+	})
+}
+
+// MigrateDown rolls back the last steps applied migrations (steps <= 0 = 1)
+func MigrateDown(steps int) error {
+	if steps <= 0 {
+		steps = 1
+	}
+	return withMigrationLock(func() error {
+		if err := ensureMigrationsTable(); err != nil {
+			return err
+		}
+		migrations, err := LoadMigrations()
+		if err != nil {
+			return err
+		}
+		byVersion := make(map[int]Migration, len(migrations))
+		for _, m := range migrations {
+			byVersion[m.Version] = m
+		}
+
+		var rows []SchemaMigration
+		if err := DB.Order("version desc").Limit(steps).Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			m, ok := byVersion[row.Version]
+			if !ok {
+				return fmt.Errorf("no down file for applied migration %04d_%s", row.Version, row.Name)
+			}
+			log.Printf("Reverting migration %04d_%s...", m.Version, m.Name)
+			err := DB.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(m.Down).Error; err != nil {
+					return err
+				}
+				return tx.Delete(&SchemaMigration{}, "version = ?", m.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("revert of %04d_%s failed: %w", m.Version, m.Name, err)
+			}
+		}
+		log.Printf("Reverted %d migration(s)", len(rows))
+		return nil
+	})
+}
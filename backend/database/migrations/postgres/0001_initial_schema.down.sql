@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS password_reset_tokens;
+DROP TABLE IF EXISTS classes;
+DROP TABLE IF EXISTS event_logs;
+DROP TABLE IF EXISTS answers;
+DROP TABLE IF EXISTS attempts;
+DROP TABLE IF EXISTS exam_batches;
+DROP TABLE IF EXISTS question_options;
+DROP TABLE IF EXISTS questions;
+DROP TABLE IF EXISTS quizzes;
+DROP TABLE IF EXISTS subjects;
+DROP TABLE IF EXISTS institutions;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,144 @@
+-- Baseline: the schema as GORM AutoMigrate created it before versioned migrations.
+-- Everything is IF NOT EXISTS so databases created by AutoMigrate adopt this version unchanged.
+
+CREATE TABLE IF NOT EXISTS users (
+    id             text PRIMARY KEY,
+    email          text NOT NULL,
+    password       text,
+    name           text,
+    role           text,
+    institution_id text,
+    avatar_url     text,
+    created_at     timestamptz,
+    CONSTRAINT uni_users_email UNIQUE (email)
+);
+
+CREATE TABLE IF NOT EXISTS institutions (
+    id         text PRIMARY KEY,
+    name       text,
+    type       text,
+    address    text,
+    created_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS subjects (
+    id             text PRIMARY KEY,
+    department_id  text,
+    name           text,
+    code           text,
+    credits        bigint,
+    teacher_ids    text,
+    institution_id text
+);
+
+CREATE TABLE IF NOT EXISTS quizzes (
+    id             text PRIMARY KEY,
+    subject_id     text,
+    title          text,
+    description    text,
+    exam_type      text,
+    total_points   bigint,
+    passing_score  bigint,
+    status         text DEFAULT 'active',
+    institution_id text,
+    created_by     text,
+    created_at     timestamptz,
+    updated_at     timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS questions (
+    id             text PRIMARY KEY,
+    quiz_id        text,
+    type           text,
+    text           text,
+    points         bigint,
+    correct_answer text,
+    explanation    text,
+    order_index    bigint,
+    CONSTRAINT fk_quizzes_questions FOREIGN KEY (quiz_id) REFERENCES quizzes (id)
+);
+
+CREATE TABLE IF NOT EXISTS question_options (
+    id          text PRIMARY KEY,
+    question_id text,
+    text        text,
+    is_correct  boolean,
+    CONSTRAINT fk_questions_options FOREIGN KEY (question_id) REFERENCES questions (id)
+);
+
+CREATE TABLE IF NOT EXISTS exam_batches (
+    id                   text PRIMARY KEY,
+    quiz_id              text,
+    class_id             text,
+    type                 text,
+    name                 text,
+    token                text,
+    start_time           timestamptz,
+    end_time             timestamptz,
+    duration             bigint,
+    status               text,
+    allowed_participants text,
+    waitlist             text,
+    created_by           text,
+    created_at           timestamptz,
+    frozen_at            timestamptz,
+    resumed_at           timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS attempts (
+    id                   text PRIMARY KEY,
+    batch_id             text,
+    student_id           text,
+    status               text,
+    score                decimal,
+    started_at           timestamptz,
+    submitted_at         timestamptz,
+    expired_at           timestamptz,
+    frozen_at            timestamptz,
+    remaining_time       bigint,
+    server_time          timestamptz,
+    created_at           timestamptz,
+    last_active_at       timestamptz,
+    current_question_idx bigint,
+    is_paused            boolean,
+    paused_at            timestamptz,
+    total_paused_time    bigint
+);
+
+CREATE TABLE IF NOT EXISTS answers (
+    attempt_id         text,
+    question_id        text,
+    selected_option_id text,
+    text_answer        text,
+    answered_at        timestamptz,
+    PRIMARY KEY (attempt_id, question_id),
+    CONSTRAINT fk_attempts_answers FOREIGN KEY (attempt_id) REFERENCES attempts (id)
+);
+
+CREATE TABLE IF NOT EXISTS event_logs (
+    id         text PRIMARY KEY,
+    event_type text,
+    batch_id   text,
+    attempt_id text,
+    user_id    text,
+    details    text,
+    timestamp  timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS classes (
+    id          text PRIMARY KEY,
+    name        text,
+    subject_id  text,
+    teacher_id  text,
+    student_ids text,
+    created_at  timestamptz,
+    updated_at  timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    token      text PRIMARY KEY,
+    user_id    text,
+    expires_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
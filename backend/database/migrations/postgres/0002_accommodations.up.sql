@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS accommodations (
+    id                   text PRIMARY KEY,
+    student_id           text,
+    batch_id             text,
+    extra_time_percent   bigint,
+    extra_time_minutes   bigint,
+    extended_end_minutes bigint,
+    allow_breaks         boolean,
+    max_break_minutes    bigint,
+    notes                text,
+    created_by           text,
+    created_at           timestamptz,
+    updated_at           timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_accommodations_student_id ON accommodations (student_id);
+CREATE INDEX IF NOT EXISTS idx_accommodations_batch_id ON accommodations (batch_id);
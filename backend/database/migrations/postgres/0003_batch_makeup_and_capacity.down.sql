@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_exam_batches_parent_batch_id;
+ALTER TABLE exam_batches DROP COLUMN IF EXISTS no_show_grace_minutes;
+ALTER TABLE exam_batches DROP COLUMN IF EXISTS capacity;
+ALTER TABLE exam_batches DROP COLUMN IF EXISTS parent_batch_id;
@@ -0,0 +1,4 @@
+ALTER TABLE exam_batches ADD COLUMN IF NOT EXISTS parent_batch_id text;
+ALTER TABLE exam_batches ADD COLUMN IF NOT EXISTS capacity bigint;
+ALTER TABLE exam_batches ADD COLUMN IF NOT EXISTS no_show_grace_minutes bigint;
+CREATE INDEX IF NOT EXISTS idx_exam_batches_parent_batch_id ON exam_batches (parent_batch_id);
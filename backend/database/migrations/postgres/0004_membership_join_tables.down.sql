@@ -0,0 +1,19 @@
+ALTER TABLE classes ADD COLUMN student_ids text;
+ALTER TABLE subjects ADD COLUMN teacher_ids text;
+ALTER TABLE exam_batches ADD COLUMN allowed_participants text;
+ALTER TABLE exam_batches ADD COLUMN waitlist text;
+
+UPDATE classes SET student_ids = COALESCE(
+    (SELECT json_agg(student_id ORDER BY student_id)::text FROM class_students WHERE class_id = classes.id), '[]');
+UPDATE subjects SET teacher_ids = COALESCE(
+    (SELECT json_agg(teacher_id ORDER BY teacher_id)::text FROM subject_teachers WHERE subject_id = subjects.id), '[]');
+UPDATE exam_batches SET allowed_participants = COALESCE(
+    (SELECT json_agg(student_id ORDER BY created_at, student_id)::text FROM batch_participants WHERE batch_id = exam_batches.id), '[]');
+UPDATE exam_batches SET waitlist = COALESCE(
+    (SELECT json_agg(student_id ORDER BY position)::text FROM batch_waitlist_entries WHERE batch_id = exam_batches.id), '[]');
+
+DROP TABLE batch_waitlist_entries;
+DROP TABLE batch_participants;
+DROP TABLE subject_teachers;
+DROP TABLE class_students;
+DROP TABLE IF EXISTS legacy_membership_skipped;
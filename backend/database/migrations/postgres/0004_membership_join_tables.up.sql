@@ -0,0 +1,130 @@
+-- Replace the JSON-array text columns with join tables.
+-- IDs that do not match an existing user are dropped (they would violate the foreign keys).
+-- Values that are not a JSON array are skipped rather than aborting the migration, and are kept
+-- in legacy_membership_skipped for review.
+-- Safe to run on databases where the join tables already exist (created by AutoMigrate before this subsystem).
+
+CREATE TABLE IF NOT EXISTS class_students (
+    class_id   text NOT NULL,
+    student_id text NOT NULL,
+    PRIMARY KEY (class_id, student_id),
+    CONSTRAINT fk_class_students_class FOREIGN KEY (class_id) REFERENCES classes (id) ON DELETE CASCADE,
+    CONSTRAINT fk_class_students_student FOREIGN KEY (student_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_class_students_student_id ON class_students (student_id);
+
+CREATE TABLE IF NOT EXISTS subject_teachers (
+    subject_id text NOT NULL,
+    teacher_id text NOT NULL,
+    PRIMARY KEY (subject_id, teacher_id),
+    CONSTRAINT fk_subject_teachers_subject FOREIGN KEY (subject_id) REFERENCES subjects (id) ON DELETE CASCADE,
+    CONSTRAINT fk_subject_teachers_teacher FOREIGN KEY (teacher_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_subject_teachers_teacher_id ON subject_teachers (teacher_id);
+
+CREATE TABLE IF NOT EXISTS batch_participants (
+    batch_id   text NOT NULL,
+    student_id text NOT NULL,
+    created_at timestamptz,
+    PRIMARY KEY (batch_id, student_id),
+    CONSTRAINT fk_batch_participants_batch FOREIGN KEY (batch_id) REFERENCES exam_batches (id) ON DELETE CASCADE,
+    CONSTRAINT fk_batch_participants_student FOREIGN KEY (student_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_batch_participants_student_id ON batch_participants (student_id);
+
+CREATE TABLE IF NOT EXISTS batch_waitlist_entries (
+    batch_id   text NOT NULL,
+    student_id text NOT NULL,
+    position   bigint,
+    created_at timestamptz,
+    PRIMARY KEY (batch_id, student_id),
+    CONSTRAINT fk_batch_waitlist_entries_batch FOREIGN KEY (batch_id) REFERENCES exam_batches (id) ON DELETE CASCADE,
+    CONSTRAINT fk_batch_waitlist_entries_student FOREIGN KEY (student_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_batch_waitlist_entries_student_id ON batch_waitlist_entries (student_id);
+CREATE INDEX IF NOT EXISTS idx_batch_waitlist_entries_position ON batch_waitlist_entries (position);
+
+-- Data migration. The legacy columns are (re)declared so the copy also works when they were already dropped.
+
+ALTER TABLE classes ADD COLUMN IF NOT EXISTS student_ids text;
+ALTER TABLE subjects ADD COLUMN IF NOT EXISTS teacher_ids text;
+ALTER TABLE exam_batches ADD COLUMN IF NOT EXISTS allowed_participants text;
+ALTER TABLE exam_batches ADD COLUMN IF NOT EXISTS waitlist text;
+
+-- id_list parses a legacy column, NULL when it is not a JSON array
+CREATE OR REPLACE FUNCTION pg_temp.id_list(raw text) RETURNS jsonb AS $$
+BEGIN
+    IF jsonb_typeof(raw::jsonb) = 'array' THEN
+        RETURN raw::jsonb;
+    END IF;
+    RETURN NULL;
+EXCEPTION WHEN others THEN
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+CREATE TABLE IF NOT EXISTS legacy_membership_skipped (
+    table_name  text NOT NULL,
+    row_id      text NOT NULL,
+    column_name text NOT NULL,
+    raw_value   text,
+    PRIMARY KEY (table_name, row_id, column_name)
+);
+
+INSERT INTO legacy_membership_skipped (table_name, row_id, column_name, raw_value)
+SELECT 'classes', id, 'student_ids', student_ids FROM classes
+WHERE COALESCE(student_ids, '') <> '' AND pg_temp.id_list(student_ids) IS NULL
+UNION ALL
+SELECT 'subjects', id, 'teacher_ids', teacher_ids FROM subjects
+WHERE COALESCE(teacher_ids, '') <> '' AND pg_temp.id_list(teacher_ids) IS NULL
+UNION ALL
+SELECT 'exam_batches', id, 'allowed_participants', allowed_participants FROM exam_batches
+WHERE COALESCE(allowed_participants, '') <> '' AND pg_temp.id_list(allowed_participants) IS NULL
+UNION ALL
+SELECT 'exam_batches', id, 'waitlist', waitlist FROM exam_batches
+WHERE COALESCE(waitlist, '') <> '' AND pg_temp.id_list(waitlist) IS NULL
+ON CONFLICT DO NOTHING;
+
+DO $$
+DECLARE
+    skipped bigint;
+BEGIN
+    SELECT count(*) INTO skipped FROM legacy_membership_skipped;
+    IF skipped > 0 THEN
+        RAISE WARNING '% legacy membership value(s) are not JSON arrays and were skipped, see legacy_membership_skipped', skipped;
+    END IF;
+END;
+$$;
+
+INSERT INTO class_students (class_id, student_id)
+SELECT src.id, j.member
+FROM (SELECT id, pg_temp.id_list(student_ids) AS ids FROM classes WHERE COALESCE(student_ids, '') <> '') src
+CROSS JOIN LATERAL jsonb_array_elements_text(COALESCE(src.ids, '[]'::jsonb)) AS j(member)
+JOIN users u ON u.id = j.member
+ON CONFLICT DO NOTHING;
+
+INSERT INTO subject_teachers (subject_id, teacher_id)
+SELECT src.id, j.member
+FROM (SELECT id, pg_temp.id_list(teacher_ids) AS ids FROM subjects WHERE COALESCE(teacher_ids, '') <> '') src
+CROSS JOIN LATERAL jsonb_array_elements_text(COALESCE(src.ids, '[]'::jsonb)) AS j(member)
+JOIN users u ON u.id = j.member
+ON CONFLICT DO NOTHING;
+
+INSERT INTO batch_participants (batch_id, student_id, created_at)
+SELECT src.id, j.member, now()
+FROM (SELECT id, pg_temp.id_list(allowed_participants) AS ids FROM exam_batches WHERE COALESCE(allowed_participants, '') <> '') src
+CROSS JOIN LATERAL jsonb_array_elements_text(COALESCE(src.ids, '[]'::jsonb)) AS j(member)
+JOIN users u ON u.id = j.member
+ON CONFLICT DO NOTHING;
+
+INSERT INTO batch_waitlist_entries (batch_id, student_id, position, created_at)
+SELECT src.id, j.member, j.ord - 1, now()
+FROM (SELECT id, pg_temp.id_list(waitlist) AS ids FROM exam_batches WHERE COALESCE(waitlist, '') <> '') src
+CROSS JOIN LATERAL jsonb_array_elements_text(COALESCE(src.ids, '[]'::jsonb)) WITH ORDINALITY AS j(member, ord)
+JOIN users u ON u.id = j.member
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE classes DROP COLUMN IF EXISTS student_ids;
+ALTER TABLE subjects DROP COLUMN IF EXISTS teacher_ids;
+ALTER TABLE exam_batches DROP COLUMN IF EXISTS allowed_participants;
+ALTER TABLE exam_batches DROP COLUMN IF EXISTS waitlist;
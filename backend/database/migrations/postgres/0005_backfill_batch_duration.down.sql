@@ -0,0 +1,2 @@
+-- Data backfill, nothing to undo.
+SELECT 1;
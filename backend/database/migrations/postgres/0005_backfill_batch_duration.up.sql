@@ -0,0 +1,5 @@
+-- Batches created before CreateBatch derived Duration from the schedule were stored with duration 0
+-- (previously patched by hand with scripts/fix_batch.go).
+UPDATE exam_batches
+SET duration = GREATEST(1, FLOOR(EXTRACT(EPOCH FROM (end_time - start_time)) / 60))
+WHERE COALESCE(duration, 0) = 0 AND end_time > start_time;
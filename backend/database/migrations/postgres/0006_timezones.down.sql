@@ -0,0 +1,2 @@
+ALTER TABLE exam_batches DROP COLUMN IF EXISTS timezone;
+ALTER TABLE institutions DROP COLUMN IF EXISTS timezone;
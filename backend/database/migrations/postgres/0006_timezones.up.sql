@@ -0,0 +1,5 @@
+-- Batch schedules are entered as wall-clock times in the institution's zone
+-- Previously they were read in whichever zone the client happened to send (UTC vs +07:00 mix-ups).
+ALTER TABLE institutions ADD COLUMN IF NOT EXISTS timezone text NOT NULL DEFAULT 'Asia/Jakarta';
+-- Zone the batch schedule was entered in; empty means the default zone
+ALTER TABLE exam_batches ADD COLUMN IF NOT EXISTS timezone text NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE subjects DROP COLUMN IF EXISTS final_weight;
+ALTER TABLE subjects DROP COLUMN IF EXISTS midterm_weight;
+ALTER TABLE subjects DROP COLUMN IF EXISTS daily_quiz_weight;
@@ -0,0 +1,2 @@
+-- Topic (kompetensi dasar / chapter) of a question, used for per-topic breakdowns in reports
+ALTER TABLE questions ADD COLUMN IF NOT EXISTS topic text NOT NULL DEFAULT '';
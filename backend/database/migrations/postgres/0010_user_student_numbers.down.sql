@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_users_nisn;
+DROP INDEX IF EXISTS idx_users_nis;
+ALTER TABLE users DROP COLUMN IF EXISTS nisn;
+ALTER TABLE users DROP COLUMN IF EXISTS nis;
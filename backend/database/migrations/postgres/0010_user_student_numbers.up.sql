@@ -0,0 +1,5 @@
+-- Student numbers: NIS (school-issued) and NISN (national, 10 digits), used to match imported rows
+ALTER TABLE users ADD COLUMN IF NOT EXISTS nis text NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS nisn text NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_users_nis ON users (nis);
+CREATE INDEX IF NOT EXISTS idx_users_nisn ON users (nisn);
@@ -0,0 +1,4 @@
+ALTER TABLE question_options DROP COLUMN IF EXISTS image_id;
+ALTER TABLE questions DROP COLUMN IF EXISTS audio_id;
+ALTER TABLE questions DROP COLUMN IF EXISTS image_id;
+DROP TABLE IF EXISTS media;
@@ -0,0 +1,17 @@
+DROP TABLE batch_waitlist_entries;
+DROP TABLE batch_participants;
+DROP TABLE subject_teachers;
+DROP TABLE class_students;
+DROP TABLE accommodations;
+DROP TABLE password_reset_tokens;
+DROP TABLE classes;
+DROP TABLE event_logs;
+DROP TABLE answers;
+DROP TABLE attempts;
+DROP TABLE exam_batches;
+DROP TABLE question_options;
+DROP TABLE questions;
+DROP TABLE quizzes;
+DROP TABLE subjects;
+DROP TABLE institutions;
+DROP TABLE users;
@@ -0,0 +1,189 @@
+-- SQLite baseline: the schema of Postgres migrations 0001-0005 in one step.
+-- Keep new migrations for both dialects in sync (same meaning, dialect-specific SQL).
+
+CREATE TABLE users (
+    id             text PRIMARY KEY,
+    email          text NOT NULL UNIQUE,
+    password       text,
+    name           text,
+    role           text,
+    institution_id text,
+    avatar_url     text,
+    created_at     datetime
+);
+
+CREATE TABLE institutions (
+    id         text PRIMARY KEY,
+    name       text,
+    type       text,
+    address    text,
+    created_at datetime
+);
+
+CREATE TABLE subjects (
+    id             text PRIMARY KEY,
+    department_id  text,
+    name           text,
+    code           text,
+    credits        integer,
+    institution_id text
+);
+
+CREATE TABLE quizzes (
+    id             text PRIMARY KEY,
+    subject_id     text,
+    title          text,
+    description    text,
+    exam_type      text,
+    total_points   integer,
+    passing_score  integer,
+    status         text DEFAULT 'active',
+    institution_id text,
+    created_by     text,
+    created_at     datetime,
+    updated_at     datetime
+);
+
+CREATE TABLE questions (
+    id             text PRIMARY KEY,
+    quiz_id        text REFERENCES quizzes (id),
+    type           text,
+    text           text,
+    points         integer,
+    correct_answer text,
+    explanation    text,
+    order_index    integer
+);
+
+CREATE TABLE question_options (
+    id          text PRIMARY KEY,
+    question_id text REFERENCES questions (id),
+    text        text,
+    is_correct  numeric
+);
+
+CREATE TABLE exam_batches (
+    id                    text PRIMARY KEY,
+    quiz_id               text,
+    class_id              text,
+    type                  text,
+    name                  text,
+    token                 text,
+    start_time            datetime,
+    end_time              datetime,
+    duration              integer,
+    status                text,
+    parent_batch_id       text,
+    capacity              integer,
+    no_show_grace_minutes integer,
+    created_by            text,
+    created_at            datetime,
+    frozen_at             datetime,
+    resumed_at            datetime
+);
+CREATE INDEX idx_exam_batches_parent_batch_id ON exam_batches (parent_batch_id);
+
+CREATE TABLE attempts (
+    id                   text PRIMARY KEY,
+    batch_id             text,
+    student_id           text,
+    status               text,
+    score                real,
+    started_at           datetime,
+    submitted_at         datetime,
+    expired_at           datetime,
+    frozen_at            datetime,
+    remaining_time       integer,
+    server_time          datetime,
+    created_at           datetime,
+    last_active_at       datetime,
+    current_question_idx integer,
+    is_paused            numeric,
+    paused_at            datetime,
+    total_paused_time    integer
+);
+
+CREATE TABLE answers (
+    attempt_id         text REFERENCES attempts (id),
+    question_id        text,
+    selected_option_id text,
+    text_answer        text,
+    answered_at        datetime,
+    PRIMARY KEY (attempt_id, question_id)
+);
+
+CREATE TABLE event_logs (
+    id         text PRIMARY KEY,
+    event_type text,
+    batch_id   text,
+    attempt_id text,
+    user_id    text,
+    details    text,
+    timestamp  datetime
+);
+
+CREATE TABLE classes (
+    id         text PRIMARY KEY,
+    name       text,
+    subject_id text,
+    teacher_id text,
+    created_at datetime,
+    updated_at datetime
+);
+
+CREATE TABLE password_reset_tokens (
+    token      text PRIMARY KEY,
+    user_id    text,
+    expires_at datetime,
+    created_at datetime
+);
+CREATE INDEX idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
+
+CREATE TABLE accommodations (
+    id                   text PRIMARY KEY,
+    student_id           text,
+    batch_id             text,
+    extra_time_percent   integer,
+    extra_time_minutes   integer,
+    extended_end_minutes integer,
+    allow_breaks         numeric,
+    max_break_minutes    integer,
+    notes                text,
+    created_by           text,
+    created_at           datetime,
+    updated_at           datetime
+);
+CREATE INDEX idx_accommodations_student_id ON accommodations (student_id);
+CREATE INDEX idx_accommodations_batch_id ON accommodations (batch_id);
+
+CREATE TABLE class_students (
+    class_id   text NOT NULL REFERENCES classes (id) ON DELETE CASCADE,
+    student_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    PRIMARY KEY (class_id, student_id)
+);
+CREATE INDEX idx_class_students_student_id ON class_students (student_id);
+
+CREATE TABLE subject_teachers (
+    subject_id text NOT NULL REFERENCES subjects (id) ON DELETE CASCADE,
+    teacher_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    PRIMARY KEY (subject_id, teacher_id)
+);
+CREATE INDEX idx_subject_teachers_teacher_id ON subject_teachers (teacher_id);
+
+CREATE TABLE batch_participants (
+    batch_id   text NOT NULL REFERENCES exam_batches (id) ON DELETE CASCADE,
+    student_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at datetime,
+    PRIMARY KEY (batch_id, student_id)
+);
+CREATE INDEX idx_batch_participants_student_id ON batch_participants (student_id);
+
+CREATE TABLE batch_waitlist_entries (
+    batch_id   text NOT NULL REFERENCES exam_batches (id) ON DELETE CASCADE,
+    student_id text NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    position   integer,
+    created_at datetime,
+    PRIMARY KEY (batch_id, student_id)
+);
+CREATE INDEX idx_batch_waitlist_entries_student_id ON batch_waitlist_entries (student_id);
+CREATE INDEX idx_batch_waitlist_entries_position ON batch_waitlist_entries (position);
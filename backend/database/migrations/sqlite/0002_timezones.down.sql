@@ -0,0 +1,2 @@
+ALTER TABLE exam_batches DROP COLUMN timezone;
+ALTER TABLE institutions DROP COLUMN timezone;
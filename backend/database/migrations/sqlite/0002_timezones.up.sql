@@ -0,0 +1,4 @@
+-- Batch schedules are entered as wall-clock times in the institution's zone
+ALTER TABLE institutions ADD COLUMN timezone text NOT NULL DEFAULT 'Asia/Jakarta';
+-- Zone the batch schedule was entered in; empty means the default zone
+ALTER TABLE exam_batches ADD COLUMN timezone text NOT NULL DEFAULT '';
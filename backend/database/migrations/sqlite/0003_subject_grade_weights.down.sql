@@ -0,0 +1,3 @@
+ALTER TABLE subjects DROP COLUMN final_weight;
+ALTER TABLE subjects DROP COLUMN midterm_weight;
+ALTER TABLE subjects DROP COLUMN daily_quiz_weight;
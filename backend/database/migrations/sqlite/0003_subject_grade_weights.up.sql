@@ -0,0 +1,4 @@
+-- Weights (percent) of each exam type in a subject's gradebook final grade; practice quizzes are not graded
+ALTER TABLE subjects ADD COLUMN daily_quiz_weight integer NOT NULL DEFAULT 30;
+ALTER TABLE subjects ADD COLUMN midterm_weight integer NOT NULL DEFAULT 30;
+ALTER TABLE subjects ADD COLUMN final_weight integer NOT NULL DEFAULT 40;
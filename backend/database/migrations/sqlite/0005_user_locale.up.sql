@@ -0,0 +1,2 @@
+-- Preferred language for error messages and generated documents ('' = follow the browser)
+ALTER TABLE users ADD COLUMN locale text NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+DROP INDEX idx_users_nisn;
+DROP INDEX idx_users_nis;
+ALTER TABLE users DROP COLUMN nisn;
+ALTER TABLE users DROP COLUMN nis;
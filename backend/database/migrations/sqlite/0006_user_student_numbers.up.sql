@@ -0,0 +1,5 @@
+-- Student numbers: NIS (school-issued) and NISN (national, 10 digits), used to match imported rows
+ALTER TABLE users ADD COLUMN nis text NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN nisn text NOT NULL DEFAULT '';
+CREATE INDEX idx_users_nis ON users (nis);
+CREATE INDEX idx_users_nisn ON users (nisn);
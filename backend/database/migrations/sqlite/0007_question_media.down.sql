@@ -0,0 +1,4 @@
+ALTER TABLE question_options DROP COLUMN image_id;
+ALTER TABLE questions DROP COLUMN audio_id;
+ALTER TABLE questions DROP COLUMN image_id;
+DROP TABLE media;
@@ -0,0 +1,18 @@
+-- Uploaded images and audio; the files live in the blob store (media.dir), keyed by storage_key
+CREATE TABLE media (
+    id             text PRIMARY KEY,
+    institution_id text,
+    kind           text,
+    filename       text,
+    content_type   text,
+    size           integer,
+    storage_key    text,
+    uploaded_by    text,
+    created_at     datetime
+);
+CREATE INDEX idx_media_institution_id ON media (institution_id);
+
+-- Attachments of questions (an image and/or listening audio) and of choices (an image)
+ALTER TABLE questions ADD COLUMN image_id text NOT NULL DEFAULT '';
+ALTER TABLE questions ADD COLUMN audio_id text NOT NULL DEFAULT '';
+ALTER TABLE question_options ADD COLUMN image_id text NOT NULL DEFAULT '';
@@ -0,0 +1,294 @@
+package database
+
+import (
+	"academic-suite-backend/models"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Seeding is an explicit step (`./main seed ...`), not a side effect of Connect.
+// Every dataset is idempotent: running it twice leaves the database unchanged.
+
+type Dataset struct {
+	Name        string
+	Description string
+	Requires    []string // datasets that must be seeded first
+	Default     bool     // part of `seed` without arguments
+	Run         func() error
+}
+
+var Datasets = []Dataset{
+	{
+		Name:        "demo-users",
+		Description: "Admin, teacher and student demo accounts",
+		Default:     true,
+		Run:         seedUsers,
+	},
+	{
+		Name:        "academic",
+		Description: "SMP 20 SURABAYA subjects and quizzes (30 quizzes, 20 questions each)",
+		Requires:    []string{"demo-users"},
+		Default:     true,
+		Run:         seedAcademicData,
+	},
+	{
+		Name:        "loadtest-students",
+		Description: "1000 dummy students (student-dummy-N@eduexam.com) for the stress tests",
+		Run:         seedDummyStudents,
+	},
+}
+
+// Environment returns APP_ENV, defaulting to "dev"
+func Environment() string {
+	if env := os.Getenv("APP_ENV"); env != "" {
+		return env
+	}
+	return "dev"
+}
+
+func findDataset(name string) (Dataset, bool) {
+	for _, d := range Datasets {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return Dataset{}, false
+}
+
+// resolveDatasets expands requirements and returns the datasets in run order without duplicates
+func resolveDatasets(names []string) ([]Dataset, error) {
+	if len(names) == 0 {
+		for _, d := range Datasets {
+			if d.Default {
+				names = append(names, d.Name)
+			}
+		}
+	}
+
+	var ordered []Dataset
+	added := make(map[string]bool)
+	var add func(name string) error
+	add = func(name string) error {
+		if added[name] {
+			return nil
+		}
+		d, ok := findDataset(name)
+		if !ok {
+			return fmt.Errorf("unknown dataset %q", name)
+		}
+		for _, req := range d.Requires {
+			if err := add(req); err != nil {
+				return err
+			}
+		}
+		added[name] = true
+		ordered = append(ordered, d)
+		return nil
+	}
+	for _, name := range names {
+		if err := add(name); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+type SeedOptions struct {
+	Reset bool // truncate all application tables first (dev/test only)
+}
+
+// Seed runs the named datasets (or the default ones). Refuses to run in prod.
+func Seed(names []string, opts SeedOptions) error {
+	env := Environment()
+	if env == "prod" {
+		return fmt.Errorf("seeding is disabled when APP_ENV=prod")
+	}
+
+	datasets, err := resolveDatasets(names)
+	if err != nil {
+		return err
+	}
+
+	if opts.Reset {
+		if err := ResetData(); err != nil {
+			return err
+		}
+	}
+
+	for _, d := range datasets {
+		log.Printf("Seeding dataset %s...", d.Name)
+		if err := d.Run(); err != nil {
+			return fmt.Errorf("dataset %s: %w", d.Name, err)
+		}
+	}
+	return nil
+}
+
+// ResetData truncates every application table, keeping schema_migrations.
+// Only allowed for APP_ENV dev or test.
+func ResetData() error {
+	env := Environment()
+	if env != "dev" && env != "test" {
+		return fmt.Errorf("reset is only allowed when APP_ENV is dev or test (current: %s)", env)
+	}
+
+	if DB.Dialector.Name() == DriverSQLite {
+		return resetSQLite()
+	}
+
+	var tables []string
+	if err := DB.Raw(`SELECT tablename FROM pg_tables
+		WHERE schemaname = current_schema() AND tablename <> 'schema_migrations'
+		ORDER BY tablename`).Scan(&tables).Error; err != nil {
+		return err
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+
+	log.Printf("Truncating %d tables (APP_ENV=%s)...", len(tables), env)
+	return DB.Exec("TRUNCATE TABLE " + strings.Join(tables, ", ") + " RESTART IDENTITY CASCADE").Error
+}
+
+// resetSQLite deletes all rows; SQLite has no TRUNCATE and foreign keys must be off while doing so
+func resetSQLite() error {
+	var tables []string
+	if err := DB.Raw(`SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name <> 'schema_migrations'
+		ORDER BY name`).Scan(&tables).Error; err != nil {
+		return err
+	}
+
+	log.Printf("Deleting all rows from %d tables...", len(tables))
+	// PRAGMA foreign_keys is per connection, so pin one
+	return DB.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("PRAGMA foreign_keys = OFF").Error; err != nil {
+			return err
+		}
+		defer conn.Exec("PRAGMA foreign_keys = ON")
+		for _, table := range tables {
+			if err := conn.Exec(`DELETE FROM "` + table + `"`).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func hashPassword(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(bytes), err
+}
+
+// seedUsers creates the demo accounts that do not exist yet. Existing accounts are left alone,
+// so passwords changed after seeding are kept and nothing is rehashed on every run.
+func seedUsers() error {
+	users := []struct {
+		models.User
+		PlainPassword string
+	}{
+		{models.User{ID: "admin-1", Email: "admin@eduexam.com", Name: "Dr. Admin Utama", Role: models.RoleAdmin, InstitutionID: "inst-1"}, "admin123"},
+		{models.User{ID: "teacher-1", Email: "guru@eduexam.com", Name: "Pak Budi Santoso", Role: models.RoleTeacher, InstitutionID: "inst-1"}, "guru123"},
+		{models.User{ID: "student-1", Email: "siswa@eduexam.com", Name: "Andi Pratama", Role: models.RoleStudent, InstitutionID: "inst-1"}, "siswa123"},
+	}
+
+	for _, u := range users {
+		var count int64
+		DB.Model(&models.User{}).Where("id = ? OR email = ?", u.ID, u.Email).Count(&count)
+		if count > 0 {
+			log.Printf("Demo user %s already exists, skipping", u.Email)
+			continue
+		}
+
+		hashed, err := hashPassword(u.PlainPassword)
+		if err != nil {
+			return err
+		}
+		user := u.User
+		user.Password = hashed
+		user.CreatedAt = time.Now()
+		if err := DB.Create(&user).Error; err != nil {
+			return fmt.Errorf("failed to seed user %s: %w", u.Email, err)
+		}
+		log.Printf("Seeded User: %s", u.Email)
+	}
+	return nil
+}
+
+const loadTestStudentCount = 1000
+
+// seedDummyStudents inserts the missing student-dummy-N accounts (all with password siswa123)
+func seedDummyStudents() error {
+	// Older databases have dummy students named "Siswa Dummy N", give them realistic names
+	if err := renameLegacyDummyStudents(); err != nil {
+		return err
+	}
+
+	var existing []string
+	DB.Model(&models.User{}).Where("id LIKE ?", "student-dummy-%").Pluck("id", &existing)
+	have := make(map[string]bool, len(existing))
+	for _, id := range existing {
+		have[id] = true
+	}
+
+	var users []models.User
+	for i := 1; i <= loadTestStudentCount; i++ {
+		id := fmt.Sprintf("student-dummy-%d", i)
+		if have[id] {
+			continue
+		}
+		users = append(users, models.User{
+			ID:            id,
+			Email:         fmt.Sprintf("student-dummy-%d@eduexam.com", i),
+			Name:          GenerateRandomName(),
+			Role:          models.RoleStudent,
+			InstitutionID: "inst-1",
+			CreatedAt:     time.Now(),
+		})
+	}
+	if len(users) == 0 {
+		log.Println("Dummy students already seeded.")
+		return nil
+	}
+
+	// One hash for all of them, bcrypt is deliberately slow
+	password, err := hashPassword("siswa123")
+	if err != nil {
+		return err
+	}
+	for i := range users {
+		users[i].Password = password
+	}
+
+	log.Printf("Seeding %d dummy students...", len(users))
+	if err := DB.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(users, 100).Error; err != nil {
+		return fmt.Errorf("failed to batch seed dummy students: %w", err)
+	}
+	log.Printf("Successfully seeded %d dummy students", len(users))
+	return nil
+}
+
+func renameLegacyDummyStudents() error {
+	var users []models.User
+	if err := DB.Where("name LIKE ?", "%Siswa Dummy%").Find(&users).Error; err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	log.Printf("Renaming %d legacy dummy students...", len(users))
+	for _, user := range users {
+		if err := DB.Model(&user).Update("name", GenerateRandomName()).Error; err != nil {
+			return fmt.Errorf("failed to rename user %s: %w", user.ID, err)
+		}
+	}
+	return nil
+}
@@ -5,22 +5,24 @@ import (
 	"fmt"
 	"log"
 	"time"
+
+	"gorm.io/gorm/clause"
 )
 
-func seedAcademicData() {
+func seedAcademicData() error {
 	log.Println("Seeding Academic Data for SMP 20 SURABAYA...")
 
 	// 1. Ensure Institution Exists
 	institutionID := "inst-smp20"
 	var inst models.Institution
 	if err := DB.FirstOrCreate(&inst, models.Institution{
-		ID:      institutionID,
-		Name:    "SMP 20 SURABAYA",
-		Type:    "school",
-		Address: "Surabaya, Jawa Timur",
+		ID:       institutionID,
+		Name:     "SMP 20 SURABAYA",
+		Type:     "school",
+		Address:  "Surabaya, Jawa Timur",
+		Timezone: "Asia/Jakarta",
 	}).Error; err != nil {
-		log.Println("Failed to seed institution:", err)
-		return
+		return fmt.Errorf("failed to seed institution: %w", err)
 	}
 
 	// 2. Define Subjects Metadata
@@ -62,10 +64,10 @@ func seedAcademicData() {
 			Name:          subjectName,
 			Code:          qData.SubjectCode,
 			Credits:       2,
-			TeacherIDs:    `["teacher-1"]`,
 			DepartmentID:  "dept-1",
 			InstitutionID: institutionID,
 		}).FirstOrCreate(&subject)
+		DB.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(&models.SubjectTeacher{SubjectID: subject.ID, TeacherID: "teacher-1"})
 
 		// B. Create Quiz
 		// Format ID: quiz-[CODE]-G[GRADE]-001 (e.g., quiz-MAT-G7-001)
@@ -133,4 +135,5 @@ func seedAcademicData() {
 		}
 	}
 	log.Println("Seeding Completed Successfully.")
+	return nil
 }
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/models"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// findAccommodation returns the accommodation that applies to a student in a batch.
+// A batch-specific entry wins over the student's standing profile. Returns nil if none.
+func findAccommodation(db *gorm.DB, batchID, studentID string) *models.Accommodation {
+	var accs []models.Accommodation
+	db.Where("student_id = ? AND (batch_id = ? OR batch_id = '')", studentID, batchID).Find(&accs)
+
+	var profile *models.Accommodation
+	for i := range accs {
+		if accs[i].BatchID == batchID {
+			return &accs[i]
+		}
+		profile = &accs[i]
+	}
+	return profile
+}
+
+// allowedDurationSeconds is the batch duration with extra time applied.
+func allowedDurationSeconds(batch models.ExamBatch, acc *models.Accommodation) float64 {
+	seconds := float64(batch.Duration * 60)
+	if acc == nil {
+		return seconds
+	}
+	seconds += seconds * float64(acc.ExtraTimePercent) / 100
+	seconds += float64(acc.ExtraTimeMinutes * 60)
+	return seconds
+}
+
+// effectiveBatchEnd is the batch EndTime pushed back by the student's extended end.
+func effectiveBatchEnd(batch models.ExamBatch, acc *models.Accommodation) time.Time {
+	if acc == nil || acc.ExtendedEndMinutes <= 0 {
+		return batch.EndTime
+	}
+	return batch.EndTime.Add(time.Duration(acc.ExtendedEndMinutes) * time.Minute)
+}
+
+// calculateRemainingSeconds is the single source of truth for how much time an attempt has left.
+func calculateRemainingSeconds(attempt models.Attempt, batch models.ExamBatch, acc *models.Accommodation, now time.Time) int {
+	startedAt := attempt.StartedAt
+	if startedAt == nil {
+		startedAt = &attempt.CreatedAt
+	}
+
+	// Time elapsed
+	elapsedSeconds := now.Sub(*startedAt).Seconds()
+
+	// Adjust for pauses
+	effectiveElapsed := elapsedSeconds - float64(attempt.TotalPausedTime)
+
+	// If currently paused, we don't count time since PausedAt
+	if attempt.IsPaused && attempt.PausedAt != nil {
+		currentPauseDuration := now.Sub(*attempt.PausedAt).Seconds()
+		effectiveElapsed -= currentPauseDuration
+	}
+
+	secondsUntilBatchEnd := effectiveBatchEnd(batch, acc).Sub(now).Seconds()
+
+	remaining := math.Max(0, allowedDurationSeconds(batch, acc)-effectiveElapsed)
+
+	// Cap at batch end
+	if remaining > secondsUntilBatchEnd {
+		remaining = math.Max(0, secondsUntilBatchEnd)
+	}
+
+	return int(remaining)
+}
+
+// GetAccommodations godoc
+// @Summary      Get Accommodations
+// @Description  List accommodations, optionally filtered by student or batch
+// @Tags         accommodations
+// @Produce      json
+// @Param        studentId query string false "Student ID"
+// @Param        batchId   query string false "Batch ID"
+// @Success      200  {array}  models.Accommodation
+// @Router       /api/accommodations [get]
+func (s *AccommodationService) GetAccommodations(c *fiber.Ctx) error {
+	query := s.db.Order("created_at desc")
+	if studentId := c.Query("studentId"); studentId != "" {
+		query = query.Where("student_id = ?", studentId)
+	}
+	if batchId := c.Query("batchId"); batchId != "" {
+		query = query.Where("batch_id = ?", batchId)
+	}
+
+	var accs []models.Accommodation
+	if err := query.Find(&accs).Error; err != nil {
+		return apperr.Internal("accommodations_load_failed", err)
+	}
+	return c.JSON(accs)
+}
+
+// SaveAccommodation godoc
+// @Summary      Create or Update Accommodation
+// @Description  Upsert the accommodation for a student (batchId empty = standing profile)
+// @Tags         accommodations
+// @Accept       json
+// @Produce      json
+// @Param        accommodation body models.Accommodation true "Accommodation Data"
+// @Success      200  {object}  models.Accommodation
+// @Failure      400  {object}  apperr.Response
+// @Router       /api/accommodations [post]
+func (s *AccommodationService) SaveAccommodation(c *fiber.Ctx) error {
+	var req models.Accommodation
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	userId, _ := c.Locals("userId").(string)
+	now := s.clock.Now()
+
+	var acc models.Accommodation
+	err := s.db.Where("student_id = ? AND batch_id = ?", req.StudentID, req.BatchID).First(&acc).Error
+	if err != nil {
+		acc = models.Accommodation{
+			ID:        fmt.Sprintf("acc-%d", now.UnixNano()),
+			StudentID: req.StudentID,
+			BatchID:   req.BatchID,
+			CreatedAt: now,
+		}
+	}
+
+	acc.ExtraTimePercent = req.ExtraTimePercent
+	acc.ExtraTimeMinutes = req.ExtraTimeMinutes
+	acc.ExtendedEndMinutes = req.ExtendedEndMinutes
+	acc.AllowBreaks = req.AllowBreaks
+	acc.MaxBreakMinutes = req.MaxBreakMinutes
+	acc.Notes = req.Notes
+	acc.CreatedBy = userId
+	acc.UpdatedAt = now
+
+	if err := s.db.Save(&acc).Error; err != nil {
+		return apperr.Internal("accommodation_save_failed", err)
+	}
+
+	s.events.Log("ACCOMMODATION_SAVED", acc.BatchID, "", acc.StudentID,
+		fmt.Sprintf("Accommodation set by %s: +%d%%, +%d min, end +%d min, breaks=%t", userId, acc.ExtraTimePercent, acc.ExtraTimeMinutes, acc.ExtendedEndMinutes, acc.AllowBreaks))
+
+	return c.JSON(acc)
+}
+
+// DeleteAccommodation godoc
+// @Summary      Delete Accommodation
+// @Tags         accommodations
+// @Param        id   path      string  true  "Accommodation ID"
+// @Success      200  {object}  map[string]string
+// @Router       /api/accommodations/{id} [delete]
+func (s *AccommodationService) DeleteAccommodation(c *fiber.Ctx) error {
+	id := c.Params("id")
+	var acc models.Accommodation
+	if err := s.db.First(&acc, "id = ?", id).Error; err != nil {
+		return apperr.NotFound("accommodation_not_found")
+	}
+
+	s.db.Delete(&acc)
+	return c.JSON(fiber.Map{"message": "Accommodation deleted"})
+}
+
+// BreakAttempt godoc
+// @Summary      Start a Break
+// @Description  Student-initiated pause, only for students whose accommodation allows breaks. End it with /resume.
+// @Tags         attempts
+// @Param        id   path      string true "Attempt ID"
+// @Success      200  {object}  models.Attempt
+// @Failure      403  {object}  apperr.Response
+// @Router       /api/attempts/{id}/break [post]
+func (s *AttemptService) BreakAttempt(c *fiber.Ctx) error {
+	attemptId := c.Params("id")
+	var attempt models.Attempt
+	if err := s.db.First(&attempt, "id = ?", attemptId).Error; err != nil {
+		return apperr.NotFound("attempt_not_found")
+	}
+
+	if attempt.Status != models.AttemptActive {
+		return apperr.BadRequest("attempt_not_active")
+	}
+	if attempt.IsPaused {
+		return c.JSON(attempt)
+	}
+
+	acc := findAccommodation(s.db, attempt.BatchID, attempt.StudentID)
+	if acc == nil || !acc.AllowBreaks {
+		return apperr.Forbidden("breaks_not_allowed")
+	}
+	if acc.MaxBreakMinutes > 0 && attempt.TotalPausedTime >= acc.MaxBreakMinutes*60 {
+		return apperr.Forbidden("break_allowance_used")
+	}
+
+	now := s.clock.Now()
+	attempt.IsPaused = true
+	attempt.PausedAt = &now
+	s.db.Save(&attempt)
+
+	s.events.Log(eventBreakStarted, attempt.BatchID, attempt.ID, attempt.StudentID, "Student started an accommodated break")
+
+	return c.JSON(attempt)
+}
+
+// eventBreakStarted marks a pause the student took as a break rather than one a teacher made
+const eventBreakStarted models.EventType = "ATTEMPT_BREAK_STARTED"
+
+// onBreak reports whether the attempt's current pause is a student break: the latest pause
+// event is a break start
+func (s *AttemptService) onBreak(attemptID string) bool {
+	var last models.EventLog
+	err := s.db.Where("attempt_id = ? AND event_type IN ?", attemptID, []models.EventType{eventBreakStarted, "ATTEMPT_PAUSED"}).
+		Order("timestamp desc").First(&last).Error
+	return err == nil && last.EventType == eventBreakStarted
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/i18n"
+	"academic-suite-backend/models"
+	"academic-suite-backend/psychometrics"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+)
+
+// Columns per question in the answer matrix (catalog keys)
+var matrixQuestionColumns = []string{"col.answer", "col.correct", "col.points", "col.time"}
+
+// matrixFixedColumns come before the question columns
+var matrixFixedColumns = []string{"col.no", "col.student_id", "col.student_name", "col.session", "col.status", "col.score", "col.percentage"}
+
+// writeAnswerMatrixSheet writes one row per student and a group of columns per question
+func writeAnswerMatrixSheet(f *excelize.File, l i18n.Locale, sheetName string, quiz models.Quiz, batch models.ExamBatch, slips []resultSlip) {
+	styleHeader, _ := f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{"#E0E0E0"}, Pattern: 1},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
+	})
+
+	f.SetCellValue(sheetName, "A1", l.T("matrix.title", quiz.Title, batch.Name))
+
+	// Two header rows: fixed columns span both, question numbers are merged over their group
+	for i, h := range matrixFixedColumns {
+		top, _ := excelize.CoordinatesToCellName(i+1, 3)
+		bottom, _ := excelize.CoordinatesToCellName(i+1, 4)
+		f.SetCellValue(sheetName, top, l.T(h))
+		f.MergeCell(sheetName, top, bottom)
+	}
+	for i, q := range quiz.Questions {
+		first := len(matrixFixedColumns) + i*len(matrixQuestionColumns) + 1
+		start, _ := excelize.CoordinatesToCellName(first, 3)
+		end, _ := excelize.CoordinatesToCellName(first+len(matrixQuestionColumns)-1, 3)
+		f.SetCellValue(sheetName, start, l.T("matrix.question", i+1, q.Points))
+		f.MergeCell(sheetName, start, end)
+		for j, h := range matrixQuestionColumns {
+			cell, _ := excelize.CoordinatesToCellName(first+j, 4)
+			f.SetCellValue(sheetName, cell, l.T(h))
+		}
+	}
+	lastCol := len(matrixFixedColumns) + len(quiz.Questions)*len(matrixQuestionColumns)
+	lastHeader, _ := excelize.CoordinatesToCellName(lastCol, 4)
+	f.SetCellStyle(sheetName, "A3", lastHeader, styleHeader)
+
+	for i, slip := range slips {
+		row := i + 5
+		values := []interface{}{i + 1, slip.StudentID, slip.StudentName, sessionLabel(l, slip.IsMakeup), string(slip.Status), slip.Score, round2(slip.Percentage)}
+		for _, q := range slip.Questions {
+			answer := q.Choice
+			if answer == "" {
+				answer = q.Answer // typed answers
+			}
+			var correct, earned, answeredAt interface{}
+			if q.Correct != nil {
+				correct = 0
+				if *q.Correct {
+					correct = 1
+				}
+			}
+			if q.Earned != nil {
+				earned = *q.Earned
+			}
+			if q.AnsweredAt != nil {
+				answeredAt = q.AnsweredAt.Format("2006-01-02 15:04:05")
+			}
+			values = append(values, answer, correct, earned, answeredAt)
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, row)
+		f.SetSheetRow(sheetName, cell, &values)
+	}
+
+	f.SetColWidth(sheetName, "B", "C", 24)
+	f.SetPanes(sheetName, &excelize.Panes{
+		Freeze:      true,
+		XSplit:      3,
+		YSplit:      4,
+		TopLeftCell: "D5",
+		ActivePane:  "bottomRight",
+	})
+}
+
+// writeMatrixStatisticsSheet writes the score summary and per-question counts of submitted attempts
+func writeMatrixStatisticsSheet(f *excelize.File, l i18n.Locale, quiz models.Quiz, slips []resultSlip, rel *ReliabilityResponse) {
+	sheetName := l.T("sheet.statistics")
+	f.NewSheet(sheetName)
+	styleHeader, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"#E0E0E0"}, Pattern: 1},
+	})
+
+	var submitted []resultSlip
+	var scores []float64
+	passed := 0
+	for _, slip := range slips {
+		if slip.Status != models.AttemptSubmitted {
+			continue
+		}
+		submitted = append(submitted, slip)
+		scores = append(scores, slip.Score)
+		if slip.Passed {
+			passed++
+		}
+	}
+	summary := psychometrics.Describe(scores)
+
+	f.SetCellValue(sheetName, "A1", l.T("stats.title"))
+	rows := [][]interface{}{
+		{l.T("stats.participants"), len(slips)},
+		{l.T("label.submitted"), len(submitted)},
+		{l.T("label.average"), round2(summary.Mean)},
+		{l.T("stats.median"), round2(summary.Median)},
+		{l.T("stats.stddev"), round2(summary.StdDev)},
+		{l.T("stats.min"), summary.Min},
+		{l.T("stats.max"), summary.Max},
+	}
+	for _, p := range psychometrics.ReportedPercentiles {
+		key := fmt.Sprintf("p%d", int(p))
+		rows = append(rows, []interface{}{l.T("stats.percentile", int(p)), round2(summary.Percentiles[key])})
+	}
+	rows = append(rows, []interface{}{l.T("stats.passed", quiz.PassingScore), passed})
+	for i, r := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+3)
+		f.SetSheetRow(sheetName, cell, &r)
+	}
+	row := len(rows) + 3
+	if rel != nil {
+		for _, r := range []struct {
+			label string
+			value *float64
+		}{{"KR-20", rel.KR20}, {"KR-21", rel.KR21}, {"Cronbach Alpha", rel.CronbachAlpha}, {"SEM", rel.SEM}} {
+			f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), r.label)
+			setOptionalFloat(f, sheetName, fmt.Sprintf("B%d", row), r.value)
+			row++
+		}
+	}
+
+	// Per question, over submitted attempts
+	row++
+	headers := []interface{}{l.T("col.no"), l.T("col.question"), l.T("col.answered"), l.T("col.correct"), l.T("col.correct_percent"), l.T("col.average_points")}
+	cell, _ := excelize.CoordinatesToCellName(1, row)
+	f.SetSheetRow(sheetName, cell, &headers)
+	end, _ := excelize.CoordinatesToCellName(len(headers), row)
+	f.SetCellStyle(sheetName, cell, end, styleHeader)
+
+	for i, q := range quiz.Questions {
+		row++
+		answered, correct, earned, scored := 0, 0, 0, false
+		for _, slip := range submitted {
+			sq := slip.Questions[i]
+			if sq.Choice != "" || sq.Answer != "" {
+				answered++
+			}
+			if sq.Correct != nil {
+				scored = true
+				if *sq.Correct {
+					correct++
+				}
+			}
+			if sq.Earned != nil {
+				earned += *sq.Earned
+			}
+		}
+		values := []interface{}{i + 1, q.Text, answered, nil, nil, nil}
+		if scored {
+			values[3] = correct
+			if n := len(submitted); n > 0 {
+				values[4] = round2(float64(correct) / float64(n) * 100)
+				values[5] = round2(float64(earned) / float64(n))
+			}
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, row)
+		f.SetSheetRow(sheetName, cell, &values)
+	}
+	f.SetColWidth(sheetName, "A", "A", 18)
+	f.SetColWidth(sheetName, "B", "B", 50)
+}
+
+// writeAnswerKeySheet lists every question with its options and key
+func writeAnswerKeySheet(f *excelize.File, l i18n.Locale, quiz models.Quiz) {
+	sheetName := l.T("sheet.answer_key")
+	f.NewSheet(sheetName)
+	styleHeader, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"#E0E0E0"}, Pattern: 1},
+	})
+
+	headers := []interface{}{l.T("col.no"), l.T("col.question_id"), l.T("col.type"), l.T("col.topic"), l.T("col.points"), l.T("col.question"), l.T("col.options"), l.T("col.key")}
+	f.SetSheetRow(sheetName, "A1", &headers)
+	f.SetCellStyle(sheetName, "A1", "H1", styleHeader)
+
+	for i, q := range quiz.Questions {
+		var options, keys []string
+		for j, opt := range q.Options {
+			options = append(options, optionLabel(j)+". "+opt.Text)
+			if opt.IsCorrect {
+				keys = append(keys, optionLabel(j))
+			}
+		}
+		key := strings.Join(keys, ", ")
+		if len(q.Options) == 0 {
+			key = q.CorrectAnswer
+		}
+		values := []interface{}{i + 1, q.ID, string(q.Type), q.Topic, q.Points, q.Text, strings.Join(options, " | "), key}
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		f.SetSheetRow(sheetName, cell, &values)
+	}
+	f.SetColWidth(sheetName, "F", "G", 50)
+}
+
+// ExportAnswerMatrix godoc
+// @Summary      Export Answer Matrix
+// @Description  Download an .xlsx with one row per student (best attempt, makeups included) and, per question, the chosen option letter, correctness (1/0), points and time answered. Extra sheets hold score statistics, item analysis and the answer key.
+// @Tags         reports
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param        id path string true "Batch ID"
+// @Success      200  {file}  file
+// @Failure      404  {object}  apperr.Response
+// @Router       /api/export/batch/{id}/matrix [get]
+func (s *ReportService) ExportAnswerMatrix(c *fiber.Ctx) error {
+	batchId := c.Params("id")
+
+	var batch models.ExamBatch
+	if err := s.db.First(&batch, "id = ?", batchId).Error; err != nil {
+		return apperr.NotFound("batch_not_found")
+	}
+	quizID, batchIDs, err := s.itemAnalysisScope(batchId, "")
+	if err != nil {
+		return apperr.NotFound("batch_not_found")
+	}
+	quiz := quizWithQuestions(s.db, quizID)
+
+	// Best attempt per student, whatever its status, like the batch report
+	var attempts []models.Attempt
+	s.db.Where("batch_id IN ?", batchIDs).Find(&attempts)
+	best := make([]models.Attempt, 0, len(attempts))
+	for _, a := range bestAttemptPerStudent(attempts) {
+		best = append(best, a)
+	}
+	slips := s.resultSlips(quiz, best)
+
+	l := requestLocale(c)
+	f := excelize.NewFile()
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Printf("answer matrix export: closing workbook: %v", err)
+		}
+	}()
+
+	sheetName := l.T("sheet.answer_matrix")
+	f.SetSheetName("Sheet1", sheetName)
+	writeAnswerMatrixSheet(f, l, sheetName, quiz, batch, slips)
+
+	rel, _ := s.getReliabilityData(batchId, "", psychometrics.DefaultHistogramBins)
+	writeMatrixStatisticsSheet(f, l, quiz, slips, rel)
+	if analysis, err := s.getItemAnalysisData(batchId, ""); err == nil {
+		writeItemAnalysisSheet(f, l, analysis, rel)
+	}
+	writeAnswerKeySheet(f, l, quiz)
+
+	c.Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=matrix-%s.xlsx", batchId))
+
+	if err := f.Write(c.Response().BodyWriter()); err != nil {
+		return apperr.Internal("export_failed", err)
+	}
+	return nil
+}
@@ -1,9 +1,9 @@
 package handlers
 
 import (
-	"academic-suite-backend/database"
+	"academic-suite-backend/apperr"
 	"academic-suite-backend/models"
-	"encoding/json"
+	"academic-suite-backend/validation"
 	"fmt"
 	"math"
 	"time"
@@ -18,11 +18,11 @@ import (
 // @Param        batchId query string true "Batch ID"
 // @Success      200  {array}  models.Attempt
 // @Router       /api/attempts [get]
-func GetAttempts(c *fiber.Ctx) error {
+func (s *AttemptService) GetAttempts(c *fiber.Ctx) error {
 	batchId := c.Query("batchId")
 	studentId := c.Query("studentId")
 
-	db := database.DB.Preload("Answers")
+	db := s.db.Preload("Answers")
 
 	if batchId != "" {
 		db = db.Where("batch_id = ?", batchId)
@@ -33,7 +33,7 @@ func GetAttempts(c *fiber.Ctx) error {
 
 	var attempts []models.Attempt
 	if err := db.Find(&attempts).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch attempts"})
+		return apperr.Internal("attempts_load_failed", err)
 	}
 
 	return c.JSON(attempts)
@@ -47,28 +47,34 @@ func GetAttempts(c *fiber.Ctx) error {
 // @Produce      json
 // @Param        req body map[string]string true "Request (batchId, studentId)"
 // @Success      200  {object}  models.Attempt
-// @Failure      400  {object}  map[string]string
+// @Failure      400  {object}  apperr.Response
 // @Router       /api/attempts/start [post]
-func StartAttempt(c *fiber.Ctx) error {
+func (s *AttemptService) StartAttempt(c *fiber.Ctx) error {
 	type StartReq struct {
-		BatchID   string `json:"batchId"`
-		StudentID string `json:"studentId"`
+		BatchID   string `json:"batchId" validate:"required"`
+		StudentID string `json:"studentId"` // defaults to the logged-in user
 	}
 	var req StartReq
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	if req.StudentID == "" {
+		req.StudentID, _ = c.Locals("userId").(string)
+	}
+	if req.StudentID == "" {
+		return fieldErrors(validation.Field("studentId", "required"))
 	}
 
 	// 0. Check for already completed attempts
 	var completedAttempt models.Attempt
-	if err := database.DB.Where("batch_id = ? AND student_id = ? AND status IN ?",
+	if err := s.db.Where("batch_id = ? AND student_id = ? AND status IN ?",
 		req.BatchID, req.StudentID, []models.AttemptStatus{models.AttemptSubmitted, models.AttemptExpired}).First(&completedAttempt).Error; err == nil {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Anda sudah menyelesaikan ujian ini."})
+		return apperr.Forbidden("attempt_already_submitted")
 	}
 
 	// 1. Check existing active attempt
 	var existingAttempt models.Attempt
-	err := database.DB.Where("batch_id = ? AND student_id = ? AND status NOT IN ?",
+	err := s.db.Where("batch_id = ? AND student_id = ? AND status NOT IN ?",
 		req.BatchID, req.StudentID, []models.AttemptStatus{models.AttemptSubmitted, models.AttemptExpired, models.AttemptResetByAdmin}).First(&existingAttempt).Error
 
 	if err == nil {
@@ -78,36 +84,26 @@ func StartAttempt(c *fiber.Ctx) error {
 
 	// 2. Validate Batch
 	var batch models.ExamBatch
-	if err := database.DB.First(&batch, "id = ?", req.BatchID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Batch not found"})
-	}
-
-	// 3. Check Access Control (AllowedParticipants)
-	if batch.AllowedParticipants != "" {
-		var allowed []string
-		if err := json.Unmarshal([]byte(batch.AllowedParticipants), &allowed); err == nil {
-			if len(allowed) > 0 {
-				isAllowed := false
-				for _, id := range allowed {
-					if id == req.StudentID {
-						isAllowed = true
-						break
-					}
-				}
-				if !isAllowed {
-					return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Anda tidak terdaftar dalam kelas ujian ini."})
-				}
-			}
+	if err := s.db.First(&batch, "id = ?", req.BatchID).Error; err != nil {
+		return apperr.NotFound("batch_not_found")
+	}
+
+	// 3. Check Access Control (batch_participants; a batch without capacity or participants is open to all).
+	// Release no-show seats first so a waitlisted student can take one.
+	now := s.clock.Now()
+	if !batchOpenToAll(s.db, &batch) && !isBatchParticipant(s.db, batch.ID, req.StudentID) {
+		processNoShows(s.db, s.events, batch.ID, now)
+		if !isBatchParticipant(s.db, batch.ID, req.StudentID) {
+			return apperr.Forbidden("not_enrolled")
 		}
 	}
 
-	// Calculate Initial Remaining Time
-	now := time.Now()
-	secondsUntilBatchEnd := int(batch.EndTime.Sub(now).Seconds())
-	allowedDurationSeconds := batch.Duration * 60
+	// Calculate Initial Remaining Time (extra time / extended end from accommodation)
+	acc := findAccommodation(s.db, batch.ID, req.StudentID)
+	secondsUntilBatchEnd := int(effectiveBatchEnd(batch, acc).Sub(now).Seconds())
 
 	// Initial remaining time capped by batch end
-	initialRemaining := int(math.Max(0, math.Min(float64(allowedDurationSeconds), float64(secondsUntilBatchEnd))))
+	initialRemaining := int(math.Max(0, math.Min(allowedDurationSeconds(batch, acc), float64(secondsUntilBatchEnd))))
 
 	// 3. Create New Attempt
 	nowPtr := &now
@@ -121,12 +117,12 @@ func StartAttempt(c *fiber.Ctx) error {
 		CreatedAt:     now,
 	}
 
-	if err := database.DB.Create(&newAttempt).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not start attempt"})
+	if err := s.db.Create(&newAttempt).Error; err != nil {
+		return apperr.Internal("attempt_start_failed", err)
 	}
 
 	// Log Event
-	LogEvent(models.EventAttemptStart, req.BatchID, newAttempt.ID, req.StudentID, "Student started exam attempt")
+	s.events.Log(models.EventAttemptStart, req.BatchID, newAttempt.ID, req.StudentID, "Student started exam attempt")
 
 	return c.JSON(newAttempt)
 }
@@ -140,47 +136,55 @@ func StartAttempt(c *fiber.Ctx) error {
 // @Param        answer body models.Answer true "Answer Data"
 // @Success      200  {object}  models.Attempt
 // @Router       /api/attempts/{id}/answers [post]
-func SaveAnswer(c *fiber.Ctx) error {
+func (s *AttemptService) SaveAnswer(c *fiber.Ctx) error {
 	attemptId := c.Params("id")
 	var ans models.Answer
 	// Extract currentQuestionIdx if present in body (hacky but quick)
 	// Or better, define a request struct that includes Answer fields + CurrentIndex
 	type SaveAnswerReq struct {
 		models.Answer
-		CurrentQuestionIdx int `json:"currentQuestionIdx"`
+		CurrentQuestionIdx int `json:"currentQuestionIdx" validate:"min=0"`
 	}
 	var req SaveAnswerReq
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 	ans = req.Answer
 
 	// Verify attempt validity
 	var attempt models.Attempt
-	if err := database.DB.First(&attempt, "id = ?", attemptId).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Attempt not found"})
+	if err := s.db.First(&attempt, "id = ?", attemptId).Error; err != nil {
+		return apperr.NotFound("attempt_not_found")
 	}
 	if attempt.Status != models.AttemptActive {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Attempt not active"})
+		return apperr.BadRequest("attempt_not_active")
+	}
+
+	// Reject answers once the (accommodated) time is up
+	var batch models.ExamBatch
+	if err := s.db.First(&batch, "id = ?", attempt.BatchID).Error; err == nil {
+		if calculateRemainingSeconds(attempt, batch, findAccommodation(s.db, batch.ID, attempt.StudentID), s.clock.Now()) <= 0 {
+			return apperr.Forbidden("time_up")
+		}
 	}
 
 	ans.AttemptID = attemptId
-	ans.AnsweredAt = time.Now()
+	ans.AnsweredAt = s.clock.Now()
 
 	// Upsert Answer
 	// GORM Clause OnConflict for Postgres
 	// Alternatively manual check
 	var existingAns models.Answer
-	err := database.DB.Where("attempt_id = ? AND question_id = ?", attemptId, ans.QuestionID).First(&existingAns).Error
+	err := s.db.Where("attempt_id = ? AND question_id = ?", attemptId, ans.QuestionID).First(&existingAns).Error
 	if err == nil {
 		// Update
 		existingAns.SelectedOptionID = ans.SelectedOptionID
 		existingAns.TextAnswer = ans.TextAnswer
-		existingAns.AnsweredAt = time.Now()
-		database.DB.Save(&existingAns)
+		existingAns.AnsweredAt = s.clock.Now()
+		s.db.Save(&existingAns)
 	} else {
 		// Create
-		database.DB.Create(&ans)
+		s.db.Create(&ans)
 	}
 
 	return c.JSON(attempt)
@@ -194,28 +198,55 @@ func SaveAnswer(c *fiber.Ctx) error {
 // @Param        id   path      string         true  "Attempt ID"
 // @Param        answers body []models.Answer true "Final Answers"
 // @Success      200  {object}  models.Attempt
+// @Failure      409  {object}  apperr.Response
 // @Router       /api/attempts/{id}/submit [post]
-func SubmitAttempt(c *fiber.Ctx) error {
+func (s *AttemptService) SubmitAttempt(c *fiber.Ctx) error {
 	attemptId := c.Params("id")
 	var answers []models.Answer
-	if err := c.BodyParser(&answers); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	if err := parseBody(c, &answers); err != nil {
+		return err
 	}
 
 	var attempt models.Attempt
-	if err := database.DB.First(&attempt, "id = ?", attemptId).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Attempt not found"})
+	if err := s.db.First(&attempt, "id = ?", attemptId).Error; err != nil {
+		return apperr.NotFound("attempt_not_found")
+	}
+	// A paused attempt is still ACTIVE; anything else was already closed (or reset) and a stale
+	// client must not bring it back
+	if attempt.Status != models.AttemptActive {
+		return apperr.Conflict("attempt_not_active")
+	}
+
+	now := s.clock.Now()
+	var batch models.ExamBatch
+	s.db.First(&batch, "id = ?", attempt.BatchID)
+
+	// Answers sent with the submit are only kept while there is (accommodated) time left,
+	// like SaveAnswer; the score comes from the stored answers either way
+	if calculateRemainingSeconds(attempt, batch, findAccommodation(s.db, batch.ID, attempt.StudentID), now) > 0 {
+		for _, ans := range answers {
+			ans.AttemptID = attemptId
+			ans.AnsweredAt = now
+			var existingAns models.Answer
+			if err := s.db.Where("attempt_id = ? AND question_id = ?", attemptId, ans.QuestionID).First(&existingAns).Error; err == nil {
+				existingAns.SelectedOptionID = ans.SelectedOptionID
+				existingAns.TextAnswer = ans.TextAnswer
+				existingAns.AnsweredAt = now
+				s.db.Save(&existingAns)
+			} else {
+				s.db.Create(&ans)
+			}
+		}
 	}
+	var stored []models.Answer
+	s.db.Where("attempt_id = ?", attemptId).Find(&stored)
 
-	now := time.Now()
 	attempt.Status = models.AttemptSubmitted
 	attempt.SubmittedAt = &now
 
 	// Calculate Score with Normalization
-	var batch models.ExamBatch
-	database.DB.First(&batch, "id = ?", attempt.BatchID)
 	var quiz models.Quiz
-	database.DB.Preload("Questions").Preload("Questions.Options").First(&quiz, "id = ?", batch.QuizID)
+	s.db.Preload("Questions").Preload("Questions.Options").First(&quiz, "id = ?", batch.QuizID)
 
 	rawScore := 0
 	totalPossiblePoints := 0
@@ -226,7 +257,7 @@ func SubmitAttempt(c *fiber.Ctx) error {
 	}
 
 	// Calculate Earned Points
-	for _, ans := range answers {
+	for _, ans := range stored {
 		// Find question
 		for _, q := range quiz.Questions {
 			if q.ID == ans.QuestionID {
@@ -255,22 +286,7 @@ func SubmitAttempt(c *fiber.Ctx) error {
 	}
 
 	attempt.Score = finalScore
-	database.DB.Save(&attempt)
-
-	// Save answers bulk? Or rely on individual saves.
-	// To match dummyApi logic: attempt.answers = answers.
-	// We should ensure these answers are persisted.
-	for _, ans := range answers {
-		ans.AttemptID = attemptId
-		// Save to DB
-		var existingAns models.Answer
-		if err := database.DB.Where("attempt_id = ? AND question_id = ?", attemptId, ans.QuestionID).First(&existingAns).Error; err == nil {
-			existingAns.SelectedOptionID = ans.SelectedOptionID
-			database.DB.Save(&existingAns)
-		} else {
-			database.DB.Create(&ans)
-		}
-	}
+	s.db.Save(&attempt)
 
 	return c.JSON(attempt)
 }
@@ -282,51 +298,25 @@ func SubmitAttempt(c *fiber.Ctx) error {
 // @Param        id   path      string  true  "Attempt ID"
 // @Success      200  {object}  map[string]interface{}
 // @Router       /api/attempts/{id}/time [get]
-func GetServerTime(c *fiber.Ctx) error {
+func (s *AttemptService) GetServerTime(c *fiber.Ctx) error {
 	attemptId := c.Params("id")
 
 	var attempt models.Attempt
-	if err := database.DB.First(&attempt, "id = ?", attemptId).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Attempt not found"})
+	if err := s.db.First(&attempt, "id = ?", attemptId).Error; err != nil {
+		return apperr.NotFound("attempt_not_found")
 	}
 
 	var batch models.ExamBatch
-	if err := database.DB.First(&batch, "id = ?", attempt.BatchID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Batch not found"})
-	}
-
-	now := time.Now()
-
-	startedAt := attempt.StartedAt
-	if startedAt == nil {
-		startedAt = &attempt.CreatedAt
-	}
-
-	// Time elapsed
-	elapsedSeconds := now.Sub(*startedAt).Seconds()
-
-	// Adjust for pauses
-	effectiveElapsed := elapsedSeconds - float64(attempt.TotalPausedTime)
-
-	// If currently paused, we don't count time since PausedAt
-	if attempt.IsPaused && attempt.PausedAt != nil {
-		currentPauseDuration := now.Sub(*attempt.PausedAt).Seconds()
-		effectiveElapsed -= currentPauseDuration
+	if err := s.db.First(&batch, "id = ?", attempt.BatchID).Error; err != nil {
+		return apperr.NotFound("batch_not_found")
 	}
 
-	allowedDurationSeconds := float64(batch.Duration * 60)
-	secondsUntilBatchEnd := batch.EndTime.Sub(now).Seconds()
-
-	remaining := math.Max(0, allowedDurationSeconds-effectiveElapsed)
-
-	// Cap at batch end
-	if remaining > secondsUntilBatchEnd {
-		remaining = math.Max(0, secondsUntilBatchEnd)
-	}
+	now := s.clock.Now()
+	remaining := calculateRemainingSeconds(attempt, batch, findAccommodation(s.db, batch.ID, attempt.StudentID), now)
 
 	return c.JSON(fiber.Map{
 		"serverTime":    now,
-		"remainingTime": int(remaining),
+		"remainingTime": remaining,
 	})
 }
 
@@ -339,28 +329,28 @@ func GetServerTime(c *fiber.Ctx) error {
 // @Param        req  body      map[string]interface{} true "Event Data (eventType, details)"
 // @Success      200  {object}  map[string]string
 // @Router       /api/attempts/{id}/log [post]
-func LogAttemptEvent(c *fiber.Ctx) error {
+func (s *AttemptService) LogAttemptEvent(c *fiber.Ctx) error {
 	attemptId := c.Params("id")
 
 	type LogReq struct {
-		EventType string `json:"eventType"`
+		EventType string `json:"eventType" validate:"required"`
 		Details   string `json:"details"`
 	}
 	var req LogReq
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 
 	var attempt models.Attempt
-	if err := database.DB.First(&attempt, "id = ?", attemptId).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Attempt not found"})
+	if err := s.db.First(&attempt, "id = ?", attemptId).Error; err != nil {
+		return apperr.NotFound("attempt_not_found")
 	}
 
 	// Optional: Check status? Maybe allow logging even if submitted/frozen for forensics
 	// But mostly useful for Active exams.
 
 	// Save Log
-	LogEvent(models.EventType(req.EventType), attempt.BatchID, attemptId, attempt.StudentID, req.Details)
+	s.events.Log(models.EventType(req.EventType), attempt.BatchID, attemptId, attempt.StudentID, req.Details)
 
 	// Logic for Auto-Freeze could go here (e.g., if violation count > X)
 	// For now, just Log.
@@ -374,23 +364,23 @@ func LogAttemptEvent(c *fiber.Ctx) error {
 // @Param        id   path      string true "Attempt ID"
 // @Success      200  {object}  models.Attempt
 // @Router       /api/attempts/{id}/pause [post]
-func PauseAttempt(c *fiber.Ctx) error {
+func (s *AttemptService) PauseAttempt(c *fiber.Ctx) error {
 	attemptId := c.Params("id")
 	var attempt models.Attempt
-	if err := database.DB.First(&attempt, "id = ?", attemptId).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Attempt not found"})
+	if err := s.db.First(&attempt, "id = ?", attemptId).Error; err != nil {
+		return apperr.NotFound("attempt_not_found")
 	}
 
 	if attempt.IsPaused {
 		return c.JSON(attempt)
 	}
 
-	now := time.Now()
+	now := s.clock.Now()
 	attempt.IsPaused = true
 	attempt.PausedAt = &now
-	database.DB.Save(&attempt)
+	s.db.Save(&attempt)
 
-	LogEvent("ATTEMPT_PAUSED", attempt.BatchID, attempt.ID, attempt.StudentID, "Teacher paused the attempt")
+	s.events.Log("ATTEMPT_PAUSED", attempt.BatchID, attempt.ID, attempt.StudentID, "Teacher paused the attempt")
 
 	return c.JSON(attempt)
 }
@@ -401,29 +391,39 @@ func PauseAttempt(c *fiber.Ctx) error {
 // @Param        id   path      string true "Attempt ID"
 // @Success      200  {object}  models.Attempt
 // @Router       /api/attempts/{id}/resume [post]
-func ResumeAttempt(c *fiber.Ctx) error {
+func (s *AttemptService) ResumeAttempt(c *fiber.Ctx) error {
 	attemptId := c.Params("id")
 	var attempt models.Attempt
-	if err := database.DB.First(&attempt, "id = ?", attemptId).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Attempt not found"})
+	if err := s.db.First(&attempt, "id = ?", attemptId).Error; err != nil {
+		return apperr.NotFound("attempt_not_found")
 	}
 
 	if !attempt.IsPaused {
 		return c.JSON(attempt)
 	}
 
-	now := time.Now()
+	now := s.clock.Now()
 	pausedDuration := 0
 	if attempt.PausedAt != nil {
 		pausedDuration = int(now.Sub(*attempt.PausedAt).Seconds())
 	}
 
+	// A student break only stops the clock up to the break allowance; the rest counts as exam time
+	if s.onBreak(attempt.ID) {
+		if acc := findAccommodation(s.db, attempt.BatchID, attempt.StudentID); acc != nil && acc.MaxBreakMinutes > 0 {
+			left := acc.MaxBreakMinutes*60 - attempt.TotalPausedTime
+			if pausedDuration > left {
+				pausedDuration = int(math.Max(0, float64(left)))
+			}
+		}
+	}
+
 	attempt.IsPaused = false
 	attempt.PausedAt = nil
 	attempt.TotalPausedTime += pausedDuration
-	database.DB.Save(&attempt)
+	s.db.Save(&attempt)
 
-	LogEvent("ATTEMPT_RESUMED", attempt.BatchID, attempt.ID, attempt.StudentID, fmt.Sprintf("Teacher resumed the attempt. Paused for %d seconds", pausedDuration))
+	s.events.Log("ATTEMPT_RESUMED", attempt.BatchID, attempt.ID, attempt.StudentID, fmt.Sprintf("Teacher resumed the attempt. Paused for %d seconds", pausedDuration))
 
 	return c.JSON(attempt)
 }
@@ -433,35 +433,39 @@ func ResumeAttempt(c *fiber.Ctx) error {
 // @Tags         attempts
 // @Param        id   path      string true "Attempt ID"
 // @Success      200  {object}  models.Attempt
+// @Failure      409  {object}  apperr.Response
 // @Router       /api/attempts/{id}/force-submit [post]
-func ForceSubmitAttempt(c *fiber.Ctx) error {
+func (s *AttemptService) ForceSubmitAttempt(c *fiber.Ctx) error {
 	// Re-use existing submission logic but trigger by teacher
 	// We call SubmitAttempt internally? No, SubmitAttempt expects answers body.
 	// Force submit implies taking whatever is in DB.
 
 	attemptId := c.Params("id")
 	var attempt models.Attempt
-	if err := database.DB.First(&attempt, "id = ?", attemptId).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Attempt not found"})
+	if err := s.db.First(&attempt, "id = ?", attemptId).Error; err != nil {
+		return apperr.NotFound("attempt_not_found")
 	}
 
-	if attempt.Status == models.AttemptSubmitted || attempt.Status == models.AttemptExpired {
-		return c.JSON(attempt)
+	// Only an attempt still in progress can be closed; a reset one must stay open to a fresh start
+	switch attempt.Status {
+	case models.AttemptActive, models.AttemptFrozen, models.AttemptInterrupted:
+	default:
+		return apperr.Conflict("attempt_not_active")
 	}
 
-	now := time.Now()
+	now := s.clock.Now()
 	attempt.Status = models.AttemptSubmitted
 	attempt.SubmittedAt = &now
 
 	// We need to calculate score based on EXISTING answers in DB
 	var answers []models.Answer
-	database.DB.Where("attempt_id = ?", attemptId).Find(&answers)
+	s.db.Where("attempt_id = ?", attemptId).Find(&answers)
 
 	// Copy-paste scoring logic from SubmitAttempt (Refactoring suggested later)
 	var batch models.ExamBatch
-	database.DB.First(&batch, "id = ?", attempt.BatchID)
+	s.db.First(&batch, "id = ?", attempt.BatchID)
 	var quiz models.Quiz
-	database.DB.Preload("Questions").Preload("Questions.Options").First(&quiz, "id = ?", batch.QuizID)
+	s.db.Preload("Questions").Preload("Questions.Options").First(&quiz, "id = ?", batch.QuizID)
 
 	rawScore := 0
 	totalPossiblePoints := 0
@@ -492,9 +496,9 @@ func ForceSubmitAttempt(c *fiber.Ctx) error {
 	}
 
 	attempt.Score = finalScore
-	database.DB.Save(&attempt)
+	s.db.Save(&attempt)
 
-	LogEvent("ATTEMPT_FORCE_SUBMITTED", attempt.BatchID, attempt.ID, attempt.StudentID, "Teacher forced submission")
+	s.events.Log("ATTEMPT_FORCE_SUBMITTED", attempt.BatchID, attempt.ID, attempt.StudentID, "Teacher forced submission")
 
 	return c.JSON(attempt)
 }
@@ -506,7 +510,7 @@ func ForceSubmitAttempt(c *fiber.Ctx) error {
 // @Param        id   path      string true "Attempt ID"
 // @Success      200  {object}  map[string]string
 // @Router       /api/attempts/{id}/ping [post]
-func PingAttempt(c *fiber.Ctx) error {
+func (s *AttemptService) PingAttempt(c *fiber.Ctx) error {
 	attemptId := c.Params("id")
 
 	// Fast update: execute SQL directly to avoid fetch-then-save overhead
@@ -518,7 +522,7 @@ func PingAttempt(c *fiber.Ctx) error {
 	var req PingReq
 	c.BodyParser(&req) // Optional
 
-	now := time.Now()
+	now := s.clock.Now()
 
 	// We update LastActiveAt and optionally CurrentQuestionIdx
 	updates := map[string]interface{}{}
@@ -528,10 +532,10 @@ func PingAttempt(c *fiber.Ctx) error {
 		updates["current_question_idx"] = req.CurrentQuestionIdx
 	}
 
-	result := database.DB.Model(&models.Attempt{}).Where("id = ?", attemptId).Updates(updates)
+	result := s.db.Model(&models.Attempt{}).Where("id = ?", attemptId).Updates(updates)
 
 	if result.Error != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to ping"})
+		return apperr.Internal("ping_failed", result.Error)
 	}
 
 	return c.JSON(fiber.Map{"status": "ok", "timestamp": now})
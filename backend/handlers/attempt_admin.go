@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/models"
+	"academic-suite-backend/validation"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// attemptSnapshot is the part of an attempt recorded in the audit trail
+type attemptSnapshot struct {
+	Status          models.AttemptStatus `json:"status"`
+	Score           float64              `json:"score"`
+	StartedAt       *time.Time           `json:"startedAt"`
+	SubmittedAt     *time.Time           `json:"submittedAt"`
+	ExpiredAt       *time.Time           `json:"expiredAt"`
+	TotalPausedTime int                  `json:"totalPausedTime"`
+	RemainingTime   int                  `json:"remainingTime"`
+	AnswerCount     int                  `json:"answerCount"`
+}
+
+type attemptAuditDetails struct {
+	Reason string          `json:"reason"`
+	By     string          `json:"by"`
+	Before attemptSnapshot `json:"before"`
+	After  attemptSnapshot `json:"after"`
+}
+
+type AttemptAdminRequest struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+func snapshotAttempt(a models.Attempt, answerCount int) attemptSnapshot {
+	return attemptSnapshot{
+		Status:          a.Status,
+		Score:           a.Score,
+		StartedAt:       a.StartedAt,
+		SubmittedAt:     a.SubmittedAt,
+		ExpiredAt:       a.ExpiredAt,
+		TotalPausedTime: a.TotalPausedTime,
+		RemainingTime:   a.RemainingTime,
+		AnswerCount:     answerCount,
+	}
+}
+
+func (s *AttemptService) logAttemptAudit(eventType models.EventType, attempt models.Attempt, details attemptAuditDetails) {
+	payload, _ := json.Marshal(details)
+	s.events.Log(eventType, attempt.BatchID, attempt.ID, attempt.StudentID, string(payload))
+}
+
+// ResetAttempt godoc
+// @Summary      Reset Attempt (Admin)
+// @Description  Discard an attempt and its answers so the student can start a fresh one. Requires a reason.
+// @Tags         attempts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string               true  "Attempt ID"
+// @Param        req  body      AttemptAdminRequest  true  "Reason"
+// @Success      200  {object}  models.Attempt
+// @Failure      400  {object}  apperr.Response
+// @Router       /api/attempts/{id}/reset [post]
+func (s *AttemptService) ResetAttempt(c *fiber.Ctx) error {
+	attemptId := c.Params("id")
+	var req AttemptAdminRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return fieldErrors(validation.Field("reason", "required"))
+	}
+
+	var attempt models.Attempt
+	if err := s.db.Preload("Answers").First(&attempt, "id = ?", attemptId).Error; err != nil {
+		return apperr.NotFound("attempt_not_found")
+	}
+	if attempt.Status == models.AttemptResetByAdmin {
+		return apperr.BadRequest("attempt_already_reset")
+	}
+
+	before := snapshotAttempt(attempt, len(attempt.Answers))
+
+	attempt.Status = models.AttemptResetByAdmin
+	attempt.Score = 0
+	attempt.IsPaused = false
+	attempt.PausedAt = nil
+
+	// Keep the attempt row for the audit trail, drop its answers
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("attempt_id = ?", attempt.ID).Delete(&models.Answer{}).Error; err != nil {
+			return err
+		}
+		attempt.Answers = nil
+		return tx.Save(&attempt).Error
+	})
+	if err != nil {
+		return apperr.Internal("attempt_reset_failed", err)
+	}
+
+	userId, _ := c.Locals("userId").(string)
+	s.logAttemptAudit(models.EventAttemptReset, attempt, attemptAuditDetails{
+		Reason: req.Reason,
+		By:     userId,
+		Before: before,
+		After:  snapshotAttempt(attempt, 0),
+	})
+
+	return c.JSON(attempt)
+}
+
+// ReopenAttempt godoc
+// @Summary      Reopen Attempt (Admin)
+// @Description  Reactivate a submitted or expired attempt, keeping its answers and the time it had left. Requires a reason.
+// @Tags         attempts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string               true  "Attempt ID"
+// @Param        req  body      AttemptAdminRequest  true  "Reason"
+// @Success      200  {object}  models.Attempt
+// @Failure      400  {object}  apperr.Response
+// @Router       /api/attempts/{id}/reopen [post]
+func (s *AttemptService) ReopenAttempt(c *fiber.Ctx) error {
+	attemptId := c.Params("id")
+	var req AttemptAdminRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return fieldErrors(validation.Field("reason", "required"))
+	}
+
+	var attempt models.Attempt
+	if err := s.db.Preload("Answers").First(&attempt, "id = ?", attemptId).Error; err != nil {
+		return apperr.NotFound("attempt_not_found")
+	}
+
+	closedAt := attempt.SubmittedAt
+	if attempt.Status == models.AttemptExpired && attempt.ExpiredAt != nil {
+		closedAt = attempt.ExpiredAt
+	}
+	if (attempt.Status != models.AttemptSubmitted && attempt.Status != models.AttemptExpired) || closedAt == nil {
+		return apperr.BadRequest("attempt_not_reopenable")
+	}
+
+	// Only one open attempt per student per batch
+	var count int64
+	s.db.Model(&models.Attempt{}).Where("batch_id = ? AND student_id = ? AND id <> ? AND status IN ?",
+		attempt.BatchID, attempt.StudentID, attempt.ID, []models.AttemptStatus{models.AttemptActive, models.AttemptFrozen, models.AttemptInterrupted}).Count(&count)
+	if count > 0 {
+		return apperr.Conflict("attempt_already_open")
+	}
+
+	var batch models.ExamBatch
+	if err := s.db.First(&batch, "id = ?", attempt.BatchID).Error; err != nil {
+		return apperr.NotFound("batch_not_found")
+	}
+
+	before := snapshotAttempt(attempt, len(attempt.Answers))
+
+	// The time between closing and reopening does not count against the student:
+	// book it as paused time so the remaining time picks up where it stopped.
+	now := s.clock.Now()
+	attempt.TotalPausedTime += int(now.Sub(*closedAt).Seconds())
+	attempt.Status = models.AttemptActive
+	attempt.SubmittedAt = nil
+	attempt.ExpiredAt = nil
+	attempt.IsPaused = false
+	attempt.PausedAt = nil
+
+	remaining := calculateRemainingSeconds(attempt, batch, findAccommodation(s.db, batch.ID, attempt.StudentID), now)
+	if remaining <= 0 {
+		return apperr.BadRequest("reopen_no_time_left")
+	}
+	attempt.RemainingTime = remaining
+
+	if err := s.db.Save(&attempt).Error; err != nil {
+		return apperr.Internal("attempt_reopen_failed", err)
+	}
+
+	userId, _ := c.Locals("userId").(string)
+	s.logAttemptAudit(models.EventAttemptReopen, attempt, attemptAuditDetails{
+		Reason: req.Reason,
+		By:     userId,
+		Before: before,
+		After:  snapshotAttempt(attempt, len(attempt.Answers)),
+	})
+
+	return c.JSON(attempt)
+}
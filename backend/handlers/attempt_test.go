@@ -0,0 +1,488 @@
+package handlers
+
+import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/blob"
+	"academic-suite-backend/clock"
+	"academic-suite-backend/database"
+	"academic-suite-backend/models"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testEnv is a fresh in-memory SQLite database with all migrations applied,
+// the services wired to a fake clock, and a fiber app exposing the attempt, batch and export routes.
+type testEnv struct {
+	t     *testing.T
+	db    *gorm.DB
+	clock *clock.Fake
+	svc   *Services
+	app   *fiber.App
+}
+
+var testStart = time.Date(2025, 3, 10, 8, 0, 0, 0, time.FixedZone("WIB", 7*60*60))
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	if err := database.OpenWith(database.Config{Driver: database.DriverSQLite, Path: ":memory:"}); err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := database.MigrateUp(0); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	db := database.DB.Session(&gorm.Session{Logger: logger.Discard})
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	fake := clock.NewFake(testStart)
+	media, err := blob.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("media store: %v", err)
+	}
+	svc := NewServices(db, fake, media)
+	// Write events synchronously so assertions can read them
+	svc.Attempts.events.sync = true
+	svc.Batches.events.sync = true
+	svc.Accommodations.events.sync = true
+
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	app.Use(requestid.New())
+	// Stand-in for AuthMiddleware: identity comes from test headers
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("userId", c.Get("X-User"))
+		c.Locals("role", c.Get("X-Role"))
+		c.Locals("locale", c.Get("X-Locale"))
+		return c.Next()
+	})
+	attempts := app.Group("/api/attempts")
+	attempts.Post("/start", svc.Attempts.StartAttempt)
+	attempts.Post("/:id/answers", svc.Attempts.SaveAnswer)
+	attempts.Post("/:id/submit", svc.Attempts.SubmitAttempt)
+	attempts.Post("/:id/pause", svc.Attempts.PauseAttempt)
+	attempts.Post("/:id/resume", svc.Attempts.ResumeAttempt)
+	attempts.Post("/:id/force-submit", svc.Attempts.ForceSubmitAttempt)
+	attempts.Post("/:id/break", svc.Attempts.BreakAttempt)
+	attempts.Get("/:id/time", svc.Attempts.GetServerTime)
+	attempts.Post("/:id/reset", svc.Attempts.ResetAttempt)
+	attempts.Post("/:id/reopen", svc.Attempts.ReopenAttempt)
+	app.Get("/api/batches", svc.Batches.GetBatches)
+	app.Post("/api/batches", svc.Batches.CreateBatch)
+	app.Put("/api/batches/:id", svc.Batches.UpdateBatch)
+	app.Post("/api/batches/:id/makeup", svc.Batches.CreateMakeupBatch)
+	app.Post("/api/batches/:id/waitlist", svc.Batches.JoinWaitlist)
+	app.Put("/api/batches/:id/waitlist", svc.Batches.ReorderWaitlist)
+	app.Delete("/api/batches/:id/waitlist/:studentId", svc.Batches.LeaveWaitlist)
+	app.Delete("/api/batches/:id/participants/:studentId", svc.Batches.RemoveParticipant)
+	app.Post("/api/quizzes", svc.Quizzes.CreateQuiz)
+	app.Put("/api/quizzes/:id", svc.Quizzes.UpdateQuiz)
+	app.Get("/api/quizzes/:id/export", svc.Quizzes.ExportQuiz)
+	app.Get("/api/import/users/template", svc.Imports.GetUserImportTemplate)
+	app.Post("/api/import/users", svc.Imports.ImportUsers)
+	app.Post("/api/import/questions/:quizId", svc.Imports.ImportQuestions)
+	app.Post("/api/import/quizzes", svc.Imports.ImportQuiz)
+	app.Post("/api/media", svc.Media.UploadMedia)
+	app.Get("/api/media/:id", svc.Media.GetMedia)
+	app.Get("/api/export/batch/:id/documents", svc.Reports.ExportBatchDocuments)
+	app.Get("/api/export/attempts/:id/slip", svc.Reports.ExportResultSlip)
+	app.Get("/api/export/batch/:id/results", svc.Reports.ExportBatchResults)
+	app.Get("/api/export/batch/:id/matrix", svc.Reports.ExportAnswerMatrix)
+	app.Get("/api/export/batch/:id/answers", svc.Reports.ExportBatchAnswers)
+	app.Get("/api/export/logs", svc.Reports.ExportEventLogs)
+	app.Post("/api/subjects", svc.Subjects.CreateSubject)
+	app.Put("/api/subjects/:id", svc.Subjects.UpdateSubject)
+	app.Get("/api/gradebook", svc.Gradebook.GetGradebook)
+	app.Get("/api/gradebook/export", svc.Gradebook.ExportGradebook)
+	app.Get("/api/reports/batch", svc.Reports.GetBatchReport)
+	app.Get("/api/reports/compare", svc.Reports.CompareCohorts)
+	app.Get("/api/reports/logs", svc.Reports.GetEventLogs)
+	app.Get("/api/reports/answers", svc.Reports.GetAnswers)
+
+	return &testEnv{t: t, db: db, clock: fake, svc: svc, app: app}
+}
+
+func (e *testEnv) create(values ...interface{}) {
+	e.t.Helper()
+	for _, v := range values {
+		if err := e.db.Create(v).Error; err != nil {
+			e.t.Fatalf("create %T: %v", v, err)
+		}
+	}
+}
+
+// seedExam creates a student and a 60 minute batch (window 08:00-10:00) with a two-question quiz
+func (e *testEnv) seedExam() models.ExamBatch {
+	e.t.Helper()
+	e.create(
+		&models.User{ID: "student-1", Email: "siswa@example.com", Role: models.RoleStudent},
+		&models.Quiz{ID: "quiz-1", Title: "Matematika", TotalPoints: 100},
+		&models.Question{ID: "q1", QuizID: "quiz-1", Type: models.TypeMCQ, Points: 5},
+		&models.Question{ID: "q2", QuizID: "quiz-1", Type: models.TypeMCQ, Points: 15},
+		&models.QuestionOption{ID: "q1-a", QuestionID: "q1", IsCorrect: true},
+		&models.QuestionOption{ID: "q1-b", QuestionID: "q1"},
+		&models.QuestionOption{ID: "q2-a", QuestionID: "q2"},
+		&models.QuestionOption{ID: "q2-b", QuestionID: "q2", IsCorrect: true},
+	)
+	batch := models.ExamBatch{
+		ID:        "batch-1",
+		QuizID:    "quiz-1",
+		Type:      models.BatchRegular,
+		Name:      "UTS Matematika",
+		StartTime: testStart,
+		EndTime:   testStart.Add(2 * time.Hour),
+		Duration:  60,
+		Status:    models.StatusActive,
+	}
+	e.create(&batch)
+	return batch
+}
+
+// do sends a JSON request and decodes the JSON response into out (if not nil)
+func (e *testEnv) do(method, path string, body interface{}, out interface{}, headers ...string) int {
+	e.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		payload, _ := json.Marshal(body)
+		reader = bytes.NewReader(payload)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := e.app.Test(req, -1)
+	if err != nil {
+		e.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		data, _ := io.ReadAll(resp.Body)
+		if err := json.Unmarshal(data, out); err != nil {
+			e.t.Fatalf("%s %s: decode %q: %v", method, path, data, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func (e *testEnv) start(studentID string) models.Attempt {
+	e.t.Helper()
+	var attempt models.Attempt
+	if code := e.do("POST", "/api/attempts/start", fiber.Map{"batchId": "batch-1", "studentId": studentID}, &attempt); code != 200 {
+		e.t.Fatalf("start attempt: status %d", code)
+	}
+	return attempt
+}
+
+func (e *testEnv) remaining(attemptID string) int {
+	e.t.Helper()
+	var res struct {
+		RemainingTime int `json:"remainingTime"`
+	}
+	if code := e.do("GET", "/api/attempts/"+attemptID+"/time", nil, &res); code != 200 {
+		e.t.Fatalf("server time: status %d", code)
+	}
+	return res.RemainingTime
+}
+
+func (e *testEnv) attempt(id string) models.Attempt {
+	e.t.Helper()
+	var a models.Attempt
+	if err := e.db.Preload("Answers").First(&a, "id = ?", id).Error; err != nil {
+		e.t.Fatalf("load attempt: %v", err)
+	}
+	return a
+}
+
+func TestAttemptTimerFollowsClock(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedExam()
+
+	attempt := e.start("student-1")
+	if attempt.RemainingTime != 3600 {
+		t.Fatalf("initial remaining = %d, want 3600", attempt.RemainingTime)
+	}
+
+	e.clock.Advance(10 * time.Minute)
+	if got := e.remaining(attempt.ID); got != 3000 {
+		t.Fatalf("remaining after 10m = %d, want 3000", got)
+	}
+
+	// Starting again resumes the same attempt
+	if again := e.start("student-1"); again.ID != attempt.ID {
+		t.Fatalf("second start created %s, want resume of %s", again.ID, attempt.ID)
+	}
+}
+
+func TestAttemptStartedLateIsCappedByBatchEnd(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedExam()
+
+	// 09:30, half an hour before the window closes
+	e.clock.Advance(90 * time.Minute)
+	attempt := e.start("student-1")
+	if attempt.RemainingTime != 1800 {
+		t.Fatalf("remaining = %d, want 1800 (capped by batch end)", attempt.RemainingTime)
+	}
+}
+
+func TestPausedTimeIsNotCounted(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedExam()
+	attempt := e.start("student-1")
+
+	e.clock.Advance(5 * time.Minute)
+	if code := e.do("POST", "/api/attempts/"+attempt.ID+"/pause", nil, nil); code != 200 {
+		t.Fatalf("pause: status %d", code)
+	}
+
+	e.clock.Advance(15 * time.Minute)
+	if got := e.remaining(attempt.ID); got != 3300 {
+		t.Fatalf("remaining while paused = %d, want 3300", got)
+	}
+
+	if code := e.do("POST", "/api/attempts/"+attempt.ID+"/resume", nil, nil); code != 200 {
+		t.Fatalf("resume: status %d", code)
+	}
+	if got := e.attempt(attempt.ID).TotalPausedTime; got != 900 {
+		t.Fatalf("total paused = %d, want 900", got)
+	}
+
+	e.clock.Advance(5 * time.Minute)
+	if got := e.remaining(attempt.ID); got != 3000 {
+		t.Fatalf("remaining after resume = %d, want 3000", got)
+	}
+}
+
+func TestAnswersRejectedAfterTimeIsUp(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedExam()
+	attempt := e.start("student-1")
+
+	e.clock.Advance(59 * time.Minute)
+	if code := e.do("POST", "/api/attempts/"+attempt.ID+"/answers", fiber.Map{"questionId": "q1", "selectedOptionId": "q1-a"}, nil); code != 200 {
+		t.Fatalf("answer before deadline: status %d", code)
+	}
+
+	e.clock.Advance(2 * time.Minute)
+	if code := e.do("POST", "/api/attempts/"+attempt.ID+"/answers", fiber.Map{"questionId": "q2", "selectedOptionId": "q2-b"}, nil); code != fiber.StatusForbidden {
+		t.Fatalf("answer after deadline: status %d, want 403", code)
+	}
+	if n := len(e.attempt(attempt.ID).Answers); n != 1 {
+		t.Fatalf("stored answers = %d, want 1", n)
+	}
+
+	// Answers sent with a late submit are dropped too; only the stored one is scored
+	var submitted models.Attempt
+	late := []fiber.Map{{"questionId": "q1", "selectedOptionId": "q1-a"}, {"questionId": "q2", "selectedOptionId": "q2-b"}}
+	if code := e.do("POST", "/api/attempts/"+attempt.ID+"/submit", late, &submitted); code != 200 {
+		t.Fatalf("late submit: status %d", code)
+	}
+	if submitted.Score != 25 || len(e.attempt(attempt.ID).Answers) != 1 {
+		t.Fatalf("late submit = %v with %d answers, want 25 with 1", submitted.Score, len(e.attempt(attempt.ID).Answers))
+	}
+}
+
+func TestSubmitScoresAndBlocksRestart(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedExam()
+	attempt := e.start("student-1")
+
+	e.clock.Advance(20 * time.Minute)
+	answers := []fiber.Map{
+		{"questionId": "q1", "selectedOptionId": "q1-b"}, // wrong
+		{"questionId": "q2", "selectedOptionId": "q2-b"}, // correct, 15 of 20 points
+	}
+	var submitted models.Attempt
+	if code := e.do("POST", "/api/attempts/"+attempt.ID+"/submit", answers, &submitted); code != 200 {
+		t.Fatalf("submit: status %d", code)
+	}
+	if submitted.Status != models.AttemptSubmitted {
+		t.Fatalf("status = %s, want SUBMITTED", submitted.Status)
+	}
+	if submitted.Score != 75 {
+		t.Fatalf("score = %v, want 75", submitted.Score)
+	}
+	if submitted.SubmittedAt == nil || !submitted.SubmittedAt.Equal(testStart.Add(20*time.Minute)) {
+		t.Fatalf("submittedAt = %v, want fake clock time", submitted.SubmittedAt)
+	}
+
+	if code := e.do("POST", "/api/attempts/start", fiber.Map{"batchId": "batch-1", "studentId": "student-1"}, nil); code != fiber.StatusForbidden {
+		t.Fatalf("restart after submit: status %d, want 403", code)
+	}
+}
+
+func TestForceSubmitScoresSavedAnswers(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedExam()
+	attempt := e.start("student-1")
+
+	e.do("POST", "/api/attempts/"+attempt.ID+"/answers", fiber.Map{"questionId": "q1", "selectedOptionId": "q1-a"}, nil)
+
+	var forced models.Attempt
+	if code := e.do("POST", "/api/attempts/"+attempt.ID+"/force-submit", nil, &forced); code != 200 {
+		t.Fatalf("force submit: status %d", code)
+	}
+	if forced.Status != models.AttemptSubmitted || forced.Score != 25 {
+		t.Fatalf("forced = %s/%v, want SUBMITTED/25", forced.Status, forced.Score)
+	}
+	if code := e.do("POST", "/api/attempts/"+attempt.ID+"/force-submit", nil, nil); code != fiber.StatusConflict {
+		t.Fatalf("second force submit: status %d, want 409", code)
+	}
+}
+
+func TestAccommodationExtraTimeAndBreaks(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedExam()
+	e.create(&models.Accommodation{
+		ID:                 "acc-1",
+		StudentID:          "student-1",
+		ExtraTimePercent:   50,
+		ExtendedEndMinutes: 30,
+		AllowBreaks:        true,
+		MaxBreakMinutes:    10,
+	})
+
+	attempt := e.start("student-1")
+	if attempt.RemainingTime != 5400 {
+		t.Fatalf("remaining with +50%% = %d, want 5400", attempt.RemainingTime)
+	}
+
+	e.clock.Advance(30 * time.Minute)
+	if code := e.do("POST", "/api/attempts/"+attempt.ID+"/break", nil, nil); code != 200 {
+		t.Fatalf("break: status %d", code)
+	}
+	e.clock.Advance(10 * time.Minute)
+	e.do("POST", "/api/attempts/"+attempt.ID+"/resume", nil, nil)
+
+	if got := e.remaining(attempt.ID); got != 3600 {
+		t.Fatalf("remaining after break = %d, want 3600", got)
+	}
+	if code := e.do("POST", "/api/attempts/"+attempt.ID+"/break", nil, nil); code != fiber.StatusForbidden {
+		t.Fatalf("second break over allowance: status %d, want 403", code)
+	}
+}
+
+func TestOverlongBreakIsCappedOnResume(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedExam()
+	e.create(&models.Accommodation{ID: "acc-1", StudentID: "student-1", AllowBreaks: true, MaxBreakMinutes: 10})
+	attempt := e.start("student-1")
+
+	// 25 minutes away on a 10 minute allowance: 15 of them are exam time
+	e.do("POST", "/api/attempts/"+attempt.ID+"/break", nil, nil)
+	e.clock.Advance(25 * time.Minute)
+	e.do("POST", "/api/attempts/"+attempt.ID+"/resume", nil, nil)
+	if got := e.attempt(attempt.ID).TotalPausedTime; got != 600 {
+		t.Fatalf("booked break = %d, want 600", got)
+	}
+	if got := e.remaining(attempt.ID); got != 2700 {
+		t.Fatalf("remaining = %d, want 2700", got)
+	}
+
+	// A teacher's pause is not a break and is not capped
+	e.do("POST", "/api/attempts/"+attempt.ID+"/pause", nil, nil)
+	e.clock.Advance(20 * time.Minute)
+	e.do("POST", "/api/attempts/"+attempt.ID+"/resume", nil, nil)
+	if got := e.remaining(attempt.ID); got != 2700 {
+		t.Fatalf("remaining after teacher pause = %d, want 2700", got)
+	}
+}
+
+func TestExtendedEndOutlastsBatchWindow(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedExam()
+	e.create(
+		&models.User{ID: "student-2", Email: "siswa2@example.com", Role: models.RoleStudent},
+		&models.Accommodation{ID: "acc-2", StudentID: "student-2", BatchID: "batch-1", ExtendedEndMinutes: 30},
+	)
+
+	// 09:30: regular students have 30 minutes left, the accommodated one until 10:30
+	e.clock.Advance(90 * time.Minute)
+	regular := e.start("student-1")
+	extended := e.start("student-2")
+	if regular.RemainingTime != 1800 || extended.RemainingTime != 3600 {
+		t.Fatalf("remaining = %d/%d, want 1800/3600", regular.RemainingTime, extended.RemainingTime)
+	}
+
+	// 10:15: the batch window is over but the extended end is not
+	e.clock.Advance(45 * time.Minute)
+	if got := e.remaining(regular.ID); got != 0 {
+		t.Fatalf("regular remaining = %d, want 0", got)
+	}
+	if got := e.remaining(extended.ID); got != 900 {
+		t.Fatalf("extended remaining = %d, want 900", got)
+	}
+}
+
+func TestResetAndReopen(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedExam()
+	admin := []string{"X-User", "admin-1", "X-Role", string(models.RoleAdmin)}
+
+	attempt := e.start("student-1")
+	e.clock.Advance(10 * time.Minute)
+	e.do("POST", "/api/attempts/"+attempt.ID+"/submit", []fiber.Map{{"questionId": "q1", "selectedOptionId": "q1-a"}}, nil)
+
+	// Reopen 20 minutes later: the student gets back the 50 minutes they had left
+	e.clock.Advance(20 * time.Minute)
+	if code := e.do("POST", "/api/attempts/"+attempt.ID+"/reopen", fiber.Map{}, nil, admin...); code != fiber.StatusBadRequest {
+		t.Fatalf("reopen without reason: status %d, want 400", code)
+	}
+	var reopened models.Attempt
+	if code := e.do("POST", "/api/attempts/"+attempt.ID+"/reopen", fiber.Map{"reason": "network outage"}, &reopened, admin...); code != 200 {
+		t.Fatalf("reopen: status %d", code)
+	}
+	if reopened.Status != models.AttemptActive || reopened.RemainingTime != 3000 {
+		t.Fatalf("reopened = %s/%d, want ACTIVE/3000", reopened.Status, reopened.RemainingTime)
+	}
+	if n := len(e.attempt(attempt.ID).Answers); n != 1 {
+		t.Fatalf("answers after reopen = %d, want 1 (kept)", n)
+	}
+
+	// Reset discards answers and lets the student start over with the full duration
+	if code := e.do("POST", "/api/attempts/"+attempt.ID+"/reset", fiber.Map{"reason": "wrong student"}, nil, admin...); code != 200 {
+		t.Fatalf("reset: status %d", code)
+	}
+	reset := e.attempt(attempt.ID)
+	if reset.Status != models.AttemptResetByAdmin || len(reset.Answers) != 0 {
+		t.Fatalf("reset = %s with %d answers, want RESET_BY_ADMIN with 0", reset.Status, len(reset.Answers))
+	}
+
+	// A stale client submitting the reset attempt must not undo the reset
+	if code := e.do("POST", "/api/attempts/"+attempt.ID+"/submit", []fiber.Map{{"questionId": "q1", "selectedOptionId": "q1-a"}}, nil); code != fiber.StatusConflict {
+		t.Fatalf("submit after reset: status %d, want 409", code)
+	}
+	if late := e.attempt(attempt.ID); late.Status != models.AttemptResetByAdmin || len(late.Answers) != 0 {
+		t.Fatalf("after late submit = %s with %d answers, want RESET_BY_ADMIN with 0", late.Status, len(late.Answers))
+	}
+	// Nor may a teacher force-submit it and lock the student out
+	if code := e.do("POST", "/api/attempts/"+attempt.ID+"/force-submit", nil, nil); code != fiber.StatusConflict {
+		t.Fatalf("force submit after reset: status %d, want 409", code)
+	}
+	if forced := e.attempt(attempt.ID); forced.Status != models.AttemptResetByAdmin {
+		t.Fatalf("after force submit = %s, want RESET_BY_ADMIN", forced.Status)
+	}
+
+	fresh := e.start("student-1")
+	if fresh.ID == attempt.ID || fresh.RemainingTime != 3600 {
+		t.Fatalf("fresh attempt = %s/%d, want new attempt with 3600", fresh.ID, fresh.RemainingTime)
+	}
+
+	var audits int64
+	e.db.Model(&models.EventLog{}).Where("attempt_id = ? AND event_type IN ?", attempt.ID,
+		[]models.EventType{models.EventAttemptReset, models.EventAttemptReopen}).Count(&audits)
+	if audits != 2 {
+		t.Fatalf("audit events = %d, want 2", audits)
+	}
+}
@@ -1,7 +1,7 @@
 package handlers
 
 import (
-	"academic-suite-backend/database"
+	"academic-suite-backend/apperr"
 	"academic-suite-backend/models"
 	"fmt"
 	"time"
@@ -16,8 +16,8 @@ import (
 var jwtSecret = []byte("secret")
 
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
 }
 
 type AuthTokens struct {
@@ -34,38 +34,38 @@ type AuthTokens struct {
 // @Produce      json
 // @Param        request body LoginRequest true "Login Credentials"
 // @Success      200  {object} map[string]interface{}
-// @Failure      400  {object} map[string]string
-// @Failure      401  {object} map[string]string
+// @Failure      400  {object} apperr.Response
+// @Failure      401  {object} apperr.Response
 // @Router       /api/auth/login [post]
-func Login(c *fiber.Ctx) error {
+func (s *AuthService) Login(c *fiber.Ctx) error {
 	var req LoginRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 
-	var user models.User
-	result := database.DB.Where("email = ?", req.Email).First(&user)
-	if result.Error != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User tidak ditemukan"})
+	user, err := s.users.FindByEmail(req.Email)
+	if err != nil {
+		return apperr.Unauthorized("user_not_found")
 	}
 
 	// Verify Password
-	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Password salah"})
+		return apperr.Unauthorized("wrong_password")
 	}
 
 	// Generate Token
 	claims := jwt.MapClaims{
 		"userId": user.ID,
 		"role":   user.Role,
-		"exp":    time.Now().Add(time.Hour * 1).Unix(),
+		"locale": user.Locale,
+		"exp":    s.clock.Now().Add(time.Hour * 1).Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	t, err := token.SignedString(jwtSecret)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not login"})
+		return apperr.Internal("login_failed", err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -73,7 +73,7 @@ func Login(c *fiber.Ctx) error {
 		"tokens": AuthTokens{
 			AccessToken:  t,
 			RefreshToken: t + "_refresh", // Mock refresh token
-			ExpiresAt:    time.Now().Add(time.Hour*1).Unix() * 1000,
+			ExpiresAt:    s.clock.Now().Add(time.Hour*1).Unix() * 1000,
 		},
 	})
 }
@@ -86,27 +86,27 @@ func Login(c *fiber.Ctx) error {
 // @Produce      json
 // @Security     ApiKeyAuth
 // @Success      200  {object} models.User
-// @Failure      404  {object} map[string]string
+// @Failure      404  {object} apperr.Response
 // @Router       /api/auth/profile [get]
-func GetProfile(c *fiber.Ctx) error {
+func (s *AuthService) GetProfile(c *fiber.Ctx) error {
 	// userId from middleware
 	userId := c.Locals("userId").(string)
 
-	var user models.User
-	if err := database.DB.First(&user, "id = ?", userId).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	user, err := s.users.FindByID(userId)
+	if err != nil {
+		return apperr.NotFound("user_not_found")
 	}
 
 	return c.JSON(user)
 }
 
 type ForgotPasswordRequest struct {
-	Email string `json:"email"`
+	Email string `json:"email" validate:"required,email"`
 }
 
 type ResetPasswordRequest struct {
-	Token       string `json:"token"`
-	NewPassword string `json:"newPassword"`
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required,min=6,max=72"` // bcrypt reads at most 72 bytes
 }
 
 // ForgotPassword godoc
@@ -117,19 +117,19 @@ type ResetPasswordRequest struct {
 // @Produce      json
 // @Param        request body ForgotPasswordRequest true "Email"
 // @Success      200  {object} map[string]string
-// @Failure      404  {object} map[string]string
+// @Failure      404  {object} apperr.Response
 // @Router       /api/auth/forgot-password [post]
-func ForgotPassword(c *fiber.Ctx) error {
+func (s *AuthService) ForgotPassword(c *fiber.Ctx) error {
 	var req ForgotPasswordRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 
-	var user models.User
-	if err := database.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
+	user, err := s.users.FindByEmail(req.Email)
+	if err != nil {
 		// Return 200 even if not found to prevent enumeration, or 404 for dev convenience?
 		// For this project, let's return 404 to be helpful.
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Email tidak terdaftar"})
+		return apperr.NotFound("email_not_registered")
 	}
 
 	// Generate Token
@@ -137,11 +137,11 @@ func ForgotPassword(c *fiber.Ctx) error {
 	resetToken := models.PasswordResetToken{
 		Token:     token,
 		UserID:    user.ID,
-		ExpiresAt: time.Now().Add(15 * time.Minute),
-		CreatedAt: time.Now(),
+		ExpiresAt: s.clock.Now().Add(15 * time.Minute),
+		CreatedAt: s.clock.Now(),
 	}
 
-	database.DB.Create(&resetToken)
+	s.db.Create(&resetToken)
 
 	// In a real app, send email. Here, log to console.
 	fmt.Printf("\n=== PASSWORD RESET TOKEN ===\nEmail: %s\nToken: %s\nLink: /reset-password?token=%s\n============================\n", user.Email, token, token)
@@ -159,32 +159,35 @@ func ForgotPassword(c *fiber.Ctx) error {
 // @Produce      json
 // @Param        request body ResetPasswordRequest true "Token and New Password"
 // @Success      200  {object} map[string]string
-// @Failure      400  {object} map[string]string
+// @Failure      400  {object} apperr.Response
 // @Router       /api/auth/reset-password [post]
-func ResetPassword(c *fiber.Ctx) error {
+func (s *AuthService) ResetPassword(c *fiber.Ctx) error {
 	var req ResetPasswordRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 
 	var resetToken models.PasswordResetToken
-	if err := database.DB.Where("token = ?", req.Token).First(&resetToken).Error; err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Token tidak valid"})
+	if err := s.db.Where("token = ?", req.Token).First(&resetToken).Error; err != nil {
+		return apperr.BadRequest("invalid_token")
 	}
 
-	if time.Now().After(resetToken.ExpiresAt) {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Token kedaluwarsa"})
+	if s.clock.Now().After(resetToken.ExpiresAt) {
+		return apperr.BadRequest("token_expired")
 	}
 
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return apperr.Internal("password_hash_failed", err)
+	}
 
 	// Update User Password
-	if err := database.DB.Model(&models.User{}).Where("id = ?", resetToken.UserID).Update("password", string(hashedPassword)).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Gagal mereset password"})
+	if err := s.users.UpdatePassword(resetToken.UserID, string(hashedPassword)); err != nil {
+		return apperr.Internal("password_reset_failed", err)
 	}
 
 	// Delete used token
-	database.DB.Delete(&resetToken)
+	s.db.Delete(&resetToken)
 
 	return c.JSON(fiber.Map{"message": "Password berhasil diubah. Silakan login."})
 }
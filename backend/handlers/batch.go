@@ -1,73 +1,111 @@
 package handlers
 
 import (
-	"academic-suite-backend/database"
+	"academic-suite-backend/apperr"
 	"academic-suite-backend/models"
-	"encoding/json"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
-// GetBatches godoc
-// @Summary      Get All Exam Batches
-// @Description  Retrieve a list of all exam batches
-// @Tags         batches
-// @Produce      json
-// @Success      200  {array}  models.ExamBatch
-// @Router       /api/batches [get]
-// BatchResponse with proper JSON types
+// BatchResponse adds the participant and waitlist IDs (from their join tables) to the batch
 type BatchResponse struct {
 	models.ExamBatch
 	AllowedParticipants []string `json:"allowedParticipants"`
 	Waitlist            []string `json:"waitlist"`
 }
 
-func toBatchResponse(b models.ExamBatch) BatchResponse {
-	var participants []string
-	if b.AllowedParticipants != "" {
-		_ = json.Unmarshal([]byte(b.AllowedParticipants), &participants)
-	}
-	if participants == nil {
-		participants = []string{}
-	}
+// inBatchZone renders the schedule in the batch's timezone, so "2025-12-22T14:50:00+07:00"
+// reads as the wall time the teacher entered
+func inBatchZone(b models.ExamBatch) models.ExamBatch {
+	loc := batchLocation(b)
+	b.StartTime = b.StartTime.In(loc)
+	b.EndTime = b.EndTime.In(loc)
+	b.Timezone = loc.String()
+	return b
+}
 
-	var waitlist []string
-	if b.Waitlist != "" {
-		_ = json.Unmarshal([]byte(b.Waitlist), &waitlist)
+func (s *BatchService) toBatchResponse(b models.ExamBatch) BatchResponse {
+	return BatchResponse{
+		ExamBatch:           inBatchZone(b),
+		AllowedParticipants: batchParticipantIDs(s.db, b.ID),
+		Waitlist:            batchWaitlistIDs(s.db, b.ID),
 	}
-	if waitlist == nil {
-		waitlist = []string{}
+}
+
+// toBatchResponses loads participants and waitlists for many batches in two queries
+func (s *BatchService) toBatchResponses(batches []models.ExamBatch) []BatchResponse {
+	ids := make([]string, 0, len(batches))
+	for _, b := range batches {
+		ids = append(ids, b.ID)
 	}
 
-	// Prevent shadowing/recursion issues by manually copying or embedding
-	// But embedding overrides fields.
-	// To avoid "AllowedParticipants" duplicate in JSON (one from embedded, one specific),
-	// we should use a struct that *doesn't* embed the raw string fields if we want control,
-	// OR use the `json:"-"` on the model if possible (but model is used elsewhere).
-	// Easier Strategy: Create a response struct that maps fields manually or use map[string]interface{}.
-	// Robust Strategy: Copy meaningful fields.
+	participants := make(map[string][]string)
+	waitlists := make(map[string][]string)
+	if len(ids) > 0 {
+		var ps []models.BatchParticipant
+		s.db.Where("batch_id IN ?", ids).Order("created_at, student_id").Find(&ps)
+		for _, p := range ps {
+			participants[p.BatchID] = append(participants[p.BatchID], p.StudentID)
+		}
+
+		var ws []models.BatchWaitlistEntry
+		s.db.Where("batch_id IN ?", ids).Order("position, created_at").Find(&ws)
+		for _, w := range ws {
+			waitlists[w.BatchID] = append(waitlists[w.BatchID], w.StudentID)
+		}
+	}
 
-	return BatchResponse{
-		ExamBatch:           b,
-		AllowedParticipants: participants,
-		Waitlist:            waitlist,
+	responses := []BatchResponse{}
+	for _, b := range batches {
+		resp := BatchResponse{ExamBatch: inBatchZone(b), AllowedParticipants: participants[b.ID], Waitlist: waitlists[b.ID]}
+		if resp.AllowedParticipants == nil {
+			resp.AllowedParticipants = []string{}
+		}
+		if resp.Waitlist == nil {
+			resp.Waitlist = []string{}
+		}
+		responses = append(responses, resp)
 	}
+	return responses
 }
 
-// But wait, embedding `models.ExamBatch` will still export `AllowedParticipants` string field unless we hide it.
-// The frontend will receive BOTH `allowedParticipants` (string) and `AllowedParticipants` (array) if casing differs?
-// Go JSON is case-insensitive match on decode, but strictly respecting tags on encode.
-// models.ExamBatch has `json:"allowedParticipants"`.
-// BatchResponse has `json:"allowedParticipants"`.
-// The one in BatchResponse (top level) should override.
+// GetBatches godoc
+// @Summary      Get All Exam Batches
+// @Description  Retrieve exam batches, optionally only those a student may sit (listed as participant or open to all)
+// @Tags         batches
+// @Produce      json
+// @Param        studentId query string false "Student ID"
+// @Param        quizId    query string false "Quiz ID"
+// @Param        classId   query string false "Class ID"
+// @Success      200  {array}  BatchResponse
+// @Router       /api/batches [get]
+func (s *BatchService) GetBatches(c *fiber.Ctx) error {
+	query := s.db
+	if studentId := c.Query("studentId"); studentId != "" {
+		query = query.Where("EXISTS (SELECT 1 FROM batch_participants bp WHERE bp.batch_id = exam_batches.id AND bp.student_id = ?)"+
+			" OR NOT EXISTS (SELECT 1 FROM batch_participants bp WHERE bp.batch_id = exam_batches.id)", studentId)
+	}
+	if quizId := c.Query("quizId"); quizId != "" {
+		query = query.Where("quiz_id = ?", quizId)
+	}
+	if classId := c.Query("classId"); classId != "" {
+		query = query.Where("class_id = ?", classId)
+	}
 
-func GetBatches(c *fiber.Ctx) error {
 	var batches []models.ExamBatch
-	database.DB.Find(&batches)
+	query.Find(&batches)
+
+	var waitlisted []string
+	s.db.Model(&models.BatchWaitlistEntry{}).Distinct().Pluck("batch_id", &waitlisted)
+	hasWaitlist := make(map[string]bool, len(waitlisted))
+	for _, id := range waitlisted {
+		hasWaitlist[id] = true
+	}
 
 	// Lazy status update logic... (Keep existing logic)
-	now := time.Now()
+	now := s.clock.Now()
 	for i := range batches {
 		b := &batches[i]
 		changed := false
@@ -80,131 +118,219 @@ func GetBatches(c *fiber.Ctx) error {
 			changed = true
 		}
 		if changed {
-			database.DB.Model(b).Update("Status", b.Status)
+			s.db.Model(b).Update("Status", b.Status)
 		}
-	}
 
-	var responses []BatchResponse
-	for _, b := range batches {
-		responses = append(responses, toBatchResponse(b))
+		// Lazy no-show release: hand unused seats to the waitlist after the grace period
+		if b.NoShowGraceMinutes > 0 && hasWaitlist[b.ID] && now.After(b.StartTime.Add(time.Duration(b.NoShowGraceMinutes)*time.Minute)) {
+			processNoShows(s.db, s.events, b.ID, now)
+		}
 	}
-	return c.JSON(responses)
+
+	return c.JSON(s.toBatchResponses(batches))
 }
 
-func CreateBatch(c *fiber.Ctx) error {
+// CreateBatch godoc
+// @Summary      Create Exam Batch
+// @Description  startTime/endTime without an offset ("2025-12-22T14:50") are read in `timezone`, defaulting to the quiz's institution timezone
+// @Tags         batches
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  BatchResponse
+// @Failure      400  {object}  apperr.Response
+// @Router       /api/batches [post]
+func (s *BatchService) CreateBatch(c *fiber.Ctx) error {
 	type CreateBatchReq struct {
 		models.ExamBatch
+		// Raw strings so the zone is applied explicitly, these shadow the embedded time fields
+		StartTime           string   `json:"startTime"`
+		EndTime             string   `json:"endTime"`
 		AllowedParticipants []string `json:"allowedParticipants"`
 		Waitlist            []string `json:"waitlist"`
 		ClassID             string   `json:"classId"`
 	}
 
 	var req CreateBatchReq
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	userId, _ := c.Locals("userId").(string)
+	createdBy := req.CreatedBy
+	if createdBy == "" {
+		createdBy = userId
+	}
+
+	tzName, loc, err := batchTimezone(s.db, req.Timezone, req.QuizID, createdBy)
+	if err != nil {
+		return err
+	}
+	startTime, endTime, err := parseSchedule(req.StartTime, req.EndTime, loc)
+	if err != nil {
+		return err
 	}
 
 	batch := req.ExamBatch
 	batch.ID = "batch-" + time.Now().Format("20060102150405")
-	batch.CreatedAt = time.Now()
+	batch.CreatedAt = s.clock.Now()
 	batch.Status = models.StatusScheduled
+	batch.StartTime = startTime
+	batch.EndTime = endTime
+	batch.Timezone = tzName
 
 	// Fix: Auto-calculate duration if 0
 	if batch.Duration == 0 {
-		duration := int(batch.EndTime.Sub(batch.StartTime).Minutes())
-		if duration > 0 {
-			batch.Duration = duration
-		}
+		batch.Duration = scheduleMinutes(startTime, endTime)
 	}
 
 	// Sync participants if ClassID is provided
 	if req.ClassID != "" {
 		var class models.Class
-		if err := database.DB.First(&class, "id = ?", req.ClassID).Error; err == nil {
+		if err := s.db.First(&class, "id = ?", req.ClassID).Error; err == nil {
 			batch.ClassID = req.ClassID
-			// Class.StudentIDs is a JSON string of []string
-			var classStudentIDs []string
-			_ = json.Unmarshal([]byte(class.StudentIDs), &classStudentIDs)
-
-			// If we want to allow manually added participants on top of class, we merge them
-			// But user said "1 batch can only have 1 class". So we overwrite or take unique.
-			// Let's assume class defines the list.
-			req.AllowedParticipants = classStudentIDs
+			// "1 batch can only have 1 class": the class defines the list
+			req.AllowedParticipants = classStudentIDs(s.db, class.ID)
 		}
 	}
 
-	// Marshal arrays to string
-	ap, _ := json.Marshal(req.AllowedParticipants)
-	batch.AllowedParticipants = string(ap)
-
-	wl, _ := json.Marshal(req.Waitlist)
-	batch.Waitlist = string(wl)
-
-	if err := database.DB.Create(&batch).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create batch"})
+	now := s.clock.Now()
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&batch).Error; err != nil {
+			return err
+		}
+		if err := setBatchParticipants(tx, batch.ID, req.AllowedParticipants, now); err != nil {
+			return err
+		}
+		if err := setBatchWaitlist(tx, batch.ID, req.Waitlist, now); err != nil {
+			return err
+		}
+		// Participants beyond capacity go to the waitlist
+		return enforceCapacity(tx, &batch, now)
+	})
+	if err != nil {
+		return apperr.Internal("batch_create_failed", err)
 	}
 
-	LogEvent(models.EventBatchCreated, batch.ID, "", batch.CreatedBy, "Batch created")
-	return c.JSON(toBatchResponse(batch))
+	s.events.Log(models.EventBatchCreated, batch.ID, "", batch.CreatedBy, "Batch created")
+	return c.JSON(s.toBatchResponse(batch))
 }
 
-func UpdateBatch(c *fiber.Ctx) error {
+// UpdateBatch godoc
+// @Summary      Update Exam Batch
+// @Description  Times without an offset are read in `timezone`, else the zone the batch was scheduled in. Empty times keep the current schedule.
+// @Tags         batches
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "Batch ID"
+// @Success      200  {object}  BatchResponse
+// @Failure      400  {object}  apperr.Response
+// @Router       /api/batches/{id} [put]
+func (s *BatchService) UpdateBatch(c *fiber.Ctx) error {
 	id := c.Params("id")
 
 	type UpdateBatchReq struct {
 		models.ExamBatch
+		StartTime           string   `json:"startTime"`
+		EndTime             string   `json:"endTime"`
 		AllowedParticipants []string `json:"allowedParticipants"`
 		ClassID             string   `json:"classId"`
+		// Left out of the body, these keep their current value
+		Capacity           *int `json:"capacity" validate:"omitempty,min=0"`
+		NoShowGraceMinutes *int `json:"noShowGraceMinutes" validate:"omitempty,min=0"`
 	}
 
 	var req UpdateBatchReq
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 
 	var batch models.ExamBatch
-	if err := database.DB.First(&batch, "id = ?", id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Batch not found"})
+	if err := s.db.First(&batch, "id = ?", id).Error; err != nil {
+		return apperr.NotFound("batch_not_found")
+	}
+
+	tzName := batch.Timezone
+	if req.Timezone != "" {
+		tzName = req.Timezone
+	} else if tzName == "" {
+		// Batches from before timezones were stored: adopt the institution's zone now
+		tzName, _, _ = batchTimezone(s.db, "", batch.QuizID, batch.CreatedBy)
+	}
+	loc, err := loadTimezone(tzName)
+	if err != nil {
+		return err
+	}
+
+	startTime, endTime := batch.StartTime, batch.EndTime
+	if req.StartTime != "" || req.EndTime != "" {
+		start, end := req.StartTime, req.EndTime
+		if start == "" {
+			start = batch.StartTime.Format(time.RFC3339)
+		}
+		if end == "" {
+			end = batch.EndTime.Format(time.RFC3339)
+		}
+		startTime, endTime, err = parseSchedule(start, end, loc)
+		if err != nil {
+			return err
+		}
 	}
 
 	batch.Name = req.Name
 	batch.QuizID = req.QuizID
-	batch.StartTime = req.StartTime
-	batch.EndTime = req.EndTime
+	batch.StartTime = startTime
+	batch.EndTime = endTime
+	batch.Timezone = tzName
 	batch.Token = req.Token
+	if req.Capacity != nil {
+		batch.Capacity = *req.Capacity
+	}
+	if req.NoShowGraceMinutes != nil {
+		batch.NoShowGraceMinutes = *req.NoShowGraceMinutes
+	}
 
 	// Fix: Allow updating duration. If 0, auto-calculate.
 	if req.Duration > 0 {
 		batch.Duration = req.Duration
 	} else if req.Duration == 0 {
 		// Auto-calculate
-		duration := int(req.EndTime.Sub(req.StartTime).Minutes())
-		if duration > 0 {
-			batch.Duration = duration
-		}
+		batch.Duration = scheduleMinutes(startTime, endTime)
 	}
 
 	if req.ClassID != "" && req.ClassID != batch.ClassID {
 		var class models.Class
-		if err := database.DB.First(&class, "id = ?", req.ClassID).Error; err == nil {
+		if err := s.db.First(&class, "id = ?", req.ClassID).Error; err == nil {
 			batch.ClassID = req.ClassID
-			var classStudentIDs []string
-			_ = json.Unmarshal([]byte(class.StudentIDs), &classStudentIDs)
-			req.AllowedParticipants = classStudentIDs // Override manual list
+			req.AllowedParticipants = classStudentIDs(s.db, class.ID) // Override manual list
 		}
 	}
 
-	if req.AllowedParticipants != nil {
-		ap, _ := json.Marshal(req.AllowedParticipants)
-		batch.AllowedParticipants = string(ap)
-	}
-
-	if err := database.DB.Save(&batch).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update batch"})
+	var promoted []string
+	now := s.clock.Now()
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&batch).Error; err != nil {
+			return err
+		}
+		if req.AllowedParticipants != nil {
+			if err := setBatchParticipants(tx, batch.ID, req.AllowedParticipants, now); err != nil {
+				return err
+			}
+		}
+		// Capacity may have shrunk or grown
+		if err := enforceCapacity(tx, &batch, now); err != nil {
+			return err
+		}
+		var err error
+		promoted, err = promoteWaitlist(tx, &batch, now)
+		return err
+	})
+	if err != nil {
+		return apperr.Internal("batch_update_failed", err)
 	}
 
-	LogEvent(models.EventBatchUpdated, batch.ID, "", "", "Batch details updated")
-	return c.JSON(toBatchResponse(batch))
+	s.events.Log(models.EventBatchUpdated, batch.ID, "", "", "Batch details updated")
+	logPromotions(s.events, batch.ID, "batch update", promoted)
+	return c.JSON(s.toBatchResponse(batch))
 }
 
 // UpdateBatchStatus godoc
@@ -216,35 +342,35 @@ func UpdateBatch(c *fiber.Ctx) error {
 // @Param        id    path      string             true  "Batch ID"
 // @Param        status body      map[string]string  true  "Status Object (e.g. {'status': 'active'})"
 // @Success      200   {object}  models.ExamBatch
-// @Failure      404   {object}  map[string]string
+// @Failure      404   {object}  apperr.Response
 // @Router       /api/batches/{id}/status [put]
-func UpdateBatchStatus(c *fiber.Ctx) error {
+func (s *BatchService) UpdateBatchStatus(c *fiber.Ctx) error {
 	id := c.Params("id")
 	type StatusReq struct {
-		Status models.BatchStatus `json:"status"`
+		Status models.BatchStatus `json:"status" validate:"required,oneof=scheduled active frozen finished"`
 	}
 	var req StatusReq
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 
 	var batch models.ExamBatch
-	if err := database.DB.First(&batch, "id = ?", id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Batch not found"})
+	if err := s.db.First(&batch, "id = ?", id).Error; err != nil {
+		return apperr.NotFound("batch_not_found")
 	}
 
 	batch.Status = req.Status
 	// Handle freeze/resume logic dates if needed
 
-	database.DB.Save(&batch)
+	s.db.Save(&batch)
 
 	if req.Status == models.StatusFrozen {
-		LogEvent(models.EventBatchFrozen, batch.ID, "", "", "Batch frozen manually")
+		s.events.Log(models.EventBatchFrozen, batch.ID, "", "", "Batch frozen manually")
 	} else if req.Status == models.StatusActive {
-		LogEvent(models.EventBatchResumed, batch.ID, "", "", "Batch resumed manually")
+		s.events.Log(models.EventBatchResumed, batch.ID, "", "", "Batch resumed manually")
 	}
 
-	return c.JSON(toBatchResponse(batch))
+	return c.JSON(s.toBatchResponse(batch))
 }
 
 // GetBatchLiveStatus godoc
@@ -253,19 +379,19 @@ func UpdateBatchStatus(c *fiber.Ctx) error {
 // @Param        id   path      string true "Batch ID"
 // @Success      200  {array}   map[string]interface{}
 // @Router       /api/batches/{id}/live [get]
-func GetBatchLiveStatus(c *fiber.Ctx) error {
+func (s *BatchService) GetBatchLiveStatus(c *fiber.Ctx) error {
 	batchId := c.Params("id")
 
 	// Get all attempts for this batch
 	var attempts []models.Attempt
-	if err := database.DB.Where("batch_id = ?", batchId).Find(&attempts).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch attempts"})
+	if err := s.db.Where("batch_id = ?", batchId).Find(&attempts).Error; err != nil {
+		return apperr.Internal("attempts_load_failed", err)
 	}
 
 	// 1. Get Batch to check allowed participants
 	var batch models.ExamBatch
-	if err := database.DB.First(&batch, "id = ?", batchId).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Batch not found"})
+	if err := s.db.First(&batch, "id = ?", batchId).Error; err != nil {
+		return apperr.NotFound("batch_not_found")
 	}
 
 	userIds := []string{}
@@ -275,7 +401,7 @@ func GetBatchLiveStatus(c *fiber.Ctx) error {
 
 	var users []models.User
 	if len(userIds) > 0 {
-		database.DB.Where("id IN ?", userIds).Find(&users)
+		s.db.Where("id IN ?", userIds).Find(&users)
 	}
 	userMap := make(map[string]models.User)
 	for _, u := range users {
@@ -295,7 +421,7 @@ func GetBatchLiveStatus(c *fiber.Ctx) error {
 	}
 
 	var liveStatuses []LiveStatus
-	now := time.Now()
+	now := s.clock.Now()
 	threshold := now.Add(-30 * time.Second) // Online if active in last 30s
 
 	// Deduplicate: Keep latest attempt per student
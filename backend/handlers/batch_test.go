@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"academic-suite-backend/models"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// seedInstitution creates an institution with the given zone, a teacher and a quiz belonging to it
+func (e *testEnv) seedInstitution(timezone string) {
+	e.t.Helper()
+	e.create(
+		&models.Institution{ID: "inst-1", Name: "SMP 20 SURABAYA", Timezone: timezone},
+		&models.User{ID: "teacher-1", Email: "guru@example.com", Role: models.RoleTeacher, InstitutionID: "inst-1"},
+		&models.Quiz{ID: "quiz-1", Title: "Matematika", TotalPoints: 100, InstitutionID: "inst-1"},
+	)
+}
+
+func (e *testEnv) storedBatch(id string) models.ExamBatch {
+	e.t.Helper()
+	var b models.ExamBatch
+	if err := e.db.First(&b, "id = ?", id).Error; err != nil {
+		e.t.Fatalf("load batch %s: %v", id, err)
+	}
+	return b
+}
+
+func TestCreateBatchReadsWallTimeInInstitutionZone(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedInstitution("Asia/Jakarta")
+
+	var created BatchResponse
+	status := e.do("POST", "/api/batches", map[string]interface{}{
+		"name": "UTS", "quizId": "quiz-1", "startTime": "2025-12-22T13:50", "endTime": "2025-12-22T14:50",
+	}, &created, "X-User", "teacher-1")
+	if status != http.StatusOK {
+		t.Fatalf("create: status %d", status)
+	}
+
+	stored := e.storedBatch(created.ID)
+	if want := time.Date(2025, 12, 22, 7, 50, 0, 0, time.UTC); !stored.EndTime.Equal(want) {
+		t.Errorf("stored end = %s, want %s", stored.EndTime.UTC(), want)
+	}
+	if stored.Timezone != "Asia/Jakarta" || stored.Duration != 60 {
+		t.Errorf("stored timezone %q duration %d", stored.Timezone, stored.Duration)
+	}
+	// Rendered back with the +07:00 offset, so the edit form shows 14:50 again
+	if got := created.EndTime.Format(time.RFC3339); got != "2025-12-22T14:50:00+07:00" {
+		t.Errorf("response end = %s", got)
+	}
+}
+
+func TestCreateBatchAcceptsUTCInstant(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedInstitution("Asia/Jakarta")
+
+	var created BatchResponse
+	e.do("POST", "/api/batches", map[string]interface{}{
+		"name": "UTS", "quizId": "quiz-1", "startTime": "2025-12-22T06:50:00.000Z", "endTime": "2025-12-22T07:50:00.000Z",
+	}, &created, "X-User", "teacher-1")
+
+	if got := e.storedBatch(created.ID).EndTime; !got.Equal(time.Date(2025, 12, 22, 7, 50, 0, 0, time.UTC)) {
+		t.Errorf("stored end = %s", got.UTC())
+	}
+}
+
+func TestCreateBatchValidatesSchedule(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedInstitution("America/New_York")
+
+	cases := map[string]map[string]interface{}{
+		"end before start": {"quizId": "quiz-1", "startTime": "2025-12-22T10:00", "endTime": "2025-12-22T09:00"},
+		"missing end":      {"quizId": "quiz-1", "startTime": "2025-12-22T10:00"},
+		"unknown zone":     {"quizId": "quiz-1", "startTime": "2025-12-22T10:00", "endTime": "2025-12-22T11:00", "timezone": "WIB"},
+		"DST gap":          {"quizId": "quiz-1", "startTime": "2025-03-09T02:30", "endTime": "2025-03-09T04:00"},
+	}
+	for name, body := range cases {
+		if status := e.do("POST", "/api/batches", body, nil); status != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", name, status)
+		}
+	}
+}
+
+func TestBatchDurationAcrossDST(t *testing.T) {
+	// Batch IDs have one-second resolution, so each case gets its own database
+	t.Run("fall back", func(t *testing.T) {
+		e := newTestEnv(t)
+		e.seedInstitution("America/New_York")
+
+		// 00:00-03:00 on fall-back day is four real hours (01:00-02:00 happens twice)
+		var created BatchResponse
+		e.do("POST", "/api/batches", map[string]interface{}{
+			"quizId": "quiz-1", "startTime": "2025-11-02T00:00", "endTime": "2025-11-02T03:00",
+		}, &created)
+		if created.Duration != 240 {
+			t.Errorf("duration = %d, want 240", created.Duration)
+		}
+	})
+
+	t.Run("spring forward", func(t *testing.T) {
+		e := newTestEnv(t)
+		e.seedInstitution("Asia/Jakarta")
+
+		// 00:00-04:00 on spring-forward day is three real hours; the request zone overrides the institution's
+		var created BatchResponse
+		e.do("POST", "/api/batches", map[string]interface{}{
+			"quizId": "quiz-1", "startTime": "2025-03-09T00:00", "endTime": "2025-03-09T04:00", "timezone": "America/New_York",
+		}, &created)
+		if created.Duration != 180 {
+			t.Errorf("duration = %d, want 180", created.Duration)
+		}
+		if got := created.EndTime.Format(time.RFC3339); got != "2025-03-09T04:00:00-04:00" {
+			t.Errorf("end rendered as %s", got)
+		}
+	})
+}
+
+func TestUpdateBatchKeepsItsZone(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedInstitution("Asia/Jakarta")
+
+	var created BatchResponse
+	e.do("POST", "/api/batches", map[string]interface{}{
+		"name": "UTS", "quizId": "quiz-1", "startTime": "2025-12-22T08:00", "endTime": "2025-12-22T10:00",
+	}, &created, "X-User", "teacher-1")
+
+	// Changing the institution setting later does not move existing batches
+	e.db.Model(&models.Institution{}).Where("id = ?", "inst-1").Update("timezone", "Asia/Makassar")
+
+	var updated BatchResponse
+	status := e.do("PUT", "/api/batches/"+created.ID, map[string]interface{}{
+		"name": "UTS", "quizId": "quiz-1", "startTime": "2025-12-22T09:00", "endTime": "2025-12-22T10:00",
+	}, &updated)
+	if status != http.StatusOK {
+		t.Fatalf("update: status %d", status)
+	}
+	if got := e.storedBatch(created.ID).StartTime; !got.Equal(time.Date(2025, 12, 22, 2, 0, 0, 0, time.UTC)) {
+		t.Errorf("stored start = %s, want 02:00 UTC", got.UTC())
+	}
+	if updated.Timezone != "Asia/Jakarta" || updated.Duration != 60 {
+		t.Errorf("updated timezone %q duration %d", updated.Timezone, updated.Duration)
+	}
+}
+
+// A PUT without capacity or grace period leaves them as they were
+func TestUpdateBatchKeepsCapacity(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedInstitution("Asia/Jakarta")
+
+	var created BatchResponse
+	e.do("POST", "/api/batches", map[string]interface{}{
+		"name": "UTS", "quizId": "quiz-1", "startTime": "2025-12-22T08:00", "endTime": "2025-12-22T10:00",
+		"capacity": 30, "noShowGraceMinutes": 15,
+	}, &created, "X-User", "teacher-1")
+
+	update := map[string]interface{}{"name": "UTS Ganjil", "quizId": "quiz-1"}
+	if status := e.do("PUT", "/api/batches/"+created.ID, update, nil); status != http.StatusOK {
+		t.Fatalf("update: status %d", status)
+	}
+	if stored := e.storedBatch(created.ID); stored.Capacity != 30 || stored.NoShowGraceMinutes != 15 {
+		t.Errorf("capacity %d, grace %d after update, want 30 and 15", stored.Capacity, stored.NoShowGraceMinutes)
+	}
+
+	update["capacity"] = 0
+	e.do("PUT", "/api/batches/"+created.ID, update, nil)
+	if stored := e.storedBatch(created.ID); stored.Capacity != 0 || stored.NoShowGraceMinutes != 15 {
+		t.Errorf("capacity %d, grace %d after clearing capacity", stored.Capacity, stored.NoShowGraceMinutes)
+	}
+
+	update["noShowGraceMinutes"] = -5
+	var res validationResponse
+	if status := e.do("PUT", "/api/batches/"+created.ID, update, &res); status != http.StatusBadRequest || fieldRules(res.Error.Details)["noShowGraceMinutes"] != "min" {
+		t.Errorf("negative grace: status %d %+v", status, res.Error.Details)
+	}
+}
+
+func TestMakeupBatchUsesParentZone(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedInstitution("Asia/Jakarta")
+	e.create(
+		&models.User{ID: "student-1", Email: "siswa@example.com", Role: models.RoleStudent},
+		&models.ExamBatch{ID: "batch-1", QuizID: "quiz-1", Type: models.BatchRegular, Name: "UTS", Timezone: "Asia/Jakarta",
+			StartTime: testStart, EndTime: testStart.Add(2 * time.Hour), Duration: 60, Status: models.StatusFinished},
+		&models.BatchParticipant{BatchID: "batch-1", StudentID: "student-1", CreatedAt: testStart},
+	)
+
+	var makeup BatchResponse
+	status := e.do("POST", "/api/batches/batch-1/makeup", map[string]interface{}{
+		"startTime": "2025-03-12T08:00", "endTime": "2025-03-12T10:00",
+	}, &makeup, "X-User", "teacher-1")
+	if status != http.StatusOK {
+		t.Fatalf("makeup: status %d", status)
+	}
+	if got := makeup.StartTime.Format(time.RFC3339); got != "2025-03-12T08:00:00+07:00" {
+		t.Errorf("makeup start = %s", got)
+	}
+}
+
+// A batch open to all takes its absentees from its class; without a class only resets count
+func TestMakeupOfOpenBatch(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedExam()
+	e.create(
+		&models.User{ID: "student-2", Email: "budi@example.com", Role: models.RoleStudent},
+		&models.Class{ID: "class-1", Name: "X-1"},
+		&models.ClassStudent{ClassID: "class-1", StudentID: "student-1"},
+		&models.ClassStudent{ClassID: "class-1", StudentID: "student-2"},
+	)
+	e.submitAll("student-1", "q1-a")
+	schedule := map[string]interface{}{"startTime": "2025-03-12T08:00", "endTime": "2025-03-12T10:00", "source": MakeupFromAbsentees}
+
+	if status := e.do("POST", "/api/batches/batch-1/makeup", schedule, nil); status != http.StatusBadRequest {
+		t.Fatalf("absentees without a class: status %d, want 400", status)
+	}
+
+	e.db.Model(&models.ExamBatch{}).Where("id = ?", "batch-1").Update("class_id", "class-1")
+	var first, second BatchResponse
+	e.do("POST", "/api/batches/batch-1/makeup", schedule, &first)
+	// A second makeup in the same second gets its own ID
+	if status := e.do("POST", "/api/batches/batch-1/makeup", schedule, &second); status != http.StatusOK || second.ID == first.ID {
+		t.Fatalf("second makeup: status %d, IDs %s and %s", status, first.ID, second.ID)
+	}
+	if got := batchParticipantIDs(e.db, first.ID); len(got) != 1 || got[0] != "student-2" {
+		t.Errorf("makeup participants = %v, want [student-2]", got)
+	}
+}
+
+func TestBatchStatusFollowsClockAcrossUTCMidnight(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedInstitution("Asia/Jakarta")
+
+	// 06:00-08:00 WIB on Jan 1 is 23:00-01:00 UTC spanning Dec 31/Jan 1
+	var created BatchResponse
+	e.do("POST", "/api/batches", map[string]interface{}{
+		"quizId": "quiz-1", "startTime": "2026-01-01T06:00", "endTime": "2026-01-01T08:00",
+	}, &created)
+
+	statusAt := func(at time.Time) models.BatchStatus {
+		e.clock.Set(at)
+		var batches []BatchResponse
+		e.do("GET", "/api/batches", nil, &batches)
+		return batches[0].Status
+	}
+
+	if got := statusAt(time.Date(2025, 12, 31, 22, 59, 0, 0, time.UTC)); got != models.StatusScheduled {
+		t.Errorf("05:59 WIB: %s", got)
+	}
+	if got := statusAt(time.Date(2025, 12, 31, 23, 30, 0, 0, time.UTC)); got != models.StatusActive {
+		t.Errorf("06:30 WIB: %s", got)
+	}
+	if got := statusAt(time.Date(2026, 1, 1, 1, 1, 0, 0, time.UTC)); got != models.StatusFinished {
+		t.Errorf("08:01 WIB: %s", got)
+	}
+}
+
+func TestRemainingTimeNearBatchEndInUTCPlus7(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedInstitution("Asia/Jakarta")
+	e.create(&models.User{ID: "student-1", Email: "siswa@example.com", Role: models.RoleStudent})
+
+	// The old bug: a batch ending 14:50 WIB stored as 14:50 UTC gave 7 extra hours; as 07:50 UTC it must give 9 minutes
+	var created BatchResponse
+	e.do("POST", "/api/batches", map[string]interface{}{
+		"quizId": "quiz-1", "startTime": "2025-12-22T13:50", "endTime": "2025-12-22T14:50", "duration": 60,
+	}, &created)
+	e.db.Model(&models.ExamBatch{}).Where("id = ?", created.ID).Update("status", models.StatusActive)
+
+	e.clock.Set(time.Date(2025, 12, 22, 14, 41, 0, 0, time.FixedZone("WIB", 7*60*60)))
+	var attempt models.Attempt
+	if status := e.do("POST", "/api/attempts/start", map[string]string{"batchId": created.ID}, &attempt,
+		"X-User", "student-1", "X-Role", string(models.RoleStudent)); status != http.StatusOK {
+		t.Fatalf("start: status %d", status)
+	}
+	if got := e.remaining(attempt.ID); got != 9*60 {
+		t.Errorf("remaining = %d, want %d", got, 9*60)
+	}
+}
@@ -1,26 +1,74 @@
 package handlers
 
 import (
-	"academic-suite-backend/database"
+	"academic-suite-backend/apperr"
 	"academic-suite-backend/models"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
+// ClassResponse adds the member IDs from class_students to the class
+type ClassResponse struct {
+	models.Class
+	StudentIDs []string `json:"studentIds"`
+}
+
+// ClassRequest accepts studentIds as a JSON array or as a stringified JSON array
+type ClassRequest struct {
+	Name       string          `json:"name" validate:"required"`
+	SubjectID  string          `json:"subjectId"`
+	TeacherID  string          `json:"teacherId"`
+	StudentIDs json.RawMessage `json:"studentIds" swaggertype:"array,string"`
+}
+
+func (s *ClassService) toClassResponse(class models.Class) ClassResponse {
+	return ClassResponse{Class: class, StudentIDs: classStudentIDs(s.db, class.ID)}
+}
+
 // GetClasses godoc
 // @Summary      Get All Classes
 // @Tags         classes
 // @Produce      json
-// @Success      200  {array}  models.Class
+// @Param        studentId query string false "Only classes this student belongs to"
+// @Success      200  {array}  ClassResponse
 // @Router       /api/classes [get]
-func GetClasses(c *fiber.Ctx) error {
+func (s *ClassService) GetClasses(c *fiber.Ctx) error {
+	query := s.db
+	if studentId := c.Query("studentId"); studentId != "" {
+		query = query.Where("id IN (?)", s.db.Model(&models.ClassStudent{}).Select("class_id").Where("student_id = ?", studentId))
+	}
+
 	var classes []models.Class
-	if err := database.DB.Find(&classes).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch classes"})
+	if err := query.Find(&classes).Error; err != nil {
+		return apperr.Internal("classes_load_failed", err)
+	}
+
+	ids := make([]string, 0, len(classes))
+	for _, class := range classes {
+		ids = append(ids, class.ID)
+	}
+	members := make(map[string][]string)
+	if len(ids) > 0 {
+		var rows []models.ClassStudent
+		s.db.Where("class_id IN ?", ids).Order("student_id").Find(&rows)
+		for _, r := range rows {
+			members[r.ClassID] = append(members[r.ClassID], r.StudentID)
+		}
 	}
-	return c.JSON(classes)
+
+	responses := []ClassResponse{}
+	for _, class := range classes {
+		studentIDs := members[class.ID]
+		if studentIDs == nil {
+			studentIDs = []string{}
+		}
+		responses = append(responses, ClassResponse{Class: class, StudentIDs: studentIDs})
+	}
+	return c.JSON(responses)
 }
 
 // GetClass godoc
@@ -28,15 +76,15 @@ func GetClasses(c *fiber.Ctx) error {
 // @Tags         classes
 // @Produce      json
 // @Param        id   path      string  true  "Class ID"
-// @Success      200  {object}  models.Class
+// @Success      200  {object}  ClassResponse
 // @Router       /api/classes/{id} [get]
-func GetClass(c *fiber.Ctx) error {
+func (s *ClassService) GetClass(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var class models.Class
-	if err := database.DB.First(&class, "id = ?", id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Class not found"})
+	if err := s.db.First(&class, "id = ?", id).Error; err != nil {
+		return apperr.NotFound("class_not_found")
 	}
-	return c.JSON(class)
+	return c.JSON(s.toClassResponse(class))
 }
 
 // CreateClass godoc
@@ -44,23 +92,38 @@ func GetClass(c *fiber.Ctx) error {
 // @Tags         classes
 // @Accept       json
 // @Produce      json
-// @Param        class body models.Class true "Class Data"
-// @Success      200  {object}  models.Class
+// @Param        class body ClassRequest true "Class Data"
+// @Success      200  {object}  ClassResponse
 // @Router       /api/classes [post]
-func CreateClass(c *fiber.Ctx) error {
-	var class models.Class
-	if err := c.BodyParser(&class); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+func (s *ClassService) CreateClass(c *fiber.Ctx) error {
+	var req ClassRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	studentIDs, err := decodeIDListJSON(req.StudentIDs)
+	if err != nil {
+		return apperr.BadRequest("invalid_student_ids")
 	}
 
-	class.ID = fmt.Sprintf("class-%d", time.Now().UnixNano())
-	class.CreatedAt = time.Now()
+	class := models.Class{
+		ID:        fmt.Sprintf("class-%d", time.Now().UnixNano()),
+		Name:      req.Name,
+		SubjectID: req.SubjectID,
+		TeacherID: req.TeacherID,
+		CreatedAt: s.clock.Now(),
+	}
 
-	if err := database.DB.Create(&class).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create class"})
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&class).Error; err != nil {
+			return err
+		}
+		return setClassStudents(tx, class.ID, studentIDs)
+	})
+	if err != nil {
+		return apperr.Internal("class_create_failed", err)
 	}
 
-	return c.JSON(class)
+	return c.JSON(s.toClassResponse(class))
 }
 
 // UpdateClass godoc
@@ -69,29 +132,44 @@ func CreateClass(c *fiber.Ctx) error {
 // @Accept       json
 // @Produce      json
 // @Param        id   path      string       true  "Class ID"
-// @Param        class body models.Class true "Class Data"
-// @Success      200  {object}  models.Class
+// @Param        class body ClassRequest true "Class Data"
+// @Success      200  {object}  ClassResponse
 // @Router       /api/classes/{id} [put]
-func UpdateClass(c *fiber.Ctx) error {
+func (s *ClassService) UpdateClass(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var class models.Class
-	if err := database.DB.First(&class, "id = ?", id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Class not found"})
+	if err := s.db.First(&class, "id = ?", id).Error; err != nil {
+		return apperr.NotFound("class_not_found")
 	}
 
-	var updateData models.Class
-	if err := c.BodyParser(&updateData); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	var updateData ClassRequest
+	if err := parseBody(c, &updateData); err != nil {
+		return err
+	}
+	studentIDs, err := decodeIDListJSON(updateData.StudentIDs)
+	if err != nil {
+		return apperr.BadRequest("invalid_student_ids")
 	}
 
 	class.Name = updateData.Name
 	class.SubjectID = updateData.SubjectID
 	class.TeacherID = updateData.TeacherID
-	class.StudentIDs = updateData.StudentIDs
-	class.UpdatedAt = time.Now()
+	class.UpdatedAt = s.clock.Now()
 
-	database.DB.Save(&class)
-	return c.JSON(class)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&class).Error; err != nil {
+			return err
+		}
+		// Omitted studentIds leaves membership unchanged
+		if studentIDs == nil {
+			return nil
+		}
+		return setClassStudents(tx, class.ID, studentIDs)
+	})
+	if err != nil {
+		return apperr.Internal("class_update_failed", err)
+	}
+	return c.JSON(s.toClassResponse(class))
 }
 
 // DeleteClass godoc
@@ -100,13 +178,13 @@ func UpdateClass(c *fiber.Ctx) error {
 // @Param        id   path      string  true  "Class ID"
 // @Success      200  {object}  map[string]string
 // @Router       /api/classes/{id} [delete]
-func DeleteClass(c *fiber.Ctx) error {
+func (s *ClassService) DeleteClass(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var class models.Class
-	if err := database.DB.First(&class, "id = ?", id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Class not found"})
+	if err := s.db.First(&class, "id = ?", id).Error; err != nil {
+		return apperr.NotFound("class_not_found")
 	}
 
-	database.DB.Delete(&class)
+	s.db.Delete(&class)
 	return c.JSON(fiber.Map{"message": "Class deleted"})
 }
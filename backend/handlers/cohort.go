@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/models"
+	"academic-suite-backend/psychometrics"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type TopicBreakdown struct {
+	Topic     string  `json:"topic"` // empty for questions without a topic, listed last; the client labels it
+	Items     int     `json:"items"`
+	MaxPoints float64 `json:"maxPoints"`
+	// MeanPercentage is the share of the topic's points the group earned on average
+	MeanPercentage float64 `json:"meanPercentage"`
+}
+
+// CohortGroup aggregates one class (or one batch without a class) sitting the quiz
+type CohortGroup struct {
+	Key       string   `json:"key"` // class ID, or batch ID when the batch has no class
+	ClassID   string   `json:"classId"`
+	ClassName string   `json:"className"`
+	BatchIDs  []string `json:"batchIds"`
+	// Summary is over percentage scores (0-100) of the best submitted attempt per student
+	Summary   psychometrics.Summary `json:"summary"`
+	PassCount int                   `json:"passCount"`
+	PassRate  float64               `json:"passRate"` // 0-1, score >= quiz passing score
+	Topics    []TopicBreakdown      `json:"topics"`
+}
+
+type CohortComparisonResponse struct {
+	QuizID       string        `json:"quizId"`
+	QuizTitle    string        `json:"quizTitle"`
+	PassingScore int           `json:"passingScore"`
+	Groups       []CohortGroup `json:"groups"`
+	Overall      CohortGroup   `json:"overall"`
+}
+
+var errCohortQuizMismatch = apperr.BadRequest("cohort_quiz_mismatch")
+
+// cohortBatches loads the batches to compare with their makeups. Either batchIDs or quizID is set.
+func (s *ReportService) cohortBatches(batchIDs []string, quizID string) (string, []models.ExamBatch, error) {
+	var batches []models.ExamBatch
+	if len(batchIDs) > 0 {
+		s.db.Where("id IN ?", batchIDs).Find(&batches)
+		if len(batches) != len(batchIDs) {
+			return "", nil, errBatchNotFound
+		}
+		for _, b := range batches {
+			if b.QuizID != batches[0].QuizID {
+				return "", nil, errCohortQuizMismatch
+			}
+		}
+		quizID = batches[0].QuizID
+	} else {
+		var count int64
+		s.db.Model(&models.Quiz{}).Where("id = ?", quizID).Count(&count)
+		if count == 0 {
+			return "", nil, errQuizNotFound
+		}
+		s.db.Where("quiz_id = ? AND type <> ?", quizID, models.BatchMakeup).Find(&batches)
+	}
+
+	// Makeups count with the batch they make up for
+	ids := make([]string, 0, len(batches))
+	have := make(map[string]bool)
+	for _, b := range batches {
+		ids = append(ids, b.ID)
+		have[b.ID] = true
+	}
+	if len(ids) > 0 {
+		var makeups []models.ExamBatch
+		s.db.Where("parent_batch_id IN ?", ids).Find(&makeups)
+		for _, m := range makeups {
+			if !have[m.ID] {
+				batches = append(batches, m)
+				have[m.ID] = true
+			}
+		}
+	}
+	return quizID, batches, nil
+}
+
+// cohortKey groups batches by class; a batch without a class is its own group
+func cohortKey(b models.ExamBatch) string {
+	if b.ClassID != "" {
+		return b.ClassID
+	}
+	if b.Type == models.BatchMakeup && b.ParentBatchID != "" {
+		return b.ParentBatchID
+	}
+	return b.ID
+}
+
+// summarizeCohort computes score statistics, pass rate and topic breakdown for one group
+func summarizeCohort(in *analysisInput) CohortGroup {
+	group := CohortGroup{BatchIDs: in.batchIDs, Topics: []TopicBreakdown{}}
+
+	percentages := make([]float64, 0, len(in.attempts))
+	for _, a := range in.attempts {
+		percentages = append(percentages, percentageOf(a.Score, in.quiz.TotalPoints))
+		if a.Score >= float64(in.quiz.PassingScore) {
+			group.PassCount++
+		}
+	}
+	group.Summary = psychometrics.Describe(percentages)
+	if len(in.attempts) > 0 {
+		group.PassRate = float64(group.PassCount) / float64(len(in.attempts))
+	}
+
+	// in.items follows in.quiz.Questions, so the topic of items[i] is Questions[i].Topic
+	type topicTotals struct {
+		items     int
+		maxPoints float64
+		earned    float64
+	}
+	topics := make(map[string]*topicTotals)
+	var order []string
+	for i, item := range in.items {
+		if !item.Scored {
+			continue
+		}
+		topic := strings.TrimSpace(in.quiz.Questions[i].Topic)
+		t, ok := topics[topic]
+		if !ok {
+			t = &topicTotals{}
+			topics[topic] = t
+			order = append(order, topic)
+		}
+		t.items++
+		t.maxPoints += item.Points
+		for _, sheet := range in.sheets {
+			if sheet.Responses[item.ID].Correct {
+				t.earned += item.Points
+			}
+		}
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return order[i] != "" && (order[j] == "" || order[i] < order[j])
+	})
+	for _, topic := range order {
+		t := topics[topic]
+		breakdown := TopicBreakdown{Topic: topic, Items: t.items, MaxPoints: t.maxPoints}
+		if n := len(in.sheets); n > 0 && t.maxPoints > 0 {
+			breakdown.MeanPercentage = t.earned / (float64(n) * t.maxPoints) * 100
+		}
+		group.Topics = append(group.Topics, breakdown)
+	}
+	return group
+}
+
+func (s *ReportService) getCohortComparison(batchIDs []string, quizID string) (*CohortComparisonResponse, error) {
+	quizID, batches, err := s.cohortBatches(batchIDs, quizID)
+	if err != nil {
+		return nil, err
+	}
+
+	groupBatches := make(map[string][]string)
+	groupClass := make(map[string]string)
+	var keys []string
+	allIDs := make([]string, 0, len(batches))
+	for _, b := range batches {
+		key := cohortKey(b)
+		if _, ok := groupBatches[key]; !ok {
+			keys = append(keys, key)
+		}
+		groupBatches[key] = append(groupBatches[key], b.ID)
+		if b.ClassID != "" {
+			groupClass[key] = b.ClassID
+		}
+		allIDs = append(allIDs, b.ID)
+	}
+	sort.Strings(keys)
+
+	classNames := make(map[string]string)
+	if len(groupClass) > 0 {
+		var classes []models.Class
+		classIDs := make([]string, 0, len(groupClass))
+		for _, id := range groupClass {
+			classIDs = append(classIDs, id)
+		}
+		s.db.Where("id IN ?", classIDs).Find(&classes)
+		for _, c := range classes {
+			classNames[c.ID] = c.Name
+		}
+	}
+
+	overall := s.buildAnalysisInput(quizID, allIDs)
+	response := &CohortComparisonResponse{
+		QuizID:       overall.quiz.ID,
+		QuizTitle:    overall.quiz.Title,
+		PassingScore: overall.quiz.PassingScore,
+		Groups:       []CohortGroup{},
+		Overall:      summarizeCohort(overall),
+	}
+	response.Overall.Key = "overall"
+
+	for _, key := range keys {
+		ids := groupBatches[key]
+		sort.Strings(ids)
+		group := summarizeCohort(s.buildAnalysisInput(quizID, ids))
+		group.Key = key
+		group.ClassID = groupClass[key]
+		group.ClassName = classNames[group.ClassID]
+		response.Groups = append(response.Groups, group)
+	}
+	return response, nil
+}
+
+// CompareCohorts godoc
+// @Summary      Compare Classes on a Quiz
+// @Description  Mean, median, standard deviation, percentiles, pass rate and per-topic breakdown per class (batches without a class are compared on their own), plus the overall figures. Pass several batch IDs of the same quiz, or a quiz ID for all its batches. Makeup batches count with their class.
+// @Tags         reports
+// @Produce      json
+// @Param        batchIds query string false "Comma separated batch IDs"
+// @Param        quizId   query string false "Quiz ID (all batches)"
+// @Success      200  {object}  CohortComparisonResponse
+// @Failure      400  {object}  apperr.Response
+// @Failure      404  {object}  apperr.Response
+// @Router       /api/reports/compare [get]
+func (s *ReportService) CompareCohorts(c *fiber.Ctx) error {
+	var batchIDs []string
+	seen := make(map[string]bool)
+	for _, id := range strings.Split(c.Query("batchIds"), ",") {
+		if id = strings.TrimSpace(id); id != "" && !seen[id] {
+			seen[id] = true
+			batchIDs = append(batchIDs, id)
+		}
+	}
+	quizId := c.Query("quizId")
+	if len(batchIDs) == 0 && quizId == "" {
+		return apperr.BadRequest("batch_ids_or_quiz_required")
+	}
+
+	report, err := s.getCohortComparison(batchIDs, quizId)
+	if err != nil {
+		return err
+	}
+	return c.JSON(report)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"academic-suite-backend/models"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Two classes and a batch without a class sit one quiz; class-1's makeup counts with class-1
+func TestCompareCohorts(t *testing.T) {
+	e := newTestEnv(t)
+	e.create(
+		&models.Quiz{ID: "quiz-c", Title: "Matematika", TotalPoints: 100, PassingScore: 60},
+		&models.Question{ID: "q1", QuizID: "quiz-c", Type: models.TypeMCQ, Points: 10, Topic: "Aljabar"},
+		&models.Question{ID: "q2", QuizID: "quiz-c", Type: models.TypeMCQ, Points: 10, Topic: " Aljabar "},
+		&models.Question{ID: "q3", QuizID: "quiz-c", Type: models.TypeMCQ, Points: 20},
+		&models.QuestionOption{ID: "q1-a", QuestionID: "q1", IsCorrect: true},
+		&models.QuestionOption{ID: "q1-b", QuestionID: "q1"},
+		&models.QuestionOption{ID: "q2-a", QuestionID: "q2", IsCorrect: true},
+		&models.QuestionOption{ID: "q2-b", QuestionID: "q2"},
+		&models.QuestionOption{ID: "q3-a", QuestionID: "q3", IsCorrect: true},
+		&models.QuestionOption{ID: "q3-b", QuestionID: "q3"},
+		&models.Class{ID: "class-1", Name: "X-1"},
+		&models.Class{ID: "class-2", Name: "X-2"},
+	)
+	batch := func(id, classID, parent string) *models.ExamBatch {
+		b := &models.ExamBatch{ID: id, QuizID: "quiz-c", ClassID: classID, Type: models.BatchRegular, StartTime: testStart, EndTime: testStart.Add(time.Hour), Duration: 60}
+		if parent != "" {
+			b.Type, b.ParentBatchID = models.BatchMakeup, parent
+		}
+		return b
+	}
+	e.create(batch("b1", "class-1", ""), batch("b1-makeup", "class-1", "b1"), batch("b2", "class-2", ""), batch("b3", "", ""))
+
+	submit := func(id, batchID, studentID string, score float64, options ...string) {
+		e.create(&models.Attempt{ID: id, BatchID: batchID, StudentID: studentID, Status: models.AttemptSubmitted, Score: score})
+		for i, opt := range options {
+			e.create(&models.Answer{AttemptID: id, QuestionID: []string{"q1", "q2", "q3"}[i], SelectedOptionID: opt})
+		}
+	}
+	submit("a1", "b1", "s1", 80, "q1-a", "q2-b", "q3-a")
+	submit("a2", "b1-makeup", "s2", 50, "q1-a", "q2-b", "q3-b")
+	submit("a3", "b2", "s3", 100, "q1-a", "q2-a", "q3-a")
+	submit("a4", "b3", "s4", 30, "q1-b", "q2-b", "q3-b")
+
+	var report CohortComparisonResponse
+	if status := e.do("GET", "/api/reports/compare?quizId=quiz-c", nil, &report); status != http.StatusOK {
+		t.Fatalf("status %d", status)
+	}
+	if len(report.Groups) != 3 {
+		t.Fatalf("groups = %+v", report.Groups)
+	}
+	if keys := []string{report.Groups[0].Key, report.Groups[1].Key, report.Groups[2].Key}; !reflect.DeepEqual(keys, []string{"b3", "class-1", "class-2"}) {
+		t.Errorf("group keys = %v", keys)
+	}
+
+	class1 := report.Groups[1]
+	if class1.ClassName != "X-1" || !reflect.DeepEqual(class1.BatchIDs, []string{"b1", "b1-makeup"}) {
+		t.Errorf("class-1 = %s %v", class1.ClassName, class1.BatchIDs)
+	}
+	if class1.Summary.Count != 2 || class1.Summary.Mean != 65 || class1.PassCount != 1 || class1.PassRate != 0.5 {
+		t.Errorf("class-1 summary = %+v, pass %d (%v)", class1.Summary, class1.PassCount, class1.PassRate)
+	}
+	// Aljabar (q1, q2): both got q1 = 20 of 40 points; untagged (q3) last: s1 only = 20 of 40
+	want := []TopicBreakdown{
+		{Topic: "Aljabar", Items: 2, MaxPoints: 20, MeanPercentage: 50},
+		{Topic: "", Items: 1, MaxPoints: 20, MeanPercentage: 50},
+	}
+	if !reflect.DeepEqual(class1.Topics, want) {
+		t.Errorf("class-1 topics = %+v", class1.Topics)
+	}
+
+	if report.Overall.Key != "overall" || report.Overall.Summary.Count != 4 || report.Overall.PassCount != 2 {
+		t.Errorf("overall = %+v", report.Overall)
+	}
+
+	// Batches of different quizzes are not compared
+	e.create(&models.Quiz{ID: "quiz-other", Title: "Fisika"}, &models.ExamBatch{ID: "b-other", QuizID: "quiz-other"})
+	var res validationResponse
+	if status := e.do("GET", "/api/reports/compare?batchIds=b1,b-other", nil, &res); status != http.StatusBadRequest || res.Error.Code != "cohort_quiz_mismatch" {
+		t.Errorf("mixed quizzes: status %d code %q", status, res.Error.Code)
+	}
+}
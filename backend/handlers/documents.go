@@ -0,0 +1,394 @@
+package handlers
+
+import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/models"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// slipQuestion is one row of a result slip
+type slipQuestion struct {
+	Number     int
+	Text       string
+	Choice     string // letter of the chosen option
+	Answer     string // option letter and text, or the typed answer
+	Key        string
+	Points     int
+	Earned     *int       // nil when the question is not scored automatically
+	Correct    *bool      // nil when not scored automatically
+	AnsweredAt *time.Time // in the batch timezone, nil when unanswered
+}
+
+// resultSlip is one student's result, used for the slip, the certificate and the answer matrix
+type resultSlip struct {
+	InstitutionName string
+	QuizTitle       string
+	BatchName       string
+	ClassName       string
+	StudentID       string
+	StudentName     string
+	Status          models.AttemptStatus
+	IsMakeup        bool
+	Score           float64
+	TotalPoints     int
+	PassingScore    int
+	Percentage      float64
+	Passed          bool
+	SubmittedAt     *time.Time // in the batch timezone
+	Questions       []slipQuestion
+}
+
+// batchDocuments is what the printable documents of a batch are made from
+type batchDocuments struct {
+	institution models.Institution
+	quiz        models.Quiz
+	batch       models.ExamBatch
+	className   string
+	report      *BatchReportResponse
+	slips       []resultSlip // best submitted attempt per student, by name
+}
+
+// quizWithQuestions loads a quiz with its questions and options in display order
+func quizWithQuestions(db *gorm.DB, quizID string) models.Quiz {
+	var quiz models.Quiz
+	db.Preload("Questions", func(db *gorm.DB) *gorm.DB { return db.Order("order_index, id") }).
+		Preload("Questions.Options", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		First(&quiz, "id = ?", quizID)
+	return quiz
+}
+
+// resultSlips builds a slip per attempt, scoring each question the way SubmitAttempt does
+func (s *ReportService) resultSlips(quiz models.Quiz, attempts []models.Attempt) []resultSlip {
+	if len(attempts) == 0 {
+		return []resultSlip{}
+	}
+
+	var institution models.Institution
+	if quiz.InstitutionID != "" {
+		s.db.First(&institution, "id = ?", quiz.InstitutionID)
+	}
+
+	attemptIDs := make([]string, 0, len(attempts))
+	studentIDs := make([]string, 0, len(attempts))
+	batchIDs := make([]string, 0, len(attempts))
+	for _, a := range attempts {
+		attemptIDs = append(attemptIDs, a.ID)
+		studentIDs = append(studentIDs, a.StudentID)
+		batchIDs = append(batchIDs, a.BatchID)
+	}
+
+	var answers []models.Answer
+	s.db.Where("attempt_id IN ?", attemptIDs).Find(&answers)
+	answersByAttempt := make(map[string]map[string]models.Answer)
+	for _, ans := range answers {
+		if answersByAttempt[ans.AttemptID] == nil {
+			answersByAttempt[ans.AttemptID] = make(map[string]models.Answer)
+		}
+		answersByAttempt[ans.AttemptID][ans.QuestionID] = ans
+	}
+
+	var students []models.User
+	s.db.Where("id IN ?", studentIDs).Find(&students)
+	studentNames := make(map[string]string)
+	for _, u := range students {
+		studentNames[u.ID] = u.Name
+	}
+
+	var batchList []models.ExamBatch
+	s.db.Where("id IN ?", batchIDs).Find(&batchList)
+	batches := make(map[string]models.ExamBatch)
+	for _, b := range batchList {
+		batches[b.ID] = b
+	}
+	classNames := s.classNames(batchList)
+
+	slips := make([]resultSlip, 0, len(attempts))
+	for _, a := range attempts {
+		batch := batches[a.BatchID]
+		slip := resultSlip{
+			InstitutionName: institution.Name,
+			QuizTitle:       quiz.Title,
+			BatchName:       batch.Name,
+			ClassName:       classNames[batch.ClassID],
+			StudentID:       a.StudentID,
+			StudentName:     studentNames[a.StudentID],
+			Status:          a.Status,
+			IsMakeup:        batch.Type == models.BatchMakeup,
+			Score:           a.Score,
+			TotalPoints:     quiz.TotalPoints,
+			PassingScore:    quiz.PassingScore,
+			Percentage:      percentageOf(a.Score, quiz.TotalPoints),
+			Passed:          a.Status == models.AttemptSubmitted && a.Score >= float64(quiz.PassingScore),
+			Questions:       []slipQuestion{},
+		}
+		if slip.StudentName == "" {
+			slip.StudentName = a.StudentID
+		}
+		if a.SubmittedAt != nil {
+			submitted := a.SubmittedAt.In(batchLocation(batch))
+			slip.SubmittedAt = &submitted
+		}
+
+		for i, q := range quiz.Questions {
+			ans, answered := answersByAttempt[a.ID][q.ID]
+			row := slipQuestion{Number: i + 1, Text: q.Text, Points: q.Points}
+			if answered && !ans.AnsweredAt.IsZero() {
+				answeredAt := ans.AnsweredAt.In(batchLocation(batch))
+				row.AnsweredAt = &answeredAt
+			}
+
+			if len(q.Options) == 0 {
+				// Typed answers are not scored automatically
+				row.Answer = strings.TrimSpace(ans.TextAnswer)
+				row.Key = q.CorrectAnswer
+				slip.Questions = append(slip.Questions, row)
+				continue
+			}
+
+			var keys []string
+			for j, opt := range q.Options {
+				label := optionLabel(j) + ". " + opt.Text
+				if opt.IsCorrect {
+					keys = append(keys, label)
+				}
+				if answered && opt.ID == ans.SelectedOptionID {
+					row.Choice = optionLabel(j)
+					row.Answer = label
+				}
+			}
+			row.Key = strings.Join(keys, ", ")
+			if len(keys) > 0 {
+				earned, correct := 0, false
+				for _, opt := range q.Options {
+					if answered && opt.IsCorrect && opt.ID == ans.SelectedOptionID {
+						earned, correct = q.Points, true
+					}
+				}
+				row.Earned = &earned
+				row.Correct = &correct
+			}
+			slip.Questions = append(slip.Questions, row)
+		}
+		slips = append(slips, slip)
+	}
+
+	sort.SliceStable(slips, func(i, j int) bool {
+		if slips[i].StudentName != slips[j].StudentName {
+			return slips[i].StudentName < slips[j].StudentName
+		}
+		return slips[i].StudentID < slips[j].StudentID
+	})
+	return slips
+}
+
+func (s *ReportService) classNames(batches []models.ExamBatch) map[string]string {
+	names := make(map[string]string)
+	var classIDs []string
+	for _, b := range batches {
+		if b.ClassID != "" {
+			classIDs = append(classIDs, b.ClassID)
+		}
+	}
+	if len(classIDs) == 0 {
+		return names
+	}
+	var classes []models.Class
+	s.db.Where("id IN ?", classIDs).Find(&classes)
+	for _, c := range classes {
+		names[c.ID] = c.Name
+	}
+	return names
+}
+
+// loadBatchDocuments gathers the report and the slips of a batch (with its makeups)
+func (s *ReportService) loadBatchDocuments(batchId string) (*batchDocuments, error) {
+	report, err := s.getBatchReportData(batchId)
+	if err != nil {
+		return nil, err
+	}
+	// Printed in alphabetical order rather than in map order
+	sort.SliceStable(report.Attempts, func(i, j int) bool {
+		return report.Attempts[i].StudentName < report.Attempts[j].StudentName
+	})
+
+	var batch models.ExamBatch
+	s.db.First(&batch, "id = ?", batchId)
+	quizID, batchIDs, err := s.itemAnalysisScope(batchId, "")
+	if err != nil {
+		return nil, err
+	}
+	quiz := quizWithQuestions(s.db, quizID)
+
+	docs := &batchDocuments{
+		quiz:      quiz,
+		batch:     batch,
+		className: s.classNames([]models.ExamBatch{batch})[batch.ClassID],
+		report:    report,
+		slips:     s.resultSlips(quiz, s.analysisAttempts(batchIDs)),
+	}
+	if quiz.InstitutionID != "" {
+		s.db.First(&docs.institution, "id = ?", quiz.InstitutionID)
+	}
+	return docs, nil
+}
+
+// documentsError passes API errors (batch not found, ...) through; anything else is an export failure
+func documentsError(err error) error {
+	var appErr *apperr.Error
+	if errors.As(err, &appErr) {
+		return err
+	}
+	return apperr.Internal("export_failed", err)
+}
+
+// fileSafe turns a name into something usable in a file name, fallback when nothing is left
+func fileSafe(name, fallback string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			b.WriteRune(r)
+		case r == ' ' || r == '.':
+			b.WriteRune('_')
+		}
+	}
+	if b.Len() == 0 {
+		return fallback
+	}
+	return b.String()
+}
+
+// ExportBatchReportPDF godoc
+// @Summary      Export Batch Report to PDF
+// @Description  Printable batch report: schedule, summary figures and the result of each student (makeup results included)
+// @Tags         reports
+// @Produce      application/pdf
+// @Param        id path string true "Batch ID"
+// @Success      200  {file}  file
+// @Failure      404  {object}  apperr.Response
+// @Router       /api/export/batch/{id}/pdf [get]
+func (s *ReportService) ExportBatchReportPDF(c *fiber.Ctx) error {
+	batchId := c.Params("id")
+
+	docs, err := s.loadBatchDocuments(batchId)
+	if err != nil {
+		return documentsError(err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeBatchReportPDF(&buf, requestLocale(c), docs); err != nil {
+		return apperr.Internal("export_failed", err)
+	}
+
+	c.Set("Content-Type", "application/pdf")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=report-%s.pdf", batchId))
+	return c.Send(buf.Bytes())
+}
+
+// ExportResultSlip godoc
+// @Summary      Export Result Slip
+// @Description  PDF result slip of a submitted attempt with the answer, key and points of every question. Students can only download their own.
+// @Tags         reports
+// @Produce      application/pdf
+// @Param        id path string true "Attempt ID"
+// @Success      200  {file}  file
+// @Failure      400  {object}  apperr.Response
+// @Failure      403  {object}  apperr.Response
+// @Failure      404  {object}  apperr.Response
+// @Router       /api/export/attempts/{id}/slip [get]
+func (s *ReportService) ExportResultSlip(c *fiber.Ctx) error {
+	var attempt models.Attempt
+	if err := s.db.First(&attempt, "id = ?", c.Params("id")).Error; err != nil {
+		return apperr.NotFound("attempt_not_found")
+	}
+	if role, _ := c.Locals("role").(string); models.UserRole(role) == models.RoleStudent {
+		if userId, _ := c.Locals("userId").(string); userId != attempt.StudentID {
+			return apperr.Forbidden("forbidden")
+		}
+	}
+	if attempt.Status != models.AttemptSubmitted {
+		return apperr.BadRequest("attempt_not_submitted")
+	}
+
+	var batch models.ExamBatch
+	s.db.First(&batch, "id = ?", attempt.BatchID)
+	slips := s.resultSlips(quizWithQuestions(s.db, batch.QuizID), []models.Attempt{attempt})
+
+	l := requestLocale(c)
+	var buf bytes.Buffer
+	if err := writeResultSlipPDF(&buf, l, slips[0]); err != nil {
+		return apperr.Internal("export_failed", err)
+	}
+
+	c.Set("Content-Type", "application/pdf")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=slip-%s.pdf", fileSafe(slips[0].StudentName, l.T("file.participant"))))
+	return c.Send(buf.Bytes())
+}
+
+// ExportBatchDocuments godoc
+// @Summary      Export Batch Documents (zip)
+// @Description  Zip with the PDF batch report, a result slip per student and a certificate per passing student, each in a folder. File and folder names follow the request locale. Uses the best submitted attempt per student, makeups included.
+// @Tags         reports
+// @Produce      application/zip
+// @Param        id path string true "Batch ID"
+// @Success      200  {file}  file
+// @Failure      404  {object}  apperr.Response
+// @Router       /api/export/batch/{id}/documents [get]
+func (s *ReportService) ExportBatchDocuments(c *fiber.Ctx) error {
+	batchId := c.Params("id")
+
+	docs, err := s.loadBatchDocuments(batchId)
+	if err != nil {
+		return documentsError(err)
+	}
+
+	// Built in memory first, so a failure can still be reported as JSON
+	l := requestLocale(c)
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	add := func(name string, write func(w *bytes.Buffer) error) error {
+		var pdf bytes.Buffer
+		if err := write(&pdf); err != nil {
+			return err
+		}
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(pdf.Bytes())
+		return err
+	}
+
+	slipDir, certificateDir := l.T("file.slips")+"/", l.T("file.certificates")+"/"
+	err = add(l.T("file.batch_report", batchId), func(w *bytes.Buffer) error { return writeBatchReportPDF(w, l, docs) })
+	for i, slip := range docs.slips {
+		if err != nil {
+			break
+		}
+		slip := slip
+		name := fmt.Sprintf("%02d-%s.pdf", i+1, fileSafe(slip.StudentName, l.T("file.participant")))
+		err = add(slipDir+name, func(w *bytes.Buffer) error { return writeResultSlipPDF(w, l, slip) })
+		if err == nil && slip.Passed {
+			err = add(certificateDir+name, func(w *bytes.Buffer) error { return writeCertificatePDF(w, l, slip) })
+		}
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		return apperr.Internal("export_failed", err)
+	}
+
+	c.Set("Content-Type", "application/zip")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=documents-%s.zip", batchId))
+	return c.Send(buf.Bytes())
+}
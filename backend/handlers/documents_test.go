@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"academic-suite-backend/i18n"
+	"academic-suite-backend/models"
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// download sends a GET request and returns the status, content type and raw body
+func (e *testEnv) download(path string, headers ...string) (int, string, []byte) {
+	e.t.Helper()
+	req := httptest.NewRequest("GET", path, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := e.app.Test(req, -1)
+	if err != nil {
+		e.t.Fatalf("GET %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, resp.Header.Get("Content-Type"), body
+}
+
+// submitAll starts and submits an attempt for a student with the given option per question
+func (e *testEnv) submitAll(studentID string, options ...string) models.Attempt {
+	e.t.Helper()
+	attempt := e.start(studentID)
+	var answers []fiber.Map
+	for i, opt := range options {
+		answers = append(answers, fiber.Map{"questionId": []string{"q1", "q2"}[i], "selectedOptionId": opt})
+	}
+	if code := e.do("POST", "/api/attempts/"+attempt.ID+"/submit", answers, nil); code != 200 {
+		e.t.Fatalf("submit: status %d", code)
+	}
+	return attempt
+}
+
+func TestBatchDocumentsZip(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedExam()
+	e.db.Model(&models.Quiz{}).Where("id = ?", "quiz-1").Update("passing_score", 70)
+	e.create(&models.User{ID: "student-2", Email: "budi@example.com", Name: "Budi", Role: models.RoleStudent})
+
+	e.submitAll("student-1", "q1-a", "q2-b") // 100
+	e.submitAll("student-2", "q1-b", "q2-a") // 0
+
+	status, contentType, body := e.download("/api/export/batch/batch-1/documents")
+	if status != 200 || contentType != "application/zip" {
+		t.Fatalf("status %d, content type %q", status, contentType)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("read zip: %v", err)
+	}
+
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		rc, _ := f.Open()
+		head := make([]byte, 5)
+		io.ReadFull(rc, head)
+		rc.Close()
+		if string(head) != "%PDF-" {
+			t.Errorf("%s is not a PDF", f.Name)
+		}
+	}
+	sort.Strings(names)
+	// Slips in name order; only the passing student gets a certificate
+	want := []string{"laporan-batch-1.pdf", "sertifikat/02-student-1.pdf", "slip/01-Budi.pdf", "slip/02-student-1.pdf"}
+	if len(names) != len(want) {
+		t.Fatalf("zip entries = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("zip entries = %v, want %v", names, want)
+		}
+	}
+
+	// Names follow the request locale
+	_, _, body = e.download("/api/export/batch/batch-1/documents", "X-Locale", "en")
+	zr, _ = zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	names = names[:0]
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+	want = []string{"certificates/02-student-1.pdf", "report-batch-1.pdf", "slips/01-Budi.pdf", "slips/02-student-1.pdf"}
+	if strings.Join(names, " ") != strings.Join(want, " ") {
+		t.Errorf("english zip entries = %v, want %v", names, want)
+	}
+
+	if status, _, _ := e.download("/api/export/batch/missing/documents"); status != fiber.StatusNotFound {
+		t.Errorf("unknown batch: status %d, want 404", status)
+	}
+}
+
+// Long names are cut after the cp1252 translation without mangling accented letters
+func TestPDFFitKeepsAccents(t *testing.T) {
+	d := newPDF(i18n.DefaultLocale, "P")
+	d.AddPage()
+	d.SetFont("Helvetica", "", 10)
+	text := d.tr(strings.Repeat("Zoë Ångström ", 10))
+	got := d.fit(text, 40)
+	if !strings.HasSuffix(got, "...") || len(got) >= len(text) || !strings.HasPrefix(text, strings.TrimSuffix(got, "...")) {
+		t.Fatalf("fit = %q", got)
+	}
+	if strings.Contains(got, "\uFFFD") || !strings.Contains(got, "\xeb") {
+		t.Errorf("fit mangled the accents: %q", got)
+	}
+}
+
+func TestResultSlipAccess(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedExam()
+	e.create(&models.User{ID: "student-2", Email: "budi@example.com", Name: "Budi", Role: models.RoleStudent})
+
+	active := e.start("student-2")
+	if status, _, _ := e.download("/api/export/attempts/" + active.ID + "/slip"); status != fiber.StatusBadRequest {
+		t.Errorf("slip of an active attempt: status %d, want 400", status)
+	}
+
+	attempt := e.submitAll("student-1", "q1-a")
+	path := "/api/export/attempts/" + attempt.ID + "/slip"
+	if status, _, _ := e.download(path, "X-User", "student-2", "X-Role", string(models.RoleStudent)); status != fiber.StatusForbidden {
+		t.Errorf("other student's slip: status %d, want 403", status)
+	}
+	status, contentType, body := e.download(path, "X-User", "student-1", "X-Role", string(models.RoleStudent))
+	if status != 200 || contentType != "application/pdf" || !bytes.HasPrefix(body, []byte("%PDF-")) {
+		t.Errorf("own slip: status %d, content type %q", status, contentType)
+	}
+}
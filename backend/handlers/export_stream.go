@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/i18n"
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+)
+
+// Streaming exports read the query row by row and write each row to the response as it goes,
+// so memory use does not grow with the batch size. The xlsx flavour uses excelize's StreamWriter,
+// which keeps rows in a temporary file until the workbook is written out.
+
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+	ExportXLSX   = "xlsx"
+)
+
+// streamFlushRows is how often the response is flushed to the client
+const streamFlushRows = 500
+
+// exportColumn is a column of a streamed export: the key is used in CSV and NDJSON,
+// the title (a catalog key) is translated for the xlsx header
+type exportColumn struct {
+	key   string
+	title string
+}
+
+type rowWriter interface {
+	writeRow(values []interface{}) error
+	flush() error
+	close() error
+	// discard drops what is buffered without finishing the file
+	discard()
+}
+
+// exportFormat reads ?format= (csv by default)
+func exportFormat(c *fiber.Ctx) (string, error) {
+	switch format := c.Query("format", ExportCSV); format {
+	case ExportCSV, ExportNDJSON, ExportXLSX:
+		return format, nil
+	default:
+		return "", apperr.BadRequest("invalid_export_format")
+	}
+}
+
+// streamRows sends the rows as an attachment; scan reads the current row into cell values.
+// The rows are closed when the stream ends.
+func streamRows(c *fiber.Ctx, format, filename string, columns []exportColumn, rows *sql.Rows, scan func() ([]interface{}, error)) error {
+	contentTypes := map[string]string{
+		ExportCSV:    "text/csv; charset=utf-8",
+		ExportNDJSON: "application/x-ndjson",
+		ExportXLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	}
+	c.Set("Content-Type", contentTypes[format])
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", filename, format))
+	// The context is not usable inside the stream writer
+	l := requestLocale(c)
+	requestID, _ := c.Locals(apperr.RequestIDKey).(string)
+	logf := func(err error) { log.Printf("[%s] export %s.%s: %v", requestID, filename, format, err) }
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer rows.Close()
+
+		out, err := newRowWriter(format, l, w, columns)
+		if err != nil {
+			logf(err)
+			return
+		}
+		count := 0
+		for rows.Next() {
+			values, err := scan()
+			if err == nil {
+				err = out.writeRow(values)
+			}
+			if err != nil {
+				// Headers are already sent; leave the file unfinished so it does not pass for a whole one
+				logf(err)
+				out.discard()
+				w.Flush()
+				return
+			}
+			count++
+			if count%streamFlushRows == 0 {
+				if out.flush() != nil || w.Flush() != nil {
+					out.discard()
+					return // client went away
+				}
+			}
+		}
+		if err := rows.Err(); err != nil {
+			logf(err)
+			out.discard()
+			w.Flush()
+			return
+		}
+		if err := out.close(); err != nil {
+			logf(err)
+		}
+		w.Flush()
+	})
+	return nil
+}
+
+func newRowWriter(format string, l i18n.Locale, w io.Writer, columns []exportColumn) (rowWriter, error) {
+	switch format {
+	case ExportNDJSON:
+		return &ndjsonRowWriter{w: w, columns: columns}, nil
+	case ExportXLSX:
+		return newXLSXRowWriter(w, l, columns)
+	default:
+		out := &csvRowWriter{w: csv.NewWriter(w)}
+		header := make([]string, len(columns))
+		for i, col := range columns {
+			header[i] = col.key
+		}
+		return out, out.w.Write(header)
+	}
+}
+
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (r *csvRowWriter) writeRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = formatExportValue(v)
+	}
+	return r.w.Write(record)
+}
+
+func (r *csvRowWriter) flush() error {
+	r.w.Flush()
+	return r.w.Error()
+}
+
+func (r *csvRowWriter) close() error { return r.flush() }
+func (r *csvRowWriter) discard()     {}
+
+// ndjsonRowWriter writes one JSON object per line, keys in column order
+type ndjsonRowWriter struct {
+	w       io.Writer
+	columns []exportColumn
+}
+
+func (r *ndjsonRowWriter) writeRow(values []interface{}) error {
+	line := []byte{'{'}
+	for i, col := range r.columns {
+		if i > 0 {
+			line = append(line, ',')
+		}
+		key, _ := json.Marshal(col.key)
+		value, err := json.Marshal(values[i])
+		if err != nil {
+			return err
+		}
+		line = append(append(append(line, key...), ':'), value...)
+	}
+	_, err := r.w.Write(append(line, '}', '\n'))
+	return err
+}
+
+func (r *ndjsonRowWriter) flush() error { return nil }
+func (r *ndjsonRowWriter) close() error { return nil }
+func (r *ndjsonRowWriter) discard()     {}
+
+type xlsxRowWriter struct {
+	w      io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+}
+
+func newXLSXRowWriter(w io.Writer, l i18n.Locale, columns []exportColumn) (*xlsxRowWriter, error) {
+	f := excelize.NewFile()
+	stream, err := f.NewStreamWriter("Sheet1")
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	styleHeader, _ := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	header := make([]interface{}, len(columns))
+	for i, col := range columns {
+		header[i] = excelize.Cell{StyleID: styleHeader, Value: l.T(col.title)}
+	}
+	if err := stream.SetRow("A1", header); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &xlsxRowWriter{w: w, file: f, stream: stream, row: 1}, nil
+}
+
+func (r *xlsxRowWriter) writeRow(values []interface{}) error {
+	r.row++
+	cell, _ := excelize.CoordinatesToCellName(1, r.row)
+	return r.stream.SetRow(cell, values)
+}
+
+// The workbook can only be written once all rows are in
+func (r *xlsxRowWriter) flush() error { return nil }
+
+func (r *xlsxRowWriter) close() error {
+	defer r.file.Close()
+	if err := r.stream.Flush(); err != nil {
+		return err
+	}
+	return r.file.Write(r.w)
+}
+
+// discard removes the temporary files of the stream writer
+func (r *xlsxRowWriter) discard() { r.file.Close() }
+
+// formatExportValue renders a cell for CSV; nil becomes an empty cell
+func formatExportValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
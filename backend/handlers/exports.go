@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/models"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// AnswerRow is one saved answer with the attempt, student, question and chosen option
+type AnswerRow struct {
+	AttemptID        string `json:"attemptId"`
+	BatchID          string `json:"batchId"`
+	StudentID        string `json:"studentId"`
+	StudentName      string `json:"studentName"`
+	QuestionID       string `json:"questionId"`
+	QuestionOrder    int    `json:"questionOrder"`
+	QuestionText     string `json:"questionText"`
+	SelectedOptionID string `json:"selectedOptionId"`
+	OptionText       string `json:"optionText"`
+	TextAnswer       string `json:"textAnswer"`
+	// IsCorrect is nil when no option was chosen (typed answers are not scored automatically)
+	IsCorrect  *bool     `json:"isCorrect"`
+	AnsweredAt time.Time `json:"answeredAt"`
+}
+
+type AnswerPage struct {
+	Items      []AnswerRow `json:"items"`
+	NextCursor string      `json:"nextCursor"` // empty on the last page
+}
+
+// answerRows selects the answers of the batches as AnswerRow, sorted by attempt and question ID
+func (s *ReportService) answerRows(batchIDs []string, c *fiber.Ctx) *gorm.DB {
+	query := s.db.Table("answers").
+		Select(`answers.attempt_id, attempts.batch_id, attempts.student_id, users.name AS student_name,
+			answers.question_id, questions.order_index AS question_order, questions.text AS question_text,
+			answers.selected_option_id, question_options.text AS option_text, question_options.is_correct,
+			answers.text_answer, answers.answered_at`).
+		Joins("JOIN attempts ON attempts.id = answers.attempt_id").
+		Joins("LEFT JOIN users ON users.id = attempts.student_id").
+		Joins("LEFT JOIN questions ON questions.id = answers.question_id").
+		Joins("LEFT JOIN question_options ON question_options.id = answers.selected_option_id").
+		Where("attempts.batch_id IN ?", batchIDs)
+
+	studentId := c.Query("studentId")
+	// A student only ever sees their own answers
+	if role, _ := c.Locals("role").(string); models.UserRole(role) == models.RoleStudent {
+		studentId, _ = c.Locals("userId").(string)
+	}
+	if studentId != "" {
+		query = query.Where("attempts.student_id = ?", studentId)
+	}
+	if questionId := c.Query("questionId"); questionId != "" {
+		query = query.Where("answers.question_id = ?", questionId)
+	}
+	return query.Order("answers.attempt_id, answers.question_id")
+}
+
+// GetAnswers godoc
+// @Summary      Get Raw Answers
+// @Description  Saved answers of a batch (makeups included), one page at a time. Pass nextCursor back as cursor for the next page.
+// @Description  Students only get their own answers.
+// @Tags         reports
+// @Produce      json
+// @Param        batchId    query     string  true   "Batch ID"
+// @Param        studentId  query     string  false  "Only this student"
+// @Param        questionId query     string  false  "Only this question"
+// @Param        limit      query     int     false  "Page size (default 100, max 1000)"
+// @Param        cursor     query     string  false  "nextCursor of the previous page"
+// @Success      200  {object}  AnswerPage
+// @Failure      400  {object}  apperr.Response
+// @Failure      404  {object}  apperr.Response
+// @Router       /api/reports/answers [get]
+func (s *ReportService) GetAnswers(c *fiber.Ctx) error {
+	batchId := c.Query("batchId")
+	if batchId == "" {
+		return apperr.BadRequest("batch_id_required")
+	}
+	limit, cursor, err := pageParams(c, 2)
+	if err != nil {
+		return err
+	}
+	_, batchIDs, err := s.itemAnalysisScope(batchId, "")
+	if err != nil {
+		return apperr.NotFound("batch_not_found")
+	}
+
+	query := s.answerRows(batchIDs, c)
+	if cursor != nil {
+		query = query.Where("(answers.attempt_id > ? OR (answers.attempt_id = ? AND answers.question_id > ?))", cursor[0], cursor[0], cursor[1])
+	}
+
+	var rows []AnswerRow
+	if err := query.Limit(limit + 1).Scan(&rows).Error; err != nil {
+		return apperr.Internal("answers_load_failed", err)
+	}
+
+	page := AnswerPage{Items: rows}
+	if len(rows) > limit {
+		page.Items = rows[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeCursor(last.AttemptID, last.QuestionID)
+	}
+	if page.Items == nil {
+		page.Items = []AnswerRow{}
+	}
+	return c.JSON(page)
+}
+
+// ExportBatchAnswers godoc
+// @Summary      Export Raw Answers
+// @Description  Streams every saved answer of a batch (makeups included) as CSV, NDJSON or xlsx
+// @Tags         reports
+// @Produce      text/csv
+// @Param        id         path      string  true   "Batch ID"
+// @Param        format     query     string  false  "csv (default), ndjson or xlsx"
+// @Param        studentId  query     string  false  "Only this student"
+// @Param        questionId query     string  false  "Only this question"
+// @Success      200  {file}  file
+// @Failure      400  {object}  apperr.Response
+// @Failure      404  {object}  apperr.Response
+// @Router       /api/export/batch/{id}/answers [get]
+func (s *ReportService) ExportBatchAnswers(c *fiber.Ctx) error {
+	format, err := exportFormat(c)
+	if err != nil {
+		return err
+	}
+	batchId := c.Params("id")
+	_, batchIDs, err := s.itemAnalysisScope(batchId, "")
+	if err != nil {
+		return apperr.NotFound("batch_not_found")
+	}
+	locations := s.batchLocations(batchIDs)
+
+	rows, err := s.answerRows(batchIDs, c).Rows()
+	if err != nil {
+		return apperr.Internal("answers_load_failed", err)
+	}
+
+	columns := []exportColumn{
+		{"attempt_id", "col.attempt_id"}, {"batch_id", "col.batch"}, {"student_id", "col.student_id"}, {"student_name", "col.student_name"},
+		{"question_id", "col.question_id"}, {"question_order", "col.question_order"}, {"question_text", "col.question"},
+		{"selected_option_id", "col.option_id"}, {"option_text", "col.option"}, {"text_answer", "col.text_answer"},
+		{"is_correct", "col.correct"}, {"answered_at", "col.answered_at"},
+	}
+	return streamRows(c, format, "answers-"+batchId, columns, rows, func() ([]interface{}, error) {
+		var r AnswerRow
+		if err := s.db.ScanRows(rows, &r); err != nil {
+			return nil, err
+		}
+		var correct interface{}
+		if r.IsCorrect != nil {
+			correct = *r.IsCorrect
+		}
+		return []interface{}{
+			r.AttemptID, r.BatchID, r.StudentID, r.StudentName,
+			r.QuestionID, r.QuestionOrder, r.QuestionText,
+			r.SelectedOptionID, r.OptionText, r.TextAnswer,
+			correct, r.AnsweredAt.In(locations[r.BatchID]).Format(time.RFC3339),
+		}, nil
+	})
+}
+
+// resultRow is an attempt joined with its student for the results export
+type resultRow struct {
+	ID          string
+	BatchID     string
+	StudentID   string
+	StudentName string
+	Status      models.AttemptStatus
+	Score       float64
+	StartedAt   *time.Time
+	SubmittedAt *time.Time
+}
+
+// ExportBatchResults godoc
+// @Summary      Export Batch Results
+// @Description  Streams every attempt of a batch (makeups included) as CSV, NDJSON or xlsx. Unlike the report, attempts are not reduced to one per student.
+// @Tags         reports
+// @Produce      text/csv
+// @Param        id      path      string  true   "Batch ID"
+// @Param        format  query     string  false  "csv (default), ndjson or xlsx"
+// @Param        status  query     string  false  "Comma separated attempt statuses"
+// @Success      200  {file}  file
+// @Failure      400  {object}  apperr.Response
+// @Failure      404  {object}  apperr.Response
+// @Router       /api/export/batch/{id}/results [get]
+func (s *ReportService) ExportBatchResults(c *fiber.Ctx) error {
+	format, err := exportFormat(c)
+	if err != nil {
+		return err
+	}
+	batchId := c.Params("id")
+	quizID, batchIDs, err := s.itemAnalysisScope(batchId, "")
+	if err != nil {
+		return apperr.NotFound("batch_not_found")
+	}
+
+	var quiz models.Quiz
+	s.db.First(&quiz, "id = ?", quizID)
+	var batches []models.ExamBatch
+	s.db.Where("id IN ?", batchIDs).Find(&batches)
+	makeup := make(map[string]bool)
+	locations := make(map[string]*time.Location)
+	for _, b := range batches {
+		makeup[b.ID] = b.Type == models.BatchMakeup
+		locations[b.ID] = batchLocation(b)
+	}
+
+	query := s.db.Table("attempts").
+		Select("attempts.id, attempts.batch_id, attempts.student_id, users.name AS student_name, attempts.status, attempts.score, attempts.started_at, attempts.submitted_at").
+		Joins("LEFT JOIN users ON users.id = attempts.student_id").
+		Where("attempts.batch_id IN ?", batchIDs)
+	if statuses := splitList(strings.ToUpper(c.Query("status"))); len(statuses) > 0 {
+		query = query.Where("attempts.status IN ?", statuses)
+	}
+	rows, err := query.Order("users.name, attempts.id").Rows()
+	if err != nil {
+		return apperr.Internal("attempts_load_failed", err)
+	}
+
+	columns := []exportColumn{
+		{"attempt_id", "col.attempt_id"}, {"batch_id", "col.batch"}, {"session", "col.session"},
+		{"student_id", "col.student_id"}, {"student_name", "col.student_name"}, {"status", "col.status"},
+		{"score", "col.score"}, {"percentage", "col.percentage"}, {"passed", "col.passed"},
+		{"started_at", "col.started_at"}, {"submitted_at", "col.submitted_at"}, {"duration_seconds", "col.duration_seconds"},
+	}
+	return streamRows(c, format, "results-"+batchId, columns, rows, func() ([]interface{}, error) {
+		var r resultRow
+		if err := s.db.ScanRows(rows, &r); err != nil {
+			return nil, err
+		}
+		loc := locations[r.BatchID]
+		session := "regular"
+		if makeup[r.BatchID] {
+			session = "makeup"
+		}
+		var startedAt, submittedAt, duration interface{}
+		if r.StartedAt != nil {
+			startedAt = r.StartedAt.In(loc).Format(time.RFC3339)
+		}
+		if r.SubmittedAt != nil {
+			submittedAt = r.SubmittedAt.In(loc).Format(time.RFC3339)
+			if r.StartedAt != nil {
+				duration = int(r.SubmittedAt.Sub(*r.StartedAt).Seconds())
+			}
+		}
+		return []interface{}{
+			r.ID, r.BatchID, session,
+			r.StudentID, r.StudentName, string(r.Status),
+			r.Score, percentageOf(r.Score, quiz.TotalPoints), r.Status == models.AttemptSubmitted && r.Score >= float64(quiz.PassingScore),
+			startedAt, submittedAt, duration,
+		}, nil
+	})
+}
+
+// ExportEventLogs godoc
+// @Summary      Export Event Logs
+// @Description  Streams the event logs matching the filters, oldest first, as CSV, NDJSON or xlsx
+// @Tags         reports
+// @Produce      text/csv
+// @Param        format     query     string  false  "csv (default), ndjson or xlsx"
+// @Param        batchId    query     string  false  "Batch ID to filter"
+// @Param        attemptId  query     string  false  "Attempt ID to filter"
+// @Param        userId     query     string  false  "User ID to filter"
+// @Param        eventType  query     string  false  "Comma separated event types"
+// @Param        from       query     string  false  "RFC3339, inclusive"
+// @Param        to         query     string  false  "RFC3339, exclusive"
+// @Success      200  {file}  file
+// @Failure      400  {object}  apperr.Response
+// @Router       /api/export/logs [get]
+func (s *ReportService) ExportEventLogs(c *fiber.Ctx) error {
+	format, err := exportFormat(c)
+	if err != nil {
+		return err
+	}
+	query, err := filterEventLogs(s.db.Model(&models.EventLog{}), c)
+	if err != nil {
+		return err
+	}
+	rows, err := query.Order("timestamp, id").Rows()
+	if err != nil {
+		return apperr.Internal("logs_load_failed", err)
+	}
+
+	filename := "logs"
+	if batchId := c.Query("batchId"); batchId != "" {
+		filename += "-" + batchId
+	}
+	columns := []exportColumn{
+		{"id", "col.id"}, {"timestamp", "col.time"}, {"event_type", "col.event"}, {"batch_id", "col.batch"},
+		{"attempt_id", "col.attempt"}, {"user_id", "col.user"}, {"details", "col.details"},
+	}
+	return streamRows(c, format, filename, columns, rows, func() ([]interface{}, error) {
+		var l models.EventLog
+		if err := s.db.ScanRows(rows, &l); err != nil {
+			return nil, err
+		}
+		return []interface{}{
+			l.ID, l.Timestamp.Format(time.RFC3339Nano), string(l.EventType), l.BatchID, l.AttemptID, l.UserID, l.Details,
+		}, nil
+	})
+}
+
+// batchLocations maps batch IDs to their timezones, so exported times match the schedule
+func (s *ReportService) batchLocations(batchIDs []string) map[string]*time.Location {
+	var batches []models.ExamBatch
+	s.db.Where("id IN ?", batchIDs).Find(&batches)
+	locations := make(map[string]*time.Location)
+	for _, b := range batches {
+		locations[b.ID] = batchLocation(b)
+	}
+	return locations
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"academic-suite-backend/models"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestEventLogsCursorPagination(t *testing.T) {
+	e := newTestEnv(t)
+	// Two logs share a timestamp, so the ID has to break the tie
+	for i, minute := range []int{1, 2, 2, 3, 4} {
+		e.create(&models.EventLog{
+			ID: fmt.Sprintf("log-%d", i), EventType: models.EventBatchUpdated, BatchID: "batch-1",
+			Timestamp: testStart.Add(time.Duration(minute) * time.Minute),
+		})
+	}
+	e.create(&models.EventLog{ID: "other", EventType: models.EventBatchUpdated, BatchID: "batch-2", Timestamp: testStart})
+
+	var seen []string
+	path := "/api/reports/logs?batchId=batch-1&limit=2"
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("pagination does not end, seen %v", seen)
+		}
+		var page EventLogPage
+		if status := e.do("GET", path, nil, &page); status != 200 {
+			t.Fatalf("GET %s: status %d", path, status)
+		}
+		for _, l := range page.Items {
+			seen = append(seen, l.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		path = "/api/reports/logs?batchId=batch-1&limit=2&cursor=" + url.QueryEscape(page.NextCursor)
+	}
+
+	want := []string{"log-4", "log-3", "log-2", "log-1", "log-0"}
+	if fmt.Sprint(seen) != fmt.Sprint(want) {
+		t.Errorf("pages = %v, want %v", seen, want)
+	}
+
+	for _, bad := range []string{"?cursor=nope", "?limit=0", "?from=yesterday"} {
+		if status := e.do("GET", "/api/reports/logs"+bad, nil, nil); status != 400 {
+			t.Errorf("%s: status %d, want 400", bad, status)
+		}
+	}
+}
+
+func TestStreamedBatchExports(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedExam()
+	e.db.Model(&models.Quiz{}).Where("id = ?", "quiz-1").Update("passing_score", 70)
+	attempt := e.start("student-1")
+	e.clock.Advance(10 * time.Minute)
+	e.do("POST", "/api/attempts/"+attempt.ID+"/submit", []map[string]string{
+		{"questionId": "q1", "selectedOptionId": "q1-a"},
+		{"questionId": "q2", "selectedOptionId": "q2-a"},
+	}, nil)
+
+	t.Run("results csv", func(t *testing.T) {
+		status, contentType, body := e.download("/api/export/batch/batch-1/results")
+		if status != 200 || contentType != "text/csv; charset=utf-8" {
+			t.Fatalf("status %d, content type %q", status, contentType)
+		}
+		records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+		if err != nil || len(records) != 2 {
+			t.Fatalf("records = %v, err %v", records, err)
+		}
+		row := map[string]string{}
+		for i, key := range records[0] {
+			row[key] = records[1][i]
+		}
+		// 5 of 20 points, scaled to 100
+		if row["status"] != "SUBMITTED" || row["score"] != "25" || row["passed"] != "false" || row["duration_seconds"] != "600" {
+			t.Errorf("row = %v", row)
+		}
+		if row["submitted_at"] != "2025-03-10T08:10:00+07:00" {
+			t.Errorf("submitted_at = %s, want batch-zone time", row["submitted_at"])
+		}
+	})
+
+	t.Run("answers ndjson", func(t *testing.T) {
+		_, contentType, body := e.download("/api/export/batch/batch-1/answers?format=ndjson")
+		if contentType != "application/x-ndjson" {
+			t.Fatalf("content type %q", contentType)
+		}
+		var correct []interface{}
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		for scanner.Scan() {
+			var row map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+				t.Fatalf("line %q: %v", scanner.Text(), err)
+			}
+			correct = append(correct, row["is_correct"])
+		}
+		if fmt.Sprint(correct) != "[true false]" {
+			t.Errorf("is_correct per answer = %v, want [true false]", correct)
+		}
+	})
+
+	t.Run("answers xlsx", func(t *testing.T) {
+		_, _, body := e.download("/api/export/batch/batch-1/answers?format=xlsx&questionId=q2")
+		f, err := excelize.OpenReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("open xlsx: %v", err)
+		}
+		defer f.Close()
+		rows, _ := f.GetRows("Sheet1")
+		if len(rows) != 2 || rows[0][0] != "ID Attempt" || rows[1][4] != "q2" {
+			t.Errorf("rows = %v", rows)
+		}
+	})
+
+	t.Run("answers json page", func(t *testing.T) {
+		var page AnswerPage
+		e.do("GET", "/api/reports/answers?batchId=batch-1&limit=1", nil, &page)
+		if len(page.Items) != 1 || page.Items[0].QuestionID != "q1" || page.NextCursor == "" {
+			t.Fatalf("first page = %+v", page)
+		}
+		e.do("GET", "/api/reports/answers?batchId=batch-1&limit=1&cursor="+url.QueryEscape(page.NextCursor), nil, &page)
+		if len(page.Items) != 1 || page.Items[0].QuestionID != "q2" || page.NextCursor != "" {
+			t.Fatalf("second page = %+v", page)
+		}
+
+		// Another student asking for student-1's answers only gets their own (none)
+		e.do("GET", "/api/reports/answers?batchId=batch-1&studentId=student-1", nil, &page, "X-User", "student-2", "X-Role", string(models.RoleStudent))
+		if len(page.Items) != 0 {
+			t.Errorf("student-2 sees %d answers of student-1", len(page.Items))
+		}
+		e.do("GET", "/api/reports/answers?batchId=batch-1", nil, &page, "X-User", "student-1", "X-Role", string(models.RoleStudent))
+		if len(page.Items) != 2 {
+			t.Errorf("own answers = %d, want 2", len(page.Items))
+		}
+	})
+
+	if status, _, _ := e.download("/api/export/batch/batch-1/results?format=pdf"); status != 400 {
+		t.Errorf("unknown format: status %d, want 400", status)
+	}
+}
+
+func TestAnswerMatrixExport(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedExam()
+	e.create(&models.User{ID: "student-2", Email: "budi@example.com", Name: "Budi", Role: models.RoleStudent})
+	attempt := e.start("student-1")
+	e.clock.Advance(5 * time.Minute)
+	e.do("POST", "/api/attempts/"+attempt.ID+"/answers", map[string]string{"questionId": "q2", "selectedOptionId": "q2-b"}, nil)
+	e.do("POST", "/api/attempts/"+attempt.ID+"/submit", nil, nil)
+	e.start("student-2") // still working, listed without answers
+
+	status, _, body := e.download("/api/export/batch/batch-1/matrix")
+	if status != 200 {
+		t.Fatalf("status %d", status)
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("open xlsx: %v", err)
+	}
+	defer f.Close()
+
+	if got := fmt.Sprint(f.GetSheetList()); got != "[Matriks Jawaban Statistik Analisis Butir Kunci Jawaban]" {
+		t.Errorf("sheets = %s", got)
+	}
+	rows, _ := f.GetRows("Matriks Jawaban")
+	if len(rows) != 6 {
+		t.Fatalf("matrix rows = %d, want title, blank, 2 header rows and 2 students", len(rows))
+	}
+	// Budi first (by name); student-1 left q1 blank and got q2 (B) right at 08:05 WIB
+	if rows[4][2] != "Budi" || rows[4][4] != string(models.AttemptActive) {
+		t.Errorf("first student row = %v", rows[4])
+	}
+	want := []string{"", "0", "0", "", "B", "1", "15", "2025-03-10 08:05:00"}
+	got := rows[5][len(matrixFixedColumns):]
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("question cells = %q, want %q", got, want)
+	}
+	if key, _ := f.GetCellValue("Kunci Jawaban", "H3"); key != "B" {
+		t.Errorf("key of q2 = %q, want B", key)
+	}
+}
+
+// A row that fails halfway leaves the xlsx unfinished instead of a workbook missing rows
+func TestStreamedExportStopsOnError(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedExam()
+	e.app.Get("/test/broken-export", func(c *fiber.Ctx) error {
+		rows, err := e.db.Model(&models.Question{}).Select("id").Order("id").Rows()
+		if err != nil {
+			return err
+		}
+		n := 0
+		return streamRows(c, ExportXLSX, "broken", []exportColumn{{"id", "col.question"}}, rows, func() ([]interface{}, error) {
+			var id string
+			if n++; n > 1 {
+				return nil, errors.New("scan failed")
+			}
+			err := rows.Scan(&id)
+			return []interface{}{id}, err
+		})
+	})
+
+	status, _, body := e.download("/test/broken-export")
+	if status != 200 {
+		t.Fatalf("status %d", status)
+	}
+	if f, err := excelize.OpenReader(bytes.NewReader(body)); err == nil {
+		f.Close()
+		t.Errorf("a failed export still reads as a workbook (%d bytes)", len(body))
+	}
+}
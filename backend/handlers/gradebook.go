@@ -0,0 +1,439 @@
+package handlers
+
+import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/models"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+)
+
+// GradeWeights are the percentages of daily quizzes, midterm and final in a subject's final grade
+type GradeWeights struct {
+	DailyQuiz int `json:"dailyQuiz"`
+	Midterm   int `json:"midterm"`
+	Final     int `json:"final"`
+}
+
+var DefaultGradeWeights = GradeWeights{DailyQuiz: 30, Midterm: 30, Final: 40}
+
+// gradedExamTypes are the exam types that count towards the final grade, in report order
+var gradedExamTypes = []models.ExamType{models.ExamDaily, models.ExamMidterm, models.ExamFinal}
+
+func (w GradeWeights) validate() error {
+	if w.DailyQuiz < 0 || w.Midterm < 0 || w.Final < 0 {
+		return apperr.BadRequest("grade_weights_negative")
+	}
+	if w.DailyQuiz+w.Midterm+w.Final != 100 {
+		return apperr.BadRequest("grade_weights_sum", w.DailyQuiz+w.Midterm+w.Final)
+	}
+	return nil
+}
+
+func (w GradeWeights) applyTo(subject *models.Subject) {
+	subject.DailyQuizWeight = w.DailyQuiz
+	subject.MidtermWeight = w.Midterm
+	subject.FinalWeight = w.Final
+}
+
+func (w GradeWeights) of(examType models.ExamType) int {
+	switch examType {
+	case models.ExamDaily:
+		return w.DailyQuiz
+	case models.ExamMidterm:
+		return w.Midterm
+	case models.ExamFinal:
+		return w.Final
+	}
+	return 0
+}
+
+// subjectGradeWeights reads the weights of a subject, all zero meaning never configured
+func subjectGradeWeights(subject models.Subject) GradeWeights {
+	w := GradeWeights{DailyQuiz: subject.DailyQuizWeight, Midterm: subject.MidtermWeight, Final: subject.FinalWeight}
+	if w.DailyQuiz+w.Midterm+w.Final == 0 {
+		return DefaultGradeWeights
+	}
+	return w
+}
+
+type GradebookQuiz struct {
+	QuizID       string          `json:"quizId"`
+	Title        string          `json:"title"`
+	ExamType     models.ExamType `json:"examType"`
+	AttemptID    string          `json:"attemptId"`
+	BatchID      string          `json:"batchId"`
+	Score        float64         `json:"score"`
+	TotalPoints  int             `json:"totalPoints"`
+	Percentage   float64         `json:"percentage"`
+	PassingScore int             `json:"passingScore"`
+	Passed       bool            `json:"passed"`
+	SubmittedAt  *time.Time      `json:"submittedAt"`
+}
+
+type GradebookComponent struct {
+	ExamType models.ExamType `json:"examType"`
+	Weight   int             `json:"weight"` // percent, 0 for practice
+	// Average percentage over the quizzes taken, nil when none
+	Average *float64        `json:"average"`
+	Quizzes []GradebookQuiz `json:"quizzes"`
+}
+
+type GradebookSubject struct {
+	SubjectID    string               `json:"subjectId"`
+	SubjectName  string               `json:"subjectName"`
+	SubjectCode  string               `json:"subjectCode"`
+	GradeWeights GradeWeights         `json:"gradeWeights"`
+	Components   []GradebookComponent `json:"components"`
+	// FinalGrade is the weighted average of the component averages. Components without any
+	// quiz taken yet are left out and the remaining weights rescaled; nil when nothing is graded.
+	FinalGrade  *float64 `json:"finalGrade"`
+	PassedCount int      `json:"passedCount"`
+	FailedCount int      `json:"failedCount"`
+}
+
+type GradebookStudent struct {
+	StudentID   string             `json:"studentId"`
+	StudentName string             `json:"studentName"`
+	Subjects    []GradebookSubject `json:"subjects"`
+}
+
+// GradebookFilter selects the students and subjects of a gradebook
+type GradebookFilter struct {
+	StudentID string
+	ClassID   string
+	SubjectID string
+}
+
+// bestQuizAttempts keeps the highest scoring submitted attempt per quiz (latest wins ties)
+func bestQuizAttempts(rows []gradebookAttemptRow) map[string]gradebookAttemptRow {
+	best := make(map[string]gradebookAttemptRow)
+	for _, r := range rows {
+		existing, ok := best[r.QuizID]
+		if !ok || r.Score > existing.Score || (r.Score == existing.Score && r.CreatedAt.After(existing.CreatedAt)) {
+			best[r.QuizID] = r
+		}
+	}
+	return best
+}
+
+type gradebookAttemptRow struct {
+	models.Attempt
+	QuizID string
+}
+
+// percentageOf converts a normalized attempt score to a percentage of the quiz total
+func percentageOf(score float64, totalPoints int) float64 {
+	if totalPoints <= 0 {
+		return score // SubmitAttempt scales to 100 when the quiz has no total
+	}
+	return score / float64(totalPoints) * 100
+}
+
+// buildGradebook aggregates each student's best submitted attempt per quiz by subject and exam type
+func (s *GradebookService) buildGradebook(filter GradebookFilter) ([]GradebookStudent, error) {
+	var studentIDs []string
+	switch {
+	case filter.StudentID != "":
+		studentIDs = []string{filter.StudentID}
+	case filter.ClassID != "":
+		var class models.Class
+		if err := s.db.First(&class, "id = ?", filter.ClassID).Error; err != nil {
+			return nil, apperr.NotFound("class_not_found")
+		}
+		studentIDs = classStudentIDs(s.db, class.ID)
+	default:
+		return nil, apperr.BadRequest("student_or_class_required")
+	}
+
+	var students []models.User
+	if len(studentIDs) > 0 {
+		s.db.Where("id IN ?", studentIDs).Order("name, id").Find(&students)
+	}
+	if filter.StudentID != "" && len(students) == 0 {
+		return nil, apperr.NotFound("student_not_found")
+	}
+
+	var rows []gradebookAttemptRow
+	if len(studentIDs) > 0 {
+		query := s.db.Model(&models.Attempt{}).
+			Select("attempts.*, exam_batches.quiz_id").
+			Joins("JOIN exam_batches ON exam_batches.id = attempts.batch_id").
+			Where("attempts.student_id IN ? AND attempts.status = ?", studentIDs, models.AttemptSubmitted)
+		if filter.SubjectID != "" {
+			query = query.Joins("JOIN quizzes ON quizzes.id = exam_batches.quiz_id").Where("quizzes.subject_id = ?", filter.SubjectID)
+		}
+		if err := query.Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	rowsByStudent := make(map[string][]gradebookAttemptRow)
+	quizIDs := map[string]bool{}
+	for _, r := range rows {
+		rowsByStudent[r.StudentID] = append(rowsByStudent[r.StudentID], r)
+		quizIDs[r.QuizID] = true
+	}
+
+	quizzes := make(map[string]models.Quiz)
+	subjects := make(map[string]models.Subject)
+	if len(quizIDs) > 0 {
+		var list []models.Quiz
+		s.db.Where("id IN ?", keys(quizIDs)).Find(&list)
+		subjectIDs := map[string]bool{}
+		for _, q := range list {
+			quizzes[q.ID] = q
+			subjectIDs[q.SubjectID] = true
+		}
+		var subjectList []models.Subject
+		s.db.Where("id IN ?", keys(subjectIDs)).Find(&subjectList)
+		for _, subj := range subjectList {
+			subjects[subj.ID] = subj
+		}
+	}
+
+	result := []GradebookStudent{}
+	for _, student := range students {
+		entry := GradebookStudent{StudentID: student.ID, StudentName: student.Name, Subjects: []GradebookSubject{}}
+
+		// subject ID -> exam type -> quizzes
+		bySubject := make(map[string]map[models.ExamType][]GradebookQuiz)
+		for _, r := range bestQuizAttempts(rowsByStudent[student.ID]) {
+			quiz := quizzes[r.QuizID]
+			gq := GradebookQuiz{
+				QuizID:       quiz.ID,
+				Title:        quiz.Title,
+				ExamType:     quiz.ExamType,
+				AttemptID:    r.ID,
+				BatchID:      r.BatchID,
+				Score:        r.Score,
+				TotalPoints:  quiz.TotalPoints,
+				Percentage:   percentageOf(r.Score, quiz.TotalPoints),
+				PassingScore: quiz.PassingScore,
+				Passed:       r.Score >= float64(quiz.PassingScore),
+				SubmittedAt:  r.SubmittedAt,
+			}
+			if bySubject[quiz.SubjectID] == nil {
+				bySubject[quiz.SubjectID] = make(map[models.ExamType][]GradebookQuiz)
+			}
+			bySubject[quiz.SubjectID][quiz.ExamType] = append(bySubject[quiz.SubjectID][quiz.ExamType], gq)
+		}
+
+		for subjectID, byType := range bySubject {
+			entry.Subjects = append(entry.Subjects, gradeSubject(subjects[subjectID], subjectID, byType))
+		}
+		sort.Slice(entry.Subjects, func(i, j int) bool { return entry.Subjects[i].SubjectName < entry.Subjects[j].SubjectName })
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+// gradeSubject computes component averages and the weighted final grade of one subject
+func gradeSubject(subject models.Subject, subjectID string, byType map[models.ExamType][]GradebookQuiz) GradebookSubject {
+	weights := subjectGradeWeights(subject)
+	gs := GradebookSubject{
+		SubjectID:    subjectID,
+		SubjectName:  subject.Name,
+		SubjectCode:  subject.Code,
+		GradeWeights: weights,
+		Components:   []GradebookComponent{},
+	}
+
+	// Graded types first, then practice and anything unknown
+	types := append([]models.ExamType{}, gradedExamTypes...)
+	for t := range byType {
+		if weights.of(t) == 0 && !containsExamType(types, t) {
+			types = append(types, t)
+		}
+	}
+
+	weighted, weightSum := 0.0, 0
+	for _, t := range types {
+		quizzes := byType[t]
+		if len(quizzes) == 0 && !containsExamType(gradedExamTypes, t) {
+			continue
+		}
+		sort.Slice(quizzes, func(i, j int) bool { return quizzes[i].Title < quizzes[j].Title })
+
+		comp := GradebookComponent{ExamType: t, Weight: weights.of(t), Quizzes: quizzes}
+		if comp.Quizzes == nil {
+			comp.Quizzes = []GradebookQuiz{}
+		}
+		if len(quizzes) > 0 {
+			sum := 0.0
+			for _, q := range quizzes {
+				sum += q.Percentage
+				if q.Passed {
+					gs.PassedCount++
+				} else {
+					gs.FailedCount++
+				}
+			}
+			avg := sum / float64(len(quizzes))
+			comp.Average = &avg
+			if comp.Weight > 0 {
+				weighted += avg * float64(comp.Weight)
+				weightSum += comp.Weight
+			}
+		}
+		gs.Components = append(gs.Components, comp)
+	}
+
+	if weightSum > 0 {
+		final := weighted / float64(weightSum)
+		gs.FinalGrade = &final
+	}
+	return gs
+}
+
+func containsExamType(types []models.ExamType, t models.ExamType) bool {
+	for _, x := range types {
+		if x == t {
+			return true
+		}
+	}
+	return false
+}
+
+func keys(set map[string]bool) []string {
+	list := make([]string, 0, len(set))
+	for k := range set {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// gradebookFilter reads the query, a student only ever sees their own gradebook
+func gradebookFilter(c *fiber.Ctx) GradebookFilter {
+	filter := GradebookFilter{
+		StudentID: c.Query("studentId"),
+		ClassID:   c.Query("classId"),
+		SubjectID: c.Query("subjectId"),
+	}
+	if role, _ := c.Locals("role").(string); models.UserRole(role) == models.RoleStudent {
+		userId, _ := c.Locals("userId").(string)
+		filter.StudentID = userId
+		filter.ClassID = ""
+	}
+	return filter
+}
+
+// GetGradebook godoc
+// @Summary      Get Gradebook
+// @Description  Best submitted attempt per quiz for a student or a class, grouped by subject and exam type, with pass/fail against the quiz passing score and the weighted final grade per subject
+// @Tags         gradebook
+// @Produce      json
+// @Param        studentId query string false "Student ID"
+// @Param        classId   query string false "Class ID (all its students)"
+// @Param        subjectId query string false "Only this subject"
+// @Success      200  {array}   GradebookStudent
+// @Failure      400  {object}  apperr.Response
+// @Router       /api/gradebook [get]
+func (s *GradebookService) GetGradebook(c *fiber.Ctx) error {
+	gradebook, err := s.buildGradebook(gradebookFilter(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(gradebook)
+}
+
+// ExportGradebook godoc
+// @Summary      Export Gradebook to Excel
+// @Description  Download the gradebook as .xlsx: a summary sheet (one row per student and subject) and a detail sheet (one row per quiz)
+// @Tags         gradebook
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param        studentId query string false "Student ID"
+// @Param        classId   query string false "Class ID"
+// @Param        subjectId query string false "Only this subject"
+// @Success      200  {file}  file
+// @Router       /api/gradebook/export [get]
+func (s *GradebookService) ExportGradebook(c *fiber.Ctx) error {
+	filter := gradebookFilter(c)
+	gradebook, err := s.buildGradebook(filter)
+	if err != nil {
+		return err
+	}
+
+	l := requestLocale(c)
+	f := excelize.NewFile()
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Printf("gradebook export: closing workbook: %v", err)
+		}
+	}()
+
+	styleHeader, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"#E0E0E0"}, Pattern: 1},
+	})
+	writeHeader := func(sheet string, headers []string) {
+		for i, h := range headers {
+			cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+			f.SetCellValue(sheet, cell, l.T(h))
+			f.SetCellStyle(sheet, cell, cell, styleHeader)
+		}
+	}
+
+	summary := l.T("sheet.gradebook_summary")
+	f.SetSheetName("Sheet1", summary)
+	writeHeader(summary, []string{"col.student", "col.subject", "exam_type.daily_quiz", "exam_type.midterm", "exam_type.final", "col.weights", "col.final_grade", "col.passed_count", "col.failed_count"})
+
+	detail := l.T("sheet.gradebook_detail")
+	f.NewSheet(detail)
+	writeHeader(detail, []string{"col.student", "col.subject", "col.exam_type", "col.exam", "col.score", "col.percent", "col.passing_score", "col.status"})
+
+	row, detailRow := 2, 2
+	for _, student := range gradebook {
+		for _, subj := range student.Subjects {
+			f.SetCellValue(summary, fmt.Sprintf("A%d", row), student.StudentName)
+			f.SetCellValue(summary, fmt.Sprintf("B%d", row), subj.SubjectName)
+			for _, comp := range subj.Components {
+				col := map[models.ExamType]string{models.ExamDaily: "C", models.ExamMidterm: "D", models.ExamFinal: "E"}[comp.ExamType]
+				if col != "" {
+					setOptionalFloat(f, summary, fmt.Sprintf("%s%d", col, row), comp.Average)
+				}
+				for _, q := range comp.Quizzes {
+					status := l.T("status.failed")
+					if q.Passed {
+						status = l.T("status.passed")
+					}
+					f.SetCellValue(detail, fmt.Sprintf("A%d", detailRow), student.StudentName)
+					f.SetCellValue(detail, fmt.Sprintf("B%d", detailRow), subj.SubjectName)
+					f.SetCellValue(detail, fmt.Sprintf("C%d", detailRow), examTypeLabel(l, q.ExamType))
+					f.SetCellValue(detail, fmt.Sprintf("D%d", detailRow), q.Title)
+					f.SetCellValue(detail, fmt.Sprintf("E%d", detailRow), round2(q.Score))
+					f.SetCellValue(detail, fmt.Sprintf("F%d", detailRow), round2(q.Percentage))
+					f.SetCellValue(detail, fmt.Sprintf("G%d", detailRow), q.PassingScore)
+					f.SetCellValue(detail, fmt.Sprintf("H%d", detailRow), status)
+					detailRow++
+				}
+			}
+			w := subj.GradeWeights
+			f.SetCellValue(summary, fmt.Sprintf("F%d", row), fmt.Sprintf("%d/%d/%d", w.DailyQuiz, w.Midterm, w.Final))
+			setOptionalFloat(f, summary, fmt.Sprintf("G%d", row), subj.FinalGrade)
+			f.SetCellValue(summary, fmt.Sprintf("H%d", row), subj.PassedCount)
+			f.SetCellValue(summary, fmt.Sprintf("I%d", row), subj.FailedCount)
+			row++
+		}
+	}
+	f.SetColWidth(summary, "A", "B", 28)
+	f.SetColWidth(detail, "A", "B", 28)
+	f.SetColWidth(detail, "D", "D", 36)
+
+	name := filter.StudentID
+	if name == "" {
+		name = filter.ClassID
+	}
+	c.Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=gradebook-%s.xlsx", name))
+
+	if err := f.Write(c.Response().BodyWriter()); err != nil {
+		return apperr.Internal("export_failed", err)
+	}
+	return nil
+}
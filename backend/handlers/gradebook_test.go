@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"academic-suite-backend/models"
+	"bytes"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+)
+
+// seedGradebook sets up Matematika weighted 20/30/50 without a midterm yet, and student-1 with:
+// two attempts on a daily quiz (best 80%), 50% on a second daily quiz, 90% on the final and a
+// practice quiz; student-2 with one daily quiz. Both are in class-1.
+func (e *testEnv) seedGradebook() {
+	e.t.Helper()
+	e.create(
+		&models.User{ID: "student-1", Name: "Ani", Email: "ani@example.com", Role: models.RoleStudent},
+		&models.User{ID: "student-2", Name: "Budi", Email: "budi@example.com", Role: models.RoleStudent},
+		&models.Subject{ID: "subject-1", Name: "Matematika", Code: "MTK", DailyQuizWeight: 20, MidtermWeight: 30, FinalWeight: 50},
+		&models.Class{ID: "class-1", Name: "X-1"},
+	)
+	addClassStudents(e.db, "class-1", []string{"student-1", "student-2"})
+
+	quizzes := []models.Quiz{
+		{ID: "daily-1", Title: "Kuis 1", ExamType: models.ExamDaily, TotalPoints: 100, PassingScore: 70},
+		{ID: "daily-2", Title: "Kuis 2", ExamType: models.ExamDaily, TotalPoints: 50, PassingScore: 30},
+		{ID: "final-1", Title: "UAS", ExamType: models.ExamFinal, TotalPoints: 100, PassingScore: 60},
+		{ID: "practice-1", Title: "Latihan", ExamType: models.ExamPractice, TotalPoints: 100},
+	}
+	for _, q := range quizzes {
+		q.SubjectID = "subject-1"
+		e.create(&q, &models.ExamBatch{ID: "batch-" + q.ID, QuizID: q.ID, Name: q.Title, StartTime: testStart, EndTime: testStart.Add(time.Hour), Duration: 60})
+	}
+
+	attempt := func(id, quizID, studentID string, status models.AttemptStatus, score float64, at time.Duration) *models.Attempt {
+		return &models.Attempt{ID: id, BatchID: "batch-" + quizID, StudentID: studentID, Status: status, Score: score, CreatedAt: testStart.Add(at)}
+	}
+	e.create(
+		attempt("a1", "daily-1", "student-1", models.AttemptSubmitted, 60, 0),
+		attempt("a2", "daily-1", "student-1", models.AttemptSubmitted, 80, time.Hour),
+		attempt("a3", "daily-1", "student-1", models.AttemptResetByAdmin, 100, 2*time.Hour), // not counted
+		attempt("a4", "daily-2", "student-1", models.AttemptSubmitted, 25, 0),
+		attempt("a5", "final-1", "student-1", models.AttemptSubmitted, 90, 0),
+		attempt("a6", "practice-1", "student-1", models.AttemptSubmitted, 40, 0),
+		attempt("a7", "daily-1", "student-2", models.AttemptSubmitted, 50, 0),
+	)
+}
+
+func TestGradebookBestAttemptsAndRescaledFinal(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedGradebook()
+
+	var gradebook []GradebookStudent
+	if status := e.do("GET", "/api/gradebook?studentId=student-1", nil, &gradebook); status != http.StatusOK {
+		t.Fatalf("status %d", status)
+	}
+	if len(gradebook) != 1 || len(gradebook[0].Subjects) != 1 {
+		t.Fatalf("gradebook = %+v", gradebook)
+	}
+	subj := gradebook[0].Subjects[0]
+
+	byType := map[models.ExamType]GradebookComponent{}
+	for _, comp := range subj.Components {
+		byType[comp.ExamType] = comp
+	}
+	daily := byType[models.ExamDaily]
+	if len(daily.Quizzes) != 2 || daily.Quizzes[0].AttemptID != "a2" || daily.Quizzes[1].Percentage != 50 {
+		t.Fatalf("daily = %+v", daily.Quizzes)
+	}
+	if daily.Average == nil || *daily.Average != 65 {
+		t.Errorf("daily average = %v, want 65", daily.Average)
+	}
+	if midterm := byType[models.ExamMidterm]; midterm.Average != nil || len(midterm.Quizzes) != 0 {
+		t.Errorf("midterm = %+v, want listed but empty", midterm)
+	}
+	if practice := byType[models.ExamPractice]; practice.Weight != 0 || len(practice.Quizzes) != 1 {
+		t.Errorf("practice = %+v", practice)
+	}
+
+	// The missing midterm's 30% is left out: (65*20 + 90*50) / 70
+	if want := (65.0*20 + 90*50) / 70; subj.FinalGrade == nil || math.Abs(*subj.FinalGrade-want) > 1e-9 {
+		t.Errorf("final grade = %v, want %v", subj.FinalGrade, want)
+	}
+	// Kuis 2 (25 of 30 needed) failed; practice has no passing score
+	if subj.PassedCount != 3 || subj.FailedCount != 1 {
+		t.Errorf("passed/failed = %d/%d, want 3/1", subj.PassedCount, subj.FailedCount)
+	}
+}
+
+func TestGradebookStudentSeesOnlyTheirOwn(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedGradebook()
+	student := []string{"X-User", "student-1", "X-Role", string(models.RoleStudent)}
+
+	var gradebook []GradebookStudent
+	for _, query := range []string{"studentId=student-2", "classId=class-1"} {
+		if status := e.do("GET", "/api/gradebook?"+query, nil, &gradebook, student...); status != http.StatusOK {
+			t.Fatalf("%s: status %d", query, status)
+		}
+		if len(gradebook) != 1 || gradebook[0].StudentID != "student-1" {
+			t.Errorf("%s: gradebook = %+v, want only student-1", query, gradebook)
+		}
+	}
+
+	// Teachers get the whole class
+	if e.do("GET", "/api/gradebook?classId=class-1", nil, &gradebook); len(gradebook) != 2 {
+		t.Errorf("class gradebook = %d students, want 2", len(gradebook))
+	}
+}
+
+func TestGradebookExport(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedGradebook()
+
+	status, _, body := e.download("/api/gradebook/export?classId=class-1", "X-Locale", "en")
+	if status != http.StatusOK {
+		t.Fatalf("status %d", status)
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	sheets := f.GetSheetList()
+	if len(sheets) != 2 {
+		t.Fatalf("sheets = %v", sheets)
+	}
+	summary, _ := f.GetRows(sheets[0])
+	detail, _ := f.GetRows(sheets[1])
+	// Header plus Ani and Budi in Matematika; header plus 4 + 1 quizzes
+	if len(summary) != 3 || len(detail) != 6 {
+		t.Fatalf("rows = %d summary, %d detail", len(summary), len(detail))
+	}
+	if ani := summary[1]; ani[0] != "Ani" || ani[2] != "65" || ani[5] != "20/30/50" || ani[7] != "3" || ani[8] != "1" {
+		t.Errorf("summary row = %v", ani)
+	}
+}
+
+func TestSubjectGradeWeightsValidation(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedGradebook()
+	admin := []string{"X-User", "student-1", "X-Role", string(models.RoleAdmin)}
+
+	cases := []struct {
+		weights fiber.Map
+		code    string
+	}{
+		{fiber.Map{"dailyQuiz": 50, "midterm": 30, "final": 30}, "grade_weights_sum"},
+		{fiber.Map{"dailyQuiz": -10, "midterm": 60, "final": 50}, "grade_weights_negative"},
+	}
+	for _, c := range cases {
+		var res validationResponse
+		status := e.do("PUT", "/api/subjects/subject-1", fiber.Map{"name": "Matematika", "gradeWeights": c.weights}, &res, admin...)
+		if status != http.StatusBadRequest || res.Error.Code != c.code {
+			t.Errorf("%v: status %d code %q, want %s", c.weights, status, res.Error.Code, c.code)
+		}
+	}
+
+	var subject SubjectResponse
+	if status := e.do("POST", "/api/subjects", fiber.Map{"name": "Fisika", "code": "FIS"}, &subject, admin...); status != http.StatusOK {
+		t.Fatalf("create: status %d", status)
+	}
+	if subject.GradeWeights != DefaultGradeWeights {
+		t.Errorf("default weights = %+v", subject.GradeWeights)
+	}
+}
@@ -1,235 +1,256 @@
 package handlers
 
 import (
-	"academic-suite-backend/database"
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/i18n"
 	"academic-suite-backend/models"
+	"academic-suite-backend/moodle"
+	"academic-suite-backend/qti"
+	"academic-suite-backend/tabular"
+	"academic-suite-backend/validation"
+	"errors"
 	"fmt"
+	"io"
+	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
-	"github.com/xuri/excelize/v2"
-	"golang.org/x/crypto/bcrypt"
 )
 
-// ImportUsers godoc
-// @Summary      Import Users from Excel
-// @Description  Import students or teachers from an Excel file (.xlsx)
-// @Tags         import
-// @Accept       multipart/form-data
-// @Produce      json
-// @Param        file formData file true "Excel file"
-// @Success      200  {object}  map[string]interface{}
-// @Failure      400  {object}  map[string]string
-// @Router       /api/import/users [post]
-func ImportUsers(c *fiber.Ctx) error {
+// uploadedFile returns the name and content of the file uploaded as "file"
+func uploadedFile(c *fiber.Ctx) (string, []byte, error) {
 	file, err := c.FormFile("file")
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "File parsing failed"})
+		return "", nil, apperr.BadRequest("file_parse_failed")
 	}
 
 	f, err := file.Open()
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Could not open file"})
+		return "", nil, apperr.BadRequest("file_open_failed")
 	}
 	defer f.Close()
-
-	excelFile, err := excelize.OpenReader(f)
+	data, err := io.ReadAll(f)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Excel file"})
+		return "", nil, apperr.BadRequest("file_open_failed")
 	}
+	return file.Filename, data, nil
+}
 
-	// Assuming first sheet
-	rows, err := excelFile.GetRows(excelFile.GetSheetName(0))
+// uploadedRows reads the first sheet of the spreadsheet uploaded as "file"
+func uploadedRows(c *fiber.Ctx) ([][]string, error) {
+	_, data, err := uploadedFile(c)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not read rows"})
+		return nil, err
 	}
+	return spreadsheetRows(data)
+}
 
-	successCount := 0
-	errors := []string{}
-
-	// Skip header row
-	for i, row := range rows {
-		if i == 0 {
-			continue // Skip header
-		}
-		if len(row) < 3 {
-			errors = append(errors, fmt.Sprintf("Row %d: Not enough columns", i+1))
-			continue
-		}
-
-		// Expected Columns: Name, Email, Password, Role (optional), InstitutionID (optional)
-		name := row[0]
-		email := row[1]
-		password := row[2]
-
-		role := models.RoleStudent // Default
-		if len(row) > 3 && row[3] != "" {
-			r := models.UserRole(strings.ToLower(row[3]))
-			if r == models.RoleTeacher || r == models.RoleAdmin || r == models.RoleStudent {
-				role = r
-			}
-		}
-
-		institutionId := ""
-		if len(row) > 4 {
-			institutionId = row[4]
-		}
-
-		if email == "" || password == "" {
-			errors = append(errors, fmt.Sprintf("Row %d: Missing email or password", i+1))
-			continue
-		}
-
-		// Check if user exists
-		var existingUser models.User
-		if err := database.DB.Where("email = ?", email).First(&existingUser).Error; err == nil {
-			errors = append(errors, fmt.Sprintf("Row %d: Email %s already exists", i+1, email))
-			continue
-		}
+// spreadsheetRows reads .xlsx, .ods or CSV (any delimiter, UTF-8/UTF-16/Windows-1252),
+// told apart by content
+func spreadsheetRows(data []byte) ([][]string, error) {
+	rows, _, err := tabular.Read(data)
+	if errors.Is(err, tabular.ErrUnsupported) {
+		return nil, apperr.BadRequest("unsupported_import_file")
+	}
+	if err != nil {
+		return nil, apperr.BadRequest("invalid_excel_file").Wrap(err)
+	}
+	return rows, nil
+}
 
-		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// importedQuestion is a question read from an upload, with the row or line it came from
+type importedQuestion struct {
+	Line     int
+	Question models.Question
+	Errors   validation.Errors
+}
 
-		user := models.User{
-			ID:            "user-" + uuid.New().String(),
-			Email:         email,
-			Password:      string(hashedPassword),
-			Name:          name,
-			Role:          role,
-			InstitutionID: institutionId,
-			CreatedAt:     time.Now(),
-		}
+// Question import formats; "auto" picks one from the file name
+const (
+	questionFormatSpreadsheet = "spreadsheet"
+	questionFormatGIFT        = "gift"
+	questionFormatAiken       = "aiken"
+	questionFormatQTI         = "qti"
+)
 
-		if err := database.DB.Create(&user).Error; err != nil {
-			errors = append(errors, fmt.Sprintf("Row %d: Database error", i+1))
-		} else {
-			successCount++
+// questionImportFormat resolves ?format=: zips with an imsmanifest.xml are QTI, .gift files
+// GIFT, .txt files Aiken when they have ANSWER lines and GIFT otherwise, anything else a spreadsheet
+func questionImportFormat(format, filename string, data []byte) (string, error) {
+	switch format {
+	case questionFormatSpreadsheet, questionFormatGIFT, questionFormatAiken, questionFormatQTI:
+		return format, nil
+	case "", "auto":
+	default:
+		return "", apperr.BadRequest("invalid_question_import_format")
+	}
+	if qti.IsPackage(data) {
+		return questionFormatQTI, nil
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".gift":
+		return questionFormatGIFT, nil
+	case ".txt":
+		if moodle.IsAiken(string(data)) {
+			return questionFormatAiken, nil
 		}
+		return questionFormatGIFT, nil
 	}
-
-	return c.JSON(fiber.Map{
-		"message":      fmt.Sprintf("Imported %d users successfully", successCount),
-		"errors":       errors,
-		"successCount": successCount,
-	})
+	return questionFormatSpreadsheet, nil
 }
 
 // ImportQuestions godoc
-// @Summary      Import Questions from Excel
-// @Description  Import questions for a specific quiz from an Excel file (.xlsx)
+// @Summary      Import Questions
+// @Description  Import questions for a specific quiz from a spreadsheet (.xlsx, .ods or .csv) with the columns
+// @Description  Type | Text | A | B | C | D | Correct (A-D, or the answer for other types) | Points,
+// @Description  from a Moodle GIFT or Aiken text file (.gift/.txt), or from an IMS QTI 2.1/3.0 package (.zip).
+// @Description  Each question is checked with the same rules as creating a quiz; failed ones are listed in errors.
 // @Tags         import
 // @Accept       multipart/form-data
 // @Produce      json
 // @Param        quizId path string true "Quiz ID"
-// @Param        file formData file true "Excel file"
+// @Param        file formData file true "Spreadsheet, GIFT, Aiken or QTI file"
+// @Param        format query string false "auto (default, from the file), spreadsheet, gift, aiken or qti"
 // @Success      200  {object}  map[string]interface{}
-// @Failure      400  {object}  map[string]string
+// @Failure      400  {object}  apperr.Response
 // @Router       /api/import/questions/{quizId} [post]
-func ImportQuestions(c *fiber.Ctx) error {
+func (s *ImportService) ImportQuestions(c *fiber.Ctx) error {
 	quizId := c.Params("quizId")
-	file, err := c.FormFile("file")
+	filename, data, err := uploadedFile(c)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "File parsing failed"})
+		return err
 	}
-
-	f, err := file.Open()
+	format, err := questionImportFormat(c.Query("format"), filename, data)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Could not open file"})
+		return err
 	}
-	defer f.Close()
 
-	excelFile, err := excelize.OpenReader(f)
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Excel file"})
-	}
-
-	rows, err := excelFile.GetRows(excelFile.GetSheetName(0))
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not read rows"})
+	l := requestLocale(c)
+	var questions []importedQuestion
+	lineLabel := l.T("col.row")
+	switch format {
+	case questionFormatSpreadsheet:
+		rows, err := spreadsheetRows(data)
+		if err != nil {
+			return err
+		}
+		questions = rowQuestions(rows)
+	case questionFormatQTI:
+		var user models.User
+		if err := s.db.First(&user, "id = ?", c.Locals("userId").(string)).Error; err != nil {
+			return apperr.Unauthorized("user_not_found")
+		}
+		if questions, _, err = s.qtiQuestions(data, l, user); err != nil {
+			return err
+		}
+		lineLabel = l.T("col.question")
+	default:
+		if questions, err = textQuestions(format, data, l); err != nil {
+			return err
+		}
+		lineLabel = l.T("col.line")
 	}
 
 	successCount := 0
-	errors := []string{}
-
-	// Skip header
-	for i, row := range rows {
-		if i == 0 {
+	rowErrors := []string{}
+	for _, imported := range questions {
+		if len(imported.Errors) > 0 {
+			rowErrors = append(rowErrors, importErrorText(l, lineLabel, imported.Line, imported.Errors))
 			continue
 		}
-		// Expected: Type (mcq), Text, A, B, C, D, Correct (A/B/C/D or text), Points
-		if len(row) < 3 {
-			errors = append(errors, fmt.Sprintf("Row %d: Not enough columns", i+1))
-			continue
+		question := imported.Question
+		question.QuizID = quizId
+		question.OrderIndex = imported.Line // maintain order
+
+		if err := s.db.Create(&question).Error; err != nil {
+			rowErrors = append(rowErrors, fmt.Sprintf("%s %d: %s", lineLabel, imported.Line, l.T("import_failed")))
+		} else {
+			successCount++
 		}
+	}
 
-		qType := models.QuestionType(strings.ToLower(row[0]))
-		text := row[1]
+	return c.JSON(fiber.Map{
+		"message":      l.T("questions_imported", successCount),
+		"format":       format,
+		"errors":       rowErrors,
+		"successCount": successCount,
+	})
+}
 
-		// Basic validation
-		if text == "" {
+// rowQuestions reads the question rows of a spreadsheet, skipping the header and blank rows
+func rowQuestions(rows [][]string) []importedQuestion {
+	var questions []importedQuestion
+	for i, row := range rows {
+		if i == 0 || len(row) == 0 {
 			continue
 		}
+		question, errs := questionFromRow(row)
+		questions = append(questions, importedQuestion{Line: i + 1, Question: question, Errors: errs})
+	}
+	return questions
+}
 
-		if qType == "" {
-			qType = models.TypeMCQ
-		}
+// newQuestionIDs gives q and its options IDs; option IDs sort in the order given, which is
+// the order options are shown in
+func newQuestionIDs(q *models.Question) {
+	q.ID = uuid.New().String()
+	for i := range q.Options {
+		q.Options[i].ID = fmt.Sprintf("%s-%02d", q.ID, i+1)
+		q.Options[i].QuestionID = q.ID
+	}
+}
 
-		question := models.Question{
-			ID:         uuid.New().String(),
-			QuizID:     quizId,
-			Type:       qType,
-			Text:       text,
-			OrderIndex: i, // maintain order
+// questionFromRow reads one question row and checks it like CreateQuiz does
+func questionFromRow(row []string) (models.Question, validation.Errors) {
+	cell := func(i int) string {
+		if i < len(row) {
+			return strings.TrimSpace(row[i])
 		}
+		return ""
+	}
 
-		// Options & Correct Answer Handling
-		if qType == models.TypeMCQ {
-			if len(row) < 7 {
-				errors = append(errors, fmt.Sprintf("Row %d: MCQ requires Options A-D and Correct Answer", i+1))
-				continue
-			}
-			optA := row[2]
-			optB := row[3]
-			optC := row[4]
-			optD := row[5]
-			correct := strings.ToUpper(strings.TrimSpace(row[6])) // Expecting "A", "B", "C", "D"
-
-			dbOptions := []models.QuestionOption{
-				{ID: uuid.New().String(), QuestionID: question.ID, Text: optA, IsCorrect: correct == "A"},
-				{ID: uuid.New().String(), QuestionID: question.ID, Text: optB, IsCorrect: correct == "B"},
-				{ID: uuid.New().String(), QuestionID: question.ID, Text: optC, IsCorrect: correct == "C"},
-				{ID: uuid.New().String(), QuestionID: question.ID, Text: optD, IsCorrect: correct == "D"},
-			}
-			question.Options = dbOptions
-		} else {
-			// Other types, correct answer is just the text in col 6 (index 6, but might be different pos if not MCQ)
-			// For simplicity let's assume same column index for "Correct Answer" even if options are empty
-			// But usually templates differ. Let's rely on standard template:
-			// Type | Text | OptA | OptB | OptC | OptD | Correct | Points
-			if len(row) > 6 {
-				question.CorrectAnswer = row[6]
-			}
-		}
+	qType := models.QuestionType(strings.ToLower(cell(0)))
+	if qType == "" {
+		qType = models.TypeMCQ
+	}
+	question := models.Question{
+		Type: qType,
+		Text: cell(1),
+	}
 
-		// Points
-		if len(row) > 7 {
-			fmt.Sscanf(row[7], "%d", &question.Points)
-		}
-		if question.Points == 0 {
-			question.Points = 1 // Default
+	// Choice questions: options A-D (blank ones left out), the correct one named by letter
+	if qType == models.TypeMCQ || qType == models.TypeTrueFalse {
+		correct := strings.ToUpper(cell(6))
+		for j, letter := range []string{"A", "B", "C", "D"} {
+			if text := cell(2 + j); text != "" {
+				question.Options = append(question.Options, models.QuestionOption{Text: text, IsCorrect: correct == letter})
+			}
 		}
+	} else {
+		question.CorrectAnswer = cell(6)
+	}
 
-		if err := database.DB.Create(&question).Error; err != nil {
-			errors = append(errors, fmt.Sprintf("Row %d: DB Error", i+1))
-		} else {
-			successCount++
-		}
+	fmt.Sscanf(cell(7), "%d", &question.Points)
+	if question.Points == 0 {
+		question.Points = 1 // Default
 	}
 
-	return c.JSON(fiber.Map{
-		"message":      fmt.Sprintf("Imported %d questions successfully", successCount),
-		"errors":       errors,
-		"successCount": successCount,
-	})
+	newQuestionIDs(&question)
+	return question, checkQuestion(question)
+}
+
+// checkQuestion validates an imported question like CreateQuiz validates one
+func checkQuestion(q models.Question) validation.Errors {
+	errs := validation.Struct(&q)
+	return append(errs, answerKeyRules(q)...)
+}
+
+// importErrorText is one line of the import's error list: "Row 3: text: Required; ..."
+func importErrorText(l i18n.Locale, label string, line int, errs validation.Errors) string {
+	localized, _ := errs.Localize(l).(validation.Errors)
+	parts := make([]string, len(localized))
+	for i, fe := range localized {
+		parts[i] = fe.Field + ": " + fe.Message
+	}
+	return fmt.Sprintf("%s %d: %s", label, line, strings.Join(parts, "; "))
 }
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"academic-suite-backend/models"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestImportUsersFromExcelCSV(t *testing.T) {
+	e := newTestEnv(t)
+	seedImportData(e)
+
+	// What Excel on Windows saves as "CSV" in an Indonesian locale: ";" and Windows-1252
+	csv, _ := charmap.Windows1252.NewEncoder().String(
+		"Nama;Email;Kata Sandi;Peran;NIS;NISN;Kelas\r\n" +
+			"José Ramírez;jose@example.com;rahasia1;student;1001;0012345678;7A\r\n")
+	status, _, body := e.upload("/api/import/users", "siswa.csv", []byte(csv), "X-User", "admin-1")
+	if status != http.StatusOK {
+		t.Fatalf("status %d %s", status, body)
+	}
+
+	var jose models.User
+	e.db.First(&jose, "email = ?", "jose@example.com")
+	if jose.Name != "José Ramírez" || jose.NISN != "0012345678" {
+		t.Errorf("jose = %+v", jose)
+	}
+}
+
+func TestImportRejectsUnsupportedFile(t *testing.T) {
+	e := newTestEnv(t)
+	seedImportData(e)
+
+	legacyXLS := []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1, 0, 0, 0, 0}
+	status, _, body := e.upload("/api/import/users", "siswa.xls", legacyXLS, "X-User", "admin-1")
+	if status != http.StatusBadRequest || !strings.Contains(string(body), "unsupported_import_file") {
+		t.Errorf("status %d %s", status, body)
+	}
+}
+
+func TestImportQuestionsChecksRows(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedInstitution("")
+
+	csv := "Type,Text,A,B,C,D,Correct,Points\n" +
+		"mcq,2 + 2 = ?,3,4,,,B,5\n" +
+		"true_false,Bumi itu bulat,Benar,Salah,,,A,\n" +
+		"mcq,Tanpa kunci,1,2,3,4,,2\n" +
+		"essay,,,,,,,\n" +
+		"\n" +
+		"short_answer,Ibu kota Indonesia,,,,,Jakarta,2\n"
+	status, _, body := e.upload("/api/import/questions/quiz-1", "soal.csv", []byte(csv), "Accept-Language", "en")
+	if status != http.StatusOK {
+		t.Fatalf("status %d %s", status, body)
+	}
+	var res struct {
+		Message      string   `json:"message"`
+		Errors       []string `json:"errors"`
+		SuccessCount int      `json:"successCount"`
+	}
+	json.Unmarshal(body, &res)
+	if res.SuccessCount != 3 || len(res.Errors) != 2 {
+		t.Fatalf("result = %+v", res)
+	}
+	if res.Message != "Imported 3 questions successfully" {
+		t.Errorf("message = %q", res.Message)
+	}
+	if res.Errors[0] != "Row 4: options: Exactly one option must be correct (got 0)" || res.Errors[1] != "Row 5: text: Required" {
+		t.Errorf("errors = %q", res.Errors)
+	}
+
+	var mcq models.Question
+	e.db.Preload("Options").First(&mcq, "quiz_id = ? AND text = ?", "quiz-1", "2 + 2 = ?")
+	if len(mcq.Options) != 2 || !mcq.Options[1].IsCorrect || mcq.Points != 5 {
+		t.Errorf("mcq = %+v", mcq)
+	}
+}
@@ -0,0 +1,578 @@
+package handlers
+
+import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/i18n"
+	"academic-suite-backend/models"
+	"academic-suite-backend/validation"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// The user import runs in two phases. planUserImport reads every row and checks it against the
+// database without writing anything; the plan is then either returned as a preview (dryRun) or
+// committed in one transaction. A commit writes all rows or, if any row failed, none of them.
+
+// userImportColumns are the template columns, in order. Headers are matched by key or by their
+// label in any language, so a template downloaded in English imports the same.
+var userImportColumns = []string{"name", "email", "password", "role", "nis", "nisn", "class", "institution"}
+
+// legacyUserColumns is the order of the old template (Name, Email, Password, Role, InstitutionID),
+// used when the header names none of the columns
+var legacyUserColumns = []string{"name", "email", "password", "role", "institution"}
+
+// userImportRow is one sheet row, checked like a request body
+type userImportRow struct {
+	Name        string `json:"name" validate:"required"`
+	Email       string `json:"email" validate:"required,email"`
+	Password    string `json:"password" validate:"omitempty,min=6,max=72"` // required for new users only
+	Role        string `json:"role" validate:"omitempty,oneof=admin teacher student"`
+	NIS         string `json:"nis" validate:"digits,max=20"`
+	NISN        string `json:"nisn" validate:"nisn"`
+	Class       string `json:"class"`       // class ID or name
+	Institution string `json:"institution"` // institution ID, defaults to the importer's
+}
+
+const (
+	importCreate = "create"
+	importUpdate = "update"
+	importError  = "error"
+)
+
+// UserImportRowResult is what happens (or would happen) to one row
+type UserImportRowResult struct {
+	Row     int               `json:"row"` // line in the sheet, the header is line 1
+	Name    string            `json:"name"`
+	Email   string            `json:"email"`
+	Action  string            `json:"action"` // create | update | error
+	UserID  string            `json:"userId,omitempty"`
+	ClassID string            `json:"classId,omitempty"`
+	Errors  validation.Errors `json:"errors,omitempty"`
+
+	fields   userImportRow
+	existing *models.User
+}
+
+// UserImportReport is the preview or the outcome of an import
+type UserImportReport struct {
+	DryRun    bool                  `json:"dryRun"`
+	Upsert    bool                  `json:"upsert"`
+	Committed bool                  `json:"committed"`
+	Total     int                   `json:"total"`
+	Created   int                   `json:"created"`
+	Updated   int                   `json:"updated"`
+	Failed    int                   `json:"failed"`
+	Rows      []UserImportRowResult `json:"rows"`
+}
+
+// Localize fills in the row error messages; it satisfies apperr.LocalizedDetails
+func (r UserImportReport) Localize(l i18n.Locale) interface{} {
+	out := r
+	out.Rows = make([]UserImportRowResult, len(r.Rows))
+	for i, row := range r.Rows {
+		if len(row.Errors) > 0 {
+			row.Errors = row.Errors.Localize(l).(validation.Errors)
+		}
+		out.Rows[i] = row
+	}
+	return out
+}
+
+// normalizeHeader reduces "Kata Sandi (min. 6)" to "katasandi"
+func normalizeHeader(h string) string {
+	if i := strings.Index(h, "("); i >= 0 {
+		h = h[:i]
+	}
+	var b strings.Builder
+	for _, r := range strings.ToLower(h) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func userColumnKey(header string) (string, bool) {
+	h := normalizeHeader(header)
+	if h == "institutionid" {
+		return "institution", true
+	}
+	for _, key := range userImportColumns {
+		if h == key {
+			return key, true
+		}
+		for _, l := range i18n.Supported {
+			if h == normalizeHeader(l.T("import.col."+key)) {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
+
+// userImportHeader maps column keys to their index in the sheet
+func userImportHeader(header []string) map[string]int {
+	index := make(map[string]int)
+	for i, h := range header {
+		if key, ok := userColumnKey(h); ok {
+			if _, seen := index[key]; !seen {
+				index[key] = i
+			}
+		}
+	}
+	if _, ok := index["email"]; !ok {
+		index = make(map[string]int)
+		for i, key := range legacyUserColumns {
+			index[key] = i
+		}
+	}
+	return index
+}
+
+func blankRow(row []string) bool {
+	for _, v := range row {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// planUserImport checks every row and decides what to do with it, without writing.
+// rows[0] is the header.
+func (s *ImportService) planUserImport(rows [][]string, upsert bool, importer models.User) UserImportReport {
+	report := UserImportReport{Upsert: upsert, Rows: []UserImportRowResult{}}
+	if len(rows) == 0 {
+		return report
+	}
+	columns := userImportHeader(rows[0])
+	cell := func(row []string, key string) string {
+		i, ok := columns[key]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	var parsed []UserImportRowResult
+	var emails, numbers []string
+	for i, row := range rows[1:] {
+		if blankRow(row) {
+			continue
+		}
+		fields := userImportRow{
+			Name:        cell(row, "name"),
+			Email:       cell(row, "email"),
+			Password:    cell(row, "password"),
+			Role:        strings.ToLower(cell(row, "role")),
+			NIS:         cell(row, "nis"),
+			NISN:        cell(row, "nisn"),
+			Class:       cell(row, "class"),
+			Institution: cell(row, "institution"),
+		}
+		parsed = append(parsed, UserImportRowResult{Row: i + 2, Name: fields.Name, Email: fields.Email, fields: fields})
+		emails = append(emails, strings.ToLower(fields.Email))
+		numbers = append(numbers, fields.NIS, fields.NISN)
+	}
+
+	// Everything the rows are checked against, loaded once
+	var users []models.User
+	s.db.Where("LOWER(email) IN ? OR nis IN ? OR nisn IN ?", emails, nonEmpty(numbers), nonEmpty(numbers)).Find(&users)
+	byEmail := make(map[string]*models.User)
+	nisOwner := make(map[string]string)
+	nisnOwner := make(map[string]string)
+	for i := range users {
+		u := &users[i]
+		byEmail[strings.ToLower(u.Email)] = u
+		if u.NIS != "" {
+			nisOwner[u.NIS] = u.ID
+		}
+		if u.NISN != "" {
+			nisnOwner[u.NISN] = u.ID
+		}
+	}
+	var classes []models.Class
+	s.db.Find(&classes)
+	var institutionIDs []string
+	s.db.Model(&models.Institution{}).Pluck("id", &institutionIDs)
+	institutions := make(map[string]bool)
+	for _, id := range institutionIDs {
+		institutions[id] = true
+	}
+
+	firstEmail := make(map[string]int)
+	firstNIS := make(map[string]int)
+	firstNISN := make(map[string]int)
+	for _, res := range parsed {
+		f := res.fields
+		errs := validation.Struct(&f)
+
+		email := strings.ToLower(f.Email)
+		if first, dup := firstEmail[email]; dup && email != "" {
+			errs.Add("email", "duplicate", strconv.Itoa(first))
+		} else {
+			firstEmail[email] = res.Row
+		}
+
+		res.Action = importCreate
+		role := models.UserRole(f.Role)
+		if existing := byEmail[email]; existing != nil && email != "" {
+			switch {
+			case !upsert:
+				errs.Add("email", "exists")
+			case existing.Role == models.RoleAdmin:
+				// An import never changes the password or role of an admin
+				errs.Add("email", "admin_account")
+			}
+			res.Action = importUpdate
+			res.existing = existing
+			res.UserID = existing.ID
+			if role == "" {
+				role = existing.Role
+			}
+		} else if f.Password == "" {
+			errs.Add("password", "required")
+		}
+		if role == "" {
+			role = models.RoleStudent
+		}
+
+		ownerID := ""
+		if res.existing != nil {
+			ownerID = res.existing.ID
+		}
+		checkNumber := func(field, value string, first map[string]int, owners map[string]string) {
+			if value == "" {
+				return
+			}
+			if row, dup := first[value]; dup {
+				errs.Add(field, "duplicate", strconv.Itoa(row))
+				return
+			}
+			first[value] = res.Row
+			if owner, taken := owners[value]; taken && owner != ownerID {
+				errs.Add(field, "taken")
+			}
+		}
+		checkNumber("nis", f.NIS, firstNIS, nisOwner)
+		checkNumber("nisn", f.NISN, firstNISN, nisnOwner)
+
+		if f.Class != "" {
+			classID, rule := resolveClass(classes, f.Class)
+			switch {
+			case rule != "":
+				errs.Add("class", rule)
+			case role != models.RoleStudent:
+				errs.Add("class", "students_only")
+			default:
+				res.ClassID = classID
+			}
+		}
+		if f.Institution != "" && !institutions[f.Institution] {
+			errs.Add("institution", "not_found")
+		}
+		if res.Action == importCreate && f.Institution == "" {
+			res.fields.Institution = importer.InstitutionID
+		}
+		res.fields.Role = string(role)
+
+		res.Errors = errs
+		if len(errs) > 0 {
+			res.Action = importError
+			report.Failed++
+		} else if res.Action == importCreate {
+			report.Created++
+		} else {
+			report.Updated++
+		}
+		report.Rows = append(report.Rows, res)
+	}
+	report.Total = len(report.Rows)
+	return report
+}
+
+func nonEmpty(values []string) []string {
+	out := []string{}
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// resolveClass finds a class by ID, else by name (case-insensitive). rule is the failed
+// validation rule, if any.
+func resolveClass(classes []models.Class, ref string) (id, rule string) {
+	var matches []string
+	for _, class := range classes {
+		if class.ID == ref {
+			return class.ID, ""
+		}
+		if strings.EqualFold(strings.TrimSpace(class.Name), ref) {
+			matches = append(matches, class.ID)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", "not_found"
+	case 1:
+		return matches[0], ""
+	default:
+		return "", "ambiguous"
+	}
+}
+
+// commitUserImport writes a plan without failed rows in one transaction
+func (s *ImportService) commitUserImport(report *UserImportReport) error {
+	now := s.clock.Now()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for i := range report.Rows {
+			row := &report.Rows[i]
+			f := row.fields
+
+			var user models.User
+			if row.existing != nil {
+				user = *row.existing
+			} else {
+				user = models.User{ID: "user-" + uuid.New().String(), Email: f.Email, CreatedAt: now}
+			}
+			user.Name = f.Name
+			user.Role = models.UserRole(f.Role)
+			// Blank cells keep what an existing user already has
+			if f.NIS != "" {
+				user.NIS = f.NIS
+			}
+			if f.NISN != "" {
+				user.NISN = f.NISN
+			}
+			if f.Institution != "" {
+				user.InstitutionID = f.Institution
+			}
+			if f.Password != "" {
+				hashed, err := bcrypt.GenerateFromPassword([]byte(f.Password), bcrypt.DefaultCost)
+				if err != nil {
+					return err
+				}
+				user.Password = string(hashed)
+			}
+
+			if err := tx.Save(&user).Error; err != nil {
+				return fmt.Errorf("row %d: %w", row.Row, err)
+			}
+			row.UserID = user.ID
+			if row.ClassID != "" {
+				if err := addClassStudents(tx, row.ClassID, []string{user.ID}); err != nil {
+					return fmt.Errorf("row %d: %w", row.Row, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// ImportUsers godoc
+// @Summary      Import Users from a spreadsheet
+// @Description  Import users from an .xlsx, .ods or .csv laid out like /api/import/users/template (the old Name, Email, Password, Role, InstitutionID layout still works).
+// @Description  Every row is validated first. With dryRun nothing is written and the response previews each row; otherwise all rows are written in one transaction, or none if any row fails.
+// @Description  Rows whose email exists are rejected unless upsert is set, in which case they update the user (blank cells keep current values); admin accounts are never updated. A class column adds students to that class (by ID or name).
+// @Tags         import
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file    formData  file    true   "Spreadsheet (.xlsx, .ods or .csv)"
+// @Param        dryRun  query     bool    false  "Validate and preview only"
+// @Param        upsert  query     bool    false  "Update users whose email already exists"
+// @Param        format  query     string  false  "json (default) or xlsx: the rows back with a status column and failed cells highlighted"
+// @Success      200  {object}  UserImportReport
+// @Failure      400  {object}  apperr.Response
+// @Router       /api/import/users [post]
+func (s *ImportService) ImportUsers(c *fiber.Ctx) error {
+	format := c.Query("format", "json")
+	if format != "json" && format != "xlsx" {
+		return apperr.BadRequest("invalid_import_result_format")
+	}
+
+	rows, err := uploadedRows(c)
+	if err != nil {
+		return err
+	}
+
+	var importer models.User
+	if userId, _ := c.Locals("userId").(string); userId != "" {
+		s.db.First(&importer, "id = ?", userId)
+	}
+
+	report := s.planUserImport(rows, c.QueryBool("upsert"), importer)
+	if report.Total == 0 {
+		return apperr.BadRequest("import_empty")
+	}
+	report.DryRun = c.QueryBool("dryRun")
+	if !report.DryRun && report.Failed == 0 {
+		if err := s.commitUserImport(&report); err != nil {
+			return apperr.Internal("import_failed", err)
+		}
+		report.Committed = true
+	}
+
+	l := requestLocale(c)
+	if format == "xlsx" {
+		return writeUserImportResult(c, l, report)
+	}
+	if !report.DryRun && !report.Committed {
+		return apperr.BadRequest("import_has_errors", report.Failed).WithDetails(report)
+	}
+	return c.JSON(report.Localize(l))
+}
+
+var importHeaderStyle = &excelize.Style{
+	Font: &excelize.Font{Bold: true},
+	Fill: excelize.Fill{Type: "pattern", Color: []string{"#E0E0E0"}, Pattern: 1},
+}
+
+// writeUserImportResult sends the rows back with their outcome. Cells of failed fields are red;
+// passwords are never echoed.
+func writeUserImportResult(c *fiber.Ctx, l i18n.Locale, report UserImportReport) error {
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := l.T("sheet.import_result")
+	f.SetSheetName("Sheet1", sheet)
+
+	styleHeader, _ := f.NewStyle(importHeaderStyle)
+	styleFailed, _ := f.NewStyle(&excelize.Style{Fill: excelize.Fill{Type: "pattern", Color: []string{"#F8D7DA"}, Pattern: 1}})
+	styleOK, _ := f.NewStyle(&excelize.Style{Fill: excelize.Fill{Type: "pattern", Color: []string{"#D4EDDA"}, Pattern: 1}})
+
+	var columns []string
+	for _, key := range userImportColumns {
+		if key != "password" {
+			columns = append(columns, key)
+		}
+	}
+	header := []interface{}{l.T("col.row")}
+	for _, key := range columns {
+		header = append(header, l.T("import.col."+key))
+	}
+	header = append(header, l.T("col.status"), l.T("col.errors"))
+	f.SetSheetRow(sheet, "A1", &header)
+	last, _ := excelize.CoordinatesToCellName(len(header), 1)
+	f.SetCellStyle(sheet, "A1", last, styleHeader)
+
+	statusCol := len(columns) + 2
+	for i, row := range report.Rows {
+		line := i + 2
+		fields := map[string]string{
+			"name": row.fields.Name, "email": row.fields.Email, "role": row.fields.Role, "nis": row.fields.NIS,
+			"nisn": row.fields.NISN, "class": row.fields.Class, "institution": row.fields.Institution,
+		}
+		values := []interface{}{row.Row}
+		for _, key := range columns {
+			values = append(values, fields[key])
+		}
+		var messages []string
+		for _, fe := range row.Errors.Localize(l).(validation.Errors) {
+			messages = append(messages, l.T("import.col."+fe.Field)+": "+fe.Message)
+		}
+		values = append(values, l.T("import.action."+row.Action), strings.Join(messages, "; "))
+		start, _ := excelize.CoordinatesToCellName(1, line)
+		f.SetSheetRow(sheet, start, &values)
+
+		status, _ := excelize.CoordinatesToCellName(statusCol, line)
+		if row.Action != importError {
+			f.SetCellStyle(sheet, status, status, styleOK)
+			continue
+		}
+		f.SetCellStyle(sheet, status, status, styleFailed)
+		for _, fe := range row.Errors {
+			for j, key := range columns {
+				if key == fe.Field {
+					cell, _ := excelize.CoordinatesToCellName(j+2, line)
+					f.SetCellStyle(sheet, cell, cell, styleFailed)
+				}
+			}
+		}
+	}
+	f.SetColWidth(sheet, "B", "C", 28)
+	errorsCol, _ := excelize.ColumnNumberToName(len(header))
+	f.SetColWidth(sheet, errorsCol, errorsCol, 60)
+
+	c.Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Set("Content-Disposition", "attachment; filename=import-users-result.xlsx")
+	c.Set("X-Import-Committed", strconv.FormatBool(report.Committed))
+	if err := f.Write(c.Response().BodyWriter()); err != nil {
+		return apperr.Internal("export_failed", err)
+	}
+	return nil
+}
+
+// GetUserImportTemplate godoc
+// @Summary      Download User Import Template
+// @Description  .xlsx with the import columns (headers in the request language), example rows and a sheet explaining each column
+// @Tags         import
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Success      200  {file}  file
+// @Router       /api/import/users/template [get]
+func (s *ImportService) GetUserImportTemplate(c *fiber.Ctx) error {
+	l := requestLocale(c)
+	f := excelize.NewFile()
+	defer f.Close()
+	styleHeader, _ := f.NewStyle(importHeaderStyle)
+
+	sheet := l.T("sheet.users")
+	f.SetSheetName("Sheet1", sheet)
+	header := make([]interface{}, len(userImportColumns))
+	for i, key := range userImportColumns {
+		header[i] = l.T("import.col." + key)
+	}
+	f.SetSheetRow(sheet, "A1", &header)
+	last, _ := excelize.CoordinatesToCellName(len(header), 1)
+	f.SetCellStyle(sheet, "A1", last, styleHeader)
+	examples := [][]interface{}{
+		{"Budi Santoso", "budi@example.com", "rahasia123", "student", "2024001", "0012345678", "7A", ""},
+		{"Siti Aminah", "siti@example.com", "rahasia123", "teacher", "", "", "", ""},
+	}
+	for i, example := range examples {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		f.SetSheetRow(sheet, cell, &example)
+	}
+	f.SetColWidth(sheet, "A", "B", 28)
+	f.SetColWidth(sheet, "C", "H", 16)
+	// NIS/NISN as text so leading zeros survive
+	textStyle, _ := f.NewStyle(&excelize.Style{NumFmt: 49})
+	f.SetColStyle(sheet, "E:F", textStyle)
+
+	roles := excelize.NewDataValidation(true)
+	roles.Sqref = "D2:D1000"
+	roles.SetDropList([]string{"student", "teacher", "admin"})
+	f.AddDataValidation(sheet, roles)
+
+	help := l.T("sheet.instructions")
+	f.NewSheet(help)
+	helpHeader := []interface{}{l.T("import.help.column"), l.T("import.help.required"), l.T("import.help.description")}
+	f.SetSheetRow(help, "A1", &helpHeader)
+	f.SetCellStyle(help, "A1", "C1", styleHeader)
+	for i, key := range userImportColumns {
+		required := l.T("import.optional")
+		if key == "name" || key == "email" {
+			required = l.T("import.required")
+		}
+		values := []interface{}{l.T("import.col." + key), required, l.T("import.help." + key)}
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		f.SetSheetRow(help, cell, &values)
+	}
+	f.SetColWidth(help, "A", "B", 16)
+	f.SetColWidth(help, "C", "C", 90)
+
+	c.Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Set("Content-Disposition", "attachment; filename=template-users.xlsx")
+	if err := f.Write(c.Response().BodyWriter()); err != nil {
+		return apperr.Internal("export_failed", err)
+	}
+	return nil
+}
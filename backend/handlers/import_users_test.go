@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"academic-suite-backend/models"
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// xlsxOf builds a one-sheet workbook
+func xlsxOf(t *testing.T, rows [][]interface{}) []byte {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		f.SetSheetRow("Sheet1", cell, &row)
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatalf("write xlsx: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// upload posts data as the multipart "file" field
+func (e *testEnv) upload(path, filename string, data []byte, headers ...string) (int, http.Header, []byte) {
+	e.t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, _ := w.CreateFormFile("file", filename)
+	part.Write(data)
+	w.Close()
+
+	req := httptest.NewRequest("POST", path, &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := e.app.Test(req, -1)
+	if err != nil {
+		e.t.Fatalf("POST %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	out, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, resp.Header, out
+}
+
+func (e *testEnv) importUsers(query string, rows [][]interface{}, headers ...string) (int, UserImportReport) {
+	e.t.Helper()
+	status, _, body := e.upload("/api/import/users"+query, "users.xlsx", xlsxOf(e.t, rows), headers...)
+	var report UserImportReport
+	if status == http.StatusOK {
+		json.Unmarshal(body, &report)
+	} else {
+		var res struct {
+			Error struct {
+				Code    string           `json:"code"`
+				Details UserImportReport `json:"details"`
+			} `json:"error"`
+		}
+		json.Unmarshal(body, &res)
+		if res.Error.Code != "import_has_errors" {
+			e.t.Fatalf("import: status %d %s", status, body)
+		}
+		report = res.Error.Details
+	}
+	return status, report
+}
+
+func (e *testEnv) userCount() int64 {
+	var n int64
+	e.db.Model(&models.User{}).Count(&n)
+	return n
+}
+
+var userHeader = []interface{}{"Nama", "Email", "Kata Sandi", "Peran", "NIS", "NISN", "Kelas"}
+
+func seedImportData(e *testEnv) {
+	e.create(
+		&models.Institution{ID: "inst-1", Name: "SMP 20"},
+		&models.User{ID: "admin-1", Email: "admin@example.com", Role: models.RoleAdmin, InstitutionID: "inst-1"},
+		&models.Class{ID: "class-7a", Name: "7A"},
+	)
+}
+
+func TestImportUsersDryRunPreviewsWithoutWriting(t *testing.T) {
+	e := newTestEnv(t)
+	seedImportData(e)
+	e.create(&models.User{ID: "old", Email: "lama@example.com", Role: models.RoleStudent, NISN: "0099999999"})
+	before := e.userCount()
+
+	status, report := e.importUsers("?dryRun=true", [][]interface{}{
+		userHeader,
+		{"Budi", "budi@example.com", "rahasia1", "student", "1001", "0012345678", "7a"},
+		{"Ani", "bukan-email", "rahasia1", "", "", "", ""},
+		{"Budi Lagi", "BUDI@example.com", "rahasia1", "", "", "", ""},
+		{},
+		{"Guru", "guru@example.com", "rahasia1", "teacher", "", "", "7A"},
+		{"Cici", "cici@example.com", "", "student", "", "0099999999", "9Z"},
+		{"Lama", "lama@example.com", "", "", "", "", ""},
+	}, "X-User", "admin-1", "Accept-Language", "en")
+	if status != http.StatusOK {
+		t.Fatalf("status %d", status)
+	}
+	if e.userCount() != before {
+		t.Fatal("dry run wrote users")
+	}
+	if !report.DryRun || report.Committed || report.Total != 6 || report.Created != 1 || report.Failed != 5 {
+		t.Errorf("summary = %+v", report)
+	}
+
+	want := map[int]map[string]string{
+		2: {},
+		3: {"email": "email"},
+		4: {"email": "duplicate"},
+		6: {"class": "students_only"},
+		7: {"password": "required", "nisn": "taken", "class": "not_found"},
+		8: {"email": "exists"},
+	}
+	for _, row := range report.Rows {
+		got := fieldRules(row.Errors)
+		if len(got) != len(want[row.Row]) {
+			t.Errorf("row %d: errors %v, want %v", row.Row, got, want[row.Row])
+			continue
+		}
+		for field, rule := range want[row.Row] {
+			if got[field] != rule {
+				t.Errorf("row %d: %s rule %q, want %q", row.Row, field, got[field], rule)
+			}
+		}
+	}
+	if budi := report.Rows[0]; budi.Action != importCreate || budi.ClassID != "class-7a" {
+		t.Errorf("row 2 = %+v", budi)
+	}
+	if msg := report.Rows[2].Errors[0].Message; msg != "Duplicate of row 2" {
+		t.Errorf("duplicate message = %q", msg)
+	}
+}
+
+func TestImportUsersCommitsAllOrNothing(t *testing.T) {
+	e := newTestEnv(t)
+	seedImportData(e)
+	before := e.userCount()
+
+	rows := [][]interface{}{
+		userHeader,
+		{"Budi", "budi@example.com", "rahasia1", "student", "1001", "0012345678", "7A"},
+		{"Ani", "ani@example.com", "123", "student", "", "", ""},
+	}
+	status, report := e.importUsers("", rows, "X-User", "admin-1")
+	if status != http.StatusBadRequest || report.Committed || report.Failed != 1 {
+		t.Fatalf("status %d report %+v", status, report)
+	}
+	if e.userCount() != before {
+		t.Fatal("a failed import wrote users")
+	}
+
+	rows[2][2] = "rahasia2"
+	status, report = e.importUsers("", rows, "X-User", "admin-1")
+	if status != http.StatusOK || !report.Committed || report.Created != 2 {
+		t.Fatalf("status %d report %+v", status, report)
+	}
+
+	var budi models.User
+	e.db.First(&budi, "email = ?", "budi@example.com")
+	if budi.NIS != "1001" || budi.NISN != "0012345678" || budi.InstitutionID != "inst-1" || budi.Role != models.RoleStudent {
+		t.Errorf("budi = %+v", budi)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(budi.Password), []byte("rahasia1")) != nil {
+		t.Error("password not hashed from the sheet")
+	}
+	if members := classStudentIDs(e.db, "class-7a"); len(members) != 1 || members[0] != budi.ID {
+		t.Errorf("class members = %v", members)
+	}
+}
+
+func TestImportUsersUpsert(t *testing.T) {
+	e := newTestEnv(t)
+	seedImportData(e)
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("lama123"), bcrypt.MinCost)
+	e.create(&models.User{ID: "student-1", Email: "budi@example.com", Name: "Budi", Password: string(hashed), Role: models.RoleStudent, InstitutionID: "inst-1"})
+
+	rows := [][]interface{}{userHeader, {"Budi Santoso", "budi@example.com", "", "", "1001", "", "7A"}}
+	if status, report := e.importUsers("", rows, "X-User", "admin-1"); status != http.StatusBadRequest || fieldRules(report.Rows[0].Errors)["email"] != "exists" {
+		t.Fatalf("without upsert: status %d report %+v", status, report)
+	}
+
+	status, report := e.importUsers("?upsert=true", rows, "X-User", "admin-1")
+	if status != http.StatusOK || report.Updated != 1 || report.Rows[0].UserID != "student-1" {
+		t.Fatalf("upsert: status %d report %+v", status, report)
+	}
+	var budi models.User
+	e.db.First(&budi, "id = ?", "student-1")
+	if budi.Name != "Budi Santoso" || budi.NIS != "1001" || budi.Role != models.RoleStudent {
+		t.Errorf("budi = %+v", budi)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(budi.Password), []byte("lama123")) != nil {
+		t.Error("a blank password cell changed the password")
+	}
+	if members := classStudentIDs(e.db, "class-7a"); len(members) != 1 {
+		t.Errorf("class members = %v", members)
+	}
+}
+
+// Upsert cannot take over an admin account through its email
+func TestImportUsersUpsertSkipsAdmins(t *testing.T) {
+	e := newTestEnv(t)
+	seedImportData(e)
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("rahasia"), bcrypt.MinCost)
+	e.db.Model(&models.User{}).Where("id = ?", "admin-1").Update("password", string(hashed))
+
+	rows := [][]interface{}{userHeader, {"Penyusup", "admin@example.com", "baru123", "student", "", "", ""}}
+	status, report := e.importUsers("?upsert=true", rows, "X-User", "admin-1")
+	if status != http.StatusBadRequest || fieldRules(report.Rows[0].Errors)["email"] != "admin_account" {
+		t.Fatalf("status %d report %+v", status, report)
+	}
+	var admin models.User
+	e.db.First(&admin, "id = ?", "admin-1")
+	if admin.Role != models.RoleAdmin || bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte("rahasia")) != nil {
+		t.Errorf("admin was changed: %+v", admin)
+	}
+}
+
+func TestImportUsersResultFileHighlightsErrors(t *testing.T) {
+	e := newTestEnv(t)
+	seedImportData(e)
+
+	status, header, body := e.upload("/api/import/users?format=xlsx", "users.xlsx", xlsxOf(t, [][]interface{}{
+		userHeader,
+		{"Budi", "budi@example.com", "rahasia1", "student", "", "", ""},
+		{"Ani", "bukan-email", "rahasia1", "student", "", "", ""},
+	}), "X-User", "admin-1")
+	if status != http.StatusOK || header.Get("X-Import-Committed") != "false" {
+		t.Fatalf("status %d committed %q", status, header.Get("X-Import-Committed"))
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("open result: %v", err)
+	}
+	defer f.Close()
+
+	rows, _ := f.GetRows("Hasil Impor")
+	if len(rows) != 3 || rows[1][2] != "budi@example.com" || rows[2][8] != "Gagal" {
+		t.Fatalf("rows = %q", rows)
+	}
+	for _, row := range rows {
+		for _, v := range row {
+			if v == "rahasia1" {
+				t.Fatal("password echoed in the result file")
+			}
+		}
+	}
+	okEmail, _ := f.GetCellStyle("Hasil Impor", "C2")
+	badEmail, _ := f.GetCellStyle("Hasil Impor", "C3")
+	if okEmail == badEmail {
+		t.Error("failed email cell is not highlighted")
+	}
+}
+
+func TestUserImportTemplateImportsBack(t *testing.T) {
+	e := newTestEnv(t)
+	seedImportData(e)
+
+	status, _, body := e.download("/api/import/users/template", "Accept-Language", "en")
+	if status != http.StatusOK {
+		t.Fatalf("template: status %d", status)
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("open template: %v", err)
+	}
+	if sheets := f.GetSheetList(); len(sheets) != 2 || sheets[0] != "Users" || sheets[1] != "Instructions" {
+		t.Errorf("sheets = %v", sheets)
+	}
+	f.Close()
+
+	// The English template, examples included, goes straight back in
+	_, _, out := e.upload("/api/import/users?dryRun=true", "template.xlsx", body, "X-User", "admin-1")
+	var report UserImportReport
+	json.Unmarshal(out, &report)
+	if report.Total != 2 || report.Created != 2 {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestImportUsersReadsLegacyLayout(t *testing.T) {
+	e := newTestEnv(t)
+	seedImportData(e)
+
+	status, report := e.importUsers("", [][]interface{}{
+		{"Nama Lengkap", "Alamat Surel", "Sandi", "Tipe", "Institusi"},
+		{"Budi", "budi@example.com", "rahasia1", "teacher", "inst-1"},
+	}, "X-User", "admin-1")
+	if status != http.StatusOK || report.Created != 1 {
+		t.Fatalf("status %d report %+v", status, report)
+	}
+	var budi models.User
+	e.db.First(&budi, "email = ?", "budi@example.com")
+	if budi.Role != models.RoleTeacher || budi.InstitutionID != "inst-1" {
+		t.Errorf("budi = %+v", budi)
+	}
+}
@@ -1,9 +1,11 @@
 package handlers
 
 import (
-	"academic-suite-backend/database"
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/clock"
 	"academic-suite-backend/models"
-
+	"academic-suite-backend/validation"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -16,9 +18,9 @@ import (
 // @Produce      json
 // @Success      200  {array}  models.Institution
 // @Router       /api/institutions [get]
-func GetInstitutions(c *fiber.Ctx) error {
+func (s *InstitutionService) GetInstitutions(c *fiber.Ctx) error {
 	var institutions []models.Institution
-	database.DB.Find(&institutions)
+	s.db.Find(&institutions)
 	return c.JSON(institutions)
 }
 
@@ -31,22 +33,74 @@ func GetInstitutions(c *fiber.Ctx) error {
 // @Param        institution body models.Institution true "Institution Data"
 // @Success      200  {object}  models.Institution
 // @Router       /api/institutions [post]
-func CreateInstitution(c *fiber.Ctx) error {
+func (s *InstitutionService) CreateInstitution(c *fiber.Ctx) error {
 	var req models.Institution
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(req.Name) == "" {
+		return fieldErrors(validation.Field("name", "required"))
 	}
 
-	if req.Name == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Name is required"})
+	if req.Timezone == "" {
+		req.Timezone = clock.DefaultTimezone
+	}
+	if _, err := loadTimezone(req.Timezone); err != nil {
+		return err
 	}
 
 	req.ID = "inst-" + time.Now().Format("20060102150405")
-	req.CreatedAt = time.Now()
+	req.CreatedAt = s.clock.Now()
 
-	if err := database.DB.Create(&req).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create institution"})
+	if err := s.db.Create(&req).Error; err != nil {
+		return apperr.Internal("institution_create_failed", err)
 	}
 
 	return c.JSON(req)
 }
+
+// UpdateInstitution godoc
+// @Summary      Update Institution
+// @Description  Update an institution, including its timezone (IANA name such as Asia/Jakarta). New batch schedules are read in this zone; existing batches keep the zone they were scheduled in.
+// @Tags         institutions
+// @Accept       json
+// @Produce      json
+// @Param        id          path  string              true  "Institution ID"
+// @Param        institution body  models.Institution  true  "Institution Data"
+// @Success      200  {object}  models.Institution
+// @Failure      400  {object}  apperr.Response
+// @Failure      404  {object}  apperr.Response
+// @Router       /api/institutions/{id} [put]
+func (s *InstitutionService) UpdateInstitution(c *fiber.Ctx) error {
+	var req models.Institution
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	var inst models.Institution
+	if err := s.db.First(&inst, "id = ?", c.Params("id")).Error; err != nil {
+		return apperr.NotFound("institution_not_found")
+	}
+
+	if req.Name != "" {
+		inst.Name = req.Name
+	}
+	if req.Type != "" {
+		inst.Type = req.Type
+	}
+	if req.Address != "" {
+		inst.Address = req.Address
+	}
+	if req.Timezone != "" {
+		if _, err := loadTimezone(req.Timezone); err != nil {
+			return err
+		}
+		inst.Timezone = req.Timezone
+	}
+
+	if err := s.db.Save(&inst).Error; err != nil {
+		return apperr.Internal("institution_update_failed", err)
+	}
+	return c.JSON(inst)
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/i18n"
+	"academic-suite-backend/models"
+	"academic-suite-backend/psychometrics"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+)
+
+// ItemAnalysisItem is one question with its statistics
+type ItemAnalysisItem struct {
+	psychometrics.ItemStats
+	QuestionID string              `json:"questionId"`
+	Number     int                 `json:"number"` // 1-based position in the quiz
+	Text       string              `json:"text"`
+	Type       models.QuestionType `json:"type"`
+	Points     int                 `json:"points"`
+	// Option letters (A, B, ...) matching Options, for display
+	OptionLabels []string `json:"optionLabels"`
+}
+
+type ItemAnalysisResponse struct {
+	QuizID    string             `json:"quizId"`
+	QuizTitle string             `json:"quizTitle"`
+	BatchIDs  []string           `json:"batchIds"`  // batches whose attempts were analysed
+	Examinees int                `json:"examinees"` // submitted attempts, one per student
+	GroupSize int                `json:"groupSize"` // size of the upper and lower groups used for discrimination
+	Items     []ItemAnalysisItem `json:"items"`
+}
+
+// analysisAttempts loads the best submitted attempt per student for the given batches
+func (s *ReportService) analysisAttempts(batchIDs []string) []models.Attempt {
+	var attempts []models.Attempt
+	if len(batchIDs) > 0 {
+		s.db.Where("batch_id IN ?", batchIDs).Find(&attempts)
+	}
+
+	best := bestAttemptPerStudent(attempts)
+	result := make([]models.Attempt, 0, len(best))
+	for _, a := range best {
+		if a.Status == models.AttemptSubmitted {
+			result = append(result, a)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].StudentID < result[j].StudentID })
+	return result
+}
+
+// itemAnalysisScope resolves the batches to analyse: a regular batch with its makeups, or every batch of a quiz
+func (s *ReportService) itemAnalysisScope(batchId, quizId string) (string, []string, error) {
+	var batchIDs []string
+	if batchId != "" {
+		var batch models.ExamBatch
+		if err := s.db.First(&batch, "id = ?", batchId).Error; err != nil {
+			return "", nil, errBatchNotFound
+		}
+		batchIDs = append(batchIDs, batch.ID)
+		if batch.Type != models.BatchMakeup {
+			var makeupIDs []string
+			s.db.Model(&models.ExamBatch{}).Where("parent_batch_id = ?", batch.ID).Order("id").Pluck("id", &makeupIDs)
+			batchIDs = append(batchIDs, makeupIDs...)
+		}
+		return batch.QuizID, batchIDs, nil
+	}
+
+	var count int64
+	s.db.Model(&models.Quiz{}).Where("id = ?", quizId).Count(&count)
+	if count == 0 {
+		return "", nil, errQuizNotFound
+	}
+	s.db.Model(&models.ExamBatch{}).Where("quiz_id = ?", quizId).Order("id").Pluck("id", &batchIDs)
+	return quizId, batchIDs, nil
+}
+
+// analysisInput is a quiz's questions as psychometric items plus one response sheet per examinee.
+// sheets[i] belongs to attempts[i].
+type analysisInput struct {
+	quiz     models.Quiz
+	batchIDs []string
+	items    []psychometrics.Item
+	attempts []models.Attempt
+	sheets   []psychometrics.Sheet
+}
+
+// loadAnalysisInput builds the items and sheets for a batch (with makeups) or a quiz
+func (s *ReportService) loadAnalysisInput(batchId, quizId string) (*analysisInput, error) {
+	quizID, batchIDs, err := s.itemAnalysisScope(batchId, quizId)
+	if err != nil {
+		return nil, err
+	}
+	return s.buildAnalysisInput(quizID, batchIDs), nil
+}
+
+// buildAnalysisInput builds the items and sheets from the Answer rows of submitted attempts in batchIDs
+func (s *ReportService) buildAnalysisInput(quizID string, batchIDs []string) *analysisInput {
+	quiz := quizWithQuestions(s.db, quizID)
+
+	attempts := s.analysisAttempts(batchIDs)
+	attemptIDs := make([]string, 0, len(attempts))
+	for _, a := range attempts {
+		attemptIDs = append(attemptIDs, a.ID)
+	}
+
+	var answers []models.Answer
+	if len(attemptIDs) > 0 {
+		s.db.Where("attempt_id IN ?", attemptIDs).Find(&answers)
+	}
+	answersByAttempt := make(map[string][]models.Answer)
+	for _, ans := range answers {
+		answersByAttempt[ans.AttemptID] = append(answersByAttempt[ans.AttemptID], ans)
+	}
+
+	// Items: a question is scored the same way SubmitAttempt scores it, by its correct option(s)
+	items := make([]psychometrics.Item, 0, len(quiz.Questions))
+	correctOption := make(map[string]bool)
+	for _, q := range quiz.Questions {
+		item := psychometrics.Item{ID: q.ID, Points: float64(q.Points)}
+		for _, opt := range q.Options {
+			item.Options = append(item.Options, opt.ID)
+			if opt.IsCorrect {
+				item.Keys = append(item.Keys, opt.ID)
+				correctOption[opt.ID] = true
+			}
+		}
+		item.Scored = len(item.Keys) > 0
+		items = append(items, item)
+	}
+
+	sheets := make([]psychometrics.Sheet, 0, len(attempts))
+	for _, a := range attempts {
+		sheet := psychometrics.Sheet{ExamineeID: a.StudentID, Responses: map[string]psychometrics.Response{}}
+		for _, ans := range answersByAttempt[a.ID] {
+			sheet.Responses[ans.QuestionID] = psychometrics.Response{
+				OptionID: ans.SelectedOptionID,
+				Blank:    ans.SelectedOptionID == "" && strings.TrimSpace(ans.TextAnswer) == "",
+				Correct:  correctOption[ans.SelectedOptionID],
+			}
+		}
+		sheets = append(sheets, sheet)
+	}
+
+	if batchIDs == nil {
+		batchIDs = []string{}
+	}
+	return &analysisInput{quiz: quiz, batchIDs: batchIDs, items: items, attempts: attempts, sheets: sheets}
+}
+
+// getItemAnalysisData computes per-question statistics for a batch or a quiz
+func (s *ReportService) getItemAnalysisData(batchId, quizId string) (*ItemAnalysisResponse, error) {
+	in, err := s.loadAnalysisInput(batchId, quizId)
+	if err != nil {
+		return nil, err
+	}
+	quiz, items, sheets := in.quiz, in.items, in.sheets
+
+	stats := psychometrics.AnalyzeItems(items, sheets)
+	upper, _ := psychometrics.UpperLowerGroups(sheets, psychometrics.TotalScores(items, sheets))
+
+	report := &ItemAnalysisResponse{
+		QuizID:    quiz.ID,
+		QuizTitle: quiz.Title,
+		BatchIDs:  in.batchIDs,
+		Examinees: len(sheets),
+		GroupSize: len(upper),
+		Items:     []ItemAnalysisItem{},
+	}
+	for i, q := range quiz.Questions {
+		labels := make([]string, len(q.Options))
+		for j := range q.Options {
+			labels[j] = optionLabel(j)
+		}
+		report.Items = append(report.Items, ItemAnalysisItem{
+			ItemStats:    stats[i],
+			QuestionID:   q.ID,
+			Number:       i + 1,
+			Text:         q.Text,
+			Type:         q.Type,
+			Points:       q.Points,
+			OptionLabels: labels,
+		})
+	}
+	return report, nil
+}
+
+// optionLabel turns 0, 1, 2 into A, B, C
+func optionLabel(i int) string {
+	if i < 26 {
+		return string(rune('A' + i))
+	}
+	return fmt.Sprintf("%d", i+1)
+}
+
+// GetItemAnalysis godoc
+// @Summary      Get Item Analysis
+// @Description  Per-question difficulty (p-value), discrimination index (upper/lower 27%), point-biserial (item-rest), distractor frequencies and blank rate, from submitted attempts of a batch (with its makeups) or of all batches of a quiz
+// @Tags         reports
+// @Produce      json
+// @Param        batchId query string false "Batch ID"
+// @Param        quizId  query string false "Quiz ID (all batches)"
+// @Success      200  {object}  ItemAnalysisResponse
+// @Failure      400  {object}  apperr.Response
+// @Failure      404  {object}  apperr.Response
+// @Router       /api/reports/items [get]
+func (s *ReportService) GetItemAnalysis(c *fiber.Ctx) error {
+	batchId := c.Query("batchId")
+	quizId := c.Query("quizId")
+	if batchId == "" && quizId == "" {
+		return apperr.BadRequest("batch_or_quiz_required")
+	}
+
+	report, err := s.getItemAnalysisData(batchId, quizId)
+	if err != nil {
+		return err
+	}
+	return c.JSON(report)
+}
+
+// writeItemAnalysisSheet adds the "Analisis Butir" sheet to a report workbook, with the
+// test reliability on top when rel is given
+func writeItemAnalysisSheet(f *excelize.File, l i18n.Locale, analysis *ItemAnalysisResponse, rel *ReliabilityResponse) {
+	sheetName := l.T("sheet.item_analysis")
+	f.NewSheet(sheetName)
+
+	styleHeader, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"#E0E0E0"}, Pattern: 1},
+	})
+
+	f.SetCellValue(sheetName, "A1", l.T("item_analysis.title"))
+	f.SetCellValue(sheetName, "A2", l.T("item_analysis.summary", analysis.Examinees, analysis.GroupSize))
+	if rel != nil {
+		f.SetCellValue(sheetName, "C2", "KR-20")
+		setOptionalFloat(f, sheetName, "D2", rel.KR20)
+		f.SetCellValue(sheetName, "E2", "Alpha")
+		setOptionalFloat(f, sheetName, "F2", rel.CronbachAlpha)
+		f.SetCellValue(sheetName, "G2", "SEM")
+		setOptionalFloat(f, sheetName, "H2", rel.SEM)
+	}
+
+	headers := []string{"col.no", "col.question", "col.type", "col.difficulty", "col.discrimination", "col.point_biserial", "col.blank_percent", "col.distribution"}
+	for i, h := range headers {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 4)
+		f.SetCellValue(sheetName, cell, l.T(h))
+		f.SetCellStyle(sheetName, cell, cell, styleHeader)
+	}
+
+	row := 5
+	for _, item := range analysis.Items {
+		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), item.Number)
+		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), item.Text)
+		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), string(item.Type))
+		setOptionalFloat(f, sheetName, fmt.Sprintf("D%d", row), item.Difficulty)
+		setOptionalFloat(f, sheetName, fmt.Sprintf("E%d", row), item.Discrimination)
+		setOptionalFloat(f, sheetName, fmt.Sprintf("F%d", row), item.PointBiserial)
+		f.SetCellValue(sheetName, fmt.Sprintf("G%d", row), round2(item.BlankRate*100))
+
+		// e.g. "A*: 12 | B: 3 | C: 0", the key is starred
+		parts := make([]string, 0, len(item.Options))
+		for j, opt := range item.Options {
+			key := ""
+			if opt.IsKey {
+				key = "*"
+			}
+			parts = append(parts, fmt.Sprintf("%s%s: %d", item.OptionLabels[j], key, opt.Count))
+		}
+		f.SetCellValue(sheetName, fmt.Sprintf("H%d", row), strings.Join(parts, " | "))
+		row++
+	}
+
+	f.SetColWidth(sheetName, "B", "B", 50)
+	f.SetColWidth(sheetName, "D", "F", 18)
+	f.SetColWidth(sheetName, "H", "H", 40)
+}
+
+func setOptionalFloat(f *excelize.File, sheet, cell string, v *float64) {
+	if v == nil {
+		f.SetCellValue(sheet, cell, "-")
+		return
+	}
+	f.SetCellValue(sheet, cell, round2(*v))
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/i18n"
+	"academic-suite-backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Generated documents are written in the reader's language (see i18n.FromRequest);
+// error responses are localized by apperr.Handler.
+
+// Errors shared by several handlers
+var (
+	errBatchNotFound = apperr.NotFound("batch_not_found")
+	errQuizNotFound  = apperr.NotFound("quiz_not_found")
+)
+
+// requestLocale is the language of the response
+func requestLocale(c *fiber.Ctx) i18n.Locale {
+	return i18n.FromRequest(c)
+}
+
+// sessionLabel names a regular or makeup sitting
+func sessionLabel(l i18n.Locale, isMakeup bool) string {
+	if isMakeup {
+		return l.T("session.makeup")
+	}
+	return l.T("session.regular")
+}
+
+// examTypeLabel names a quiz's exam type, or returns it as is when the catalog has no entry
+func examTypeLabel(l i18n.Locale, t models.ExamType) string {
+	if key := "exam_type." + string(t); i18n.Has(key) {
+		return l.T(key)
+	}
+	return string(t)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"academic-suite-backend/apperr"
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestErrorsFollowRequestLocale(t *testing.T) {
+	e := newTestEnv(t)
+
+	cases := []struct {
+		name    string
+		path    string
+		headers []string
+		want    string
+	}{
+		{"default", "/api/reports/logs?limit=0", nil, "limit harus antara 1 dan 1000"},
+		{"accept-language", "/api/reports/logs?limit=0", []string{"Accept-Language", "en-US,en;q=0.9,id;q=0.5"}, "limit must be between 1 and 1000"},
+		{"user preference wins over header", "/api/reports/logs?limit=0", []string{"X-Locale", "id", "Accept-Language", "en"}, "limit harus antara 1 dan 1000"},
+		{"query wins over preference", "/api/reports/logs?limit=0&lang=en", []string{"X-Locale", "id"}, "limit must be between 1 and 1000"},
+		{"unsupported falls back", "/api/reports/logs?limit=0", []string{"Accept-Language", "fr-FR"}, "limit harus antara 1 dan 1000"},
+	}
+	for _, tc := range cases {
+		var body apperr.Response
+		if status := e.do("GET", tc.path, nil, &body, tc.headers...); status != 400 {
+			t.Fatalf("%s: status %d", tc.name, status)
+		}
+		if body.Error.Message != tc.want || body.Error.Code != "invalid_page_limit" {
+			t.Errorf("%s: got %q (%s), want %q", tc.name, body.Error.Message, body.Error.Code, tc.want)
+		}
+	}
+
+	var body apperr.Response
+	e.do("GET", "/api/export/batch/nope/matrix", nil, &body, "Accept-Language", "en")
+	if body.Error.Code != "batch_not_found" || body.Error.Message != "Batch not found" {
+		t.Errorf("missing batch: %+v", body)
+	}
+}
+
+func TestExportsInEnglish(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedExam()
+	e.submitAll("student-1", "q1-a", "q2-b")
+
+	status, _, body := e.download("/api/export/batch/batch-1/matrix", "Accept-Language", "en")
+	if status != 200 {
+		t.Fatalf("status %d", status)
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("open xlsx: %v", err)
+	}
+	defer f.Close()
+	if got := fmt.Sprint(f.GetSheetList()); got != "[Answer Matrix Statistics Item Analysis Answer Key]" {
+		t.Errorf("sheets = %s", got)
+	}
+	if v, _ := f.GetCellValue("Answer Matrix", "H3"); v != "Q1 (5 pts)" {
+		t.Errorf("question header = %q", v)
+	}
+	if v, _ := f.GetCellValue("Answer Matrix", "D5"); v != "Regular" {
+		t.Errorf("session = %q", v)
+	}
+
+	// Streamed xlsx headers are translated, CSV keeps machine keys
+	_, _, body = e.download("/api/export/batch/batch-1/results?format=xlsx", "Accept-Language", "en")
+	results, err := excelize.OpenReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("open results xlsx: %v", err)
+	}
+	defer results.Close()
+	if v, _ := results.GetCellValue("Sheet1", "E1"); v != "Student name" {
+		t.Errorf("results header = %q", v)
+	}
+	_, _, body = e.download("/api/export/batch/batch-1/results", "Accept-Language", "en")
+	if !bytes.HasPrefix(body, []byte("attempt_id,batch_id,session")) {
+		t.Errorf("csv header = %q", body[:40])
+	}
+}
@@ -1,16 +1,30 @@
 package handlers
 
 import (
-	"academic-suite-backend/database"
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/clock"
 	"academic-suite-backend/models"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-// LogEvent is a helper to create event logs from other handlers
-func LogEvent(eventType models.EventType, batchID, attemptID, userID, details string) {
+// EventLogger writes event logs for the other services
+type EventLogger struct {
+	db    *gorm.DB
+	clock clock.Clock
+	sync  bool // write before returning instead of in the background (tests)
+}
+
+func NewEventLogger(db *gorm.DB, clk clock.Clock) *EventLogger {
+	return &EventLogger{db: db, clock: clk}
+}
+
+// Log records an event. Runs in the background so it never blocks the request.
+func (l *EventLogger) Log(eventType models.EventType, batchID, attemptID, userID, details string) {
 	log := models.EventLog{
 		ID:        uuid.New().String(),
 		EventType: eventType,
@@ -18,36 +32,109 @@ func LogEvent(eventType models.EventType, batchID, attemptID, userID, details st
 		AttemptID: attemptID,
 		UserID:    userID,
 		Details:   details,
-		Timestamp: time.Now(),
+		Timestamp: l.clock.Now(),
+	}
+	if l.sync {
+		l.db.Create(&log)
+		return
 	}
-	// Run in background to not block main request?
-	// For simplicity, just run it. If strict performance needed, use goroutine.
 	go func() {
-		database.DB.Create(&log)
+		l.db.Create(&log)
 	}()
 }
 
+type EventLogPage struct {
+	Items      []models.EventLog `json:"items"`
+	NextCursor string            `json:"nextCursor"` // empty on the last page
+}
+
+// filterEventLogs applies the log filters shared by the JSON list and the export
+func filterEventLogs(query *gorm.DB, c *fiber.Ctx) (*gorm.DB, error) {
+	if batchID := c.Query("batchId"); batchID != "" {
+		query = query.Where("batch_id = ?", batchID)
+	}
+	if attemptID := c.Query("attemptId"); attemptID != "" {
+		query = query.Where("attempt_id = ?", attemptID)
+	}
+	if userID := c.Query("userId"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if types := splitList(c.Query("eventType")); len(types) > 0 {
+		query = query.Where("event_type IN ?", types)
+	}
+	for _, bound := range []struct{ param, op string }{{"from", ">="}, {"to", "<"}} {
+		value := c.Query(bound.param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, apperr.BadRequest("invalid_time_filter", bound.param)
+		}
+		query = query.Where("timestamp "+bound.op+" ?", t)
+	}
+	return query, nil
+}
+
+// splitList splits a comma separated query value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // GetEventLogs godoc
 // @Summary      Get Event Logs
-// @Description  Retrieve event logs, optionally filtered by batchId
+// @Description  Event logs, newest first, one page at a time. Pass nextCursor back as cursor for the next page.
 // @Tags         reports
 // @Produce      json
 // @Param        batchId    query     string  false  "Batch ID to filter"
-// @Success      200  {array}  models.EventLog
+// @Param        attemptId  query     string  false  "Attempt ID to filter"
+// @Param        userId     query     string  false  "User ID to filter"
+// @Param        eventType  query     string  false  "Comma separated event types"
+// @Param        from       query     string  false  "RFC3339, inclusive"
+// @Param        to         query     string  false  "RFC3339, exclusive"
+// @Param        limit      query     int     false  "Page size (default 100, max 1000)"
+// @Param        cursor     query     string  false  "nextCursor of the previous page"
+// @Success      200  {object}  EventLogPage
+// @Failure      400  {object}  apperr.Response
 // @Router       /api/reports/logs [get]
-func GetEventLogs(c *fiber.Ctx) error {
-	batchID := c.Query("batchId")
-
-	var logs []models.EventLog
-	query := database.DB.Order("timestamp desc")
+func (s *ReportService) GetEventLogs(c *fiber.Ctx) error {
+	limit, cursor, err := pageParams(c, 2)
+	if err != nil {
+		return err
+	}
+	query, err := filterEventLogs(s.db.Model(&models.EventLog{}), c)
+	if err != nil {
+		return err
+	}
 
-	if batchID != "" {
-		query = query.Where("batch_id = ?", batchID)
+	// Sorted by (timestamp, id) descending; the cursor is the last row's pair
+	if cursor != nil {
+		after, err := time.Parse(time.RFC3339Nano, cursor[0])
+		if err != nil {
+			return errInvalidCursor
+		}
+		query = query.Where("(timestamp < ? OR (timestamp = ? AND id < ?))", after, after, cursor[1])
 	}
 
-	if err := query.Find(&logs).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch logs"})
+	var logs []models.EventLog
+	if err := query.Order("timestamp desc, id desc").Limit(limit + 1).Find(&logs).Error; err != nil {
+		return apperr.Internal("logs_load_failed", err)
 	}
 
-	return c.JSON(logs)
+	page := EventLogPage{Items: logs}
+	if len(logs) > limit {
+		page.Items = logs[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeCursor(last.Timestamp.Format(time.RFC3339Nano), last.ID)
+	}
+	if page.Items == nil {
+		page.Items = []models.EventLog{}
+	}
+	return c.JSON(page)
 }
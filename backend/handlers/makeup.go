@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/models"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Sources for makeup participants
+const (
+	MakeupFromAbsentees = "absentees"
+	MakeupFromReset     = "reset"
+	MakeupFromBoth      = "both"
+)
+
+type CreateMakeupReq struct {
+	Name      string `json:"name"`
+	Token     string `json:"token"`
+	StartTime string `json:"startTime"` // RFC 3339, or wall time in the regular batch's timezone
+	EndTime   string `json:"endTime"`
+	Duration  int    `json:"duration" validate:"min=0"`                              // minutes, 0 = same as regular batch
+	Source    string `json:"source" validate:"omitempty,oneof=absentees reset both"` // default both
+}
+
+// makeupCandidates derives who needs a makeup from a regular batch.
+// Absentees: allowed participants with no attempt at all. A batch open to all takes its class as
+// the allowed list; without a class absentees cannot be told, so "both" only takes resets.
+// Reset: students whose attempts were all reset by an admin.
+func (s *BatchService) makeupCandidates(batch models.ExamBatch, source string) ([]string, error) {
+	allowed := batchParticipantIDs(s.db, batch.ID)
+	if batchOpenToAll(s.db, &batch) {
+		switch {
+		case batch.ClassID != "":
+			allowed = classStudentIDs(s.db, batch.ClassID)
+		case source == MakeupFromAbsentees:
+			return nil, apperr.BadRequest("makeup_absentees_unknown")
+		}
+	}
+
+	var attempts []models.Attempt
+	s.db.Where("batch_id = ?", batch.ID).Find(&attempts)
+
+	hasAttempt := make(map[string]bool)
+	hasLiveAttempt := make(map[string]bool) // any attempt not reset
+	for _, a := range attempts {
+		hasAttempt[a.StudentID] = true
+		if a.Status != models.AttemptResetByAdmin {
+			hasLiveAttempt[a.StudentID] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	result := []string{}
+	add := func(id string) {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+
+	if source == MakeupFromAbsentees || source == MakeupFromBoth {
+		for _, id := range allowed {
+			if !hasAttempt[id] {
+				add(id)
+			}
+		}
+	}
+	if source == MakeupFromReset || source == MakeupFromBoth {
+		for _, a := range attempts {
+			if a.Status == models.AttemptResetByAdmin && !hasLiveAttempt[a.StudentID] {
+				add(a.StudentID)
+			}
+		}
+	}
+	return result, nil
+}
+
+// CreateMakeupBatch godoc
+// @Summary      Create Makeup Batch
+// @Description  Create a MAKEUP batch for absentees and/or reset attempts of a regular batch.
+// @Description  Absentees of a batch open to all are the students of its class; without a class only resets can be taken.
+// @Tags         batches
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string           true  "Regular Batch ID"
+// @Param        req  body      CreateMakeupReq  true  "Makeup schedule"
+// @Success      200  {object}  BatchResponse
+// @Failure      400  {object}  apperr.Response
+// @Router       /api/batches/{id}/makeup [post]
+func (s *BatchService) CreateMakeupBatch(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	var req CreateMakeupReq
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	if req.Source == "" {
+		req.Source = MakeupFromBoth
+	}
+
+	var parent models.ExamBatch
+	if err := s.db.First(&parent, "id = ?", id).Error; err != nil {
+		return apperr.NotFound("batch_not_found")
+	}
+	if parent.Type == models.BatchMakeup {
+		return apperr.BadRequest("makeup_of_makeup")
+	}
+
+	// The makeup is scheduled in the same zone as the regular batch
+	startTime, endTime, err := parseSchedule(req.StartTime, req.EndTime, batchLocation(parent))
+	if err != nil {
+		return err
+	}
+
+	participants, err := s.makeupCandidates(parent, req.Source)
+	if err != nil {
+		return err
+	}
+	if len(participants) == 0 {
+		return apperr.BadRequest("no_makeup_candidates")
+	}
+
+	duration := req.Duration
+	if duration == 0 {
+		duration = parent.Duration
+	}
+
+	name := req.Name
+	if name == "" {
+		name = parent.Name + " (Susulan)"
+	}
+
+	userId, _ := c.Locals("userId").(string)
+
+	batch := models.ExamBatch{
+		ID:            "batch-" + uuid.New().String(),
+		QuizID:        parent.QuizID,
+		ClassID:       parent.ClassID,
+		Type:          models.BatchMakeup,
+		Name:          name,
+		Token:         req.Token,
+		StartTime:     startTime,
+		EndTime:       endTime,
+		Timezone:      parent.Timezone,
+		Duration:      duration,
+		Status:        models.StatusScheduled,
+		ParentBatchID: parent.ID,
+		CreatedBy:     userId,
+		CreatedAt:     s.clock.Now(),
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&batch).Error; err != nil {
+			return err
+		}
+		return setBatchParticipants(tx, batch.ID, participants, batch.CreatedAt)
+	})
+	if err != nil {
+		return apperr.Internal("makeup_batch_create_failed", err)
+	}
+
+	s.events.Log(models.EventMakeupCreated, batch.ID, "", userId,
+		fmt.Sprintf("Makeup batch for %s created from %s with %d participants", parent.ID, req.Source, len(participants)))
+
+	return c.JSON(s.toBatchResponse(batch))
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/blob"
+	"academic-suite-backend/models"
+	"academic-suite-backend/qti"
+	"academic-suite-backend/validation"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxMediaSize caps one upload; listening audio is the large case
+const maxMediaSize = 20 << 20
+
+// mediaKinds are the accepted uploads by content type, sniffed from the bytes rather than
+// trusted from the client
+var mediaKinds = map[string]models.MediaKind{
+	"image/png":  models.MediaImage,
+	"image/jpeg": models.MediaImage,
+	"image/gif":  models.MediaImage,
+	"image/webp": models.MediaImage,
+	"audio/mpeg": models.MediaAudio,
+	"audio/wave": models.MediaAudio,
+	"audio/ogg":  models.MediaAudio,
+	"audio/mp4":  models.MediaAudio,
+}
+
+// mediaContentType sniffs data. Ogg comes back as application/ogg and M4A as video/mp4, which
+// for our uploads are audio; MP3s without an ID3 tag are only recognized by their frame sync.
+func mediaContentType(filename string, data []byte) string {
+	contentType, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	ext := strings.ToLower(filepath.Ext(filename))
+	switch {
+	case contentType == "application/ogg":
+		return "audio/ogg"
+	case contentType == "video/mp4" && ext == ".m4a":
+		return "audio/mp4"
+	case contentType == "application/octet-stream" && ext == ".mp3" && len(data) > 1 && data[0] == 0xFF && data[1]&0xE0 == 0xE0:
+		return "audio/mpeg"
+	}
+	return contentType
+}
+
+// saveMedia checks an uploaded file, puts it in the blob store and records it for the user's
+// institution
+func saveMedia(db *gorm.DB, store blob.Store, user models.User, filename string, data []byte, now time.Time) (models.Media, error) {
+	if len(data) > maxMediaSize {
+		return models.Media{}, apperr.BadRequest("media_too_large", maxMediaSize>>20)
+	}
+	contentType := mediaContentType(filename, data)
+	kind, ok := mediaKinds[contentType]
+	if !ok {
+		return models.Media{}, apperr.BadRequest("unsupported_media_type")
+	}
+
+	id := uuid.New().String()
+	m := models.Media{
+		ID:            id,
+		InstitutionID: user.InstitutionID,
+		Kind:          kind,
+		Filename:      filepath.Base(filename),
+		ContentType:   contentType,
+		Size:          int64(len(data)),
+		StorageKey:    "media/" + id,
+		UploadedBy:    user.ID,
+		CreatedAt:     now,
+	}
+
+	if err := store.Put(m.StorageKey, bytes.NewReader(data)); err != nil {
+		return models.Media{}, apperr.Internal("media_store_failed", err)
+	}
+	if err := db.Create(&m).Error; err != nil {
+		store.Delete(m.StorageKey)
+		return models.Media{}, apperr.Internal("media_store_failed", err)
+	}
+	return m, nil
+}
+
+// UploadMedia godoc
+// @Summary      Upload Media
+// @Description  Upload an image (PNG, JPEG, GIF, WebP) or audio file (MP3, WAV, Ogg, M4A) of at most 20 MB,
+// @Description  to attach to questions (imageId, audioId) or options (imageId).
+// @Tags         media
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file formData file true "Image or audio file"
+// @Success      200  {object}  models.Media
+// @Failure      400  {object}  apperr.Response
+// @Router       /api/media [post]
+func (s *MediaService) UploadMedia(c *fiber.Ctx) error {
+	filename, data, err := uploadedFile(c)
+	if err != nil {
+		return err
+	}
+	var user models.User
+	if err := s.db.First(&user, "id = ?", c.Locals("userId").(string)).Error; err != nil {
+		return apperr.Unauthorized("user_not_found")
+	}
+	m, err := saveMedia(s.db, s.media, user, filename, data, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	return c.JSON(m)
+}
+
+// GetMedia godoc
+// @Summary      Get Media
+// @Description  Download an uploaded image or audio file. Only users of the institution it was uploaded to
+// @Description  (and admins) can read it; the frontend fetches it with its token and shows it as an object URL.
+// @Tags         media
+// @Produce      octet-stream
+// @Param        id   path      string  true  "Media ID"
+// @Success      200  {file}  file
+// @Failure      404  {object}  apperr.Response
+// @Router       /api/media/{id} [get]
+func (s *MediaService) GetMedia(c *fiber.Ctx) error {
+	var m models.Media
+	if err := s.db.First(&m, "id = ?", c.Params("id")).Error; err != nil {
+		return apperr.NotFound("media_not_found")
+	}
+	if role, _ := c.Locals("role").(string); models.UserRole(role) != models.RoleAdmin {
+		var user models.User
+		userId, _ := c.Locals("userId").(string)
+		if err := s.db.First(&user, "id = ?", userId).Error; err != nil || user.InstitutionID != m.InstitutionID {
+			return apperr.NotFound("media_not_found")
+		}
+	}
+
+	r, err := s.media.Open(m.StorageKey)
+	if errors.Is(err, blob.ErrNotFound) {
+		return apperr.NotFound("media_not_found")
+	}
+	if err != nil {
+		return apperr.Internal("media_read_failed", err)
+	}
+	c.Set("Content-Type", m.ContentType)
+	c.Set("Content-Length", strconv.FormatInt(m.Size, 10))
+	c.Set("X-Content-Type-Options", "nosniff")
+	// A media ID always holds the same file
+	c.Set("Cache-Control", "private, max-age=86400, immutable")
+	return c.SendStream(r, int(m.Size))
+}
+
+// mediaRules checks that the images and audio questions refer to were uploaded to the
+// institution and are of the right kind, reporting fields under path ("questions[0].audioId")
+func mediaRules(db *gorm.DB, institutionID string, questions []models.Question, path string) validation.Errors {
+	type ref struct {
+		field, id string
+		kind      models.MediaKind
+	}
+	var refs []ref
+	var ids []string
+	add := func(field, id string, kind models.MediaKind) {
+		if id != "" {
+			refs = append(refs, ref{field, id, kind})
+			ids = append(ids, id)
+		}
+	}
+	for i, q := range questions {
+		prefix := fmt.Sprintf("%s[%d].", path, i)
+		add(prefix+"imageId", q.ImageID, models.MediaImage)
+		add(prefix+"audioId", q.AudioID, models.MediaAudio)
+		for j, opt := range q.Options {
+			add(fmt.Sprintf("%soptions[%d].imageId", prefix, j), opt.ImageID, models.MediaImage)
+		}
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	var media []models.Media
+	db.Where("id IN ? AND institution_id = ?", ids, institutionID).Find(&media)
+	kinds := map[string]models.MediaKind{}
+	for _, m := range media {
+		kinds[m.ID] = m.Kind
+	}
+	var errs validation.Errors
+	for _, r := range refs {
+		if kinds[r.id] != r.kind {
+			errs.Add(r.field, "media_"+string(r.kind))
+		}
+	}
+	return errs
+}
+
+// mediaFiles loads the images and audio of questions for a QTI package, by media ID
+func mediaFiles(db *gorm.DB, store blob.Store, questions []models.Question) (map[string]qti.File, error) {
+	var ids []string
+	for _, q := range questions {
+		ids = append(ids, q.ImageID, q.AudioID)
+		for _, opt := range q.Options {
+			ids = append(ids, opt.ImageID)
+		}
+	}
+	var media []models.Media
+	if err := db.Where("id IN ?", ids).Find(&media).Error; err != nil {
+		return nil, err
+	}
+
+	files := map[string]qti.File{}
+	for _, m := range media {
+		r, err := store.Open(m.StorageKey)
+		if errors.Is(err, blob.ErrNotFound) {
+			continue // Lost file: the package goes out without it
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[m.ID] = qti.File{Name: m.Filename, ContentType: m.ContentType, Data: data}
+	}
+	return files, nil
+}
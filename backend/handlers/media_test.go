@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"academic-suite-backend/models"
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func pngOf(t *testing.T, size int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, size, size))); err != nil {
+		t.Fatalf("png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// mp3Frame is the start of an MP3 without an ID3 tag: an MPEG-1 Layer III frame header
+var mp3Frame = append([]byte{0xFF, 0xFB, 0x90, 0x64}, make([]byte, 64)...)
+
+func (e *testEnv) uploadMedia(filename string, data []byte) models.Media {
+	e.t.Helper()
+	status, _, body := e.upload("/api/media", filename, data, "X-User", "teacher-1")
+	if status != http.StatusOK {
+		e.t.Fatalf("upload %s: status %d %s", filename, status, body)
+	}
+	var m models.Media
+	json.Unmarshal(body, &m)
+	return m
+}
+
+func TestUploadAndServeMedia(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedInstitution("")
+	e.create(
+		&models.Institution{ID: "inst-2", Name: "SMA 5"},
+		&models.User{ID: "teacher-2", Email: "guru2@example.com", Role: models.RoleTeacher, InstitutionID: "inst-2"},
+	)
+
+	img := pngOf(t, 4)
+	m := e.uploadMedia("../peta jawa.png", img)
+	if m.Kind != models.MediaImage || m.ContentType != "image/png" || m.Filename != "peta jawa.png" || m.InstitutionID != "inst-1" || m.Size != int64(len(img)) {
+		t.Fatalf("media = %+v", m)
+	}
+	if audio := e.uploadMedia("dialog.mp3", mp3Frame); audio.Kind != models.MediaAudio || audio.ContentType != "audio/mpeg" {
+		t.Errorf("audio = %+v", audio)
+	}
+
+	status, contentType, body := e.download("/api/media/"+m.ID, "X-User", "teacher-1")
+	if status != http.StatusOK || contentType != "image/png" || !bytes.Equal(body, img) {
+		t.Errorf("get: status %d %s", status, contentType)
+	}
+	if status, _, _ := e.download("/api/media/"+m.ID, "X-User", "teacher-2"); status != http.StatusNotFound {
+		t.Errorf("other institution: status %d", status)
+	}
+	if status, _, _ := e.download("/api/media/"+m.ID, "X-User", "teacher-2", "X-Role", "admin"); status != http.StatusOK {
+		t.Errorf("admin: status %d", status)
+	}
+
+	var res validationResponse
+	status, _, body = e.upload("/api/media", "soal.html", []byte("<html><script>alert(1)</script></html>"), "X-User", "teacher-1")
+	json.Unmarshal(body, &res)
+	if status != http.StatusBadRequest || res.Error.Code != "unsupported_media_type" {
+		t.Errorf("html upload: status %d %s", status, body)
+	}
+}
+
+func TestQuizQuestionsReferToMedia(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedInstitution("")
+	img := e.uploadMedia("grafik.png", pngOf(t, 2))
+	e.create(&models.Media{ID: "media-other", InstitutionID: "inst-2", Kind: models.MediaImage})
+
+	quiz := map[string]interface{}{
+		"title": "Listening",
+		"questions": []interface{}{
+			map[string]interface{}{"type": "mcq", "text": "Pilih gambar yang sesuai", "points": 1, "imageId": img.ID, "options": []interface{}{
+				map[string]interface{}{"imageId": img.ID, "isCorrect": true},
+				map[string]interface{}{"text": "Bukan gambar"},
+				map[string]interface{}{"imageId": "media-other"},
+			}},
+			map[string]interface{}{"type": "essay", "text": "Dengarkan", "points": 1, "audioId": img.ID},
+		},
+	}
+	var res validationResponse
+	status := e.do("POST", "/api/quizzes", quiz, &res, "X-User", "teacher-1")
+	if status != http.StatusBadRequest || res.Error.Code != "validation_failed" {
+		t.Fatalf("status %d code %q", status, res.Error.Code)
+	}
+	want := map[string]string{
+		"questions[0].options[2].imageId": "media_image", // another institution's
+		"questions[1].audioId":            "media_audio", // an image
+	}
+	if got := fieldRules(res.Error.Details); !reflect.DeepEqual(got, want) {
+		t.Errorf("field errors = %v, want %v", got, want)
+	}
+
+	// A choice needs a text or a picture
+	first := quiz["questions"].([]interface{})[0].(map[string]interface{})
+	first["options"] = []interface{}{
+		map[string]interface{}{"imageId": img.ID, "isCorrect": true},
+		map[string]interface{}{},
+	}
+	quiz["questions"] = []interface{}{first}
+	e.do("POST", "/api/quizzes", quiz, &res, "X-User", "teacher-1")
+	if got := fieldRules(res.Error.Details); !reflect.DeepEqual(got, map[string]string{"questions[0].options[1].text": "required"}) {
+		t.Errorf("empty choice: field errors = %v", got)
+	}
+
+	first["options"].([]interface{})[1] = map[string]interface{}{"text": "Bukan gambar"}
+	var created models.Quiz
+	if status := e.do("POST", "/api/quizzes", quiz, &created, "X-User", "teacher-1"); status != http.StatusOK {
+		t.Fatalf("valid quiz: status %d", status)
+	}
+	if q := created.Questions[0]; q.ImageID != img.ID || q.Options[0].ImageID != img.ID {
+		t.Errorf("created = %+v", q)
+	}
+}
+
+// Images, audio and formulas survive a QTI export and import into a new quiz; the imported
+// files are stored again as the importing institution's media
+func TestQTICarriesMediaAndMath(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedInstitution("")
+	img := pngOf(t, 3)
+	picture := e.uploadMedia("grafik.png", img)
+	audio := e.uploadMedia("dialog.mp3", mp3Frame)
+
+	const formula = `Hitung <math xmlns="http://www.w3.org/1998/Math/MathML"><msqrt><mn>16</mn></msqrt></math> + \(x^2\)`
+	q := models.Question{QuizID: "quiz-1", Type: models.TypeMCQ, Text: formula, Points: 2, ImageID: picture.ID, AudioID: audio.ID,
+		Options: []models.QuestionOption{{ImageID: picture.ID, IsCorrect: true}, {Text: "4"}}}
+	newQuestionIDs(&q)
+	e.create(&q)
+
+	_, _, pkg := e.download("/api/quizzes/quiz-1/export?format=qti")
+	status, _, body := e.upload("/api/import/quizzes", "paket.zip", pkg, "X-User", "teacher-1")
+	if status != http.StatusOK {
+		t.Fatalf("import: status %d %s", status, body)
+	}
+	var res struct {
+		Quiz models.Quiz `json:"quiz"`
+	}
+	json.Unmarshal(body, &res)
+
+	got := e.storedQuestions(res.Quiz.ID)
+	if len(got) != 1 || got[0].Text != formula || got[0].ImageID == "" || got[0].ImageID == picture.ID ||
+		got[0].Options[0].ImageID != got[0].ImageID || got[0].AudioID == "" {
+		t.Fatalf("imported = %+v", got)
+	}
+	if status, contentType, data := e.download("/api/media/"+got[0].ImageID, "X-User", "teacher-1"); status != http.StatusOK ||
+		contentType != "image/png" || !bytes.Equal(data, img) {
+		t.Errorf("imported image: status %d %s", status, contentType)
+	}
+	var stored models.Media
+	e.db.First(&stored, "id = ?", got[0].AudioID)
+	if stored.Kind != models.MediaAudio || stored.InstitutionID != "inst-1" {
+		t.Errorf("imported audio = %+v", stored)
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"academic-suite-backend/apperr"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Paginated JSON endpoints use keyset ("cursor") pagination: the cursor holds the sort key of the
+// last row of a page, so later pages stay cheap and stable while new rows are written.
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+var errInvalidCursor = apperr.BadRequest("invalid_cursor")
+
+// encodeCursor packs the sort key values of the last row into an opaque string
+func encodeCursor(values ...string) string {
+	data, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursor string, size int) ([]string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil || len(values) != size {
+		return nil, errInvalidCursor
+	}
+	return values, nil
+}
+
+// pageParams reads ?limit= and ?cursor=; the cursor is nil on the first page
+func pageParams(c *fiber.Ctx, cursorSize int) (int, []string, error) {
+	limit := c.QueryInt("limit", DefaultPageSize)
+	if limit < 1 || limit > MaxPageSize {
+		return 0, nil, apperr.BadRequest("invalid_page_limit", MaxPageSize)
+	}
+	if c.Query("cursor") == "" {
+		return limit, nil, nil
+	}
+	cursor, err := decodeCursor(c.Query("cursor"), cursorSize)
+	return limit, cursor, err
+}
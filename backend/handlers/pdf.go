@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"academic-suite-backend/i18n"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// PDF documents are drawn with the built-in Helvetica font, so text is converted to cp1252
+// (enough for Indonesian names; other characters print as "?"). Labels come from the i18n catalog.
+
+type pdfDoc struct {
+	*fpdf.Fpdf
+	tr func(string) string
+	l  i18n.Locale
+}
+
+func newPDF(l i18n.Locale, orientation string) *pdfDoc {
+	pdf := fpdf.New(orientation, "mm", "A4", "")
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 5, l.T("pdf.page", pdf.PageNo()), "", 0, "C", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	})
+	return &pdfDoc{Fpdf: pdf, tr: pdf.UnicodeTranslatorFromDescriptor(""), l: l}
+}
+
+// cell writes one line of text, shortened with "..." when it does not fit in w
+func (d *pdfDoc) cell(w, h float64, text, border string, ln int, align string, fill bool) {
+	text = d.tr(text)
+	if w > 0 {
+		text = d.fit(text, w-2)
+	}
+	d.CellFormat(w, h, text, border, ln, align, fill, 0, "")
+}
+
+// fit works on translated text: cp1252 is one byte per character, so it cuts bytes, not runes
+func (d *pdfDoc) fit(text string, width float64) string {
+	if d.GetStringWidth(text) <= width {
+		return text
+	}
+	for len(text) > 0 && d.GetStringWidth(text+"...") > width {
+		text = text[:len(text)-1]
+	}
+	return text + "..."
+}
+
+// infoRows prints "label : value" lines; labels are catalog keys
+func (d *pdfDoc) infoRows(rows [][2]string) {
+	d.SetFont("Helvetica", "", 10)
+	for _, row := range rows {
+		d.cell(40, 6, d.l.T(row[0]), "", 0, "L", false)
+		d.cell(0, 6, ": "+row[1], "", 1, "L", false)
+	}
+}
+
+// pdfColumn is a table column: header (a catalog key), width in mm and alignment
+type pdfColumn struct {
+	title string
+	width float64
+	align string
+}
+
+// table prints a bordered table, repeating the header after page breaks
+func (d *pdfDoc) table(columns []pdfColumn, rows [][]string) {
+	header := func() {
+		d.SetFont("Helvetica", "B", 9)
+		d.SetFillColor(224, 224, 224)
+		for _, col := range columns {
+			d.cell(col.width, 7, d.l.T(col.title), "1", 0, "C", true)
+		}
+		d.Ln(-1)
+		d.SetFont("Helvetica", "", 9)
+	}
+
+	header()
+	_, pageHeight := d.GetPageSize()
+	_, _, _, bottom := d.GetMargins()
+	for _, row := range rows {
+		if d.GetY()+6 > pageHeight-bottom {
+			d.AddPage()
+			header()
+		}
+		for i, col := range columns {
+			d.cell(col.width, 6, row[i], "1", 0, col.align, false)
+		}
+		d.Ln(-1)
+	}
+}
+
+// title prints the institution and the document title (a catalog key)
+func (d *pdfDoc) title(institution, title string) {
+	if institution != "" {
+		d.SetFont("Helvetica", "B", 11)
+		d.cell(0, 6, strings.ToUpper(institution), "", 1, "C", false)
+	}
+	d.SetFont("Helvetica", "B", 16)
+	d.cell(0, 10, d.l.T(title), "", 1, "C", false)
+	x, y := d.GetXY()
+	pageWidth, _ := d.GetPageSize()
+	left, _, right, _ := d.GetMargins()
+	d.Line(left, y+1, pageWidth-right, y+1)
+	d.SetXY(x, y+5)
+}
+
+// dateTime adds the time and zone abbreviation to the date, e.g. "2 Januari 2026 08:00 WIB"
+func (d *pdfDoc) dateTime(t time.Time) string {
+	return d.l.FormatDate(t) + " " + t.Format("15:04 MST")
+}
+
+// writeBatchReportPDF prints the batch summary and the results table
+func writeBatchReportPDF(w io.Writer, l i18n.Locale, docs *batchDocuments) error {
+	report := docs.report
+	d := newPDF(l, "P")
+	d.AddPage()
+	d.title(docs.institution.Name, "report.title")
+
+	loc := batchLocation(docs.batch)
+	d.infoRows([][2]string{
+		{"label.exam_title", report.QuizTitle},
+		{"label.batch", docs.batch.Name},
+		{"label.class", orDash(docs.className)},
+		{"label.schedule", d.dateTime(docs.batch.StartTime.In(loc)) + " - " + docs.batch.EndTime.In(loc).Format("15:04 MST")},
+		{"label.participants", fmt.Sprintf("%d", report.TotalParticipants)},
+		{"label.submitted", fmt.Sprintf("%d", report.SubmittedCount)},
+		{"label.average", fmt.Sprintf("%.2f", report.AverageScore)},
+		{"label.highest_lowest", fmt.Sprintf("%.2f / %.2f", report.HighestScore, report.LowestScore)},
+		{"label.passing_score", fmt.Sprintf("%d", docs.quiz.PassingScore)},
+	})
+	d.Ln(4)
+
+	columns := []pdfColumn{
+		{"col.no", 10, "C"}, {"col.student_name", 58, "L"}, {"col.status", 24, "C"}, {"col.score", 18, "R"},
+		{"col.percentage", 18, "R"}, {"col.submitted_at", 34, "C"}, {"col.session", 18, "C"},
+	}
+	rows := make([][]string, 0, len(report.Attempts))
+	for i, att := range report.Attempts {
+		submitted := "-"
+		if att.SubmittedAt != nil {
+			if t, err := time.Parse(time.RFC3339, *att.SubmittedAt); err == nil {
+				submitted = t.Format("02/01/2006 15:04")
+			}
+		}
+		rows = append(rows, []string{
+			fmt.Sprintf("%d", i+1), att.StudentName, att.Status, fmt.Sprintf("%.2f", att.Score),
+			fmt.Sprintf("%.1f", att.Percentage), submitted, sessionLabel(l, att.IsMakeup),
+		})
+	}
+	d.table(columns, rows)
+
+	return d.Output(w)
+}
+
+// writeResultSlipPDF prints one student's result with the per-question breakdown
+func writeResultSlipPDF(w io.Writer, l i18n.Locale, slip resultSlip) error {
+	d := newPDF(l, "P")
+	d.AddPage()
+	d.title(slip.InstitutionName, "slip.title")
+
+	submitted := "-"
+	if slip.SubmittedAt != nil {
+		submitted = d.dateTime(*slip.SubmittedAt)
+	}
+	result := l.T("result.failed")
+	if slip.Passed {
+		result = l.T("result.passed")
+	}
+	d.infoRows([][2]string{
+		{"label.student_name", slip.StudentName},
+		{"label.student_id", slip.StudentID},
+		{"label.exam", slip.QuizTitle},
+		{"label.batch", slip.BatchName},
+		{"label.class", orDash(slip.ClassName)},
+		{"label.submitted_at", submitted},
+		{"label.score", l.T("slip.score", slip.Score, slip.TotalPoints, slip.Percentage)},
+		{"label.result", l.T("slip.result", result, slip.PassingScore)},
+	})
+	d.Ln(4)
+
+	columns := []pdfColumn{
+		{"col.no", 10, "C"}, {"col.question", 82, "L"}, {"col.answer", 36, "L"}, {"col.key", 36, "L"}, {"col.points", 16, "C"},
+	}
+	rows := make([][]string, 0, len(slip.Questions))
+	for _, q := range slip.Questions {
+		earned := "-"
+		if q.Earned != nil {
+			earned = fmt.Sprintf("%d/%d", *q.Earned, q.Points)
+		}
+		rows = append(rows, []string{fmt.Sprintf("%d", q.Number), q.Text, orDash(q.Answer), orDash(q.Key), earned})
+	}
+	d.table(columns, rows)
+
+	d.Ln(3)
+	d.SetFont("Helvetica", "I", 8)
+	d.cell(0, 5, l.T("slip.note"), "", 1, "L", false)
+
+	return d.Output(w)
+}
+
+// writeCertificatePDF prints a landscape pass certificate
+func writeCertificatePDF(w io.Writer, l i18n.Locale, slip resultSlip) error {
+	d := newPDF(l, "L")
+	d.SetFooterFunc(nil)
+	d.SetAutoPageBreak(false, 0)
+	d.AddPage()
+
+	pageWidth, pageHeight := d.GetPageSize()
+	d.SetDrawColor(30, 60, 120)
+	d.SetLineWidth(1.5)
+	d.Rect(10, 10, pageWidth-20, pageHeight-20, "D")
+	d.SetLineWidth(0.4)
+	d.Rect(14, 14, pageWidth-28, pageHeight-28, "D")
+
+	d.SetY(30)
+	if slip.InstitutionName != "" {
+		d.SetFont("Helvetica", "B", 14)
+		d.cell(0, 8, strings.ToUpper(slip.InstitutionName), "", 1, "C", false)
+	}
+	d.Ln(6)
+	d.SetFont("Helvetica", "B", 32)
+	d.SetTextColor(30, 60, 120)
+	d.cell(0, 16, l.T("cert.title"), "", 1, "C", false)
+	d.SetTextColor(0, 0, 0)
+
+	d.Ln(6)
+	d.SetFont("Helvetica", "", 13)
+	d.cell(0, 8, l.T("cert.awarded_to"), "", 1, "C", false)
+	d.SetFont("Helvetica", "B", 24)
+	d.cell(0, 14, slip.StudentName, "", 1, "C", false)
+
+	d.SetFont("Helvetica", "", 13)
+	d.cell(0, 8, l.T("cert.for_passing"), "", 1, "C", false)
+	d.SetFont("Helvetica", "B", 16)
+	d.cell(0, 10, slip.QuizTitle, "", 1, "C", false)
+	d.SetFont("Helvetica", "", 13)
+	d.cell(0, 8, l.T("cert.score", slip.Score, slip.TotalPoints), "", 1, "C", false)
+
+	if slip.SubmittedAt != nil {
+		d.SetY(pageHeight - 50)
+		d.cell(0, 8, l.FormatDate(*slip.SubmittedAt), "", 1, "C", false)
+	}
+
+	return d.Output(w)
+}
+
+func orDash(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "-"
+	}
+	return s
+}
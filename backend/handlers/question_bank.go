@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/i18n"
+	"academic-suite-backend/models"
+	"academic-suite-backend/moodle"
+	"academic-suite-backend/qti"
+	"academic-suite-backend/tabular"
+	"academic-suite-backend/validation"
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// textQuestions reads a GIFT or Aiken upload. Questions the format parser rejects are listed
+// with a "question" error; the rest are checked like spreadsheet rows.
+func textQuestions(format string, data []byte, l i18n.Locale) ([]importedQuestion, error) {
+	text, err := tabular.DecodeText(data)
+	if err != nil {
+		return nil, apperr.BadRequest("file_parse_failed").Wrap(err)
+	}
+
+	labels := moodle.Labels{True: l.T("question.true"), False: l.T("question.false")}
+	var (
+		items     []moodle.Item
+		parseErrs []moodle.ParseError
+	)
+	if format == questionFormatAiken {
+		items, parseErrs = moodle.ParseAiken(text, labels)
+	} else {
+		items, parseErrs = moodle.ParseGIFT(text, labels)
+	}
+
+	questions := make([]importedQuestion, 0, len(items)+len(parseErrs))
+	for _, item := range items {
+		q := item.Question
+		newQuestionIDs(&q)
+		questions = append(questions, importedQuestion{Line: item.Line, Question: q, Errors: checkQuestion(q)})
+	}
+	for _, pe := range parseErrs {
+		questions = append(questions, importedQuestion{Line: pe.Line, Errors: validation.Field("question", pe.Rule, pe.Param)})
+	}
+	sort.SliceStable(questions, func(i, j int) bool { return questions[i].Line < questions[j].Line })
+	return questions, nil
+}
+
+// mediaField is a media reference of an imported question, by its field name
+type mediaField struct {
+	ref   *string
+	field string
+	kind  models.MediaKind
+}
+
+// qtiQuestions reads a QTI package, numbering questions by their position in the package.
+// Items with interactions we have no question type for are listed with a "question" error.
+// The images and audio of the questions that pass are stored as media of the user's institution.
+func (s *ImportService) qtiQuestions(data []byte, l i18n.Locale, user models.User) ([]importedQuestion, string, error) {
+	pkg, err := qti.Read(data, qti.Labels{True: l.T("question.true"), False: l.T("question.false")})
+	if errors.Is(err, qti.ErrNotPackage) {
+		return nil, "", apperr.BadRequest("invalid_qti_package")
+	}
+	if errors.Is(err, qti.ErrTooLarge) {
+		return nil, "", apperr.BadRequest("qti_package_too_large")
+	}
+	if err != nil {
+		return nil, "", apperr.BadRequest("file_parse_failed").Wrap(err)
+	}
+
+	stored := map[string]string{} // path in the package -> media ID
+	// attach swaps the package path in ref for a stored media ID, or reports field
+	attach := func(ref *string, field string, kind models.MediaKind) (validation.Errors, error) {
+		if *ref == "" {
+			return nil, nil
+		}
+		id, ok := stored[*ref]
+		if !ok {
+			f := pkg.Files[*ref]
+			if len(f.Data) > maxMediaSize || mediaKinds[mediaContentType(f.Name, f.Data)] != kind {
+				return validation.Field(field, "media_"+string(kind)), nil
+			}
+			m, err := saveMedia(s.db, s.media, user, f.Name, f.Data, s.clock.Now())
+			if err != nil {
+				return nil, err
+			}
+			id = m.ID
+			stored[*ref] = id
+		}
+		*ref = id
+		return nil, nil
+	}
+
+	questions := make([]importedQuestion, 0, len(pkg.Items)+len(pkg.Errors))
+	for _, item := range pkg.Items {
+		q := item.Question
+		newQuestionIDs(&q)
+		errs := checkQuestion(q)
+		if len(errs) == 0 {
+			fields := []mediaField{{&q.ImageID, "imageId", models.MediaImage}, {&q.AudioID, "audioId", models.MediaAudio}}
+			for i := range q.Options {
+				fields = append(fields, mediaField{&q.Options[i].ImageID, fmt.Sprintf("options[%d].imageId", i), models.MediaImage})
+			}
+			for _, f := range fields {
+				fieldErrs, err := attach(f.ref, f.field, f.kind)
+				if err != nil {
+					return nil, "", err
+				}
+				errs = append(errs, fieldErrs...)
+			}
+		}
+		questions = append(questions, importedQuestion{Line: item.Position, Question: q, Errors: errs})
+	}
+	for _, ie := range pkg.Errors {
+		questions = append(questions, importedQuestion{Line: ie.Position, Errors: validation.Field("question", ie.Rule, ie.Param)})
+	}
+	sort.SliceStable(questions, func(i, j int) bool { return questions[i].Line < questions[j].Line })
+	return questions, pkg.Title, nil
+}
+
+// ImportQuiz godoc
+// @Summary      Import a Quiz from a QTI package
+// @Description  Create a draft quiz from an IMS QTI 2.1/3.0 content package (.zip), titled after its assessment test
+// @Description  (or the file name). Items that cannot be imported, such as unsupported interactions, are listed in errors.
+// @Tags         import
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file formData file true "QTI package"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  apperr.Response
+// @Router       /api/import/quizzes [post]
+func (s *ImportService) ImportQuiz(c *fiber.Ctx) error {
+	filename, data, err := uploadedFile(c)
+	if err != nil {
+		return err
+	}
+	var user models.User
+	if err := s.db.First(&user, "id = ?", c.Locals("userId").(string)).Error; err != nil {
+		return apperr.Unauthorized("user_not_found")
+	}
+	l := requestLocale(c)
+	questions, title, err := s.qtiQuestions(data, l, user)
+	if err != nil {
+		return err
+	}
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+	quiz := models.Quiz{
+		ID:            "quiz-" + uuid.New().String(),
+		Title:         title,
+		Status:        "draft", // Reviewed by the teacher before students see it
+		InstitutionID: user.InstitutionID,
+		CreatedBy:     user.ID,
+		CreatedAt:     s.clock.Now(),
+		UpdatedAt:     s.clock.Now(),
+	}
+
+	rowErrors := []string{}
+	for _, imported := range questions {
+		if len(imported.Errors) > 0 {
+			rowErrors = append(rowErrors, importErrorText(l, l.T("col.question"), imported.Line, imported.Errors))
+			continue
+		}
+		question := imported.Question
+		question.QuizID = quiz.ID
+		question.OrderIndex = imported.Line
+		quiz.Questions = append(quiz.Questions, question)
+		quiz.TotalPoints += question.Points
+	}
+	if len(quiz.Questions) == 0 {
+		return apperr.BadRequest("no_importable_questions").WithDetails(rowErrors)
+	}
+
+	if err := s.db.Create(&quiz).Error; err != nil {
+		return apperr.Internal("quiz_create_failed", err)
+	}
+
+	return c.JSON(fiber.Map{
+		"quiz":         quiz,
+		"errors":       rowErrors,
+		"successCount": len(quiz.Questions),
+	})
+}
+
+// ExportQuiz godoc
+// @Summary      Export Quiz questions
+// @Description  Download the questions of a quiz as a Moodle GIFT or Aiken text file, importable into Moodle or back through /api/import/questions,
+// @Description  or the whole quiz as an IMS QTI content package (.zip) for other assessment platforms.
+// @Description  Aiken only holds multiple choice: other questions are left out and counted in the X-Skipped-Questions header.
+// @Description  Formulas (LaTeX, MathML) travel in every format; images and audio only in QTI packages.
+// @Tags         quizzes
+// @Produce      plain
+// @Produce      application/zip
+// @Param        id       path   string  true   "Quiz ID"
+// @Param        format   query  string  false  "gift (default), aiken or qti"
+// @Param        version  query  string  false  "QTI version: 2.1 (default) or 3.0"
+// @Success      200  {file}  file
+// @Failure      400  {object}  apperr.Response
+// @Failure      404  {object}  apperr.Response
+// @Router       /api/quizzes/{id}/export [get]
+func (s *QuizService) ExportQuiz(c *fiber.Ctx) error {
+	format := c.Query("format", questionFormatGIFT)
+	version := qti.Version(c.Query("version", string(qti.V21)))
+	switch {
+	case format != questionFormatGIFT && format != questionFormatAiken && format != questionFormatQTI:
+		return apperr.BadRequest("invalid_quiz_export_format")
+	case format == questionFormatQTI && version != qti.V21 && version != qti.V30:
+		return apperr.BadRequest("invalid_qti_version")
+	}
+	quiz := quizWithQuestions(s.db, c.Params("id"))
+	if quiz.ID == "" {
+		return apperr.NotFound("quiz_not_found")
+	}
+
+	var buf bytes.Buffer
+	var err error
+	contentType, filename := "text/plain; charset=utf-8", fmt.Sprintf("%s-%s.txt", fileSafe(quiz.Title, quiz.ID), format)
+	switch format {
+	case questionFormatQTI:
+		var media map[string]qti.File
+		if media, err = mediaFiles(s.db, s.media, quiz.Questions); err == nil {
+			err = qti.Write(&buf, quiz, version, media)
+		}
+		contentType = "application/zip"
+		filename = fmt.Sprintf("%s-qti%s.zip", fileSafe(quiz.Title, quiz.ID), strings.ReplaceAll(string(version), ".", ""))
+	case questionFormatAiken:
+		var skipped int
+		skipped, err = moodle.WriteAiken(&buf, quiz.Questions)
+		c.Set("X-Skipped-Questions", strconv.Itoa(skipped))
+	default:
+		err = moodle.WriteGIFT(&buf, quiz.Questions)
+	}
+	if err != nil {
+		return apperr.Internal("export_failed", err)
+	}
+
+	c.Set("Content-Type", contentType)
+	c.Set("Content-Disposition", "attachment; filename="+filename)
+	return c.Send(buf.Bytes())
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"academic-suite-backend/models"
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type questionImportResult struct {
+	Format       string   `json:"format"`
+	Errors       []string `json:"errors"`
+	SuccessCount int      `json:"successCount"`
+}
+
+func (e *testEnv) importQuestions(quizID, filename, content string, headers ...string) questionImportResult {
+	e.t.Helper()
+	status, _, body := e.upload("/api/import/questions/"+quizID, filename, []byte(content), headers...)
+	if status != http.StatusOK {
+		e.t.Fatalf("import %s: status %d %s", filename, status, body)
+	}
+	var res questionImportResult
+	json.Unmarshal(body, &res)
+	return res
+}
+
+// storedQuestions are the questions of a quiz in order, without the IDs an import assigns
+func (e *testEnv) storedQuestions(quizID string) []models.Question {
+	questions := quizWithQuestions(e.db, quizID).Questions
+	for i := range questions {
+		q := &questions[i]
+		q.ID, q.QuizID, q.OrderIndex = "", "", 0
+		for j := range q.Options {
+			q.Options[j].ID, q.Options[j].QuestionID = "", ""
+		}
+	}
+	return questions
+}
+
+// seedQuestionBank gives a quiz one question of each type
+func seedQuestionBank(e *testEnv, quizID string) {
+	e.t.Helper()
+	options := func(correct int, texts ...string) []models.QuestionOption {
+		out := make([]models.QuestionOption, len(texts))
+		for i, text := range texts {
+			out[i] = models.QuestionOption{Text: text, IsCorrect: i == correct}
+		}
+		return out
+	}
+	for i, q := range []models.Question{
+		{Type: models.TypeMCQ, Text: "Hasil 7 × 8 = ?", Points: 2, Options: options(2, "54", "63", "56", "{64}"), Explanation: "7 × 8 = 56", Topic: "Perkalian"},
+		{Type: models.TypeTrueFalse, Text: "Air mendidih pada 100°C:\nbenar?", Points: 1, Options: options(1, "Benar", "Salah"), Topic: "IPA"},
+		{Type: models.TypeShortAnswer, Text: "Lambang kimia air", Points: 3, CorrectAnswer: "H2O"},
+		{Type: models.TypeEssay, Text: "Jelaskan $\\frac{a}{b} \\neq 0$", Points: 10},
+	} {
+		q.QuizID, q.OrderIndex = quizID, i+1
+		newQuestionIDs(&q)
+		e.create(&q)
+	}
+}
+
+func TestImportQuestionsFromGIFT(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedInstitution("")
+
+	gift := "// points: 4\n" +
+		"::Q1:: Ibu kota Jawa Barat? {\n~Surabaya\n=Bandung\n~Semarang\n}\n\n" +
+		"Bumi itu bulat {T}\n\n" +
+		"Berapa 2+2? {#4}\n\n" +
+		"Hewan ber\\{kaki\\} empat: {=kucing}\n\n" +
+		"Dua benar {=a =%100%b ~c}\n\n" +
+		"Jelaskan gravitasi {}\n"
+	res := e.importQuestions("quiz-1", "bank.gift", gift, "Accept-Language", "en")
+	if res.Format != "gift" || res.SuccessCount != 4 {
+		t.Fatalf("result = %+v", res)
+	}
+	want := []string{
+		"Line 10: question: Question type not supported (numerical)",
+		"Line 14: options: Exactly one option must be correct (got 2)",
+	}
+	if !reflect.DeepEqual(res.Errors, want) {
+		t.Errorf("errors = %q", res.Errors)
+	}
+
+	questions := e.storedQuestions("quiz-1")
+	if len(questions) != 4 {
+		t.Fatalf("stored %d questions", len(questions))
+	}
+	if q := questions[0]; q.Points != 4 || len(q.Options) != 3 || q.Options[1].Text != "Bandung" || !q.Options[1].IsCorrect {
+		t.Errorf("mcq = %+v", q)
+	}
+	if q := questions[1]; q.Type != models.TypeTrueFalse || q.Options[0].Text != "True" || !q.Options[0].IsCorrect {
+		t.Errorf("true/false = %+v", q)
+	}
+	if q := questions[2]; q.Text != "Hewan ber{kaki} empat:" || q.CorrectAnswer != "kucing" {
+		t.Errorf("short answer = %+v", q)
+	}
+	if q := questions[3]; q.Type != models.TypeEssay || q.Points != 1 {
+		t.Errorf("essay = %+v", q)
+	}
+}
+
+func TestQuizExportImportsBack(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedInstitution("")
+	e.create(&models.Quiz{ID: "quiz-2", Title: "Salinan", InstitutionID: "inst-1"})
+	e.create(&models.Quiz{ID: "quiz-3", Title: "Salinan Aiken", InstitutionID: "inst-1"})
+
+	seedQuestionBank(e, "quiz-1")
+	original := e.storedQuestions("quiz-1")
+
+	status, contentType, gift := e.download("/api/quizzes/quiz-1/export")
+	if status != http.StatusOK || !strings.HasPrefix(contentType, "text/plain") {
+		t.Fatalf("gift export: status %d %s", status, contentType)
+	}
+	if res := e.importQuestions("quiz-2", "Matematika-gift.txt", string(gift)); res.Format != "gift" || len(res.Errors) > 0 {
+		t.Fatalf("gift import = %+v", res)
+	}
+	if got := e.storedQuestions("quiz-2"); !reflect.DeepEqual(got, original) {
+		t.Errorf("gift round trip:\n got %+v\nwant %+v", got, original)
+	}
+
+	req := httptest.NewRequest("GET", "/api/quizzes/quiz-1/export?format=aiken", nil)
+	resp, err := e.app.Test(req, -1)
+	if err != nil || resp.StatusCode != http.StatusOK || resp.Header.Get("X-Skipped-Questions") != "2" {
+		t.Fatalf("aiken export: %v %+v", err, resp)
+	}
+	_, _, aiken := e.download("/api/quizzes/quiz-1/export?format=aiken")
+	if res := e.importQuestions("quiz-3", "Matematika-aiken.txt", string(aiken)); res.Format != "aiken" || res.SuccessCount != 2 {
+		t.Fatalf("aiken import = %+v", res)
+	}
+	got := e.storedQuestions("quiz-3")
+	if len(got) != 2 || !reflect.DeepEqual(got[0].Options, original[0].Options) || got[1].Type != models.TypeTrueFalse ||
+		got[1].Text != "Air mendidih pada 100°C: benar?" || got[1].Options[1].IsCorrect != true {
+		t.Errorf("aiken round trip = %+v", got)
+	}
+}
+
+func TestQuizQTIExportImportsAsNewQuiz(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedInstitution("")
+	seedQuestionBank(e, "quiz-1")
+	original := e.storedQuestions("quiz-1")
+	for i := range original {
+		original[i].Topic = "" // QTI items carry no topic
+	}
+
+	if status, _, _ := e.download("/api/quizzes/quiz-1/export?format=qti&version=2.2"); status != http.StatusBadRequest {
+		t.Errorf("version 2.2: status %d", status)
+	}
+	status, contentType, pkg := e.download("/api/quizzes/quiz-1/export?format=qti&version=3.0")
+	if status != http.StatusOK || contentType != "application/zip" {
+		t.Fatalf("qti export: status %d %s", status, contentType)
+	}
+
+	status, _, body := e.upload("/api/import/quizzes", "Matematika-qti30.zip", pkg, "X-User", "teacher-1")
+	if status != http.StatusOK {
+		t.Fatalf("qti import: status %d %s", status, body)
+	}
+	var res struct {
+		Quiz         models.Quiz `json:"quiz"`
+		Errors       []string    `json:"errors"`
+		SuccessCount int         `json:"successCount"`
+	}
+	json.Unmarshal(body, &res)
+	if q := res.Quiz; q.Title != "Matematika" || q.Status != "draft" || q.TotalPoints != 16 || q.InstitutionID != "inst-1" ||
+		q.CreatedBy != "teacher-1" || res.SuccessCount != 4 || len(res.Errors) > 0 {
+		t.Fatalf("result = %+v", res)
+	}
+	if got := e.storedQuestions(res.Quiz.ID); !reflect.DeepEqual(got, original) {
+		t.Errorf("qti round trip:\n got %+v\nwant %+v", got, original)
+	}
+}
+
+func TestImportQuestionsReportsUnsupportedQTIItems(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedInstitution("")
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"imsmanifest.xml": `<manifest><resources>
+			<resource identifier="R1" type="imsqti_item_xmlv2p1" href="order.xml"/>
+			<resource identifier="R2" type="imsqti_item_xmlv2p1" href="choice.xml"/>
+		</resources></manifest>`,
+		"order.xml": `<assessmentItem identifier="ORDER"><itemBody><orderInteraction responseIdentifier="R"/></itemBody></assessmentItem>`,
+		"choice.xml": `<assessmentItem identifier="CHOICE">
+			<responseDeclaration identifier="R" cardinality="single"><correctResponse><value>b</value></correctResponse></responseDeclaration>
+			<itemBody><p>2 + 2 = ?</p><choiceInteraction responseIdentifier="R" maxChoices="1">
+				<simpleChoice identifier="a">3</simpleChoice><simpleChoice identifier="b">4</simpleChoice>
+			</choiceInteraction></itemBody></assessmentItem>`,
+	} {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+
+	res := e.importQuestions("quiz-1", "soal.zip", buf.String(), "Accept-Language", "en", "X-User", "teacher-1")
+	want := []string{"Question 1: question: Interaction not supported (orderInteraction)"}
+	if res.Format != "qti" || res.SuccessCount != 1 || !reflect.DeepEqual(res.Errors, want) {
+		t.Fatalf("result = %+v", res)
+	}
+	if q := e.storedQuestions("quiz-1"); len(q) != 1 || q[0].Text != "2 + 2 = ?" || !q[0].Options[1].IsCorrect {
+		t.Errorf("stored = %+v", q)
+	}
+}
+
+func TestImportQuizRefusesOversizedPackage(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedInstitution("")
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("imsmanifest.xml")
+	w.Write([]byte("<manifest>" + strings.Repeat(" ", 64<<20) + "</manifest>"))
+	zw.Close()
+
+	status, _, body := e.upload("/api/import/quizzes", "bom.zip", buf.Bytes(), "X-User", "teacher-1")
+	var res validationResponse
+	json.Unmarshal(body, &res)
+	if status != http.StatusBadRequest || res.Error.Code != "qti_package_too_large" {
+		t.Errorf("status %d code %q", status, res.Error.Code)
+	}
+}
@@ -1,8 +1,12 @@
 package handlers
 
 import (
-	"academic-suite-backend/database"
+	"academic-suite-backend/apperr"
 	"academic-suite-backend/models"
+	"academic-suite-backend/validation"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -16,20 +20,20 @@ import (
 // @Produce      json
 // @Success      200  {array}  models.Quiz
 // @Router       /api/quizzes [get]
-func GetQuizzes(c *fiber.Ctx) error {
+func (s *QuizService) GetQuizzes(c *fiber.Ctx) error {
 	var quizzes []models.Quiz
 	// Filter by InstitutionID if user is logged in (usually is)
 	userId, ok := c.Locals("userId").(string)
 	if ok {
 		var user models.User
-		if err := database.DB.First(&user, "id = ?", userId).Error; err == nil && user.InstitutionID != "" {
-			database.DB.Preload("Questions").Preload("Questions.Options").Where("institution_id = ?", user.InstitutionID).Find(&quizzes)
+		if err := s.db.First(&user, "id = ?", userId).Error; err == nil && user.InstitutionID != "" {
+			s.db.Preload("Questions").Preload("Questions.Options").Where("institution_id = ?", user.InstitutionID).Find(&quizzes)
 			return c.JSON(quizzes)
 		}
 	}
 
 	// Fallback or admin view if no user context (though middleware should catch)
-	database.DB.Preload("Questions").Preload("Questions.Options").Find(&quizzes)
+	s.db.Preload("Questions").Preload("Questions.Options").Find(&quizzes)
 	return c.JSON(quizzes)
 }
 
@@ -40,32 +44,83 @@ func GetQuizzes(c *fiber.Ctx) error {
 // @Produce      json
 // @Param        id   path      string  true  "Quiz ID"
 // @Success      200  {object}  models.Quiz
-// @Failure      404  {object}  map[string]string
+// @Failure      404  {object}  apperr.Response
 // @Router       /api/quizzes/{id} [get]
-func GetQuiz(c *fiber.Ctx) error {
+func (s *QuizService) GetQuiz(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var quiz models.Quiz
-	if err := database.DB.Preload("Questions").Preload("Questions.Options").First(&quiz, "id = ?", id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Quiz not found"})
+	if err := s.db.Preload("Questions").Preload("Questions.Options").First(&quiz, "id = ?", id).Error; err != nil {
+		return apperr.NotFound("quiz_not_found")
 	}
 	return c.JSON(quiz)
 }
 
+// QuizRequest is the body of create/update quiz: the quiz with its questions and options
+type QuizRequest struct {
+	models.Quiz
+}
+
+// Validate checks the rules of the questions that the struct tags cannot express
+func (r QuizRequest) Validate() validation.Errors {
+	return questionRules(r.Questions, "questions")
+}
+
+// questionRules checks the answer key of each question, reporting fields under path,
+// e.g. "questions[2].options"
+func questionRules(questions []models.Question, path string) validation.Errors {
+	var errs validation.Errors
+	for i, q := range questions {
+		errs = append(errs, answerKeyRules(q).Prefix(fmt.Sprintf("%s[%d]", path, i))...)
+	}
+	return errs
+}
+
+// answerKeyRules checks one question: choice questions (MCQ, true/false) need options with
+// exactly one correct, true/false exactly two options, and every option a text or a picture
+func answerKeyRules(q models.Question) validation.Errors {
+	var errs validation.Errors
+	if q.Type != models.TypeMCQ && q.Type != models.TypeTrueFalse {
+		return errs
+	}
+	for i, opt := range q.Options {
+		if strings.TrimSpace(opt.Text) == "" && opt.ImageID == "" {
+			errs.Add(fmt.Sprintf("options[%d].text", i), "required")
+		}
+	}
+	switch {
+	case q.Type == models.TypeTrueFalse && len(q.Options) != 2:
+		errs.Add("options", "len_items", "2")
+	case len(q.Options) < 2:
+		errs.Add("options", "min_items", "2")
+	}
+	correct := 0
+	for _, opt := range q.Options {
+		if opt.IsCorrect {
+			correct++
+		}
+	}
+	if correct != 1 {
+		errs.Add("options", "one_correct_option", strconv.Itoa(correct))
+	}
+	return errs
+}
+
 // CreateQuiz godoc
 // @Summary      Create New Quiz
 // @Description  Create a new quiz with questions
 // @Tags         quizzes
 // @Accept       json
 // @Produce      json
-// @Param        quiz body models.Quiz true "Quiz Data"
+// @Param        quiz body QuizRequest true "Quiz Data"
 // @Success      200  {object}  models.Quiz
-// @Failure      400  {object}  map[string]string
+// @Failure      400  {object}  apperr.Response
 // @Router       /api/quizzes [post]
-func CreateQuiz(c *fiber.Ctx) error {
-	var quiz models.Quiz
-	if err := c.BodyParser(&quiz); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+func (s *QuizService) CreateQuiz(c *fiber.Ctx) error {
+	var req QuizRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
+	quiz := req.Quiz
 
 	quiz.ID = "quiz-" + time.Now().Format("20060102150405") // Simple ID gen
 	if quiz.Status == "" {
@@ -75,19 +130,22 @@ func CreateQuiz(c *fiber.Ctx) error {
 	// Set InstitutionID from logged-in user
 	userId := c.Locals("userId").(string)
 	var user models.User
-	if err := database.DB.First(&user, "id = ?", userId).Error; err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+	if err := s.db.First(&user, "id = ?", userId).Error; err != nil {
+		return apperr.Unauthorized("user_not_found")
 	}
 	quiz.InstitutionID = user.InstitutionID
 	quiz.CreatedBy = user.ID
+	if errs := mediaRules(s.db, quiz.InstitutionID, quiz.Questions, "questions"); len(errs) > 0 {
+		return fieldErrors(errs)
+	}
 
-	quiz.CreatedAt = time.Now()
-	quiz.UpdatedAt = time.Now()
+	quiz.CreatedAt = s.clock.Now()
+	quiz.UpdatedAt = s.clock.Now()
 
 	// ... (Create logic)
 
-	if err := database.DB.Create(&quiz).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create quiz"})
+	if err := s.db.Create(&quiz).Error; err != nil {
+		return apperr.Internal("quiz_create_failed", err)
 	}
 
 	return c.JSON(quiz)
@@ -100,24 +158,28 @@ func CreateQuiz(c *fiber.Ctx) error {
 // @Accept       json
 // @Produce      json
 // @Param        id   path      string       true  "Quiz ID"
-// @Param        quiz body      models.Quiz  true  "Quiz Data"
+// @Param        quiz body      QuizRequest  true  "Quiz Data"
 // @Success      200  {object}  models.Quiz
-// @Failure      404  {object}  map[string]string
+// @Failure      400  {object}  apperr.Response
+// @Failure      404  {object}  apperr.Response
 // @Router       /api/quizzes/{id} [put]
-func UpdateQuiz(c *fiber.Ctx) error {
+func (s *QuizService) UpdateQuiz(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var quiz models.Quiz
-	if err := database.DB.Preload("Questions").First(&quiz, "id = ?", id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Quiz not found"})
+	if err := s.db.Preload("Questions").First(&quiz, "id = ?", id).Error; err != nil {
+		return apperr.NotFound("quiz_not_found")
 	}
 
-	var req models.Quiz
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	var req QuizRequest
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	if errs := mediaRules(s.db, quiz.InstitutionID, req.Questions, "questions"); len(errs) > 0 {
+		return fieldErrors(errs)
 	}
 
 	// Correct Transaction handling
-	err := database.DB.Transaction(func(tx *gorm.DB) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
 		// 1. Update basic fields
 		quiz.Title = req.Title
 		quiz.Description = req.Description
@@ -135,11 +197,11 @@ func UpdateQuiz(c *fiber.Ctx) error {
 		// User asked: "create/update quiz institution follows user who login".
 		userId := c.Locals("userId").(string)
 		var user models.User
-		if err := database.DB.First(&user, "id = ?", userId).Error; err == nil {
+		if err := s.db.First(&user, "id = ?", userId).Error; err == nil {
 			quiz.InstitutionID = user.InstitutionID
 		}
 
-		quiz.UpdatedAt = time.Now()
+		quiz.UpdatedAt = s.clock.Now()
 
 		if err := tx.Save(&quiz).Error; err != nil {
 			return err
@@ -184,11 +246,11 @@ func UpdateQuiz(c *fiber.Ctx) error {
 	})
 
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update quiz: " + err.Error()})
+		return apperr.Internal("quiz_update_failed", err)
 	}
 
 	// Reload with questions
-	database.DB.Preload("Questions").Preload("Questions.Options").First(&quiz, "id = ?", id)
+	s.db.Preload("Questions").Preload("Questions.Options").First(&quiz, "id = ?", id)
 	return c.JSON(quiz)
 }
 
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"academic-suite-backend/models"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Helpers for the class_students, subject_teachers, batch_participants and batch_waitlist_entries join tables.
+// All take the *gorm.DB to use so they work inside transactions, and the batch ones the service clock's now.
+
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	out := []string{}
+	for _, id := range ids {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// decodeIDListJSON accepts either a JSON array or a JSON string holding a JSON array
+// (older clients send class studentIds as a stringified array).
+func decodeIDListJSON(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var ids []string
+	if err := json.Unmarshal(raw, &ids); err == nil {
+		return ids, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, err
+	}
+	if s == "" {
+		return []string{}, nil
+	}
+	if err := json.Unmarshal([]byte(s), &ids); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// --- Batch participants ---
+
+func batchParticipantIDs(db *gorm.DB, batchID string) []string {
+	ids := []string{}
+	db.Model(&models.BatchParticipant{}).Where("batch_id = ?", batchID).Order("created_at, student_id").Pluck("student_id", &ids)
+	return ids
+}
+
+func countBatchParticipants(db *gorm.DB, batchID string) int {
+	var count int64
+	db.Model(&models.BatchParticipant{}).Where("batch_id = ?", batchID).Count(&count)
+	return int(count)
+}
+
+func isBatchParticipant(db *gorm.DB, batchID, studentID string) bool {
+	var count int64
+	db.Model(&models.BatchParticipant{}).Where("batch_id = ? AND student_id = ?", batchID, studentID).Count(&count)
+	return count > 0
+}
+
+func addBatchParticipants(db *gorm.DB, batchID string, studentIDs []string, now time.Time) error {
+	for _, id := range uniqueIDs(studentIDs) {
+		row := models.BatchParticipant{BatchID: batchID, StudentID: id, CreatedAt: now}
+		if err := db.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func removeBatchParticipant(db *gorm.DB, batchID, studentID string) (bool, error) {
+	res := db.Where("batch_id = ? AND student_id = ?", batchID, studentID).Delete(&models.BatchParticipant{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// setBatchParticipants only adds and removes the difference: a kept participant keeps its
+// created_at, which decides who holds a seat when the batch is over capacity
+func setBatchParticipants(db *gorm.DB, batchID string, studentIDs []string, now time.Time) error {
+	ids := uniqueIDs(studentIDs)
+	stale := db.Where("batch_id = ?", batchID)
+	if len(ids) > 0 {
+		stale = stale.Where("student_id NOT IN ?", ids)
+	}
+	if err := stale.Delete(&models.BatchParticipant{}).Error; err != nil {
+		return err
+	}
+	return addBatchParticipants(db, batchID, ids, now)
+}
+
+// --- Batch waitlist (ordered by position) ---
+
+func batchWaitlistIDs(db *gorm.DB, batchID string) []string {
+	ids := []string{}
+	db.Model(&models.BatchWaitlistEntry{}).Where("batch_id = ?", batchID).Order("position, created_at").Pluck("student_id", &ids)
+	return ids
+}
+
+func setBatchWaitlist(db *gorm.DB, batchID string, studentIDs []string, now time.Time) error {
+	if err := db.Where("batch_id = ?", batchID).Delete(&models.BatchWaitlistEntry{}).Error; err != nil {
+		return err
+	}
+	for pos, id := range uniqueIDs(studentIDs) {
+		row := models.BatchWaitlistEntry{BatchID: batchID, StudentID: id, Position: pos, CreatedAt: now}
+		if err := db.Omit(clause.Associations).Create(&row).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// --- Class students ---
+
+func classStudentIDs(db *gorm.DB, classID string) []string {
+	ids := []string{}
+	db.Model(&models.ClassStudent{}).Where("class_id = ?", classID).Order("student_id").Pluck("student_id", &ids)
+	return ids
+}
+
+func setClassStudents(db *gorm.DB, classID string, studentIDs []string) error {
+	if err := db.Where("class_id = ?", classID).Delete(&models.ClassStudent{}).Error; err != nil {
+		return err
+	}
+	for _, id := range uniqueIDs(studentIDs) {
+		row := models.ClassStudent{ClassID: classID, StudentID: id}
+		if err := db.Omit(clause.Associations).Create(&row).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addClassStudents adds members without touching the existing ones
+func addClassStudents(db *gorm.DB, classID string, studentIDs []string) error {
+	for _, id := range uniqueIDs(studentIDs) {
+		row := models.ClassStudent{ClassID: classID, StudentID: id}
+		if err := db.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// --- Subject teachers ---
+
+func subjectTeacherIDs(db *gorm.DB, subjectID string) []string {
+	ids := []string{}
+	db.Model(&models.SubjectTeacher{}).Where("subject_id = ?", subjectID).Order("teacher_id").Pluck("teacher_id", &ids)
+	return ids
+}
+
+func setSubjectTeachers(db *gorm.DB, subjectID string, teacherIDs []string) error {
+	if err := db.Where("subject_id = ?", subjectID).Delete(&models.SubjectTeacher{}).Error; err != nil {
+		return err
+	}
+	for _, id := range uniqueIDs(teacherIDs) {
+		row := models.SubjectTeacher{SubjectID: subjectID, TeacherID: id}
+		if err := db.Omit(clause.Associations).Create(&row).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"academic-suite-backend/models"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecodeIDListJSON(t *testing.T) {
+	cases := map[string][]string{
+		`["s1","s2"]`:       {"s1", "s2"},
+		`"[\"s1\",\"s2\"]"`: {"s1", "s2"}, // stringified by older clients
+		`""`:                {},
+		`null`:              nil,
+	}
+	for raw, want := range cases {
+		got, err := decodeIDListJSON(json.RawMessage(raw))
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %v, %v; want %v", raw, got, err, want)
+		}
+	}
+	if _, err := decodeIDListJSON(json.RawMessage(`"not a list"`)); err == nil {
+		t.Error("malformed string: want an error")
+	}
+}
+
+func TestBatchJoinTablesFollowTheClock(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedExam()
+	for _, id := range []string{"student-2", "student-3"} {
+		e.create(&models.User{ID: id, Email: id + "@example.com", Role: models.RoleStudent})
+	}
+
+	// Duplicates and empty IDs are dropped; rows carry the clock's time, which orders the seats
+	if err := setBatchParticipants(e.db, "batch-1", []string{"student-3", "", "student-3"}, testStart); err != nil {
+		t.Fatal(err)
+	}
+	if err := addBatchParticipants(e.db, "batch-1", []string{"student-1", "student-3"}, testStart.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if got := batchParticipantIDs(e.db, "batch-1"); !reflect.DeepEqual(got, []string{"student-3", "student-1"}) {
+		t.Errorf("participants = %v", got)
+	}
+	var row models.BatchParticipant
+	e.db.First(&row, "batch_id = ? AND student_id = ?", "batch-1", "student-1")
+	if !row.CreatedAt.Equal(testStart.Add(time.Minute)) {
+		t.Errorf("createdAt = %v, want the passed time", row.CreatedAt)
+	}
+	if countBatchParticipants(e.db, "batch-1") != 2 || !isBatchParticipant(e.db, "batch-1", "student-3") || isBatchParticipant(e.db, "batch-1", "student-2") {
+		t.Error("participant lookups disagree with the rows")
+	}
+	if removed, _ := removeBatchParticipant(e.db, "batch-1", "student-2"); removed {
+		t.Error("removed a student who was not a participant")
+	}
+
+	// Setting the list again keeps the seats already held and only stamps the newcomer
+	if err := setBatchParticipants(e.db, "batch-1", []string{"student-2", "student-1", "student-3"}, testStart.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if got := batchParticipantIDs(e.db, "batch-1"); !reflect.DeepEqual(got, []string{"student-3", "student-1", "student-2"}) {
+		t.Errorf("participants after edit = %v", got)
+	}
+	setBatchParticipants(e.db, "batch-1", []string{"student-1"}, testStart.Add(2*time.Hour))
+	if got := batchParticipantIDs(e.db, "batch-1"); !reflect.DeepEqual(got, []string{"student-1"}) {
+		t.Errorf("participants after removal = %v", got)
+	}
+	setBatchParticipants(e.db, "batch-1", nil, testStart.Add(2*time.Hour))
+	if n := countBatchParticipants(e.db, "batch-1"); n != 0 {
+		t.Errorf("participants after clearing = %d", n)
+	}
+
+	// The waitlist keeps the given order, replacing the previous one
+	setBatchWaitlist(e.db, "batch-1", []string{"student-1", "student-2"}, testStart)
+	if err := setBatchWaitlist(e.db, "batch-1", []string{"student-2", "student-1", "student-2"}, testStart); err != nil {
+		t.Fatal(err)
+	}
+	if got := batchWaitlistIDs(e.db, "batch-1"); !reflect.DeepEqual(got, []string{"student-2", "student-1"}) {
+		t.Errorf("waitlist = %v", got)
+	}
+}
+
+func TestClassAndSubjectJoinTables(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedExam()
+	e.create(
+		&models.User{ID: "student-2", Email: "siswa2@example.com", Role: models.RoleStudent},
+		&models.User{ID: "teacher-1", Email: "guru@example.com", Role: models.RoleTeacher},
+		&models.Class{ID: "class-1", Name: "XII IPA 1"},
+		&models.Subject{ID: "subject-1", Name: "Matematika"},
+	)
+
+	setClassStudents(e.db, "class-1", []string{"student-2"})
+	if err := addClassStudents(e.db, "class-1", []string{"student-1", "student-2"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := classStudentIDs(e.db, "class-1"); !reflect.DeepEqual(got, []string{"student-1", "student-2"}) {
+		t.Errorf("class students = %v", got)
+	}
+	setClassStudents(e.db, "class-1", []string{"student-1"})
+	if got := classStudentIDs(e.db, "class-1"); !reflect.DeepEqual(got, []string{"student-1"}) {
+		t.Errorf("class students after set = %v", got)
+	}
+
+	if err := setSubjectTeachers(e.db, "subject-1", []string{"teacher-1", "teacher-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := subjectTeacherIDs(e.db, "subject-1"); !reflect.DeepEqual(got, []string{"teacher-1"}) {
+		t.Errorf("subject teachers = %v", got)
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/models"
+	"academic-suite-backend/psychometrics"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReliabilityResponse struct {
+	psychometrics.ReliabilityStats
+	QuizID    string          `json:"quizId"`
+	QuizTitle string          `json:"quizTitle"`
+	ExamType  models.ExamType `json:"examType"`
+	BatchIDs  []string        `json:"batchIds"`
+}
+
+func (s *ReportService) getReliabilityData(batchId, quizId string, bins int) (*ReliabilityResponse, error) {
+	in, err := s.loadAnalysisInput(batchId, quizId)
+	if err != nil {
+		return nil, err
+	}
+	return &ReliabilityResponse{
+		ReliabilityStats: psychometrics.Reliability(in.items, in.sheets, bins),
+		QuizID:           in.quiz.ID,
+		QuizTitle:        in.quiz.Title,
+		ExamType:         in.quiz.ExamType,
+		BatchIDs:         in.batchIDs,
+	}, nil
+}
+
+// GetReliability godoc
+// @Summary      Get Test Reliability
+// @Description  KR-20, KR-21, Cronbach's alpha, standard error of measurement and a score histogram, from submitted attempts of a batch (with its makeups) or of all batches of a quiz
+// @Tags         reports
+// @Produce      json
+// @Param        batchId query string false "Batch ID"
+// @Param        quizId  query string false "Quiz ID (all batches)"
+// @Param        bins    query int    false "Histogram bins over 0-100% (default 10)"
+// @Success      200  {object}  ReliabilityResponse
+// @Failure      400  {object}  apperr.Response
+// @Failure      404  {object}  apperr.Response
+// @Router       /api/reports/reliability [get]
+func (s *ReportService) GetReliability(c *fiber.Ctx) error {
+	batchId := c.Query("batchId")
+	quizId := c.Query("quizId")
+	if batchId == "" && quizId == "" {
+		return apperr.BadRequest("batch_or_quiz_required")
+	}
+
+	bins := psychometrics.DefaultHistogramBins
+	if raw := c.Query("bins"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 100 {
+			return apperr.BadRequest("invalid_bins")
+		}
+		bins = n
+	}
+
+	report, err := s.getReliabilityData(batchId, quizId, bins)
+	if err != nil {
+		return err
+	}
+	return c.JSON(report)
+}
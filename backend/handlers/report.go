@@ -1,9 +1,12 @@
 package handlers
 
 import (
-	"academic-suite-backend/database"
+	"academic-suite-backend/apperr"
 	"academic-suite-backend/models"
+	"academic-suite-backend/psychometrics"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/xuri/excelize/v2"
@@ -19,10 +22,17 @@ type AttemptReport struct {
 	Percentage  float64 `json:"percentage"`
 	Duration    int     `json:"duration"` // seconds
 	SubmittedAt *string `json:"submittedAt"`
+	// AllowedDuration is the time the student was entitled to, including accommodations (seconds)
+	AllowedDuration int  `json:"allowedDuration"`
+	Accommodated    bool `json:"accommodated"`
+	// BatchID is the batch the attempt was taken in (the makeup batch for makeup results)
+	BatchID  string `json:"batchId"`
+	IsMakeup bool   `json:"isMakeup"`
 }
 
 type BatchReportResponse struct {
 	BatchID           string          `json:"batchId"`
+	BatchName         string          `json:"batchName"`
 	BatchType         string          `json:"batchType"`
 	QuizTitle         string          `json:"quizTitle"`
 	TotalParticipants int             `json:"totalParticipants"`
@@ -31,67 +41,50 @@ type BatchReportResponse struct {
 	HighestScore      float64         `json:"highestScore"`
 	LowestScore       float64         `json:"lowestScore"`
 	Attempts          []AttemptReport `json:"attempts"`
+	MakeupBatchIDs    []string        `json:"makeupBatchIds"`
 }
 
 // getBatchReportData is a helper to fetch and calculate batch report data
-func getBatchReportData(batchId string) (*BatchReportResponse, error) {
+func (s *ReportService) getBatchReportData(batchId string) (*BatchReportResponse, error) {
 	// 1. Get Batch & Quiz
 	var batch models.ExamBatch
-	if err := database.DB.First(&batch, "id = ?", batchId).Error; err != nil {
-		return nil, fmt.Errorf("batch not found")
+	if err := s.db.First(&batch, "id = ?", batchId).Error; err != nil {
+		return nil, errBatchNotFound
 	}
 
 	var quiz models.Quiz
-	database.DB.First(&quiz, "id = ?", batch.QuizID)
+	s.db.First(&quiz, "id = ?", batch.QuizID)
+
+	// A regular batch's report also covers its makeup batches, so each student ends up with one grade
+	batches := map[string]models.ExamBatch{batch.ID: batch}
+	batchIDs := []string{batch.ID}
+	makeupIDs := []string{}
+	if batch.Type != models.BatchMakeup {
+		var makeups []models.ExamBatch
+		s.db.Where("parent_batch_id = ?", batch.ID).Find(&makeups)
+		for _, m := range makeups {
+			batches[m.ID] = m
+			batchIDs = append(batchIDs, m.ID)
+			makeupIDs = append(makeupIDs, m.ID)
+		}
+	}
 
 	// 2. Get Attempts with Student info
 	var attempts []models.Attempt
-	database.DB.Where("batch_id = ?", batchId).Find(&attempts)
+	s.db.Where("batch_id IN ?", batchIDs).Find(&attempts)
 
 	// Deduplicate Attempts: Keep only the "best" attempt per student
-	// Priority: SUBMITTED > ACTIVE > EXPIRED/Others
-	// Tie-breaker: Highest Score > Latest CreatedAt
-	uniqueAttempts := make(map[string]models.Attempt)
-
-	for _, att := range attempts {
-		existing, exists := uniqueAttempts[att.StudentID]
-		if !exists {
-			uniqueAttempts[att.StudentID] = att
-			continue
-		}
-
-		// Compare att vs existing
-		isBetter := false
-
-		// 1. Status Priority
-		if att.Status == models.AttemptSubmitted && existing.Status != models.AttemptSubmitted {
-			isBetter = true
-		} else if att.Status == models.AttemptSubmitted && existing.Status == models.AttemptSubmitted {
-			// Both Submitted: check score
-			if att.Score > existing.Score {
-				isBetter = true
-			} else if att.Score == existing.Score {
-				// Same score: check timestamp (newer is better?)
-				if att.CreatedAt.After(existing.CreatedAt) {
-					isBetter = true
-				}
-			}
-		} else if att.Status == models.AttemptActive && existing.Status != models.AttemptSubmitted && existing.Status != models.AttemptActive {
-			isBetter = true
-		}
-
-		if isBetter {
-			uniqueAttempts[att.StudentID] = att
-		}
-	}
+	uniqueAttempts := bestAttemptPerStudent(attempts)
 
 	// 3. Process Stats
 	report := &BatchReportResponse{}
 	report.BatchID = batch.ID
+	report.BatchName = batch.Name
 	report.BatchType = string(batch.Type)
 	report.QuizTitle = quiz.Title
 	report.TotalParticipants = len(uniqueAttempts)
 	report.Attempts = []AttemptReport{}
+	report.MakeupBatchIDs = makeupIDs
 
 	totalScore := 0.0
 	highest := 0.0
@@ -106,7 +99,7 @@ func getBatchReportData(batchId string) (*BatchReportResponse, error) {
 	for _, a := range uniqueAttempts {
 		// Get Student Name (Optimize with preload/join later)
 		var student models.User
-		database.DB.First(&student, "id = ?", a.StudentID)
+		s.db.First(&student, "id = ?", a.StudentID)
 
 		// Calculate Duration
 		duration := 0
@@ -130,12 +123,15 @@ func getBatchReportData(batchId string) (*BatchReportResponse, error) {
 			lowest = a.Score
 		}
 
-		// Format SubmittedAt
+		attemptBatch := batches[a.BatchID]
+
+		// Format SubmittedAt in the batch timezone, with its offset
 		var submittedAtStr *string
 		if a.SubmittedAt != nil {
-			s := a.SubmittedAt.UTC().Format("2006-01-02T15:04:05Z")
-			submittedAtStr = &s
+			formatted := a.SubmittedAt.In(batchLocation(attemptBatch)).Format(time.RFC3339)
+			submittedAtStr = &formatted
 		}
+		acc := findAccommodation(s.db, attemptBatch.ID, a.StudentID)
 
 		percentage := 0.0
 		if quiz.TotalPoints > 0 {
@@ -152,6 +148,11 @@ func getBatchReportData(batchId string) (*BatchReportResponse, error) {
 			Percentage:  percentage,
 			Duration:    duration,
 			SubmittedAt: submittedAtStr,
+
+			AllowedDuration: int(allowedDurationSeconds(attemptBatch, acc)),
+			Accommodated:    acc != nil,
+			BatchID:         a.BatchID,
+			IsMakeup:        attemptBatch.Type == models.BatchMakeup,
 		})
 	}
 
@@ -169,6 +170,50 @@ func getBatchReportData(batchId string) (*BatchReportResponse, error) {
 	return report, nil
 }
 
+// bestAttemptPerStudent keeps one attempt per student.
+// Priority: SUBMITTED > ACTIVE > EXPIRED/Others
+// Tie-breaker: Highest Score > Latest CreatedAt
+// Attempts reset by an admin never count: the student starts over.
+func bestAttemptPerStudent(attempts []models.Attempt) map[string]models.Attempt {
+	uniqueAttempts := make(map[string]models.Attempt)
+
+	for _, att := range attempts {
+		if att.Status == models.AttemptResetByAdmin {
+			continue
+		}
+		existing, exists := uniqueAttempts[att.StudentID]
+		if !exists {
+			uniqueAttempts[att.StudentID] = att
+			continue
+		}
+
+		// Compare att vs existing
+		isBetter := false
+
+		// 1. Status Priority
+		if att.Status == models.AttemptSubmitted && existing.Status != models.AttemptSubmitted {
+			isBetter = true
+		} else if att.Status == models.AttemptSubmitted && existing.Status == models.AttemptSubmitted {
+			// Both Submitted: check score
+			if att.Score > existing.Score {
+				isBetter = true
+			} else if att.Score == existing.Score {
+				// Same score: check timestamp (newer is better?)
+				if att.CreatedAt.After(existing.CreatedAt) {
+					isBetter = true
+				}
+			}
+		} else if att.Status == models.AttemptActive && existing.Status != models.AttemptSubmitted && existing.Status != models.AttemptActive {
+			isBetter = true
+		}
+
+		if isBetter {
+			uniqueAttempts[att.StudentID] = att
+		}
+	}
+	return uniqueAttempts
+}
+
 // GetBatchReport godoc
 // @Summary      Get Batch Report
 // @Description  Get detailed report for an exam batch
@@ -177,18 +222,15 @@ func getBatchReportData(batchId string) (*BatchReportResponse, error) {
 // @Param        batchId query string true "Batch ID"
 // @Success      200  {object}  BatchReportResponse
 // @Router       /api/reports/batch [get]
-func GetBatchReport(c *fiber.Ctx) error {
+func (s *ReportService) GetBatchReport(c *fiber.Ctx) error {
 	batchId := c.Query("batchId")
 	if batchId == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Batch ID is required"})
+		return apperr.BadRequest("batch_id_required")
 	}
 
-	report, err := getBatchReportData(batchId)
+	report, err := s.getBatchReportData(batchId)
 	if err != nil {
-		if err.Error() == "batch not found" {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Batch not found"})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return err
 	}
 
 	return c.JSON(report)
@@ -202,23 +244,24 @@ func GetBatchReport(c *fiber.Ctx) error {
 // @Param        batchId path string true "Batch ID"
 // @Success      200  {file}  file
 // @Router       /api/export/batch/{batchId} [get]
-func ExportBatchReport(c *fiber.Ctx) error {
+func (s *ReportService) ExportBatchReport(c *fiber.Ctx) error {
 	batchId := c.Params("id") // Param must match route definition :id
 
-	report, err := getBatchReportData(batchId)
+	report, err := s.getBatchReportData(batchId)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Batch data not found"})
+		return apperr.NotFound("batch_not_found")
 	}
 
+	l := requestLocale(c)
 	f := excelize.NewFile()
 	defer func() {
 		if err := f.Close(); err != nil {
-			fmt.Println(err)
+			log.Printf("batch report export: closing workbook: %v", err)
 		}
 	}()
 
 	// Create Sheet
-	sheetName := "Laporan Ujian"
+	sheetName := l.T("report.sheet")
 	f.SetSheetName("Sheet1", sheetName)
 
 	// Styles
@@ -246,24 +289,24 @@ func ExportBatchReport(c *fiber.Ctx) error {
 	})
 
 	// Header Info
-	f.MergeCell(sheetName, "A1", "F1")
-	f.SetCellValue(sheetName, "A1", "LAPORAN HASIL UJIAN")
+	f.MergeCell(sheetName, "A1", "G1")
+	f.SetCellValue(sheetName, "A1", l.T("report.title"))
 	f.SetCellStyle(sheetName, "A1", "A1", styleTitle)
 
-	f.SetCellValue(sheetName, "A3", "Judul Ujian")
+	f.SetCellValue(sheetName, "A3", l.T("label.exam_title"))
 	f.SetCellValue(sheetName, "B3", report.QuizTitle)
 
-	f.SetCellValue(sheetName, "A4", "Kelas/Batch")
-	f.SetCellValue(sheetName, "B4", report.BatchID)
+	f.SetCellValue(sheetName, "A4", l.T("label.batch"))
+	f.SetCellValue(sheetName, "B4", report.BatchName)
 
-	f.SetCellValue(sheetName, "A5", "Total Peserta")
+	f.SetCellValue(sheetName, "A5", l.T("label.participants"))
 	f.SetCellValue(sheetName, "B5", report.TotalParticipants)
 
 	// Table Header (Row 7)
-	headers := []string{"No", "Nama Peserta", "Status", "Skor", "Durasi (Detik)", "Waktu Submit"}
+	headers := []string{"col.no", "col.student_name", "col.status", "col.score", "col.duration_seconds", "col.submitted_at", "col.session"}
 	for i, h := range headers {
 		cell, _ := excelize.CoordinatesToCellName(i+1, 7)
-		f.SetCellValue(sheetName, cell, h)
+		f.SetCellValue(sheetName, cell, l.T(h))
 		f.SetCellStyle(sheetName, cell, cell, styleHeader)
 	}
 
@@ -282,8 +325,10 @@ func ExportBatchReport(c *fiber.Ctx) error {
 		}
 		f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), submitTime)
 
+		f.SetCellValue(sheetName, fmt.Sprintf("G%d", row), sessionLabel(l, att.IsMakeup))
+
 		// Border for row
-		f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("F%d", row), styleBorder)
+		f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row), styleBorder)
 
 		row++
 	}
@@ -292,12 +337,18 @@ func ExportBatchReport(c *fiber.Ctx) error {
 	f.SetColWidth(sheetName, "B", "B", 30)
 	f.SetColWidth(sheetName, "F", "F", 20)
 
+	// Second sheet: per-question statistics
+	if analysis, err := s.getItemAnalysisData(batchId, ""); err == nil {
+		rel, _ := s.getReliabilityData(batchId, "", psychometrics.DefaultHistogramBins)
+		writeItemAnalysisSheet(f, l, analysis, rel)
+	}
+
 	// Set Response Headers
 	c.Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
 	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=report-%s.xlsx", batchId))
 
 	if err := f.Write(c.Response().BodyWriter()); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate excel"})
+		return apperr.Internal("export_failed", err)
 	}
 
 	return nil
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"academic-suite-backend/models"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// The parent report merges its makeup batch, one best attempt per student, without reset attempts
+func TestBatchReportMergesMakeupWithoutResets(t *testing.T) {
+	e := newTestEnv(t)
+	batch := e.seedExam()
+	submitted := func(at time.Time) *time.Time { return &at }
+	e.create(
+		&models.User{ID: "student-2", Name: "Budi", Email: "budi@example.com", Role: models.RoleStudent},
+		&models.User{ID: "student-3", Name: "Citra", Email: "citra@example.com", Role: models.RoleStudent},
+		&models.ExamBatch{ID: "makeup-1", QuizID: "quiz-1", Type: models.BatchMakeup, ParentBatchID: batch.ID, Name: "Susulan",
+			StartTime: testStart.Add(24 * time.Hour), EndTime: testStart.Add(26 * time.Hour), Duration: 60},
+		// student-1 sat the regular batch
+		&models.Attempt{ID: "a1", BatchID: batch.ID, StudentID: "student-1", Status: models.AttemptSubmitted, Score: 80,
+			StartedAt: &testStart, SubmittedAt: submitted(testStart.Add(30 * time.Minute))},
+		// student-2's regular attempt was reset; they sat the makeup instead
+		&models.Attempt{ID: "a2", BatchID: batch.ID, StudentID: "student-2", Status: models.AttemptResetByAdmin, Score: 100},
+		&models.Attempt{ID: "a3", BatchID: "makeup-1", StudentID: "student-2", Status: models.AttemptSubmitted, Score: 40,
+			StartedAt: &testStart, SubmittedAt: submitted(testStart.Add(45 * time.Minute))},
+		// student-3 only has a reset attempt
+		&models.Attempt{ID: "a4", BatchID: batch.ID, StudentID: "student-3", Status: models.AttemptResetByAdmin, Score: 90},
+	)
+
+	var report BatchReportResponse
+	if status := e.do("GET", "/api/reports/batch?batchId="+batch.ID, nil, &report); status != http.StatusOK {
+		t.Fatalf("status %d", status)
+	}
+	if report.TotalParticipants != 2 || report.SubmittedCount != 2 || len(report.MakeupBatchIDs) != 1 {
+		t.Fatalf("report = %d participants, %d submitted, makeups %v", report.TotalParticipants, report.SubmittedCount, report.MakeupBatchIDs)
+	}
+	if report.HighestScore != 80 || report.LowestScore != 40 || report.AverageScore != 60 {
+		t.Errorf("scores = %v/%v/%v, want 80/40/60", report.HighestScore, report.LowestScore, report.AverageScore)
+	}
+	for _, a := range report.Attempts {
+		if a.StudentID == "student-2" && (a.AttemptID != "a3" || !a.IsMakeup) {
+			t.Errorf("student-2 row = %+v, want the makeup attempt", a)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	errInvalidRequest   = apperr.BadRequest("invalid_request")
+	errValidationFailed = apperr.BadRequest("validation_failed")
+)
+
+// parseBody decodes the JSON body into out (a pointer to a request DTO) and checks its
+// `validate` tags and Validate rules. Failures come back as validation_failed with the
+// failed fields as details.
+func parseBody(c *fiber.Ctx, out interface{}) error {
+	if err := c.BodyParser(out); err != nil {
+		return errInvalidRequest
+	}
+	if errs := validation.Struct(out); len(errs) > 0 {
+		return fieldErrors(errs)
+	}
+	return nil
+}
+
+// fieldErrors reports rules checked by hand in the same shape as parseBody
+func fieldErrors(errs validation.Errors) error {
+	return errValidationFailed.WithDetails(errs)
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"academic-suite-backend/blob"
+	"academic-suite-backend/clock"
+	"academic-suite-backend/repository"
+
+	"gorm.io/gorm"
+)
+
+// Handlers are methods on services that receive their dependencies (database, clock,
+// event logger) through the constructor, so they can be tested against a throwaway
+// database and a fake clock. routes.SetupRoutes registers them from a *Services.
+
+type AuthService struct {
+	db    *gorm.DB
+	clock clock.Clock
+	users *repository.UserRepository
+}
+
+func NewAuthService(db *gorm.DB, clk clock.Clock, users *repository.UserRepository) *AuthService {
+	return &AuthService{db: db, clock: clk, users: users}
+}
+
+type UserService struct {
+	db    *gorm.DB
+	clock clock.Clock
+	users *repository.UserRepository
+}
+
+func NewUserService(db *gorm.DB, clk clock.Clock, users *repository.UserRepository) *UserService {
+	return &UserService{db: db, clock: clk, users: users}
+}
+
+type InstitutionService struct {
+	db    *gorm.DB
+	clock clock.Clock
+}
+
+func NewInstitutionService(db *gorm.DB, clk clock.Clock) *InstitutionService {
+	return &InstitutionService{db: db, clock: clk}
+}
+
+type SubjectService struct {
+	db    *gorm.DB
+	clock clock.Clock
+}
+
+func NewSubjectService(db *gorm.DB, clk clock.Clock) *SubjectService {
+	return &SubjectService{db: db, clock: clk}
+}
+
+type ClassService struct {
+	db    *gorm.DB
+	clock clock.Clock
+}
+
+func NewClassService(db *gorm.DB, clk clock.Clock) *ClassService {
+	return &ClassService{db: db, clock: clk}
+}
+
+type QuizService struct {
+	db    *gorm.DB
+	clock clock.Clock
+	media blob.Store
+}
+
+func NewQuizService(db *gorm.DB, clk clock.Clock, media blob.Store) *QuizService {
+	return &QuizService{db: db, clock: clk, media: media}
+}
+
+type ImportService struct {
+	db    *gorm.DB
+	clock clock.Clock
+	media blob.Store
+}
+
+func NewImportService(db *gorm.DB, clk clock.Clock, media blob.Store) *ImportService {
+	return &ImportService{db: db, clock: clk, media: media}
+}
+
+// MediaService uploads and serves question images and audio kept in a blob store
+type MediaService struct {
+	db    *gorm.DB
+	clock clock.Clock
+	media blob.Store
+}
+
+func NewMediaService(db *gorm.DB, clk clock.Clock, media blob.Store) *MediaService {
+	return &MediaService{db: db, clock: clk, media: media}
+}
+
+// BatchService covers batches, makeup batches, waitlists and participants
+type BatchService struct {
+	db     *gorm.DB
+	clock  clock.Clock
+	events *EventLogger
+}
+
+func NewBatchService(db *gorm.DB, clk clock.Clock, events *EventLogger) *BatchService {
+	return &BatchService{db: db, clock: clk, events: events}
+}
+
+// AttemptService covers the attempt lifecycle for students, teachers and admins
+type AttemptService struct {
+	db     *gorm.DB
+	clock  clock.Clock
+	events *EventLogger
+}
+
+func NewAttemptService(db *gorm.DB, clk clock.Clock, events *EventLogger) *AttemptService {
+	return &AttemptService{db: db, clock: clk, events: events}
+}
+
+type AccommodationService struct {
+	db     *gorm.DB
+	clock  clock.Clock
+	events *EventLogger
+}
+
+func NewAccommodationService(db *gorm.DB, clk clock.Clock, events *EventLogger) *AccommodationService {
+	return &AccommodationService{db: db, clock: clk, events: events}
+}
+
+// ReportService covers batch reports, exports and event logs
+type ReportService struct {
+	db    *gorm.DB
+	clock clock.Clock
+}
+
+func NewReportService(db *gorm.DB, clk clock.Clock) *ReportService {
+	return &ReportService{db: db, clock: clk}
+}
+
+// GradebookService aggregates student results across quizzes and subjects
+type GradebookService struct {
+	db    *gorm.DB
+	clock clock.Clock
+}
+
+func NewGradebookService(db *gorm.DB, clk clock.Clock) *GradebookService {
+	return &GradebookService{db: db, clock: clk}
+}
+
+// Services is the full set of handler services sharing one database, clock, media store
+// and the repositories over that database
+type Services struct {
+	Auth           *AuthService
+	Users          *UserService
+	Institutions   *InstitutionService
+	Subjects       *SubjectService
+	Classes        *ClassService
+	Quizzes        *QuizService
+	Imports        *ImportService
+	Media          *MediaService
+	Batches        *BatchService
+	Attempts       *AttemptService
+	Accommodations *AccommodationService
+	Reports        *ReportService
+	Gradebook      *GradebookService
+}
+
+func NewServices(db *gorm.DB, clk clock.Clock, media blob.Store) *Services {
+	events := NewEventLogger(db, clk)
+	repos := repository.New(db)
+	return &Services{
+		Auth:           NewAuthService(db, clk, repos.Users),
+		Users:          NewUserService(db, clk, repos.Users),
+		Institutions:   NewInstitutionService(db, clk),
+		Subjects:       NewSubjectService(db, clk),
+		Classes:        NewClassService(db, clk),
+		Quizzes:        NewQuizService(db, clk, media),
+		Imports:        NewImportService(db, clk, media),
+		Media:          NewMediaService(db, clk, media),
+		Batches:        NewBatchService(db, clk, events),
+		Attempts:       NewAttemptService(db, clk, events),
+		Accommodations: NewAccommodationService(db, clk, events),
+		Reports:        NewReportService(db, clk),
+		Gradebook:      NewGradebookService(db, clk),
+	}
+}
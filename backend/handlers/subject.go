@@ -1,12 +1,12 @@
 package handlers
 
 import (
-	"academic-suite-backend/database"
+	"academic-suite-backend/apperr"
 	"academic-suite-backend/models"
-	"encoding/json"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 // SubjectResponse adds resolved teacher objects to the subject response
@@ -19,33 +19,32 @@ type SubjectResponse struct {
 	InstitutionID string         `json:"institutionId"`
 	TeacherIDs    []string       `json:"teacherIds"` // Raw IDs
 	Teachers      []UserResponse `json:"teachers"`   // Resolved objects
+	GradeWeights  GradeWeights   `json:"gradeWeights"`
 }
 
-func toSubjectResponse(s models.Subject) SubjectResponse {
-	var teacherIDs []string
-	if s.TeacherIDs != "" {
-		_ = json.Unmarshal([]byte(s.TeacherIDs), &teacherIDs)
-	}
+func (s *SubjectService) toSubjectResponse(subject models.Subject) SubjectResponse {
+	teacherIDs := subjectTeacherIDs(s.db, subject.ID)
 
 	var teachers []UserResponse = []UserResponse{}
 	if len(teacherIDs) > 0 {
 		var teacherUsers []models.User
-		// Find users where ID is in the list
-		database.DB.Where("id IN ?", teacherIDs).Find(&teacherUsers)
+		s.db.Joins("JOIN subject_teachers st ON st.teacher_id = users.id").
+			Where("st.subject_id = ?", subject.ID).Find(&teacherUsers)
 		for _, t := range teacherUsers {
 			teachers = append(teachers, toUserResponse(t))
 		}
 	}
 
 	return SubjectResponse{
-		ID:            s.ID,
-		DepartmentID:  s.DepartmentID,
-		Name:          s.Name,
-		Code:          s.Code,
-		Credits:       s.Credits,
-		InstitutionID: s.InstitutionID,
+		ID:            subject.ID,
+		DepartmentID:  subject.DepartmentID,
+		Name:          subject.Name,
+		Code:          subject.Code,
+		Credits:       subject.Credits,
+		InstitutionID: subject.InstitutionID,
 		TeacherIDs:    teacherIDs,
 		Teachers:      teachers,
+		GradeWeights:  subjectGradeWeights(subject),
 	}
 }
 
@@ -54,21 +53,27 @@ func toSubjectResponse(s models.Subject) SubjectResponse {
 // @Description  Retrieve a list of all subjects with resolved teachers
 // @Tags         subjects
 // @Produce      json
+// @Param        institutionId query string false "Institution ID"
+// @Param        teacherId     query string false "Only subjects taught by this teacher"
 // @Success      200  {array}  SubjectResponse
 // @Router       /api/subjects [get]
-func GetSubjects(c *fiber.Ctx) error {
+func (s *SubjectService) GetSubjects(c *fiber.Ctx) error {
 	institutionId := c.Query("institutionId")
+	teacherId := c.Query("teacherId")
 	var subjects []models.Subject
 
-	query := database.DB
+	query := s.db
 	if institutionId != "" {
 		query = query.Where("institution_id = ?", institutionId)
 	}
+	if teacherId != "" {
+		query = query.Where("id IN (?)", s.db.Model(&models.SubjectTeacher{}).Select("subject_id").Where("teacher_id = ?", teacherId))
+	}
 	query.Find(&subjects)
 
 	var responses []SubjectResponse
-	for _, s := range subjects {
-		responses = append(responses, toSubjectResponse(s))
+	for _, subject := range subjects {
+		responses = append(responses, s.toSubjectResponse(subject))
 	}
 
 	return c.JSON(responses)
@@ -81,15 +86,15 @@ func GetSubjects(c *fiber.Ctx) error {
 // @Produce      json
 // @Param        id   path      string  true  "Subject ID"
 // @Success      200  {object}  SubjectResponse
-// @Failure      404  {object}  map[string]string
+// @Failure      404  {object}  apperr.Response
 // @Router       /api/subjects/{id} [get]
-func GetSubject(c *fiber.Ctx) error {
+func (s *SubjectService) GetSubject(c *fiber.Ctx) error {
 	id := c.Params("id")
 	var subject models.Subject
-	if err := database.DB.First(&subject, "id = ?", id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Subject not found"})
+	if err := s.db.First(&subject, "id = ?", id).Error; err != nil {
+		return apperr.NotFound("subject_not_found")
 	}
-	return c.JSON(toSubjectResponse(subject))
+	return c.JSON(s.toSubjectResponse(subject))
 }
 
 // CreateSubject godoc
@@ -100,35 +105,38 @@ func GetSubject(c *fiber.Ctx) error {
 // @Produce      json
 // @Param        subject body models.Subject true "Subject Data"
 // @Success      200  {object}  SubjectResponse
-// @Failure      400  {object}  map[string]string
+// @Failure      400  {object}  apperr.Response
 // @Router       /api/subjects [post]
-func CreateSubject(c *fiber.Ctx) error {
+func (s *SubjectService) CreateSubject(c *fiber.Ctx) error {
 	type CreateReq struct {
-		Name          string   `json:"name"`
-		Code          string   `json:"code"`
-		Credits       int      `json:"credits"`
+		Name          string   `json:"name" validate:"required"`
+		Code          string   `json:"code" validate:"required"`
+		Credits       int      `json:"credits" validate:"min=0"`
 		TeacherIDs    []string `json:"teacherIds"`
 		DepartmentID  string   `json:"departmentId"`
 		InstitutionID string   `json:"institutionId"`
+		// Optional, defaults to 30/30/40
+		GradeWeights *GradeWeights `json:"gradeWeights"`
 	}
 
 	var req CreateReq
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 
-	if req.Name == "" || req.Code == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Name and Code are required"})
+	weights := DefaultGradeWeights
+	if req.GradeWeights != nil {
+		if err := req.GradeWeights.validate(); err != nil {
+			return err
+		}
+		weights = *req.GradeWeights
 	}
 
-	// Serialize TeacherIDs to JSON string
-	teacherIDsJSON, _ := json.Marshal(req.TeacherIDs)
-
 	// Get User to set InstitutionID
 	userId := c.Locals("userId").(string)
 	var user models.User
-	if err := database.DB.First(&user, "id = ?", userId).Error; err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+	if err := s.db.First(&user, "id = ?", userId).Error; err != nil {
+		return apperr.Unauthorized("user_not_found")
 	}
 
 	subject := models.Subject{
@@ -137,15 +145,21 @@ func CreateSubject(c *fiber.Ctx) error {
 		Code:          req.Code,
 		Credits:       req.Credits,
 		DepartmentID:  req.DepartmentID,
-		TeacherIDs:    string(teacherIDsJSON),
 		InstitutionID: user.InstitutionID,
 	}
+	weights.applyTo(&subject)
 
-	if err := database.DB.Create(&subject).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create subject"})
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&subject).Error; err != nil {
+			return err
+		}
+		return setSubjectTeachers(tx, subject.ID, req.TeacherIDs)
+	})
+	if err != nil {
+		return apperr.Internal("subject_create_failed", err)
 	}
 
-	return c.JSON(toSubjectResponse(subject))
+	return c.JSON(s.toSubjectResponse(subject))
 }
 
 // UpdateSubject godoc
@@ -158,12 +172,12 @@ func CreateSubject(c *fiber.Ctx) error {
 // @Param        subject body      CreateReq    true  "Subject Data"
 // @Success      200  {object}  SubjectResponse
 // @Router       /api/subjects/{id} [put]
-func UpdateSubject(c *fiber.Ctx) error {
+func (s *SubjectService) UpdateSubject(c *fiber.Ctx) error {
 	id := c.Params("id")
 
 	var subject models.Subject
-	if err := database.DB.First(&subject, "id = ?", id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Subject not found"})
+	if err := s.db.First(&subject, "id = ?", id).Error; err != nil {
+		return apperr.NotFound("subject_not_found")
 	}
 
 	type UpdateReq struct {
@@ -172,11 +186,13 @@ func UpdateSubject(c *fiber.Ctx) error {
 		Credits      int      `json:"credits"`
 		TeacherIDs   []string `json:"teacherIds"`
 		DepartmentID string   `json:"departmentId"`
+		// Omit to keep the current weights
+		GradeWeights *GradeWeights `json:"gradeWeights"`
 	}
 
 	var req UpdateReq
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 
 	if req.Name != "" {
@@ -187,22 +203,34 @@ func UpdateSubject(c *fiber.Ctx) error {
 	}
 	subject.Credits = req.Credits // Allow 0?
 	subject.DepartmentID = req.DepartmentID
+	if req.GradeWeights != nil {
+		if err := req.GradeWeights.validate(); err != nil {
+			return err
+		}
+		req.GradeWeights.applyTo(&subject)
+	}
 
 	// Ensure InstitutionID matches the user updating it (or prevent moving to another inst)
 	userId := c.Locals("userId").(string)
 	var user models.User
-	if err := database.DB.First(&user, "id = ?", userId).Error; err == nil {
+	if err := s.db.First(&user, "id = ?", userId).Error; err == nil {
 		subject.InstitutionID = user.InstitutionID
 	}
 
-	if req.TeacherIDs != nil {
-		teacherIDsJSON, _ := json.Marshal(req.TeacherIDs)
-		subject.TeacherIDs = string(teacherIDsJSON)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&subject).Error; err != nil {
+			return err
+		}
+		if req.TeacherIDs == nil {
+			return nil
+		}
+		return setSubjectTeachers(tx, subject.ID, req.TeacherIDs)
+	})
+	if err != nil {
+		return apperr.Internal("subject_update_failed", err)
 	}
 
-	database.DB.Save(&subject)
-
-	return c.JSON(toSubjectResponse(subject))
+	return c.JSON(s.toSubjectResponse(subject))
 }
 
 // DeleteSubject godoc
@@ -212,10 +240,10 @@ func UpdateSubject(c *fiber.Ctx) error {
 // @Param        id   path      string  true  "Subject ID"
 // @Success      204
 // @Router       /api/subjects/{id} [delete]
-func DeleteSubject(c *fiber.Ctx) error {
+func (s *SubjectService) DeleteSubject(c *fiber.Ctx) error {
 	id := c.Params("id")
-	if err := database.DB.Delete(&models.Subject{}, "id = ?", id).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not delete subject"})
+	if err := s.db.Delete(&models.Subject{}, "id = ?", id).Error; err != nil {
+		return apperr.Internal("subject_delete_failed", err)
 	}
 	return c.SendStatus(fiber.StatusNoContent)
 }
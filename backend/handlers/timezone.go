@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/clock"
+	"academic-suite-backend/models"
+	"academic-suite-backend/validation"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Batch schedules are typed as wall-clock times ("2025-12-22T14:50") and mean that time in the
+// institution's zone, not the server's or UTC. The zone is stored on the batch so later edits
+// and responses use the same one even if the institution setting changes.
+
+// batchTimezone picks the zone for a batch schedule: the one in the request, else the quiz's
+// institution, else the creator's institution, else clock.DefaultTimezone
+func batchTimezone(db *gorm.DB, requested, quizID, userID string) (string, *time.Location, error) {
+	if requested != "" {
+		loc, err := loadTimezone(requested)
+		return requested, loc, err
+	}
+
+	institutionID := ""
+	if quizID != "" {
+		db.Model(&models.Quiz{}).Where("id = ?", quizID).Pluck("institution_id", &institutionID)
+	}
+	if institutionID == "" && userID != "" {
+		db.Model(&models.User{}).Where("id = ?", userID).Pluck("institution_id", &institutionID)
+	}
+
+	name := clock.DefaultTimezone
+	if institutionID != "" {
+		var inst models.Institution
+		if err := db.Select("timezone").First(&inst, "id = ?", institutionID).Error; err == nil && inst.Timezone != "" {
+			name = inst.Timezone
+		}
+	}
+
+	loc, err := clock.LoadLocation(name)
+	if err != nil {
+		// A bad stored setting should not block scheduling
+		name = clock.DefaultTimezone
+		loc, err = clock.LoadLocation(name)
+	}
+	return name, loc, err
+}
+
+// batchLocation is the zone a stored batch was scheduled in
+func batchLocation(b models.ExamBatch) *time.Location {
+	loc, err := clock.LoadLocation(b.Timezone)
+	if err != nil {
+		loc, _ = clock.LoadLocation(clock.DefaultTimezone)
+	}
+	return loc
+}
+
+// parseSchedule parses start and end in loc and checks that the window is not empty
+func parseSchedule(start, end string, loc *time.Location) (time.Time, time.Time, error) {
+	startTime, err := clock.ParseInZone(start, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, scheduleError("startTime", start, err)
+	}
+	endTime, err := clock.ParseInZone(end, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, scheduleError("endTime", end, err)
+	}
+	if !endTime.After(startTime) {
+		return time.Time{}, time.Time{}, apperr.BadRequest("end_before_start").WithDetails(validation.Field("endTime", "after_start"))
+	}
+	return startTime, endTime, nil
+}
+
+// loadTimezone is clock.LoadLocation with a catalog error
+func loadTimezone(name string) (*time.Location, error) {
+	loc, err := clock.LoadLocation(name)
+	if err != nil {
+		return nil, apperr.BadRequest("unknown_timezone", name)
+	}
+	return loc, nil
+}
+
+// scheduleError turns a clock.ParseInZone error for field into a catalog error,
+// with the field in the details like other validation failures
+func scheduleError(field, value string, err error) error {
+	switch {
+	case strings.TrimSpace(value) == "":
+		return apperr.BadRequest("time_required", field).WithDetails(validation.Field(field, "required"))
+	case errors.Is(err, clock.ErrNonexistentLocalTime):
+		return apperr.BadRequest("time_in_dst_gap", field, value).WithDetails(validation.Field(field, "dst_gap"))
+	default:
+		return apperr.BadRequest("invalid_time", field, value).WithDetails(validation.Field(field, "datetime"))
+	}
+}
+
+// scheduleMinutes is the real length of the window, so a batch spanning a DST change
+// gets the minutes that actually elapse rather than the wall-clock difference
+func scheduleMinutes(start, end time.Time) int {
+	return int(end.Sub(start).Minutes())
+}
@@ -1,8 +1,10 @@
 package handlers
 
 import (
-	"academic-suite-backend/database"
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/i18n"
 	"academic-suite-backend/models"
+	"academic-suite-backend/repository"
 	"math"
 	"strconv"
 	"time"
@@ -19,6 +21,9 @@ type UserResponse struct {
 	Role          models.UserRole `json:"role"`
 	InstitutionID string          `json:"institutionId"`
 	AvatarURL     string          `json:"avatarUrl"`
+	Locale        string          `json:"locale"`
+	NIS           string          `json:"nis"`
+	NISN          string          `json:"nisn"`
 	CreatedAt     time.Time       `json:"createdAt"`
 }
 
@@ -30,6 +35,9 @@ func toUserResponse(u models.User) UserResponse {
 		Role:          u.Role,
 		InstitutionID: u.InstitutionID,
 		AvatarURL:     u.AvatarURL,
+		Locale:        u.Locale,
+		NIS:           u.NIS,
+		NISN:          u.NISN,
 		CreatedAt:     u.CreatedAt,
 	}
 }
@@ -45,7 +53,7 @@ func toUserResponse(u models.User) UserResponse {
 // @Param        institutionId query     string  false  "Filter by Institution ID"
 // @Success      200           {object}  map[string]interface{}
 // @Router       /api/users [get]
-func GetUsers(c *fiber.Ctx) error {
+func (s *UserService) GetUsers(c *fiber.Ctx) error {
 	page, _ := strconv.Atoi(c.Query("page", "1"))
 	limit, _ := strconv.Atoi(c.Query("limit", "10"))
 	search := c.Query("search", "")
@@ -61,27 +69,17 @@ func GetUsers(c *fiber.Ctx) error {
 
 	offset := (page - 1) * limit
 
-	var users []models.User
-	var total int64
-
-	query := database.DB.Model(&models.User{})
-
-	if institutionId != "" {
-		query = query.Where("institution_id = ?", institutionId)
-	}
-	if role != "" {
-		query = query.Where("role = ?", role)
-	}
-
-	if search != "" {
-		searchLike := "%" + search + "%"
-		query = query.Where("name ILIKE ? OR email ILIKE ?", searchLike, searchLike)
+	users, total, err := s.users.List(repository.UserFilter{
+		InstitutionID: institutionId,
+		Role:          role,
+		Search:        search,
+		Offset:        offset,
+		Limit:         limit,
+	})
+	if err != nil {
+		return apperr.Internal("users_load_failed", err)
 	}
 
-	query.Count(&total)
-
-	query.Offset(offset).Limit(limit).Order("created_at desc").Find(&users)
-
 	totalPages := int(math.Ceil(float64(total) / float64(limit)))
 
 	// Map to response safe struct
@@ -109,30 +107,27 @@ func GetUsers(c *fiber.Ctx) error {
 // @Produce      json
 // @Param        user body models.User true "User Data"
 // @Success      200  {object}  UserResponse
-// @Failure      400  {object}  map[string]string
+// @Failure      400  {object}  apperr.Response
 // @Router       /api/users [post]
-func CreateUser(c *fiber.Ctx) error {
+func (s *UserService) CreateUser(c *fiber.Ctx) error {
 	type CreateReq struct {
-		Email         string          `json:"email"`
-		Password      string          `json:"password"`
-		Name          string          `json:"name"`
-		Role          models.UserRole `json:"role"`
+		Email         string          `json:"email" validate:"required,email"`
+		Password      string          `json:"password" validate:"required,min=6,max=72"`
+		Name          string          `json:"name" validate:"required"`
+		Role          models.UserRole `json:"role" validate:"required,oneof=admin teacher student"`
 		InstitutionID string          `json:"institutionId"`
+		NIS           string          `json:"nis" validate:"digits,max=20"`
+		NISN          string          `json:"nisn" validate:"nisn"`
 	}
 
 	var req CreateReq
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
-	}
-
-	// Basic validation
-	if req.Email == "" || req.Password == "" || req.Name == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Missing required fields"})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not hash password"})
+		return apperr.Internal("password_hash_failed", err)
 	}
 
 	user := models.User{
@@ -142,11 +137,13 @@ func CreateUser(c *fiber.Ctx) error {
 		Name:          req.Name,
 		Role:          req.Role,
 		InstitutionID: req.InstitutionID,
-		CreatedAt:     time.Now(),
+		NIS:           req.NIS,
+		NISN:          req.NISN,
+		CreatedAt:     s.clock.Now(),
 	}
 
-	if err := database.DB.Create(&user).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create user (email might be taken)"})
+	if err := s.users.Create(&user); err != nil {
+		return apperr.Internal("user_create_failed", err)
 	}
 
 	return c.JSON(toUserResponse(user))
@@ -162,24 +159,26 @@ func CreateUser(c *fiber.Ctx) error {
 // @Param        user body      models.User  true  "User Data"
 // @Success      200  {object}  UserResponse
 // @Router       /api/users/{id} [put]
-func UpdateUser(c *fiber.Ctx) error {
+func (s *UserService) UpdateUser(c *fiber.Ctx) error {
 	id := c.Params("id")
-
-	var user models.User
-	if err := database.DB.First(&user, "id = ?", id).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	user, err := s.users.FindByID(id)
+	if err != nil {
+		return apperr.NotFound("user_not_found")
 	}
 
 	type UpdateReq struct {
 		Name          string          `json:"name"`
-		Role          models.UserRole `json:"role"`
+		Role          models.UserRole `json:"role" validate:"omitempty,oneof=admin teacher student"`
 		InstitutionID string          `json:"institutionId"`
+		Locale        *string         `json:"locale"` // "" clears the preference
+		NIS           *string         `json:"nis" validate:"omitnil,digits,max=20"`
+		NISN          *string         `json:"nisn" validate:"omitnil,nisn"`
 		// Password updates should be a separate secure endpoint usually, keeping simple for now
 	}
 
 	var req UpdateReq
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
+	if err := parseBody(c, &req); err != nil {
+		return err
 	}
 
 	if req.Name != "" {
@@ -191,8 +190,23 @@ func UpdateUser(c *fiber.Ctx) error {
 	if req.InstitutionID != "" {
 		user.InstitutionID = req.InstitutionID
 	}
+	if req.NIS != nil {
+		user.NIS = *req.NIS
+	}
+	if req.NISN != nil {
+		user.NISN = *req.NISN
+	}
+	if req.Locale != nil {
+		if *req.Locale == "" {
+			user.Locale = ""
+		} else if l, ok := i18n.Parse(*req.Locale); ok {
+			user.Locale = string(l)
+		} else {
+			return apperr.BadRequest("invalid_locale")
+		}
+	}
 
-	database.DB.Save(&user)
+	s.users.Save(user)
 
-	return c.JSON(toUserResponse(user))
+	return c.JSON(toUserResponse(*user))
 }
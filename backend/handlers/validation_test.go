@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"academic-suite-backend/models"
+	"academic-suite-backend/validation"
+	"net/http"
+	"testing"
+)
+
+// validationResponse is an error response with its field errors decoded
+type validationResponse struct {
+	Error struct {
+		Code    string                  `json:"code"`
+		Details []validation.FieldError `json:"details"`
+	} `json:"error"`
+}
+
+func fieldRules(errs []validation.FieldError) map[string]string {
+	rules := make(map[string]string)
+	for _, fe := range errs {
+		rules[fe.Field] = fe.Rule
+	}
+	return rules
+}
+
+func TestCreateQuizValidatesQuestions(t *testing.T) {
+	e := newTestEnv(t)
+	e.create(&models.User{ID: "teacher-1", Email: "guru@example.com", Role: models.RoleTeacher})
+
+	question := func(typ string, points int, correct ...bool) map[string]interface{} {
+		options := []map[string]interface{}{}
+		for i, c := range correct {
+			options = append(options, map[string]interface{}{"text": string(rune('A' + i)), "isCorrect": c})
+		}
+		return map[string]interface{}{"type": typ, "text": "Soal", "points": points, "options": options}
+	}
+
+	var res validationResponse
+	status := e.do("POST", "/api/quizzes", map[string]interface{}{
+		"title": "Matematika",
+		"questions": []interface{}{
+			question("mcq", 5, false, true, false), // fine
+			question("mcq", 5, false, false),       // no correct option
+			question("mcq", 0, true, true),         // no points, two correct
+			question("true_false", 5, true),        // one option
+			question("essay", 10),                  // fine without options
+		},
+	}, &res, "X-User", "teacher-1", "Accept-Language", "en")
+	if status != http.StatusBadRequest || res.Error.Code != "validation_failed" {
+		t.Fatalf("status %d code %q", status, res.Error.Code)
+	}
+
+	got := fieldRules(res.Error.Details)
+	want := map[string]string{
+		"questions[1].options": "one_correct_option",
+		"questions[2].points":  "gt",
+		"questions[2].options": "one_correct_option",
+		"questions[3].options": "len_items",
+	}
+	if len(got) != len(want) {
+		t.Errorf("field errors = %v, want %v", got, want)
+	}
+	for field, rule := range want {
+		if got[field] != rule {
+			t.Errorf("%s: rule %q, want %q", field, got[field], rule)
+		}
+	}
+	for _, fe := range res.Error.Details {
+		if fe.Field == "questions[1].options" && fe.Message != "Exactly one option must be correct (got 0)" {
+			t.Errorf("message = %q", fe.Message)
+		}
+	}
+
+	status = e.do("POST", "/api/quizzes", map[string]interface{}{
+		"title": "Matematika", "questions": []interface{}{question("mcq", 5, false, true)},
+	}, nil, "X-User", "teacher-1")
+	if status != http.StatusOK {
+		t.Errorf("valid quiz: status %d", status)
+	}
+}
+
+func TestCreateBatchReportsFieldErrors(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedInstitution("Asia/Jakarta")
+
+	cases := []struct {
+		name  string
+		body  map[string]interface{}
+		field string
+		rule  string
+	}{
+		{"end before start", map[string]interface{}{"quizId": "quiz-1", "startTime": "2025-12-22T10:00", "endTime": "2025-12-22T09:00"}, "endTime", "after_start"},
+		{"missing quiz", map[string]interface{}{"startTime": "2025-12-22T10:00", "endTime": "2025-12-22T11:00"}, "quizId", "required"},
+		{"negative capacity", map[string]interface{}{"quizId": "quiz-1", "startTime": "2025-12-22T10:00", "endTime": "2025-12-22T11:00", "capacity": -1}, "capacity", "min"},
+		{"missing end", map[string]interface{}{"quizId": "quiz-1", "startTime": "2025-12-22T10:00"}, "endTime", "required"},
+	}
+	for _, tc := range cases {
+		var res validationResponse
+		if status := e.do("POST", "/api/batches", tc.body, &res, "X-User", "teacher-1"); status != http.StatusBadRequest {
+			t.Errorf("%s: status %d", tc.name, status)
+			continue
+		}
+		if rule := fieldRules(res.Error.Details)[tc.field]; rule != tc.rule {
+			t.Errorf("%s: %s rule %q, want %q (%+v)", tc.name, tc.field, rule, tc.rule, res.Error.Details)
+		}
+	}
+}
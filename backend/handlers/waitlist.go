@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errAlreadyParticipant = apperr.Conflict("already_participant")
+	errAlreadyWaitlisted  = apperr.Conflict("already_waitlisted")
+	errNotWaitlisted      = apperr.BadRequest("not_waitlisted")
+	errNotParticipant     = apperr.BadRequest("not_participant")
+	errBadWaitlistOrder   = apperr.BadRequest("invalid_waitlist_order")
+	errBatchOpenToAll     = apperr.BadRequest("batch_open_to_all")
+	errLastParticipant    = apperr.BadRequest("last_participant")
+)
+
+func indexOf(ids []string, id string) int {
+	for i, v := range ids {
+		if v == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func removeAt(ids []string, i int) []string {
+	return append(ids[:i:i], ids[i+1:]...)
+}
+
+// batchOpenToAll reports whether any student may sit the batch: it has neither a capacity nor
+// participants. Every other batch is restricted to its participants.
+func batchOpenToAll(db *gorm.DB, batch *models.ExamBatch) bool {
+	return batch.Capacity <= 0 && countBatchParticipants(db, batch.ID) == 0
+}
+
+// seatsAvailable reports whether the batch can take another participant
+func seatsAvailable(batch *models.ExamBatch, participantCount int) bool {
+	return batch.Capacity <= 0 || participantCount < batch.Capacity
+}
+
+// enforceCapacity moves participants beyond capacity to the front of the waitlist
+func enforceCapacity(tx *gorm.DB, batch *models.ExamBatch, now time.Time) error {
+	if batch.Capacity <= 0 {
+		return nil
+	}
+	participants := batchParticipantIDs(tx, batch.ID)
+	if len(participants) <= batch.Capacity {
+		return nil
+	}
+	overflow := participants[batch.Capacity:]
+	for _, id := range overflow {
+		if _, err := removeBatchParticipant(tx, batch.ID, id); err != nil {
+			return err
+		}
+	}
+	waitlist := append(append([]string{}, overflow...), batchWaitlistIDs(tx, batch.ID)...)
+	return setBatchWaitlist(tx, batch.ID, waitlist, now)
+}
+
+// promoteWaitlist fills free seats from the head of the waitlist and returns who was promoted
+func promoteWaitlist(tx *gorm.DB, batch *models.ExamBatch, now time.Time) ([]string, error) {
+	count := countBatchParticipants(tx, batch.ID)
+	waitlist := batchWaitlistIDs(tx, batch.ID)
+
+	promoted := []string{}
+	for len(waitlist) > 0 && seatsAvailable(batch, count) {
+		next := waitlist[0]
+		waitlist = waitlist[1:]
+		if isBatchParticipant(tx, batch.ID, next) {
+			continue
+		}
+		if err := addBatchParticipants(tx, batch.ID, []string{next}, now); err != nil {
+			return nil, err
+		}
+		count++
+		promoted = append(promoted, next)
+	}
+
+	if len(promoted) == 0 {
+		return promoted, nil
+	}
+	return promoted, setBatchWaitlist(tx, batch.ID, waitlist, now)
+}
+
+// releaseNoShows drops participants who have not started within the grace period
+// and hands their seats to the waitlist. Only runs while there is someone waiting.
+func releaseNoShows(tx *gorm.DB, batch *models.ExamBatch, now time.Time) (released, promoted []string, err error) {
+	if batch.NoShowGraceMinutes <= 0 || batch.Status == models.StatusFinished {
+		return nil, nil, nil
+	}
+	if now.Before(batch.StartTime.Add(time.Duration(batch.NoShowGraceMinutes) * time.Minute)) {
+		return nil, nil, nil
+	}
+	waiting := len(batchWaitlistIDs(tx, batch.ID))
+	if waiting == 0 {
+		return nil, nil, nil
+	}
+
+	// Participants without any attempt, release only as many seats as there are people waiting
+	var noShows []string
+	tx.Model(&models.BatchParticipant{}).
+		Where("batch_id = ? AND NOT EXISTS (SELECT 1 FROM attempts a WHERE a.batch_id = batch_participants.batch_id AND a.student_id = batch_participants.student_id)", batch.ID).
+		Order("created_at, student_id").Limit(waiting).Pluck("student_id", &noShows)
+	if len(noShows) == 0 {
+		return nil, nil, nil
+	}
+
+	if err := tx.Where("batch_id = ? AND student_id IN ?", batch.ID, noShows).Delete(&models.BatchParticipant{}).Error; err != nil {
+		return nil, nil, err
+	}
+	promoted, err = promoteWaitlist(tx, batch, now)
+	return noShows, promoted, err
+}
+
+// withLockedBatch loads a batch FOR UPDATE and runs fn in the same transaction,
+// serializing seat changes for that batch
+func withLockedBatch(db *gorm.DB, id string, fn func(tx *gorm.DB, batch *models.ExamBatch) error) (*models.ExamBatch, error) {
+	var batch models.ExamBatch
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&batch, "id = ?", id).Error; err != nil {
+			return err
+		}
+		return fn(tx, &batch)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+func logPromotions(events *EventLogger, batchID, actor string, promoted []string) {
+	for _, studentID := range promoted {
+		events.Log(models.EventWaitlistPromo, batchID, "", studentID, fmt.Sprintf("Promoted from waitlist (triggered by %s)", actor))
+	}
+}
+
+// waitlistError maps errors from the waitlist transactions; the waitlist errors above carry their status
+func waitlistError(err error) error {
+	var appErr *apperr.Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return errBatchNotFound
+	case errors.As(err, &appErr):
+		return err
+	}
+	return apperr.Internal("waitlist_update_failed", err)
+}
+
+type WaitlistJoinReq struct {
+	StudentID string `json:"studentId"` // defaults to the logged-in user
+}
+
+// checkOwnStudent keeps a student caller to their own waitlist entry
+func checkOwnStudent(c *fiber.Ctx, studentID string) error {
+	if role, _ := c.Locals("role").(string); models.UserRole(role) == models.RoleStudent {
+		if userId, _ := c.Locals("userId").(string); userId != studentID {
+			return apperr.Forbidden("forbidden")
+		}
+	}
+	return nil
+}
+
+type WaitlistOrderReq struct {
+	Order []string `json:"order"`
+}
+
+// JoinWaitlist godoc
+// @Summary      Join Batch Waitlist
+// @Description  Enroll directly if a seat is free, otherwise append to the waitlist
+// @Tags         batches
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string           true  "Batch ID"
+// @Param        req  body      WaitlistJoinReq  true  "Student"
+// @Success      200  {object}  BatchResponse
+// @Failure      403  {object}  apperr.Response
+// @Failure      404  {object}  apperr.Response
+// @Router       /api/batches/{id}/waitlist [post]
+func (s *BatchService) JoinWaitlist(c *fiber.Ctx) error {
+	var req WaitlistJoinReq
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+	if req.StudentID == "" {
+		req.StudentID, _ = c.Locals("userId").(string)
+	}
+	if err := checkOwnStudent(c, req.StudentID); err != nil {
+		return err
+	}
+	var student models.User
+	if err := s.db.First(&student, "id = ? AND role = ?", req.StudentID, models.RoleStudent).Error; err != nil {
+		return apperr.NotFound("student_not_found")
+	}
+
+	enrolled := false
+	position := 0
+	now := s.clock.Now()
+	batch, err := withLockedBatch(s.db, c.Params("id"), func(tx *gorm.DB, b *models.ExamBatch) error {
+		if isBatchParticipant(tx, b.ID, req.StudentID) {
+			return errAlreadyParticipant
+		}
+		// Enrolling the first student would lock everyone else out of an open batch
+		if batchOpenToAll(tx, b) {
+			return errBatchOpenToAll
+		}
+		waitlist := batchWaitlistIDs(tx, b.ID)
+		if indexOf(waitlist, req.StudentID) >= 0 {
+			return errAlreadyWaitlisted
+		}
+
+		if len(waitlist) == 0 && seatsAvailable(b, countBatchParticipants(tx, b.ID)) {
+			enrolled = true
+			return addBatchParticipants(tx, b.ID, []string{req.StudentID}, now)
+		}
+		position = len(waitlist) + 1
+		return setBatchWaitlist(tx, b.ID, append(waitlist, req.StudentID), now)
+	})
+	if err != nil {
+		return waitlistError(err)
+	}
+
+	if enrolled {
+		s.events.Log(models.EventWaitlistPromo, batch.ID, "", req.StudentID, "Seat available, enrolled directly")
+	} else {
+		s.events.Log(models.EventWaitlistJoin, batch.ID, "", req.StudentID, fmt.Sprintf("Joined waitlist at position %d", position))
+	}
+	return c.JSON(s.toBatchResponse(*batch))
+}
+
+// LeaveWaitlist godoc
+// @Summary      Leave Batch Waitlist
+// @Tags         batches
+// @Param        id         path  string  true  "Batch ID"
+// @Param        studentId  path  string  true  "Student ID"
+// @Success      200  {object}  BatchResponse
+// @Failure      403  {object}  apperr.Response
+// @Router       /api/batches/{id}/waitlist/{studentId} [delete]
+func (s *BatchService) LeaveWaitlist(c *fiber.Ctx) error {
+	studentId := c.Params("studentId")
+	if err := checkOwnStudent(c, studentId); err != nil {
+		return err
+	}
+	now := s.clock.Now()
+	batch, err := withLockedBatch(s.db, c.Params("id"), func(tx *gorm.DB, b *models.ExamBatch) error {
+		waitlist := batchWaitlistIDs(tx, b.ID)
+		i := indexOf(waitlist, studentId)
+		if i < 0 {
+			return errNotWaitlisted
+		}
+		return setBatchWaitlist(tx, b.ID, removeAt(waitlist, i), now)
+	})
+	if err != nil {
+		return waitlistError(err)
+	}
+
+	s.events.Log(models.EventWaitlistLeave, batch.ID, "", studentId, "Left waitlist")
+	return c.JSON(s.toBatchResponse(*batch))
+}
+
+// ReorderWaitlist godoc
+// @Summary      Reorder Batch Waitlist
+// @Description  Replace the waitlist order; must contain exactly the current waitlist members
+// @Tags         batches
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string            true  "Batch ID"
+// @Param        req  body      WaitlistOrderReq  true  "New order"
+// @Success      200  {object}  BatchResponse
+// @Router       /api/batches/{id}/waitlist [put]
+func (s *BatchService) ReorderWaitlist(c *fiber.Ctx) error {
+	var req WaitlistOrderReq
+	if err := parseBody(c, &req); err != nil {
+		return err
+	}
+
+	now := s.clock.Now()
+	batch, err := withLockedBatch(s.db, c.Params("id"), func(tx *gorm.DB, b *models.ExamBatch) error {
+		current := batchWaitlistIDs(tx, b.ID)
+		if len(current) != len(req.Order) {
+			return errBadWaitlistOrder
+		}
+		seen := make(map[string]bool)
+		for _, id := range req.Order {
+			if seen[id] || indexOf(current, id) < 0 {
+				return errBadWaitlistOrder
+			}
+			seen[id] = true
+		}
+		return setBatchWaitlist(tx, b.ID, req.Order, now)
+	})
+	if err != nil {
+		return waitlistError(err)
+	}
+
+	userId, _ := c.Locals("userId").(string)
+	s.events.Log(models.EventWaitlistOrder, batch.ID, "", userId, fmt.Sprintf("Waitlist reordered: %v", req.Order))
+	return c.JSON(s.toBatchResponse(*batch))
+}
+
+// RemoveParticipant godoc
+// @Summary      Remove Batch Participant
+// @Description  Remove a participant and promote the next student from the waitlist
+// @Tags         batches
+// @Param        id         path  string  true  "Batch ID"
+// @Param        studentId  path  string  true  "Student ID"
+// @Success      200  {object}  BatchResponse
+// @Router       /api/batches/{id}/participants/{studentId} [delete]
+func (s *BatchService) RemoveParticipant(c *fiber.Ctx) error {
+	studentId := c.Params("studentId")
+	var promoted []string
+	now := s.clock.Now()
+	batch, err := withLockedBatch(s.db, c.Params("id"), func(tx *gorm.DB, b *models.ExamBatch) error {
+		// Without a capacity, removing the last participant would open the batch to everyone
+		if b.Capacity <= 0 && countBatchParticipants(tx, b.ID) == 1 && isBatchParticipant(tx, b.ID, studentId) {
+			return errLastParticipant
+		}
+		removed, err := removeBatchParticipant(tx, b.ID, studentId)
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return errNotParticipant
+		}
+		promoted, err = promoteWaitlist(tx, b, now)
+		return err
+	})
+	if err != nil {
+		return waitlistError(err)
+	}
+
+	userId, _ := c.Locals("userId").(string)
+	s.events.Log(models.EventSeatReleased, batch.ID, "", studentId, "Removed from batch by "+userId)
+	logPromotions(s.events, batch.ID, "participant removal", promoted)
+	return c.JSON(s.toBatchResponse(*batch))
+}
+
+// processNoShows runs the no-show release for a batch outside of a request-specific transaction.
+// Batch listings and attempt starts both call it, so seats move even if nobody lists batches.
+func processNoShows(db *gorm.DB, events *EventLogger, batchID string, now time.Time) {
+	var released, promoted []string
+	_, err := withLockedBatch(db, batchID, func(tx *gorm.DB, b *models.ExamBatch) error {
+		var err error
+		released, promoted, err = releaseNoShows(tx, b, now)
+		return err
+	})
+	if err != nil {
+		return
+	}
+	for _, studentID := range released {
+		events.Log(models.EventNoShow, batchID, "", studentID, "Did not start within the grace period, seat released")
+	}
+	logPromotions(events, batchID, "no-show", promoted)
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"academic-suite-backend/models"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (e *testEnv) join(batchID, studentID string) (int, BatchResponse) {
+	e.t.Helper()
+	var res BatchResponse
+	status := e.do("POST", "/api/batches/"+batchID+"/waitlist", fiber.Map{"studentId": studentID}, &res)
+	return status, res
+}
+
+func (e *testEnv) seats(batchID string) (participants, waitlist []string) {
+	return batchParticipantIDs(e.db, batchID), batchWaitlistIDs(e.db, batchID)
+}
+
+func TestWaitlistCapacityPromotionAndReorder(t *testing.T) {
+	e := newTestEnv(t)
+	batch := e.seedExam()
+	for _, id := range []string{"student-2", "student-3", "student-4"} {
+		e.create(&models.User{ID: id, Email: id + "@example.com", Role: models.RoleStudent})
+	}
+
+	// A batch without capacity or participants is open to all: nobody joins it
+	var res validationResponse
+	if status := e.do("POST", "/api/batches/batch-1/waitlist", fiber.Map{"studentId": "student-1"}, &res); status != http.StatusBadRequest || res.Error.Code != "batch_open_to_all" {
+		t.Fatalf("join open batch: status %d code %q", status, res.Error.Code)
+	}
+	if attempt := e.start("student-2"); attempt.ID == "" {
+		t.Fatal("open batch refused a student")
+	}
+
+	e.db.Model(&batch).Update("capacity", 2)
+	for _, id := range []string{"student-1", "student-2", "student-3", "student-4"} {
+		e.clock.Advance(time.Second)
+		if status, _ := e.join("batch-1", id); status != http.StatusOK {
+			t.Fatalf("join %s: status %d", id, status)
+		}
+	}
+	participants, waitlist := e.seats("batch-1")
+	if !reflect.DeepEqual(participants, []string{"student-1", "student-2"}) || !reflect.DeepEqual(waitlist, []string{"student-3", "student-4"}) {
+		t.Fatalf("seats = %v, waitlist = %v", participants, waitlist)
+	}
+	if status, _ := e.join("batch-1", "student-3"); status != http.StatusConflict {
+		t.Errorf("join twice: status %d, want 409", status)
+	}
+	if code := e.do("POST", "/api/attempts/start", fiber.Map{"batchId": "batch-1", "studentId": "student-3"}, nil); code != http.StatusForbidden {
+		t.Errorf("waitlisted start: status %d, want 403", code)
+	}
+
+	if status := e.do("PUT", "/api/batches/batch-1/waitlist", fiber.Map{"order": []string{"student-4"}}, nil); status != http.StatusBadRequest {
+		t.Errorf("partial order: status %d, want 400", status)
+	}
+	if status := e.do("PUT", "/api/batches/batch-1/waitlist", fiber.Map{"order": []string{"student-4", "student-3"}}, nil); status != http.StatusOK {
+		t.Fatalf("reorder: status %d", status)
+	}
+
+	// A freed seat goes to the head of the reordered waitlist
+	if status := e.do("DELETE", "/api/batches/batch-1/participants/student-2", nil, nil); status != http.StatusOK {
+		t.Fatalf("remove: status %d", status)
+	}
+	participants, waitlist = e.seats("batch-1")
+	if !reflect.DeepEqual(participants, []string{"student-1", "student-4"}) || !reflect.DeepEqual(waitlist, []string{"student-3"}) {
+		t.Fatalf("after removal seats = %v, waitlist = %v", participants, waitlist)
+	}
+}
+
+// No-show seats are released when a waitlisted student starts, even if nobody lists batches
+func TestNoShowSeatReleasedOnStart(t *testing.T) {
+	e := newTestEnv(t)
+	batch := e.seedExam()
+	e.create(
+		&models.User{ID: "student-2", Email: "siswa2@example.com", Role: models.RoleStudent},
+		&models.User{ID: "student-3", Email: "siswa3@example.com", Role: models.RoleStudent},
+	)
+	e.db.Model(&batch).Updates(map[string]interface{}{"capacity": 2, "no_show_grace_minutes": 15})
+	for _, id := range []string{"student-1", "student-2", "student-3"} {
+		e.clock.Advance(time.Second)
+		e.join("batch-1", id)
+	}
+	e.start("student-1")
+
+	// Within the grace period student-2 keeps the seat
+	e.clock.Advance(10 * time.Minute)
+	if code := e.do("POST", "/api/attempts/start", fiber.Map{"batchId": "batch-1", "studentId": "student-3"}, nil); code != http.StatusForbidden {
+		t.Fatalf("start within grace: status %d, want 403", code)
+	}
+
+	e.clock.Advance(10 * time.Minute)
+	if attempt := e.start("student-3"); attempt.Status != models.AttemptActive {
+		t.Fatalf("start after grace = %+v", attempt)
+	}
+	participants, waitlist := e.seats("batch-1")
+	if !reflect.DeepEqual(participants, []string{"student-1", "student-3"}) || len(waitlist) != 0 {
+		t.Fatalf("seats = %v, waitlist = %v", participants, waitlist)
+	}
+	var noShows int64
+	e.db.Model(&models.EventLog{}).Where("event_type = ? AND user_id = ?", models.EventNoShow, "student-2").Count(&noShows)
+	if noShows != 1 {
+		t.Errorf("no-show events = %d, want 1", noShows)
+	}
+}
+
+// Removing the last participant of a batch without capacity would quietly open it to everyone
+func TestLastParticipantOfUncappedBatchStays(t *testing.T) {
+	e := newTestEnv(t)
+	e.seedExam()
+	e.create(
+		&models.User{ID: "student-2", Email: "siswa2@example.com", Role: models.RoleStudent},
+		&models.BatchParticipant{BatchID: "batch-1", StudentID: "student-1", CreatedAt: testStart},
+	)
+
+	if code := e.do("POST", "/api/attempts/start", fiber.Map{"batchId": "batch-1", "studentId": "student-2"}, nil); code != http.StatusForbidden {
+		t.Fatalf("non-participant start: status %d, want 403", code)
+	}
+	var res validationResponse
+	if status := e.do("DELETE", "/api/batches/batch-1/participants/student-1", nil, &res); status != http.StatusBadRequest || res.Error.Code != "last_participant" {
+		t.Fatalf("remove last: status %d code %q", status, res.Error.Code)
+	}
+
+	// With a second participant the first can go
+	if status, _ := e.join("batch-1", "student-2"); status != http.StatusOK {
+		t.Fatalf("join: status %d", status)
+	}
+	if status := e.do("DELETE", "/api/batches/batch-1/participants/student-1", nil, nil); status != http.StatusOK {
+		t.Fatalf("remove: status %d", status)
+	}
+}
+
+// A student joins and leaves the waitlist only for themselves, and only real students are enrolled
+func TestWaitlistStudentActsForThemselves(t *testing.T) {
+	e := newTestEnv(t)
+	batch := e.seedExam()
+	e.create(&models.User{ID: "student-2", Email: "siswa2@example.com", Role: models.RoleStudent})
+	e.db.Model(&batch).Update("capacity", 1)
+	student := []string{"X-User", "student-2", "X-Role", string(models.RoleStudent)}
+
+	if status := e.do("POST", "/api/batches/batch-1/waitlist", fiber.Map{"studentId": "student-1"}, nil, student...); status != http.StatusForbidden {
+		t.Fatalf("join for another student: status %d, want 403", status)
+	}
+	if status, _ := e.join("batch-1", "ghost"); status != http.StatusNotFound {
+		t.Fatalf("join unknown student: status %d, want 404", status)
+	}
+	if status := e.do("POST", "/api/batches/batch-1/waitlist", fiber.Map{}, nil, student...); status != http.StatusOK {
+		t.Fatalf("join self: status %d", status)
+	}
+	if status, _ := e.join("batch-1", "student-1"); status != http.StatusOK {
+		t.Fatalf("teacher adds student-1: status %d", status)
+	}
+	if participants, waitlist := e.seats("batch-1"); !reflect.DeepEqual(participants, []string{"student-2"}) || !reflect.DeepEqual(waitlist, []string{"student-1"}) {
+		t.Fatalf("participants %v, waitlist %v", participants, waitlist)
+	}
+
+	if status := e.do("DELETE", "/api/batches/batch-1/waitlist/student-1", nil, nil, student...); status != http.StatusForbidden {
+		t.Fatalf("take another student off the waitlist: status %d, want 403", status)
+	}
+	if status := e.do("DELETE", "/api/batches/batch-1/waitlist/student-1", nil, nil, "X-User", "student-1", "X-Role", string(models.RoleStudent)); status != http.StatusOK {
+		t.Fatalf("leave own place: status %d", status)
+	}
+}
@@ -0,0 +1,117 @@
+// Package i18n holds the message catalog for API errors and generated documents.
+// Messages live in locales/<locale>.json (flat keys, fmt verbs for arguments),
+// like the frontend's locale files.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Locale string
+
+const (
+	Indonesian Locale = "id"
+	English    Locale = "en"
+)
+
+// DefaultLocale is used when the request does not ask for a supported language
+const DefaultLocale = Indonesian
+
+// Supported locales, in the order they are offered
+var Supported = []Locale{Indonesian, English}
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+var catalog = map[Locale]map[string]string{}
+
+func init() {
+	for _, l := range Supported {
+		data, err := localeFiles.ReadFile("locales/" + string(l) + ".json")
+		if err != nil {
+			panic(err)
+		}
+		messages := map[string]string{}
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: locales/%s.json: %v", l, err))
+		}
+		catalog[l] = messages
+	}
+}
+
+// T returns the message for key in locale l, formatted with args.
+// Falls back to the default locale, then to the key itself.
+func (l Locale) T(key string, args ...interface{}) string {
+	msg, ok := catalog[l][key]
+	if !ok {
+		if msg, ok = catalog[DefaultLocale][key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Has reports whether key is in the catalog
+func Has(key string) bool {
+	_, ok := catalog[DefaultLocale][key]
+	return ok
+}
+
+// Parse matches a language tag such as "en", "en-US" or "id_ID" to a supported locale
+func Parse(tag string) (Locale, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	if tag == "in" {
+		tag = "id" // legacy code for Indonesian
+	}
+	for _, l := range Supported {
+		if tag == string(l) {
+			return l, true
+		}
+	}
+	return "", false
+}
+
+// Negotiate picks the best supported locale from an Accept-Language header
+// ("en-US,en;q=0.9,id;q=0.8"), or DefaultLocale when none matches.
+func Negotiate(acceptLanguage string) Locale {
+	best, bestQ := DefaultLocale, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+		l, ok := Parse(fields[0])
+		if !ok {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			if v, found := strings.CutPrefix(strings.TrimSpace(param), "q="); found {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q > bestQ {
+			best, bestQ = l, q
+		}
+	}
+	return best
+}
+
+// FormatDate writes a date the local way: "2 Januari 2026" or "January 2, 2026"
+func (l Locale) FormatDate(t time.Time) string {
+	month := l.T("month." + strconv.Itoa(int(t.Month())))
+	if l == English {
+		return fmt.Sprintf("%s %d, %d", month, t.Day(), t.Year())
+	}
+	return fmt.Sprintf("%d %s %d", t.Day(), month, t.Year())
+}
@@ -0,0 +1,58 @@
+package i18n
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocalesHaveTheSameKeys(t *testing.T) {
+	for key := range catalog[DefaultLocale] {
+		for _, l := range Supported {
+			if _, ok := catalog[l][key]; !ok {
+				t.Errorf("%s: missing %q", l, key)
+			}
+		}
+	}
+	for _, l := range Supported {
+		if len(catalog[l]) != len(catalog[DefaultLocale]) {
+			t.Errorf("%s has %d keys, %s has %d", l, len(catalog[l]), DefaultLocale, len(catalog[DefaultLocale]))
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	cases := map[string]Locale{
+		"":                            DefaultLocale,
+		"en":                          English,
+		"en-US,en;q=0.9":              English,
+		"id-ID,id;q=0.9,en;q=0.8":     Indonesian,
+		"fr-FR,en;q=0.5,id;q=0.7":     Indonesian,
+		"fr-FR,de;q=0.8":              DefaultLocale,
+		"in":                          Indonesian,
+		"EN_gb":                       English,
+		"id;q=0.1, en;q=0.2, *;q=0.9": English,
+	}
+	for header, want := range cases {
+		if got := Negotiate(header); got != want {
+			t.Errorf("Negotiate(%q) = %s, want %s", header, got, want)
+		}
+	}
+}
+
+func TestTranslateAndFormat(t *testing.T) {
+	if got := English.T("grade_weights_sum", 90); got != "Grade weights must add up to 100 (got 90)" {
+		t.Errorf("English: %q", got)
+	}
+	if got := Indonesian.T("no.such.key"); got != "no.such.key" {
+		t.Errorf("missing key: %q", got)
+	}
+
+	day := time.Date(2026, time.January, 2, 8, 0, 0, 0, time.UTC)
+	if got := Indonesian.FormatDate(day); got != "2 Januari 2026" {
+		t.Errorf("Indonesian date: %q", got)
+	}
+	if got := English.FormatDate(day); got != "January 2, 2026" {
+		t.Errorf("English date: %q", got)
+	}
+
+}
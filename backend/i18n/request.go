@@ -0,0 +1,18 @@
+package i18n
+
+import "github.com/gofiber/fiber/v2"
+
+// FromRequest resolves the language of a response: ?lang=, then the user's saved preference
+// (the "locale" token claim, stored in Locals by the auth middleware), then Accept-Language,
+// and DefaultLocale otherwise.
+func FromRequest(c *fiber.Ctx) Locale {
+	if l, ok := Parse(c.Query("lang")); ok {
+		return l
+	}
+	if pref, _ := c.Locals("locale").(string); pref != "" {
+		if l, ok := Parse(pref); ok {
+			return l
+		}
+	}
+	return Negotiate(c.Get(fiber.HeaderAcceptLanguage))
+}
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/blob"
+	"academic-suite-backend/clock"
 	"academic-suite-backend/database"
+	"academic-suite-backend/handlers"
 	"academic-suite-backend/routes"
 	"log"
+	"os"
 
 	_ "academic-suite-backend/docs"
 
@@ -11,6 +16,7 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	fiberRecover "github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/gofiber/swagger"
 )
 
@@ -29,22 +35,40 @@ import (
 // @BasePath  /
 
 func main() {
-	// 1. Initialize Database
+	// 0. CLI subcommands (migrate, ...) run and exit without starting the server
+	if runCommand(os.Args[1:]) {
+		return
+	}
+
+	// 1. Initialize Database (fails if migrations are pending) and the media store
 	database.Connect()
+	media, err := blob.Open(blob.LoadConfig())
+	if err != nil {
+		log.Fatal("Failed to open media store: ", err)
+	}
 
 	// 2. Setup Fiber App
-	app := fiber.New()
+	app := fiber.New(fiber.Config{
+		// Every error response uses the apperr envelope
+		ErrorHandler: apperr.Handler,
+		// Room for listening audio (media uploads are capped at 20 MB)
+		BodyLimit: 25 << 20,
+	})
+	app.Use(requestid.New())
 	app.Use(fiberRecover.New())
 
 	// Middleware
-	app.Use(logger.New())
+	app.Use(logger.New(logger.Config{
+		Format: "${time} | ${status} | ${latency} | ${ip} | ${method} | ${path} | ${locals:requestid} | ${error}\n",
+	}))
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "http://localhost:5173, http://localhost:8080, https://academic-suite.netlify.app", // Allow Frontend (Vite default & Custom & Netlify)
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
+		AllowOrigins:  "http://localhost:5173, http://localhost:8080, https://academic-suite.netlify.app", // Allow Frontend (Vite default & Custom & Netlify)
+		AllowHeaders:  "Origin, Content-Type, Accept, Accept-Language, Authorization",
+		ExposeHeaders: "X-Request-ID, Content-Disposition",
 	}))
 
 	// 3. Setup Routes
-	routes.SetupRoutes(app)
+	routes.SetupRoutes(app, handlers.NewServices(database.DB, clock.System{}, media))
 
 	// Swagger Route
 	app.Get("/swagger/*", swagger.HandlerDefault)
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Accommodation grants a student extra time or breaks.
+// BatchID empty means it is the student's standing profile and applies to every batch;
+// a batch-specific row takes precedence over the profile.
+type Accommodation struct {
+	ID                 string    `json:"id" gorm:"primaryKey"`
+	StudentID          string    `json:"studentId" gorm:"index" validate:"required"`
+	BatchID            string    `json:"batchId" gorm:"index"`
+	ExtraTimePercent   int       `json:"extraTimePercent" validate:"min=0"`   // e.g. 50 = 1.5x duration
+	ExtraTimeMinutes   int       `json:"extraTimeMinutes" validate:"min=0"`   // added after the percentage
+	ExtendedEndMinutes int       `json:"extendedEndMinutes" validate:"min=0"` // how long past batch EndTime the student may keep working
+	AllowBreaks        bool      `json:"allowBreaks"`
+	MaxBreakMinutes    int       `json:"maxBreakMinutes" validate:"min=0"` // 0 = unlimited
+	Notes              string    `json:"notes"`
+	CreatedBy          string    `json:"createdBy"`
+	CreatedAt          time.Time `json:"createdAt"`
+	UpdatedAt          time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type MediaKind string
+
+const (
+	MediaImage MediaKind = "image"
+	MediaAudio MediaKind = "audio"
+)
+
+// Media is an uploaded image or audio file; the bytes live in the blob store under StorageKey
+type Media struct {
+	ID            string    `json:"id" gorm:"primaryKey"`
+	InstitutionID string    `json:"institutionId" gorm:"index"`
+	Kind          MediaKind `json:"kind"`
+	Filename      string    `json:"filename"`
+	ContentType   string    `json:"contentType"`
+	Size          int64     `json:"size"`
+	StorageKey    string    `json:"-"`
+	UploadedBy    string    `json:"uploadedBy"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
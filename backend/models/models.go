@@ -20,25 +20,33 @@ type User struct {
 	Role          UserRole  `json:"role"`
 	InstitutionID string    `json:"institutionId"`
 	AvatarURL     string    `json:"avatarUrl"`
+	Locale        string    `json:"locale"` // "id", "en" or empty to follow Accept-Language
+	NIS           string    `json:"nis"`    // Nomor Induk Siswa, issued by the school
+	NISN          string    `json:"nisn"`   // Nomor Induk Siswa Nasional, 10 digits
 	CreatedAt     time.Time `json:"createdAt"`
 }
 
 type Institution struct {
 	ID        string    `json:"id" gorm:"primaryKey"`
 	Name      string    `json:"name"`
-	Type      string    `json:"type"` // 'school' | 'university'
+	Type      string    `json:"type" validate:"omitempty,oneof=school university"` // 'school' | 'university'
 	Address   string    `json:"address"`
+	Timezone  string    `json:"timezone" gorm:"default:'Asia/Jakarta'"` // IANA name; batch times are entered in this zone
 	CreatedAt time.Time `json:"createdAt"`
 }
 
+// Subject teachers live in subject_teachers
 type Subject struct {
 	ID            string `json:"id" gorm:"primaryKey"`
 	DepartmentID  string `json:"departmentId"`
 	Name          string `json:"name"`
 	Code          string `json:"code"`
 	Credits       int    `json:"credits"`
-	TeacherIDs    string `json:"teacherIds" gorm:"type:text"` // JSON array of teacher IDs
 	InstitutionID string `json:"institutionId"`
+	// Gradebook weights in percent per exam type (practice quizzes are not graded)
+	DailyQuizWeight int `json:"dailyQuizWeight"`
+	MidtermWeight   int `json:"midtermWeight"`
+	FinalWeight     int `json:"finalWeight"`
 }
 
 type QuestionType string
@@ -53,20 +61,24 @@ const (
 type QuestionOption struct {
 	ID         string `json:"id" gorm:"primaryKey"`
 	QuestionID string `json:"questionId"`
-	Text       string `json:"text"`
+	Text       string `json:"text"`    // required unless the choice is a picture (see answerKeyRules)
+	ImageID    string `json:"imageId"` // optional Media of kind image
 	IsCorrect  bool   `json:"isCorrect"`
 }
 
 type Question struct {
 	ID            string           `json:"id" gorm:"primaryKey"`
 	QuizID        string           `json:"quizId"`
-	Type          QuestionType     `json:"type"`
-	Text          string           `json:"text"`
-	Points        int              `json:"points"`
-	Options       []QuestionOption `json:"options" gorm:"foreignKey:QuestionID"`
+	Type          QuestionType     `json:"type" validate:"required,oneof=mcq true_false short_answer essay"`
+	Text          string           `json:"text" validate:"required"` // may hold LaTeX (\( \), $$ $$) and inline MathML (<math>)
+	ImageID       string           `json:"imageId"`                  // optional Media of kind image
+	AudioID       string           `json:"audioId"`                  // optional Media of kind audio, e.g. a listening section
+	Points        int              `json:"points" validate:"gt=0"`
+	Options       []QuestionOption `json:"options" gorm:"foreignKey:QuestionID" validate:"dive"`
 	CorrectAnswer string           `json:"correctAnswer"` // For non-MCQ
 	Explanation   string           `json:"explanation"`
 	OrderIndex    int              `json:"orderIndex"`
+	Topic         string           `json:"topic"` // optional, groups questions in report breakdowns
 }
 
 type ExamType string
@@ -81,14 +93,14 @@ const (
 type Quiz struct {
 	ID            string     `json:"id" gorm:"primaryKey"`
 	SubjectID     string     `json:"subjectId"`
-	Title         string     `json:"title"`
+	Title         string     `json:"title" validate:"required"`
 	Description   string     `json:"description"`
-	ExamType      ExamType   `json:"examType"`
-	TotalPoints   int        `json:"totalPoints"`
-	PassingScore  int        `json:"passingScore"`
-	Status        string     `json:"status" gorm:"default:'active'"` // 'active', 'archived', 'draft'
+	ExamType      ExamType   `json:"examType" validate:"omitempty,oneof=daily_quiz midterm final practice"`
+	TotalPoints   int        `json:"totalPoints" validate:"min=0"`
+	PassingScore  int        `json:"passingScore" validate:"min=0"`
+	Status        string     `json:"status" gorm:"default:'active'" validate:"omitempty,oneof=active archived draft"` // 'active', 'archived', 'draft'
 	InstitutionID string     `json:"institutionId"`
-	Questions     []Question `json:"questions" gorm:"foreignKey:QuizID"`
+	Questions     []Question `json:"questions" gorm:"foreignKey:QuizID" validate:"dive"`
 	CreatedBy     string     `json:"createdBy"`
 	CreatedAt     time.Time  `json:"createdAt"`
 	UpdatedAt     time.Time  `json:"updatedAt"`
@@ -109,23 +121,26 @@ const (
 	StatusFinished  BatchStatus = "finished"
 )
 
+// ExamBatch participants and waitlist live in batch_participants / batch_waitlist_entries
 type ExamBatch struct {
-	ID                  string      `json:"id" gorm:"primaryKey"`
-	QuizID              string      `json:"quizId"`
-	ClassID             string      `json:"classId"`
-	Type                BatchType   `json:"type"`
-	Name                string      `json:"name"` // Renamed from Title to match Frontend
-	Token               string      `json:"token"`
-	StartTime           time.Time   `json:"startTime"`
-	EndTime             time.Time   `json:"endTime"`
-	Duration            int         `json:"duration"` // minutes
-	Status              BatchStatus `json:"status"`
-	AllowedParticipants string      `json:"allowedParticipants" gorm:"type:text"` // Stored as JSON
-	Waitlist            string      `json:"waitlist" gorm:"type:text"`            // Stored as JSON
-	CreatedBy           string      `json:"createdBy"`
-	CreatedAt           time.Time   `json:"createdAt"`
-	FrozenAt            *time.Time  `json:"frozenAt"`
-	ResumedAt           *time.Time  `json:"resumedAt"`
+	ID                 string      `json:"id" gorm:"primaryKey"`
+	QuizID             string      `json:"quizId" validate:"required"`
+	ClassID            string      `json:"classId"`
+	Type               BatchType   `json:"type"`
+	Name               string      `json:"name"` // Renamed from Title to match Frontend
+	Token              string      `json:"token"`
+	StartTime          time.Time   `json:"startTime"`
+	EndTime            time.Time   `json:"endTime"`
+	Timezone           string      `json:"timezone"`                  // IANA zone the schedule was entered in ("" = default)
+	Duration           int         `json:"duration" validate:"min=0"` // minutes
+	Status             BatchStatus `json:"status"`
+	ParentBatchID      string      `json:"parentBatchId" gorm:"index"`          // For MAKEUP batches: the regular batch it makes up for
+	Capacity           int         `json:"capacity" validate:"min=0"`           // Max participants, 0 = unlimited
+	NoShowGraceMinutes int         `json:"noShowGraceMinutes" validate:"min=0"` // After StartTime+grace, no-shows give their seat to the waitlist (0 = off)
+	CreatedBy          string      `json:"createdBy"`
+	CreatedAt          time.Time   `json:"createdAt"`
+	FrozenAt           *time.Time  `json:"frozenAt"`
+	ResumedAt          *time.Time  `json:"resumedAt"`
 }
 
 type AttemptStatus string
@@ -142,7 +157,7 @@ const (
 
 type Answer struct {
 	AttemptID        string    `json:"attemptId" gorm:"primaryKey"` // Composite key part 1? No, better own ID or belong to Attempt
-	QuestionID       string    `json:"questionId" gorm:"primaryKey"`
+	QuestionID       string    `json:"questionId" gorm:"primaryKey" validate:"required"`
 	SelectedOptionID string    `json:"selectedOptionId"`
 	TextAnswer       string    `json:"textAnswer"`
 	AnsweredAt       time.Time `json:"answeredAt"`
@@ -176,12 +191,21 @@ const (
 	EventBatchUpdated  EventType = "BATCH_UPDATED"
 	EventBatchFrozen   EventType = "BATCH_FROZEN"
 	EventBatchResumed  EventType = "BATCH_RESUMED"
+	EventMakeupCreated EventType = "MAKEUP_BATCH_CREATED"
+	EventWaitlistJoin  EventType = "WAITLIST_JOINED"
+	EventWaitlistLeave EventType = "WAITLIST_LEFT"
+	EventWaitlistOrder EventType = "WAITLIST_REORDERED"
+	EventWaitlistPromo EventType = "WAITLIST_PROMOTED"
+	EventSeatReleased  EventType = "PARTICIPANT_REMOVED"
+	EventNoShow        EventType = "PARTICIPANT_NO_SHOW"
 	EventAttemptStart  EventType = "ATTEMPT_STARTED"
 	EventAttemptSubmit EventType = "ATTEMPT_SUBMITTED"
 	EventFocusLost     EventType = "FOCUS_LOST"
 	EventFocusGained   EventType = "FOCUS_GAINED"
 	EventCopyAttempt   EventType = "COPY_ATTEMPT"
 	EventPasteAttempt  EventType = "PASTE_ATTEMPT"
+	EventAttemptReset  EventType = "ATTEMPT_RESET"
+	EventAttemptReopen EventType = "ATTEMPT_REOPENED"
 )
 
 type EventLog struct {
@@ -194,12 +218,12 @@ type EventLog struct {
 	Timestamp time.Time `json:"timestamp"`
 }
 
+// Class members live in class_students
 type Class struct {
-	ID         string    `json:"id" gorm:"primaryKey"`
-	Name       string    `json:"name"`
-	SubjectID  string    `json:"subjectId"`
-	TeacherID  string    `json:"teacherId"`
-	StudentIDs string    `json:"studentIds" gorm:"type:text"` // JSON array of UserIDs
-	CreatedAt  time.Time `json:"createdAt"`
-	UpdatedAt  time.Time `json:"updatedAt"`
+	ID        string    `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name"`
+	SubjectID string    `json:"subjectId"`
+	TeacherID string    `json:"teacherId"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
 }
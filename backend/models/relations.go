@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Join tables for the many-to-many relationships that used to be stored as JSON arrays in text columns.
+// The embedded belongs-to fields mirror the foreign keys from migration 0004; they are never loaded.
+
+type ClassStudent struct {
+	ClassID   string `json:"classId" gorm:"primaryKey"`
+	StudentID string `json:"studentId" gorm:"primaryKey;index"`
+	Class     Class  `json:"-" gorm:"foreignKey:ClassID;constraint:OnDelete:CASCADE"`
+	Student   User   `json:"-" gorm:"foreignKey:StudentID;constraint:OnDelete:CASCADE"`
+}
+
+type SubjectTeacher struct {
+	SubjectID string  `json:"subjectId" gorm:"primaryKey"`
+	TeacherID string  `json:"teacherId" gorm:"primaryKey;index"`
+	Subject   Subject `json:"-" gorm:"foreignKey:SubjectID;constraint:OnDelete:CASCADE"`
+	Teacher   User    `json:"-" gorm:"foreignKey:TeacherID;constraint:OnDelete:CASCADE"`
+}
+
+type BatchParticipant struct {
+	BatchID   string    `json:"batchId" gorm:"primaryKey"`
+	StudentID string    `json:"studentId" gorm:"primaryKey;index"`
+	CreatedAt time.Time `json:"createdAt"`
+	Batch     ExamBatch `json:"-" gorm:"foreignKey:BatchID;constraint:OnDelete:CASCADE"`
+	Student   User      `json:"-" gorm:"foreignKey:StudentID;constraint:OnDelete:CASCADE"`
+}
+
+type BatchWaitlistEntry struct {
+	BatchID   string    `json:"batchId" gorm:"primaryKey"`
+	StudentID string    `json:"studentId" gorm:"primaryKey;index"`
+	Position  int       `json:"position" gorm:"index"` // 0 = next in line
+	CreatedAt time.Time `json:"createdAt"`
+	Batch     ExamBatch `json:"-" gorm:"foreignKey:BatchID;constraint:OnDelete:CASCADE"`
+	Student   User      `json:"-" gorm:"foreignKey:StudentID;constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,127 @@
+package moodle
+
+import (
+	"academic-suite-backend/models"
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Aiken (https://docs.moodle.org/en/Aiken_format) only has multiple choice questions:
+//
+//	Ibu kota Indonesia adalah
+//	A. Jakarta
+//	B) Bandung
+//	ANSWER: A
+//
+// A question whose two options are the true/false labels is read as true/false. Points,
+// explanations and topics are not part of the format.
+
+var (
+	aikenOption = regexp.MustCompile(`^([A-Z])[.)]\s+(.*)$`)
+	aikenAnswer = regexp.MustCompile(`^ANSWER:\s*(\S*)`)
+	aikenKey    = regexp.MustCompile(`(?m)^ANSWER:\s*[A-Z]\s*$`)
+)
+
+// IsAiken tells an Aiken file from GIFT by its "ANSWER: X" lines, which GIFT never has
+func IsAiken(text string) bool {
+	return aikenKey.MatchString(text)
+}
+
+// ParseAiken reads the questions of an Aiken file
+func ParseAiken(text string, labels Labels) ([]Item, []ParseError) {
+	var (
+		items   []Item
+		errs    []ParseError
+		q       *models.Question
+		letters []string
+		start   int
+	)
+	fail := func(rule, param string) {
+		errs = append(errs, ParseError{Line: start, Rule: rule, Param: param})
+		q = nil
+	}
+
+	for i, line := range lines(text) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if m := aikenAnswer.FindStringSubmatch(line); m != nil {
+			if q == nil {
+				start = i + 1
+				fail("syntax", "ANSWER")
+				continue
+			}
+			correct := -1
+			for j, letter := range letters {
+				if letter == m[1] {
+					correct = j
+				}
+			}
+			if correct < 0 {
+				fail("answer_not_option", m[1])
+				continue
+			}
+			q.Options[correct].IsCorrect = true
+			items = append(items, Item{Line: start, Question: aikenQuestion(*q, labels)})
+			q = nil
+			continue
+		}
+		if m := aikenOption.FindStringSubmatch(line); m != nil && q != nil {
+			letters = append(letters, m[1])
+			q.Options = append(q.Options, models.QuestionOption{Text: m[2]})
+			continue
+		}
+		if q != nil && len(q.Options) > 0 {
+			// A new question before the last one had its ANSWER line
+			fail("answer_missing", "")
+		}
+		if q == nil {
+			q = &models.Question{Type: models.TypeMCQ, Text: line, Points: DefaultPoints}
+			letters = nil
+			start = i + 1
+		} else {
+			q.Text += "\n" + line
+		}
+	}
+	if q != nil {
+		fail("answer_missing", "")
+	}
+	return items, errs
+}
+
+// aikenQuestion reads two options named like the true/false labels as a true/false question
+func aikenQuestion(q models.Question, labels Labels) models.Question {
+	if len(q.Options) == 2 && strings.EqualFold(q.Options[0].Text, labels.True) && strings.EqualFold(q.Options[1].Text, labels.False) {
+		q.Type = models.TypeTrueFalse
+		q.Options = trueFalseOptions(labels, q.Options[0].IsCorrect)
+	}
+	return q
+}
+
+// WriteAiken writes the choice questions (MCQ, true/false) with exactly one correct option;
+// the rest cannot be expressed in Aiken and are counted in skipped
+func WriteAiken(w io.Writer, questions []models.Question) (skipped int, err error) {
+	bw := bufio.NewWriter(w)
+	for _, q := range questions {
+		correct := correctIndex(q)
+		if (q.Type != models.TypeMCQ && q.Type != models.TypeTrueFalse) || correct < 0 || len(q.Options) > 26 {
+			skipped++
+			continue
+		}
+		// One line each: a line break would start a new question
+		fmt.Fprintln(bw, oneLine(q.Text))
+		for i, opt := range q.Options {
+			fmt.Fprintf(bw, "%c. %s\n", 'A'+i, oneLine(opt.Text))
+		}
+		fmt.Fprintf(bw, "ANSWER: %c\n\n", 'A'+correct)
+	}
+	return skipped, bw.Flush()
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
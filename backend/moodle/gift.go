@@ -0,0 +1,336 @@
+package moodle
+
+import (
+	"academic-suite-backend/models"
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GIFT (https://docs.moodle.org/en/GIFT_format) questions are separated by blank lines:
+//
+//	// points: 2
+//	::Q1:: Ibu kota Indonesia adalah {=Jakarta ~Bandung ~Surabaya ####Sejak 1945}
+//
+// Answers map to our types as Moodle reads them: "~" answers are multiple choice, only "="
+// answers short answer, T/F true/false and an empty {} essay. Numerical and matching
+// questions, and descriptions without answers, are reported as unsupported.
+//
+// GIFT has no points; "// points: N" comments (which Moodle ignores) carry them. Categories
+// ("$CATEGORY: $course$/top/Aljabar") become the question topic.
+
+var pointsComment = regexp.MustCompile(`^//\s*points:\s*(\d+)`)
+
+// giftSpecial are the characters escaped with a backslash in GIFT text, the backslash included
+const giftSpecial = "~=#{}:\\"
+
+// ParseGIFT reads the questions of a GIFT file
+func ParseGIFT(text string, labels Labels) ([]Item, []ParseError) {
+	var (
+		items  []Item
+		errs   []ParseError
+		block  []string
+		start  int
+		points int
+		topic  string
+	)
+	flush := func() {
+		if len(block) == 0 {
+			return
+		}
+		q, perr := parseGIFTQuestion(strings.Join(block, "\n"), labels)
+		if perr != nil {
+			perr.Line = start
+			errs = append(errs, *perr)
+		} else {
+			q.Points = DefaultPoints
+			if points > 0 {
+				q.Points = points
+			}
+			q.Topic = topic
+			items = append(items, Item{Line: start, Question: q})
+		}
+		block, points = nil, 0
+	}
+
+	for i, line := range lines(text) {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "//"):
+			if m := pointsComment.FindStringSubmatch(trimmed); m != nil {
+				points, _ = strconv.Atoi(m[1])
+			}
+			continue
+		case strings.HasPrefix(trimmed, "$CATEGORY:"):
+			flush()
+			topic = categoryTopic(strings.TrimPrefix(trimmed, "$CATEGORY:"))
+			continue
+		case trimmed == "":
+			flush()
+			continue
+		}
+		if len(block) == 0 {
+			start = i + 1
+		}
+		block = append(block, line)
+	}
+	flush()
+	return items, errs
+}
+
+// categoryTopic turns "$course$/top/Aljabar/Linear" into "Aljabar/Linear"
+func categoryTopic(category string) string {
+	category = strings.TrimSpace(category)
+	if strings.HasPrefix(category, "$") {
+		_, category, _ = strings.Cut(category, "/")
+	}
+	if category == "top" {
+		return ""
+	}
+	return strings.TrimPrefix(category, "top/")
+}
+
+var formatMarker = regexp.MustCompile(`^\[(html|moodle|plain|markdown)\]`)
+
+func parseGIFTQuestion(src string, labels Labels) (models.Question, *ParseError) {
+	var q models.Question
+	s := strings.TrimSpace(src)
+
+	// The title is only Moodle's question name
+	if strings.HasPrefix(s, "::") {
+		end := indexUnescaped(s[2:], "::")
+		if end < 0 {
+			return q, &ParseError{Rule: "syntax", Param: "::"}
+		}
+		s = strings.TrimSpace(s[end+4:])
+	}
+
+	open := indexUnescaped(s, "{")
+	if open < 0 {
+		return q, &ParseError{Rule: "unsupported_question_type", Param: "description"}
+	}
+	closing := indexUnescaped(s[open:], "}")
+	if closing < 0 {
+		return q, &ParseError{Rule: "syntax", Param: "}"}
+	}
+	body := s[open+1 : open+closing]
+
+	// Answers in the middle of the text leave a blank to fill in
+	text := strings.TrimSpace(s[:open])
+	if after := strings.TrimSpace(s[open+closing+1:]); after != "" {
+		text += " _____ " + after
+	}
+	q.Text = unescapeGIFT(formatMarker.ReplaceAllString(text, ""))
+
+	body, general, _ := cutUnescaped(body, "####")
+	q.Explanation = strings.TrimSpace(unescapeGIFT(general))
+	body = strings.TrimSpace(body)
+
+	if body == "" {
+		q.Type = models.TypeEssay
+		return q, nil
+	}
+	if body[0] == '#' {
+		return q, &ParseError{Rule: "unsupported_question_type", Param: "numerical"}
+	}
+	answer, _, _ := cutUnescaped(body, "#")
+	switch strings.ToUpper(strings.TrimSpace(answer)) {
+	case "T", "TRUE":
+		q.Type, q.Options = models.TypeTrueFalse, trueFalseOptions(labels, true)
+		return q, nil
+	case "F", "FALSE":
+		q.Type, q.Options = models.TypeTrueFalse, trueFalseOptions(labels, false)
+		return q, nil
+	}
+
+	answers, ok := splitGIFTAnswers(body)
+	if !ok {
+		return q, &ParseError{Rule: "syntax", Param: "{}"}
+	}
+	choice := false
+	for _, a := range answers {
+		if indexUnescaped(a.text, "->") >= 0 {
+			return q, &ParseError{Rule: "unsupported_question_type", Param: "matching"}
+		}
+		choice = choice || !a.exact
+	}
+
+	if !choice {
+		// Only the first accepted answer is kept: a short answer has one key
+		q.Type = models.TypeShortAnswer
+		q.CorrectAnswer = answers[0].text
+		return q, nil
+	}
+	q.Type = models.TypeMCQ
+	for _, a := range answers {
+		q.Options = append(q.Options, models.QuestionOption{Text: a.text, IsCorrect: a.correct})
+	}
+	return q, nil
+}
+
+type giftAnswer struct {
+	text    string
+	exact   bool // marked "=" rather than "~"
+	correct bool
+}
+
+// splitGIFTAnswers splits "=a ~b#feedback ~%50%c" into answers; false if text comes before the first marker
+func splitGIFTAnswers(body string) ([]giftAnswer, bool) {
+	var raw []string
+	last := -1
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			i++
+		case '=', '~':
+			if last < 0 && strings.TrimSpace(body[:i]) != "" {
+				return nil, false
+			}
+			if last >= 0 {
+				raw = append(raw, body[last:i])
+			}
+			last = i
+		}
+	}
+	if last < 0 {
+		return nil, false
+	}
+	raw = append(raw, body[last:])
+
+	answers := make([]giftAnswer, 0, len(raw))
+	for _, r := range raw {
+		a := giftAnswer{exact: r[0] == '=', correct: r[0] == '='}
+		rest := strings.TrimSpace(r[1:])
+		// Weights: any credit counts as correct, which validation then checks is a single option
+		if strings.HasPrefix(rest, "%") {
+			if end := strings.Index(rest[1:], "%"); end >= 0 {
+				weight, _ := strconv.ParseFloat(rest[1:end+1], 64)
+				a.correct = weight > 0
+				rest = rest[end+2:]
+			}
+		}
+		rest, _, _ = cutUnescaped(rest, "#")
+		a.text = strings.TrimSpace(unescapeGIFT(rest))
+		answers = append(answers, a)
+	}
+	return answers, true
+}
+
+// indexUnescaped is strings.Index skipping backslash-escaped characters
+func indexUnescaped(s, sub string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+func cutUnescaped(s, sep string) (before, after string, found bool) {
+	if i := indexUnescaped(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// unescapeGIFT resolves \~ \= \# \{ \} \: \\ and \n. Other backslashes are kept, as is "\n"
+// followed by a letter, so hand-written LaTeX such as \neq or \frac survives.
+func unescapeGIFT(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			next := s[i+1]
+			if strings.IndexByte(giftSpecial, next) >= 0 {
+				b.WriteByte(next)
+				i++
+				continue
+			}
+			if next == 'n' && !(i+2 < len(s) && isLetter(s[i+2])) {
+				b.WriteByte('\n')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// escapeGIFT is the inverse of unescapeGIFT. A line break stays a real one when a letter
+// follows (it can then neither end the question nor read as \n plus text), else becomes \n.
+func escapeGIFT(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case strings.IndexByte(giftSpecial, c) >= 0:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '\r':
+		case c == '\n' && !(i+1 < len(s) && isLetter(s[i+1])):
+			b.WriteString(`\n`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// WriteGIFT writes questions as GIFT, in order
+func WriteGIFT(w io.Writer, questions []models.Question) error {
+	bw := bufio.NewWriter(w)
+	topic := ""
+	for i, q := range questions {
+		if q.Topic != topic {
+			category := "$course$/top"
+			if q.Topic != "" {
+				category += "/" + q.Topic
+			}
+			fmt.Fprintf(bw, "$CATEGORY: %s\n\n", category)
+			topic = q.Topic
+		}
+
+		fmt.Fprintf(bw, "// points: %d\n", q.Points)
+		fmt.Fprintf(bw, "::Q%d:: %s {", i+1, escapeGIFT(q.Text))
+		general := ""
+		if q.Explanation != "" {
+			general = "####" + escapeGIFT(q.Explanation)
+		}
+		switch q.Type {
+		case models.TypeMCQ:
+			bw.WriteString("\n")
+			for _, opt := range q.Options {
+				marker := "~"
+				if opt.IsCorrect {
+					marker = "="
+				}
+				fmt.Fprintf(bw, "\t%s%s\n", marker, escapeGIFT(opt.Text))
+			}
+			if general != "" {
+				fmt.Fprintf(bw, "\t%s\n", general)
+			}
+		case models.TypeTrueFalse:
+			if trueFalseAnswer(q) {
+				bw.WriteString("TRUE" + general)
+			} else {
+				bw.WriteString("FALSE" + general)
+			}
+		case models.TypeShortAnswer:
+			bw.WriteString("=" + escapeGIFT(q.CorrectAnswer) + general)
+		default:
+			bw.WriteString(general)
+		}
+		bw.WriteString("}\n\n")
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,78 @@
+// Package moodle reads and writes question banks in the plain-text formats Moodle imports and
+// exports: GIFT (all four question types) and Aiken (multiple choice only).
+//
+// Parsed questions carry no IDs; the caller assigns them and validates the result the same way
+// as a quiz sent by the builder. A question the parser cannot read is reported with its line
+// and skipped, so one bad question does not lose the rest of the bank.
+package moodle
+
+import (
+	"academic-suite-backend/models"
+	"fmt"
+	"strings"
+)
+
+// Item is a parsed question and the line it starts on
+type Item struct {
+	Line     int
+	Question models.Question
+}
+
+// ParseError is a question that could not be read. Rule and Param name the problem like a
+// validation rule does ("unsupported_question_type", "numerical").
+type ParseError struct {
+	Line  int
+	Rule  string
+	Param string
+}
+
+func (e ParseError) Error() string {
+	if e.Param != "" {
+		return fmt.Sprintf("line %d: %s (%s)", e.Line, e.Rule, e.Param)
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Rule)
+}
+
+// Labels are the option texts given to an imported true/false question. Our true/false
+// questions are two options, "true" first; the formats only carry which one is right.
+type Labels struct {
+	True  string
+	False string
+}
+
+// DefaultPoints is given to imported questions that do not state their points
+const DefaultPoints = 1
+
+// trueFalseOptions builds the two options of a true/false question
+func trueFalseOptions(labels Labels, answer bool) []models.QuestionOption {
+	return []models.QuestionOption{
+		{Text: labels.True, IsCorrect: answer},
+		{Text: labels.False, IsCorrect: !answer},
+	}
+}
+
+// trueFalseAnswer is the answer of a true/false question: whether its first option is correct
+func trueFalseAnswer(q models.Question) bool {
+	return len(q.Options) > 0 && q.Options[0].IsCorrect
+}
+
+// correctIndex is the only correct option of q, -1 if there is not exactly one
+func correctIndex(q models.Question) int {
+	found := -1
+	for i, opt := range q.Options {
+		if opt.IsCorrect {
+			if found >= 0 {
+				return -1
+			}
+			found = i
+		}
+	}
+	return found
+}
+
+// lines splits text into lines, dropping a UTF-8 BOM and carriage returns
+func lines(text string) []string {
+	text = strings.TrimPrefix(text, "\uFEFF")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.ReplaceAll(text, "\r", "\n"), "\n")
+}
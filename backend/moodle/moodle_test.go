@@ -0,0 +1,151 @@
+package moodle
+
+import (
+	"academic-suite-backend/models"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var labels = Labels{True: "Benar", False: "Salah"}
+
+func opts(correct int, texts ...string) []models.QuestionOption {
+	out := make([]models.QuestionOption, len(texts))
+	for i, t := range texts {
+		out[i] = models.QuestionOption{Text: t, IsCorrect: i == correct}
+	}
+	return out
+}
+
+// bank has every question type, the characters GIFT has to escape and formulas (LaTeX, MathML)
+var bank = []models.Question{
+	{Type: models.TypeMCQ, Text: "Hasil dari 2 + 3 = ?", Points: 2, Options: opts(1, "4", "5", "{6}", "~7"),
+		Explanation: "Penjumlahan: 2 + 3 = 5"},
+	{Type: models.TypeMCQ, Text: "Manakah yang benar?\nPilih satu: $x \\neq y$", Points: 1, Options: opts(0, "a: b", "c#d",
+		`<math xmlns="http://www.w3.org/1998/Math/MathML"><mi>x</mi><mo>=</mo><mn>1</mn></math>`), Topic: "Aljabar"},
+	{Type: models.TypeTrueFalse, Text: "Bumi itu bulat", Points: 1, Options: opts(0, "Benar", "Salah"), Topic: "Aljabar"},
+	{Type: models.TypeTrueFalse, Text: "Matahari mengelilingi bumi", Points: 3, Options: opts(1, "Benar", "Salah"),
+		Explanation: "Bumi yang mengelilingi matahari", Topic: "IPA/Tata Surya"},
+	{Type: models.TypeShortAnswer, Text: "Ibu kota Indonesia", Points: 2, CorrectAnswer: "Jakarta", Topic: "IPA/Tata Surya"},
+	{Type: models.TypeEssay, Text: "Jelaskan fotosintesis.\n\n// bukan komentar", Points: 10, Explanation: "Nilai: 0-10"},
+	{Type: models.TypeEssay, Text: `Buktikan $A = \{1,2\}$ dan $f\: A \to B$, dengan $\=$ di antara \\`, Points: 5,
+		Explanation: `$\{x \mid x \neq 0\}$`},
+}
+
+func TestGIFTRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGIFT(&buf, bank); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	items, errs := ParseGIFT(buf.String(), labels)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v\n%s", errs, buf.String())
+	}
+	if len(items) != len(bank) {
+		t.Fatalf("parsed %d questions, want %d\n%s", len(items), len(bank), buf.String())
+	}
+	for i, item := range items {
+		if !reflect.DeepEqual(item.Question, bank[i]) {
+			t.Errorf("question %d:\n got %+v\nwant %+v", i+1, item.Question, bank[i])
+		}
+	}
+}
+
+func TestAikenRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	skipped, err := WriteAiken(&buf, bank)
+	if err != nil || skipped != 3 {
+		t.Fatalf("skipped %d, err %v", skipped, err)
+	}
+	if !IsAiken(buf.String()) {
+		t.Error("written file not detected as Aiken")
+	}
+	items, errs := ParseAiken(buf.String(), labels)
+	if len(errs) > 0 || len(items) != 4 {
+		t.Fatalf("items %d errs %v\n%s", len(items), errs, buf.String())
+	}
+	for i, item := range items {
+		// Aiken keeps the type, text (on one line) and options only
+		want := bank[i]
+		want.Text = oneLine(want.Text)
+		want.Points, want.Explanation, want.Topic = DefaultPoints, "", ""
+		if !reflect.DeepEqual(item.Question, want) {
+			t.Errorf("question %d:\n got %+v\nwant %+v", i+1, item.Question, want)
+		}
+	}
+}
+
+func TestParseGIFTMoodleExport(t *testing.T) {
+	src := "\uFEFF// question: 1  name: Capital\r\n" +
+		"$CATEGORY: $course$/top/Geografi\r\n\r\n" +
+		"::Capital::[html]<p>Ibu kota Jawa Timur?</p>{\r\n" +
+		"\t~Malang#Bukan\r\n" +
+		"\t=%100%Surabaya#Benar\r\n" +
+		"\t~%-50%Kediri\r\n" +
+		"}\r\n\r\n" +
+		"Mendengar lagu {=Indonesia Raya =Indonesia Raya!} kita berdiri.\n\n" +
+		"Bumi bulat{T#benar####Umum}\n\n" +
+		"::Angka::Berapa 2+2? {#4:0}\n\n" +
+		"Pasangkan {=a -> 1 =b -> 2}\n\n" +
+		"Hanya deskripsi tanpa jawaban\n\n" +
+		"::Rusak:: Tidak ditutup {=a\n"
+
+	items, errs := ParseGIFT(src, labels)
+	if len(items) != 3 {
+		t.Fatalf("items = %+v", items)
+	}
+	mcq := items[0]
+	if mcq.Line != 4 || mcq.Question.Type != models.TypeMCQ || mcq.Question.Text != "<p>Ibu kota Jawa Timur?</p>" ||
+		mcq.Question.Topic != "Geografi" || !reflect.DeepEqual(mcq.Question.Options, opts(1, "Malang", "Surabaya", "Kediri")) {
+		t.Errorf("mcq = %+v", mcq)
+	}
+	if q := items[1].Question; q.Type != models.TypeShortAnswer || q.Text != "Mendengar lagu _____ kita berdiri." || q.CorrectAnswer != "Indonesia Raya" {
+		t.Errorf("short answer = %+v", q)
+	}
+	if q := items[2].Question; q.Type != models.TypeTrueFalse || !q.Options[0].IsCorrect || q.Explanation != "Umum" {
+		t.Errorf("true/false = %+v", q)
+	}
+
+	want := []ParseError{
+		{Line: 14, Rule: "unsupported_question_type", Param: "numerical"},
+		{Line: 16, Rule: "unsupported_question_type", Param: "matching"},
+		{Line: 18, Rule: "unsupported_question_type", Param: "description"},
+		{Line: 20, Rule: "syntax", Param: "}"},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("errors = %+v", errs)
+	}
+}
+
+func TestParseAikenErrors(t *testing.T) {
+	src := strings.Join([]string{
+		"Ibu kota Indonesia?",
+		"A. Jakarta",
+		"B) Bandung",
+		"ANSWER: A",
+		"Soal tanpa kunci",
+		"A. satu",
+		"B. dua",
+		"Soal dengan kunci salah",
+		"A. satu",
+		"ANSWER: C",
+		"",
+		"Benar atau salah: air mendidih pada 100 C",
+		"A. benar",
+		"B. salah",
+		"ANSWER: A",
+	}, "\n")
+
+	items, errs := ParseAiken(src, labels)
+	if len(items) != 2 || items[0].Question.Options[0].Text != "Jakarta" || items[1].Line != 12 || items[1].Question.Type != models.TypeTrueFalse {
+		t.Errorf("items = %+v", items)
+	}
+	want := []ParseError{
+		{Line: 5, Rule: "answer_missing"},
+		{Line: 8, Rule: "answer_not_option", Param: "C"},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("errors = %+v", errs)
+	}
+}
@@ -0,0 +1,68 @@
+package psychometrics
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+// ReportedPercentiles are the percentiles included in a Summary
+var ReportedPercentiles = []float64{10, 25, 50, 75, 90}
+
+// Summary describes a set of scores
+type Summary struct {
+	Count  int     `json:"count"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	// StdDev is the sample standard deviation (n-1), 0 for fewer than two scores
+	StdDev float64 `json:"stdDev"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	// Percentiles maps "p10", "p25", ... to values (linear interpolation between closest ranks)
+	Percentiles map[string]float64 `json:"percentiles"`
+}
+
+// Describe summarizes values; an empty input gives a zero Summary
+func Describe(values []float64) Summary {
+	summary := Summary{Count: len(values), Percentiles: map[string]float64{}}
+	if len(values) == 0 {
+		return summary
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	summary.Mean = mean(sorted)
+	summary.Min = sorted[0]
+	summary.Max = sorted[len(sorted)-1]
+	summary.Median = Percentile(sorted, 50)
+	if n := len(sorted); n > 1 {
+		summary.StdDev = math.Sqrt(variance(sorted) * float64(n) / float64(n-1))
+	}
+	for _, p := range ReportedPercentiles {
+		summary.Percentiles[percentileKey(p)] = Percentile(sorted, p)
+	}
+	return summary
+}
+
+// Percentile of sorted values (0-100), interpolating linearly between ranks
+// (the method of Excel's PERCENTILE.INC and numpy's default)
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	if lo < 0 {
+		lo = 0
+	}
+	if hi >= len(sorted) {
+		hi = len(sorted) - 1
+	}
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
+
+func percentileKey(p float64) string {
+	return "p" + strconv.Itoa(int(p))
+}
@@ -0,0 +1,34 @@
+package psychometrics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	s := Describe([]float64{90, 40, 70, 60, 80})
+
+	if s.Count != 5 || s.Mean != 68 || s.Median != 70 || s.Min != 40 || s.Max != 90 {
+		t.Fatalf("summary = %+v", s)
+	}
+	// Sample SD of 40..90: sqrt(1480/4)
+	if math.Abs(s.StdDev-math.Sqrt(370)) > 1e-9 {
+		t.Errorf("stdDev = %v", s.StdDev)
+	}
+	// Sorted 40 60 70 80 90, rank = p/100 * 4
+	want := map[string]float64{"p10": 48, "p25": 60, "p50": 70, "p75": 80, "p90": 86}
+	for k, v := range want {
+		if math.Abs(s.Percentiles[k]-v) > 1e-9 {
+			t.Errorf("%s = %v, want %v", k, s.Percentiles[k], v)
+		}
+	}
+}
+
+func TestDescribeSmallInputs(t *testing.T) {
+	if s := Describe(nil); s.Count != 0 || s.Mean != 0 || len(s.Percentiles) != 0 {
+		t.Errorf("empty = %+v", s)
+	}
+	if s := Describe([]float64{75}); s.StdDev != 0 || s.Median != 75 || s.Percentiles["p90"] != 75 {
+		t.Errorf("single = %+v", s)
+	}
+}
@@ -0,0 +1,226 @@
+// Package psychometrics holds the classical test theory statistics used by the reports:
+// per-item difficulty, discrimination and distractor analysis. It works on plain response
+// sheets so it has no database dependency; handlers build the sheets from Answer rows.
+package psychometrics
+
+import (
+	"math"
+	"sort"
+)
+
+// UpperLowerFraction is the share of examinees in each of the upper and lower groups
+// used for the discrimination index (Kelley's 27%)
+const UpperLowerFraction = 0.27
+
+// Item is one question of the test
+type Item struct {
+	ID      string
+	Points  float64
+	Options []string // option IDs in display order, empty for open questions
+	Keys    []string // correct option IDs
+	Scored  bool     // false when there is no answer key (essays), such items only get blank/option counts
+}
+
+// Response is what one examinee gave for one item
+type Response struct {
+	OptionID string
+	Blank    bool
+	Correct  bool
+}
+
+// Sheet is one examinee's responses keyed by item ID. Items missing from the map count as blank.
+type Sheet struct {
+	ExamineeID string
+	Responses  map[string]Response
+}
+
+type OptionStats struct {
+	OptionID   string  `json:"optionId"`
+	IsKey      bool    `json:"isKey"`
+	Count      int     `json:"count"`
+	Proportion float64 `json:"proportion"` // of all examinees
+	UpperCount int     `json:"upperCount"`
+	LowerCount int     `json:"lowerCount"`
+}
+
+type ItemStats struct {
+	ItemID    string  `json:"itemId"`
+	Examinees int     `json:"examinees"`
+	Answered  int     `json:"answered"`
+	Blank     int     `json:"blank"`
+	BlankRate float64 `json:"blankRate"`
+	Correct   int     `json:"correct"`
+	// Difficulty is the p-value: proportion of examinees answering correctly (blank = wrong)
+	Difficulty *float64 `json:"difficulty"`
+	// Discrimination is D = p(upper 27%) - p(lower 27%)
+	Discrimination *float64 `json:"discrimination"`
+	// PointBiserial correlates the item (0/1) with the rest score: the total without this item
+	// (corrected item-total correlation), so the item does not correlate with itself
+	PointBiserial *float64      `json:"pointBiserial"`
+	Options       []OptionStats `json:"options"`
+}
+
+// TotalScores returns each sheet's raw score (sum of points of correctly answered scored items)
+func TotalScores(items []Item, sheets []Sheet) []float64 {
+	totals := make([]float64, len(sheets))
+	for i, sheet := range sheets {
+		for _, item := range items {
+			if item.Scored && sheet.Responses[item.ID].Correct {
+				totals[i] += item.Points
+			}
+		}
+	}
+	return totals
+}
+
+// UpperLowerGroups ranks examinees by total score and returns the indexes of the top and
+// bottom groups. Ties are broken by ExamineeID so the result is deterministic.
+func UpperLowerGroups(sheets []Sheet, totals []float64) (upper, lower []int) {
+	n := len(sheets)
+	if n < 2 {
+		return nil, nil
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if totals[order[a]] != totals[order[b]] {
+			return totals[order[a]] > totals[order[b]]
+		}
+		return sheets[order[a]].ExamineeID < sheets[order[b]].ExamineeID
+	})
+
+	size := int(math.Round(UpperLowerFraction * float64(n)))
+	if size < 1 {
+		size = 1
+	}
+	if size > n/2 {
+		size = n / 2
+	}
+	return order[:size], order[n-size:]
+}
+
+// AnalyzeItems computes the statistics for every item, in the order given
+func AnalyzeItems(items []Item, sheets []Sheet) []ItemStats {
+	totals := TotalScores(items, sheets)
+	upper, lower := UpperLowerGroups(sheets, totals)
+	inUpper := indexSet(upper)
+	inLower := indexSet(lower)
+	n := len(sheets)
+
+	stats := make([]ItemStats, 0, len(items))
+	for _, item := range items {
+		st := ItemStats{ItemID: item.ID, Examinees: n, Options: []OptionStats{}}
+
+		optionIdx := make(map[string]int, len(item.Options))
+		for i, id := range item.Options {
+			optionIdx[id] = i
+			st.Options = append(st.Options, OptionStats{OptionID: id, IsKey: contains(item.Keys, id)})
+		}
+
+		scores := make([]float64, n) // 0/1 per examinee
+		upperCorrect, lowerCorrect := 0, 0
+		for i, sheet := range sheets {
+			resp, ok := sheet.Responses[item.ID]
+			if !ok || resp.Blank {
+				st.Blank++
+				continue
+			}
+			st.Answered++
+			if oi, ok := optionIdx[resp.OptionID]; ok {
+				st.Options[oi].Count++
+				if inUpper[i] {
+					st.Options[oi].UpperCount++
+				}
+				if inLower[i] {
+					st.Options[oi].LowerCount++
+				}
+			}
+			if item.Scored && resp.Correct {
+				st.Correct++
+				scores[i] = 1
+				if inUpper[i] {
+					upperCorrect++
+				}
+				if inLower[i] {
+					lowerCorrect++
+				}
+			}
+		}
+
+		if n > 0 {
+			st.BlankRate = float64(st.Blank) / float64(n)
+			for i := range st.Options {
+				st.Options[i].Proportion = float64(st.Options[i].Count) / float64(n)
+			}
+		}
+
+		if item.Scored && n > 0 {
+			p := float64(st.Correct) / float64(n)
+			st.Difficulty = &p
+			if len(upper) > 0 {
+				d := float64(upperCorrect)/float64(len(upper)) - float64(lowerCorrect)/float64(len(lower))
+				st.Discrimination = &d
+			}
+			rest := make([]float64, n)
+			for i := range totals {
+				rest[i] = totals[i] - scores[i]*item.Points
+			}
+			st.PointBiserial = pearson(scores, rest)
+		}
+
+		stats = append(stats, st)
+	}
+	return stats
+}
+
+// pearson is the correlation of x and y, nil when either has no variance.
+// With x dichotomous this is the point-biserial coefficient.
+func pearson(x, y []float64) *float64 {
+	n := float64(len(x))
+	if n < 2 {
+		return nil
+	}
+	mx, my := mean(x), mean(y)
+	var sxy, sxx, syy float64
+	for i := range x {
+		dx, dy := x[i]-mx, y[i]-my
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return nil
+	}
+	r := sxy / math.Sqrt(sxx*syy)
+	return &r
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func indexSet(indexes []int) map[int]bool {
+	set := make(map[int]bool, len(indexes))
+	for _, i := range indexes {
+		set[i] = true
+	}
+	return set
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package psychometrics
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func near(t *testing.T, name string, got *float64, want float64) {
+	t.Helper()
+	if got == nil {
+		t.Errorf("%s = nil, want %.4f", name, want)
+		return
+	}
+	if math.Abs(*got-want) > 1e-4 {
+		t.Errorf("%s = %.4f, want %.4f", name, *got, want)
+	}
+}
+
+// Ten examinees, q1 answered correctly by the top five only, q2 by everyone, q3 an essay
+func sampleTest() ([]Item, []Sheet) {
+	items := []Item{
+		{ID: "q1", Points: 1, Options: []string{"a", "b", "c"}, Keys: []string{"a"}, Scored: true},
+		{ID: "q2", Points: 1, Options: []string{"a", "b"}, Keys: []string{"b"}, Scored: true},
+		{ID: "q3", Points: 0},
+	}
+	var sheets []Sheet
+	for i := 0; i < 10; i++ {
+		resp := map[string]Response{"q2": {OptionID: "b", Correct: true}}
+		switch {
+		case i < 5:
+			resp["q1"] = Response{OptionID: "a", Correct: true}
+		case i < 8:
+			resp["q1"] = Response{OptionID: "b"}
+		default:
+			// blank: no answer row at all
+		}
+		if i%2 == 0 {
+			resp["q3"] = Response{Blank: true}
+		}
+		sheets = append(sheets, Sheet{ExamineeID: fmt.Sprintf("s%02d", i), Responses: resp})
+	}
+	return items, sheets
+}
+
+func TestAnalyzeItems(t *testing.T) {
+	items, sheets := sampleTest()
+	stats := AnalyzeItems(items, sheets)
+
+	q1 := stats[0]
+	near(t, "q1 difficulty", q1.Difficulty, 0.5)
+	// Groups of round(2.7) = 3: upper all correct, lower none
+	near(t, "q1 discrimination", q1.Discrimination, 1)
+	// Without q1 everyone scores the same q2 point: the rest score has no variance
+	if q1.PointBiserial != nil {
+		t.Errorf("q1 point-biserial = %v, want nil (constant rest score)", *q1.PointBiserial)
+	}
+	if q1.Blank != 2 || q1.BlankRate != 0.2 {
+		t.Errorf("q1 blank = %d (%.2f), want 2 (0.20)", q1.Blank, q1.BlankRate)
+	}
+	if got := [3]int{q1.Options[0].Count, q1.Options[1].Count, q1.Options[2].Count}; got != [3]int{5, 3, 0} {
+		t.Errorf("q1 option counts = %v, want [5 3 0]", got)
+	}
+	if !q1.Options[0].IsKey || q1.Options[1].IsKey {
+		t.Errorf("q1 key flags wrong: %+v", q1.Options)
+	}
+	if q1.Options[1].LowerCount != 1 || q1.Options[1].UpperCount != 0 {
+		t.Errorf("distractor b upper/lower = %d/%d, want 0/1", q1.Options[1].UpperCount, q1.Options[1].LowerCount)
+	}
+
+	q2 := stats[1]
+	near(t, "q2 difficulty", q2.Difficulty, 1)
+	near(t, "q2 discrimination", q2.Discrimination, 0)
+	if q2.PointBiserial != nil {
+		t.Errorf("q2 point-biserial = %v, want nil (no variance)", *q2.PointBiserial)
+	}
+
+	q3 := stats[2]
+	if q3.Difficulty != nil || q3.Discrimination != nil {
+		t.Errorf("essay got difficulty/discrimination")
+	}
+	// Even examinees sent an empty answer, odd ones none: all blank
+	if q3.BlankRate != 1 {
+		t.Errorf("q3 blank rate = %.2f, want 1", q3.BlankRate)
+	}
+}
+
+// The point-biserial is the item-rest correlation; against the total including the item it
+// would be 0.9045 here
+func TestPointBiserialExcludesTheItem(t *testing.T) {
+	items := []Item{
+		{ID: "a", Points: 1, Keys: []string{"x"}, Scored: true},
+		{ID: "b", Points: 1, Keys: []string{"x"}, Scored: true},
+		{ID: "c", Points: 1, Keys: []string{"x"}, Scored: true},
+	}
+	rows := [][3]bool{{true, true, true}, {true, true, false}, {false, true, false}, {false, false, true}}
+	var sheets []Sheet
+	for i, row := range rows {
+		resp := map[string]Response{}
+		for j, correct := range row {
+			resp[items[j].ID] = Response{OptionID: "x", Correct: correct}
+		}
+		sheets = append(sheets, Sheet{ExamineeID: fmt.Sprintf("s%d", i), Responses: resp})
+	}
+	near(t, "a point-biserial", AnalyzeItems(items, sheets)[0].PointBiserial, 0.5/math.Sqrt(0.75))
+}
+
+func TestAnalyzeItemsWithoutExaminees(t *testing.T) {
+	items, _ := sampleTest()
+	stats := AnalyzeItems(items, nil)
+	if len(stats) != 3 || stats[0].Difficulty != nil || stats[0].BlankRate != 0 {
+		t.Fatalf("empty analysis = %+v", stats[0])
+	}
+}
@@ -0,0 +1,191 @@
+package psychometrics
+
+import "math"
+
+// Reliability statistics use population variances (divide by N), as in the
+// original KR-20/KR-21 and alpha formulas and most school item-analysis tools.
+
+// HistogramBin counts total scores (as a percentage of the maximum) in [From, To).
+// The last bin also includes To.
+type HistogramBin struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Count int     `json:"count"`
+}
+
+type ReliabilityStats struct {
+	Examinees int `json:"examinees"`
+	Items     int `json:"items"` // scored items only
+	// Raw total scores in points
+	MaxScore float64 `json:"maxScore"`
+	Mean     float64 `json:"mean"`
+	StdDev   float64 `json:"stdDev"`
+	Min      float64 `json:"min"`
+	Max      float64 `json:"max"`
+	// KR20 and KR21 treat every item as right/wrong (number-correct scale)
+	KR20 *float64 `json:"kr20"`
+	KR21 *float64 `json:"kr21"`
+	// CronbachAlpha uses the item points, it equals KR-20 when every item is worth the same
+	CronbachAlpha *float64 `json:"cronbachAlpha"`
+	// SEM is the standard error of measurement in points: StdDev * sqrt(1 - alpha)
+	SEM       *float64       `json:"sem"`
+	Histogram []HistogramBin `json:"histogram"`
+}
+
+// DefaultHistogramBins splits 0-100% into 10% bands
+const DefaultHistogramBins = 10
+
+// Reliability computes the test-level statistics over the scored items
+func Reliability(items []Item, sheets []Sheet, bins int) ReliabilityStats {
+	scored := make([]Item, 0, len(items))
+	maxScore := 0.0
+	for _, item := range items {
+		if item.Scored {
+			scored = append(scored, item)
+			maxScore += item.Points
+		}
+	}
+
+	n := len(sheets)
+	k := len(scored)
+	stats := ReliabilityStats{Examinees: n, Items: k, MaxScore: maxScore}
+
+	// points[i][j] and right[i][j] are examinee i's score on item j
+	points := make([][]float64, n)
+	right := make([][]float64, n)
+	for i, sheet := range sheets {
+		points[i] = make([]float64, k)
+		right[i] = make([]float64, k)
+		for j, item := range scored {
+			if sheet.Responses[item.ID].Correct {
+				points[i][j] = item.Points
+				right[i][j] = 1
+			}
+		}
+	}
+
+	totals := rowSums(points)
+	counts := rowSums(right)
+	stats.Histogram = Histogram(percentages(totals, maxScore), bins)
+	if n == 0 {
+		return stats
+	}
+
+	stats.Mean = mean(totals)
+	stats.StdDev = math.Sqrt(variance(totals))
+	stats.Min, stats.Max = totals[0], totals[0]
+	for _, t := range totals {
+		stats.Min = math.Min(stats.Min, t)
+		stats.Max = math.Max(stats.Max, t)
+	}
+
+	stats.KR20 = alpha(right)
+	stats.KR21 = kr21(k, mean(counts), variance(counts))
+	stats.CronbachAlpha = alpha(points)
+	if stats.CronbachAlpha != nil {
+		// A negative alpha means the items do not measure one thing; SEM is then capped at the SD
+		r := math.Max(0, *stats.CronbachAlpha)
+		sem := stats.StdDev * math.Sqrt(1-r)
+		stats.SEM = &sem
+	}
+	return stats
+}
+
+// alpha is Cronbach's coefficient: k/(k-1) * (1 - sum of item variances / total variance).
+// On 0/1 scores the item variance is pq, which makes this KR-20.
+// Nil with fewer than two items or examinees, or when total scores do not vary.
+func alpha(matrix [][]float64) *float64 {
+	n := len(matrix)
+	if n < 2 {
+		return nil
+	}
+	k := len(matrix[0])
+	if k < 2 {
+		return nil
+	}
+
+	totalVar := variance(rowSums(matrix))
+	if totalVar == 0 {
+		return nil
+	}
+
+	itemVarSum := 0.0
+	column := make([]float64, n)
+	for j := 0; j < k; j++ {
+		for i := range matrix {
+			column[i] = matrix[i][j]
+		}
+		itemVarSum += variance(column)
+	}
+
+	kf := float64(k)
+	r := kf / (kf - 1) * (1 - itemVarSum/totalVar)
+	return &r
+}
+
+// kr21 assumes all items are equally difficult: k/(k-1) * (1 - M(k-M) / (k * var))
+func kr21(k int, m, v float64) *float64 {
+	if k < 2 || v == 0 {
+		return nil
+	}
+	kf := float64(k)
+	r := kf / (kf - 1) * (1 - m*(kf-m)/(kf*v))
+	return &r
+}
+
+// Histogram counts percentage scores (0-100) in equal-width bins
+func Histogram(percentScores []float64, bins int) []HistogramBin {
+	if bins <= 0 {
+		bins = DefaultHistogramBins
+	}
+	width := 100.0 / float64(bins)
+	result := make([]HistogramBin, bins)
+	for b := range result {
+		result[b] = HistogramBin{From: float64(b) * width, To: float64(b+1) * width}
+	}
+	for _, p := range percentScores {
+		b := int(p / width)
+		if b >= bins {
+			b = bins - 1
+		}
+		if b < 0 {
+			b = 0
+		}
+		result[b].Count++
+	}
+	return result
+}
+
+func percentages(totals []float64, maxScore float64) []float64 {
+	result := make([]float64, len(totals))
+	if maxScore == 0 {
+		return result
+	}
+	for i, t := range totals {
+		result[i] = t / maxScore * 100
+	}
+	return result
+}
+
+func rowSums(matrix [][]float64) []float64 {
+	sums := make([]float64, len(matrix))
+	for i, row := range matrix {
+		for _, v := range row {
+			sums[i] += v
+		}
+	}
+	return sums
+}
+
+// variance is the population variance
+func variance(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	m := mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,73 @@
+package psychometrics
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Guttman pattern: examinee i gets the first 4-i items right
+func guttmanTest(points float64) ([]Item, []Sheet) {
+	var items []Item
+	for j := 0; j < 4; j++ {
+		items = append(items, Item{ID: fmt.Sprintf("q%d", j), Points: points, Options: []string{"a", "b"}, Keys: []string{"a"}, Scored: true})
+	}
+	items = append(items, Item{ID: "essay"}) // unscored, ignored
+	var sheets []Sheet
+	for i := 0; i < 5; i++ {
+		resp := map[string]Response{}
+		for j := 0; j < 4-i; j++ {
+			resp[fmt.Sprintf("q%d", j)] = Response{OptionID: "a", Correct: true}
+		}
+		sheets = append(sheets, Sheet{ExamineeID: fmt.Sprintf("s%d", i), Responses: resp})
+	}
+	return items, sheets
+}
+
+func TestReliability(t *testing.T) {
+	items, sheets := guttmanTest(1)
+	r := Reliability(items, sheets, 10)
+
+	if r.Items != 4 || r.Examinees != 5 || r.MaxScore != 4 || r.Mean != 2 {
+		t.Fatalf("items %d examinees %d max %.1f mean %.1f", r.Items, r.Examinees, r.MaxScore, r.Mean)
+	}
+	// p = .8 .6 .4 .2, sum pq = .8, total variance 2
+	near(t, "KR-20", r.KR20, 0.8)
+	near(t, "KR-21", r.KR21, 2.0/3)
+	near(t, "alpha", r.CronbachAlpha, 0.8)
+	near(t, "SEM", r.SEM, 0.632456) // sqrt(2) * sqrt(0.2)
+
+	want := []int{1, 0, 1, 0, 0, 1, 0, 1, 0, 1} // 0, 25, 50, 75, 100 percent
+	for i, bin := range r.Histogram {
+		if bin.Count != want[i] {
+			t.Errorf("bin %.0f-%.0f = %d, want %d", bin.From, bin.To, bin.Count, want[i])
+		}
+	}
+}
+
+func TestReliabilityScalesWithPoints(t *testing.T) {
+	items, sheets := guttmanTest(2.5)
+	r := Reliability(items, sheets, 10)
+
+	// Alpha and KR-20 do not depend on the point scale, SEM does
+	near(t, "KR-20", r.KR20, 0.8)
+	near(t, "alpha", r.CronbachAlpha, 0.8)
+	near(t, "SEM", r.SEM, 0.632456*2.5)
+}
+
+func TestReliabilityUndefined(t *testing.T) {
+	items, sheets := guttmanTest(1)
+
+	// Everyone with the same score: no variance
+	same := []Sheet{sheets[2], sheets[2], sheets[2]}
+	r := Reliability(items, same, 0)
+	if r.KR20 != nil || r.CronbachAlpha != nil || r.SEM != nil {
+		t.Errorf("reliability without variance should be nil")
+	}
+	if len(r.Histogram) != DefaultHistogramBins {
+		t.Errorf("histogram bins = %d", len(r.Histogram))
+	}
+
+	if r := Reliability(items, nil, 5); r.KR20 != nil || r.Mean != 0 {
+		t.Errorf("empty input: %+v", r)
+	}
+}
@@ -0,0 +1,117 @@
+// Package qti reads and writes IMS QTI content packages (a zip with imsmanifest.xml, one XML
+// file per item and an assessment test), the format most assessment platforms exchange.
+// Packages are written as QTI 2.1 or 3.0; both are read, as is 2.2.
+//
+// Our question types map to QTI interactions:
+//
+//	mcq           choiceInteraction, one choice
+//	true_false    choiceInteraction with two choices and class="true-false"
+//	short_answer  textEntryInteraction
+//	essay         extendedTextInteraction
+//
+// Points travel as the item's MAXSCORE outcome (and the test's item weight), explanations as
+// modal feedback. Items with any other interaction, or with several, are reported per item
+// rather than dropped.
+//
+// A question's image is an <img> and its audio an <object> in the item body, a choice's image
+// an <img> in the choice; the files go in the package under media/. MathML in question and
+// option texts (inline <math> markup) is written as MathML and read back as markup; LaTeX is
+// plain text and travels as is.
+package qti
+
+import (
+	"academic-suite-backend/models"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Version is a QTI version
+type Version string
+
+const (
+	V21 Version = "2.1"
+	V30 Version = "3.0"
+)
+
+// ErrNotPackage means the data is not a zip with an imsmanifest.xml
+var ErrNotPackage = errors.New("qti: not a content package")
+
+// ErrTooLarge means the package unpacks to more than an import takes
+var ErrTooLarge = errors.New("qti: package too large")
+
+// Package is what an imported content package holds
+type Package struct {
+	Title string
+	Items []Item
+	// Errors are the items that could not be imported
+	Errors []ItemError
+	// Files are the images and audio the items use, by path in the package. The ImageID and
+	// AudioID of imported questions and options hold these paths until the files are stored.
+	Files map[string]File
+}
+
+// File is an image or audio file of a package
+type File struct {
+	Name        string // file name, e.g. "peta.png"
+	ContentType string // used when writing; Read leaves it empty
+	Data        []byte
+}
+
+// Item is an imported question and where it is in the package (1-based, test order)
+type Item struct {
+	Position   int
+	Identifier string
+	Question   models.Question
+}
+
+// ItemError is an item that could not be imported; Rule and Param name the problem like a
+// validation rule does ("unsupported_interaction", "orderInteraction")
+type ItemError struct {
+	Position   int
+	Identifier string
+	Rule       string
+	Param      string
+}
+
+func (e ItemError) Error() string {
+	return fmt.Sprintf("item %d (%s): %s %s", e.Position, e.Identifier, e.Rule, e.Param)
+}
+
+// Labels are the option texts of true/false questions whose choices carry no text
+type Labels struct {
+	True  string
+	False string
+}
+
+// DefaultPoints is given to items that state no score
+const DefaultPoints = 1
+
+// IsPackage tells whether data is a zip with an imsmanifest.xml at its root
+func IsPackage(data []byte) bool {
+	if !bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return false
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return false
+	}
+	for _, f := range zr.File {
+		if f.Name == "imsmanifest.xml" {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	ns21         = "http://www.imsglobal.org/xsd/imsqti_v2p1"
+	ns30         = "http://www.imsglobal.org/xsd/imsqtiasi_v3p0"
+	nsManifest21 = "http://www.imsglobal.org/xsd/imscp_v1p1"
+	nsManifest30 = "http://www.imsglobal.org/xsd/qti/qtiv3p0/imscp_v1p1"
+	nsMathML     = "http://www.w3.org/1998/Math/MathML"
+)
+
+// trueFalseClass marks the choice interaction of a true/false question
+const trueFalseClass = "true-false"
@@ -0,0 +1,253 @@
+package qti
+
+import (
+	"academic-suite-backend/models"
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var labels = Labels{True: "Benar", False: "Salah"}
+
+func opts(correct int, texts ...string) []models.QuestionOption {
+	out := make([]models.QuestionOption, len(texts))
+	for i, t := range texts {
+		out[i] = models.QuestionOption{Text: t, IsCorrect: i == correct}
+	}
+	return out
+}
+
+var quiz = models.Quiz{
+	Title: "Ujian <Akhir> & Remedial",
+	Questions: []models.Question{
+		{Type: models.TypeMCQ, Text: "Hasil 7 × 8 = ?\nPilih satu.", Points: 2, Options: opts(2, "54", "63", "56", "<64>"), Explanation: "7 × 8 = 56"},
+		{Type: models.TypeTrueFalse, Text: "Bumi itu bulat", Points: 1, Options: opts(0, "Benar", "Salah")},
+		{Type: models.TypeShortAnswer, Text: "Lambang kimia air", Points: 3, CorrectAnswer: "H2O", Explanation: "Dua atom H\nsatu atom O"},
+		{Type: models.TypeEssay, Text: "Jelaskan fotosintesis", Points: 10},
+	},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, v := range []Version{V21, V30} {
+		t.Run(string(v), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, quiz, v, nil); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			if !IsPackage(buf.Bytes()) {
+				t.Fatal("written package not detected")
+			}
+			pkg, err := Read(buf.Bytes(), labels)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if pkg.Title != quiz.Title || len(pkg.Errors) > 0 || len(pkg.Items) != len(quiz.Questions) {
+				t.Fatalf("package = %+v", pkg)
+			}
+			for i, item := range pkg.Items {
+				if item.Position != i+1 || !reflect.DeepEqual(item.Question, quiz.Questions[i]) {
+					t.Errorf("item %d:\n got %+v\nwant %+v", i+1, item.Question, quiz.Questions[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWriteUsesVersionNames(t *testing.T) {
+	var buf bytes.Buffer
+	Write(&buf, quiz, V30, nil)
+	files := unpack(t, buf.Bytes())
+	item := files["items/Q1.xml"]
+	for _, want := range []string{`<qti-assessment-item xmlns="http://www.imsglobal.org/xsd/imsqtiasi_v3p0"`, `<qti-choice-interaction response-identifier="RESPONSE"`, "<p>Pilih satu.</p>"} {
+		if !strings.Contains(item, want) {
+			t.Errorf("item lacks %s:\n%s", want, item)
+		}
+	}
+	if !strings.Contains(files["imsmanifest.xml"], `type="imsqti_item_xmlv3p0"`) {
+		t.Errorf("manifest:\n%s", files["imsmanifest.xml"])
+	}
+}
+
+// A package from another platform: QTI 2.1 with unsupported interactions, a mapping instead of
+// a correct response, entities, a prompt and a missing file
+func TestReadForeignPackage(t *testing.T) {
+	item := func(id, body string, decl ...string) string {
+		return `<?xml version="1.0" encoding="UTF-8"?>
+<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="` + id + `" title="` + id + `" adaptive="false" timeDependent="false">` +
+			strings.Join(decl, "") + `<itemBody>` + body + `</itemBody></assessmentItem>`
+	}
+	data := zipOf(t, map[string]string{
+		"imsmanifest.xml": `<manifest xmlns="http://www.imsglobal.org/xsd/imscp_v1p1"><resources>
+			<resource identifier="i1" type="imsqti_item_xmlv2p1"><file href="items/choice%20one.xml"/></resource>
+			<resource identifier="i2" type="imsqti_item_xmlv2p1" href="items/order.xml"/>
+			<resource identifier="i3" type="imsqti_item_xmlv2p1" href="items/gaps.xml"/>
+			<resource identifier="i4" type="imsqti_item_xmlv2p1" href="items/multi.xml"/>
+			<resource identifier="i5" type="imsqti_item_xmlv2p1" href="items/missing.xml"/>
+			<resource identifier="i6" type="imsqti_item_xmlv2p1" href="items/entry.xml"/>
+		</resources></manifest>`,
+		"items/choice one.xml": item("CHOICE",
+			`<div><p>Ibu&nbsp;kota   <strong>Jawa Timur</strong>?</p></div>
+			<choiceInteraction responseIdentifier="R" maxChoices="1"><prompt>Pilih satu</prompt>
+				<simpleChoice identifier="a">Malang</simpleChoice><simpleChoice identifier="b">Surabaya</simpleChoice>
+			</choiceInteraction>`,
+			`<responseDeclaration identifier="R" cardinality="single" baseType="identifier"><mapping>
+				<mapEntry mapKey="a" mappedValue="0"/><mapEntry mapKey="b" mappedValue="4"/></mapping></responseDeclaration>`),
+		"items/order.xml": item("ORDER", `<orderInteraction responseIdentifier="R"/>`),
+		"items/gaps.xml":  item("GAPS", `<p>A <textEntryInteraction responseIdentifier="R1"/> B <textEntryInteraction responseIdentifier="R2"/></p>`),
+		"items/multi.xml": item("MULTI", `<choiceInteraction responseIdentifier="R" maxChoices="0"><simpleChoice identifier="a">x</simpleChoice></choiceInteraction>`),
+		"items/entry.xml": item("ENTRY", `<p>Lagu kebangsaan: <textEntryInteraction responseIdentifier="R"/>.</p>`,
+			`<responseDeclaration identifier="R" cardinality="single" baseType="string"><correctResponse><value>Indonesia Raya</value></correctResponse></responseDeclaration>`),
+	})
+
+	pkg, err := Read(data, labels)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if len(pkg.Items) != 2 {
+		t.Fatalf("items = %+v", pkg.Items)
+	}
+	choice := pkg.Items[0].Question
+	if choice.Text != "Ibu kota Jawa Timur?\nPilih satu" || !reflect.DeepEqual(choice.Options, opts(1, "Malang", "Surabaya")) || choice.Points != 1 {
+		t.Errorf("choice = %+v", choice)
+	}
+	if entry := pkg.Items[1]; entry.Position != 6 || entry.Question.Text != "Lagu kebangsaan: _____." || entry.Question.CorrectAnswer != "Indonesia Raya" {
+		t.Errorf("entry = %+v", entry)
+	}
+
+	want := []ItemError{
+		{Position: 2, Identifier: "ORDER", Rule: "unsupported_interaction", Param: "orderInteraction"},
+		{Position: 3, Identifier: "GAPS", Rule: "composite_item", Param: "textEntryInteraction, textEntryInteraction"},
+		{Position: 4, Identifier: "MULTI", Rule: "unsupported_interaction", Param: "choiceInteraction maxChoices=0"},
+		{Position: 5, Identifier: "items/missing.xml", Rule: "missing_file", Param: "items/missing.xml"},
+	}
+	if !reflect.DeepEqual(pkg.Errors, want) {
+		t.Errorf("errors = %+v", pkg.Errors)
+	}
+}
+
+func TestRoundTripMediaAndMath(t *testing.T) {
+	const frac = `<math xmlns="http://www.w3.org/1998/Math/MathML"><mfrac><mn>1</mn><mi>x</mi></mfrac></math>`
+	withMedia := models.Quiz{Title: "Listening", Questions: []models.Question{
+		{Type: models.TypeMCQ, Text: "Sederhanakan " + frac + " + " + frac, Points: 1, Options: opts(0, `\(\frac{2}{x}\)`, frac), ImageID: "img-1"},
+		{Type: models.TypeMCQ, Text: "Where is the speaker?", Points: 1, AudioID: "audio-1", ImageID: "img-1",
+			Options: []models.QuestionOption{{ImageID: "img-2", IsCorrect: true}, {Text: "At school"}}},
+	}}
+	media := map[string]File{
+		"img-1":   {Name: "grafik.PNG", ContentType: "image/png", Data: []byte("png")},
+		"img-2":   {Name: "pasar.jpg", ContentType: "image/jpeg", Data: []byte("jpg")},
+		"audio-1": {Name: "dialog 1.mp3", ContentType: "audio/mpeg", Data: []byte("mp3")},
+	}
+
+	for _, v := range []Version{V21, V30} {
+		t.Run(string(v), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, withMedia, v, media); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			files := unpack(t, buf.Bytes())
+			if !strings.Contains(files["items/Q1.xml"], `<math xmlns="http://www.w3.org/1998/Math/MathML"><mfrac>`) ||
+				!strings.Contains(files["imsmanifest.xml"], `<file href="media/audio-1.mp3"/>`) {
+				t.Errorf("package:\n%s\n%s", files["items/Q1.xml"], files["imsmanifest.xml"])
+			}
+
+			pkg, err := Read(buf.Bytes(), labels)
+			if err != nil || len(pkg.Items) != 2 || len(pkg.Errors) > 0 {
+				t.Fatalf("read: %v %+v", err, pkg)
+			}
+			want := map[string]File{
+				"media/img-1.png":   {Name: "img-1.png", Data: []byte("png")},
+				"media/img-2.jpg":   {Name: "img-2.jpg", Data: []byte("jpg")},
+				"media/audio-1.mp3": {Name: "audio-1.mp3", Data: []byte("mp3")},
+			}
+			if !reflect.DeepEqual(pkg.Files, want) {
+				t.Errorf("files = %+v", pkg.Files)
+			}
+			q1, q2 := pkg.Items[0].Question, pkg.Items[1].Question
+			if q1.Text != withMedia.Questions[0].Text || q1.ImageID != "media/img-1.png" ||
+				!reflect.DeepEqual(q1.Options, withMedia.Questions[0].Options) {
+				t.Errorf("math question = %+v", q1)
+			}
+			if q2.AudioID != "media/audio-1.mp3" || q2.ImageID != "media/img-1.png" ||
+				q2.Options[0] != (models.QuestionOption{ImageID: "media/img-2.jpg", IsCorrect: true}) {
+				t.Errorf("listening question = %+v", q2)
+			}
+		})
+	}
+}
+
+func TestReadRejectsOtherZips(t *testing.T) {
+	data := zipOf(t, map[string]string{"content.xml": "<x/>"})
+	if IsPackage(data) {
+		t.Error("zip without manifest detected as a package")
+	}
+	if _, err := Read(data, labels); err != ErrNotPackage {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestReadRefusesOversizedMedia(t *testing.T) {
+	data := zipOf(t, map[string]string{
+		"imsmanifest.xml": `<manifest xmlns="http://www.imsglobal.org/xsd/imscp_v1p1"><resources>
+			<resource identifier="i1" type="imsqti_item_xmlv2p1" href="items/q1.xml"/></resources></manifest>`,
+		"items/q1.xml": `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="Q1"><itemBody>
+			<p>Gambar <img src="bomb.png"/></p>
+			<choiceInteraction responseIdentifier="R" maxChoices="1"><simpleChoice identifier="a">x</simpleChoice></choiceInteraction>
+			</itemBody></assessmentItem>`,
+		"items/bomb.png": strings.Repeat("\x00", maxFileSize+1),
+	})
+	pkg, err := Read(data, labels)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	want := []ItemError{{Position: 1, Identifier: "Q1", Rule: "file_too_large", Param: "items/bomb.png"}}
+	if len(pkg.Items) != 0 || !reflect.DeepEqual(pkg.Errors, want) {
+		t.Errorf("items %+v, errors %+v", pkg.Items, pkg.Errors)
+	}
+}
+
+// Oversized XML fails the item, or the whole package when it is the manifest
+func TestReadRefusesOversizedXML(t *testing.T) {
+	huge := "<x>" + strings.Repeat(" ", maxXMLSize) + "</x>"
+	manifest := `<manifest xmlns="http://www.imsglobal.org/xsd/imscp_v1p1"><resources>
+		<resource identifier="i1" type="imsqti_item_xmlv2p1" href="items/q1.xml"/></resources></manifest>`
+
+	pkg, err := Read(zipOf(t, map[string]string{"imsmanifest.xml": manifest, "items/q1.xml": huge}), labels)
+	want := []ItemError{{Position: 1, Identifier: "items/q1.xml", Rule: "file_too_large", Param: "items/q1.xml"}}
+	if err != nil || !reflect.DeepEqual(pkg.Errors, want) {
+		t.Errorf("huge item: err %v, errors %+v", err, pkg.Errors)
+	}
+	if _, err := Read(zipOf(t, map[string]string{"imsmanifest.xml": huge}), labels); err != ErrTooLarge {
+		t.Errorf("huge manifest: err %v, want ErrTooLarge", err)
+	}
+}
+
+func zipOf(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func unpack(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("unzip: %v", err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		var b bytes.Buffer
+		b.ReadFrom(rc)
+		rc.Close()
+		files[f.Name] = b.String()
+	}
+	return files
+}
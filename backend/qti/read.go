@@ -0,0 +1,483 @@
+package qti
+
+import (
+	"academic-suite-backend/models"
+	"academic-suite-backend/unzip"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"math"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Size limits of an imported package, after decompression
+const (
+	maxXMLSize     = 10 << 20 // manifest, test or item
+	maxFileSize    = 20 << 20 // image or audio
+	maxPackageSize = 200 << 20
+)
+
+// Read imports a content package. Items come in the order of the assessment test, followed by
+// any item the test does not reference, in manifest order.
+func Read(data []byte, labels Labels) (Package, error) {
+	var pkg Package
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return pkg, ErrNotPackage
+	}
+	budget := unzip.NewBudget(maxPackageSize)
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[path.Clean(f.Name)] = f
+	}
+	manifestFile, ok := files["imsmanifest.xml"]
+	if !ok {
+		return pkg, ErrNotPackage
+	}
+	// The manifest and the test cannot be skipped like an item: too large is too large
+	manifest, err := parseFile(budget, manifestFile)
+	if errors.Is(err, unzip.ErrTooLarge) {
+		return pkg, ErrTooLarge
+	}
+	if err != nil {
+		return pkg, err
+	}
+
+	var itemHrefs []string
+	testHref := ""
+	for _, r := range manifest.find("resource") {
+		href := resourceHref(r)
+		switch typ := r.attr("type"); {
+		case href == "":
+		case strings.HasPrefix(typ, "imsqti_item_xml"):
+			itemHrefs = append(itemHrefs, href)
+		case strings.HasPrefix(typ, "imsqti_test_xml") && testHref == "":
+			testHref = href
+		}
+	}
+
+	// Test order first, and the test's item weights as a fallback for points
+	order := []string{}
+	weights := map[string]float64{}
+	if f := files[testHref]; f != nil {
+		test, err := parseFile(budget, f)
+		if errors.Is(err, unzip.ErrTooLarge) {
+			return pkg, ErrTooLarge
+		}
+		if err != nil {
+			return pkg, err
+		}
+		pkg.Title = strings.TrimSpace(test.attr("title"))
+		for _, ref := range test.find("assessmentItemRef") {
+			href := cleanHref(path.Join(path.Dir(testHref), ref.attr("href")))
+			order = append(order, href)
+			for _, w := range ref.find("weight") {
+				weights[href], _ = strconv.ParseFloat(w.attr("value"), 64)
+			}
+		}
+	}
+	seen := map[string]bool{}
+	for _, href := range order {
+		seen[href] = true
+	}
+	for _, href := range itemHrefs {
+		if !seen[href] {
+			order = append(order, href)
+			seen[href] = true
+		}
+	}
+
+	for i, href := range order {
+		fail := func(identifier, rule, param string) {
+			pkg.Errors = append(pkg.Errors, ItemError{Position: i + 1, Identifier: identifier, Rule: rule, Param: param})
+		}
+		f := files[href]
+		if f == nil {
+			fail(href, "missing_file", href)
+			continue
+		}
+		tree, err := parseFile(budget, f)
+		switch {
+		case errors.Is(err, ErrTooLarge):
+			return pkg, err
+		case errors.Is(err, unzip.ErrTooLarge):
+			fail(href, "file_too_large", href)
+			continue
+		case err != nil || tree.Name != "assessmentItem":
+			fail(href, "syntax", href)
+			continue
+		}
+		q, rule, param := readItem(tree, labels, weights[href])
+		if rule == "" {
+			if rule, param, err = readMedia(&q, href, files, budget, &pkg); err != nil {
+				return pkg, err
+			}
+		}
+		if rule != "" {
+			fail(tree.attr("identifier"), rule, param)
+			continue
+		}
+		pkg.Items = append(pkg.Items, Item{Position: i + 1, Identifier: tree.attr("identifier"), Question: q})
+	}
+	return pkg, nil
+}
+
+// readMedia loads the files q's media sources (relative to the item at itemHref) point to
+// into pkg.Files and replaces the sources with their paths in the package. The error is
+// ErrTooLarge once the package as a whole is.
+func readMedia(q *models.Question, itemHref string, files map[string]*zip.File, budget *unzip.Budget, pkg *Package) (rule, param string, err error) {
+	refs := []*string{&q.ImageID, &q.AudioID}
+	for i := range q.Options {
+		refs = append(refs, &q.Options[i].ImageID)
+	}
+	for _, ref := range refs {
+		if *ref == "" {
+			continue
+		}
+		href := cleanHref(path.Join(path.Dir(itemHref), *ref))
+		if _, ok := pkg.Files[href]; !ok {
+			f := files[href]
+			if f == nil {
+				return "missing_file", href, nil
+			}
+			data, err := budget.ReadFile(f, maxFileSize)
+			switch {
+			case errors.Is(err, unzip.ErrArchiveTooLarge):
+				return "", "", ErrTooLarge
+			case errors.Is(err, unzip.ErrTooLarge):
+				return "file_too_large", href, nil
+			case err != nil:
+				return "syntax", href, nil
+			}
+			if pkg.Files == nil {
+				pkg.Files = map[string]File{}
+			}
+			pkg.Files[href] = File{Name: path.Base(href), Data: data}
+		}
+		*ref = href
+	}
+	return "", "", nil
+}
+
+// parseFile reads an XML entry within the limits. The error is ErrTooLarge when the package
+// is too large, or unzip.ErrTooLarge when only this file is.
+func parseFile(budget *unzip.Budget, f *zip.File) (*node, error) {
+	data, err := budget.ReadFile(f, maxXMLSize)
+	if errors.Is(err, unzip.ErrArchiveTooLarge) {
+		return nil, ErrTooLarge
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseTree(bytes.NewReader(data))
+}
+
+func resourceHref(r *node) string {
+	href := r.attr("href")
+	if href == "" {
+		if file := r.child("file"); file != nil {
+			href = file.attr("href")
+		}
+	}
+	if href == "" {
+		return ""
+	}
+	return cleanHref(href)
+}
+
+func cleanHref(href string) string {
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	return path.Clean(strings.TrimPrefix(href, "./"))
+}
+
+// readItem maps an assessmentItem to a question; a non-empty rule says why it cannot be
+func readItem(item *node, labels Labels, weight float64) (q models.Question, rule, param string) {
+	body := item.child("itemBody")
+	if body == nil {
+		return q, "no_interaction", ""
+	}
+	var interactions []*node
+	var names []string
+	walk(body, func(n *node) bool {
+		if strings.HasSuffix(n.Name, "Interaction") {
+			interactions = append(interactions, n)
+			names = append(names, n.Name)
+			return false
+		}
+		return true
+	})
+	switch len(interactions) {
+	case 0:
+		return q, "no_interaction", ""
+	case 1:
+	default:
+		return q, "composite_item", strings.Join(names, ", ")
+	}
+	interaction := interactions[0]
+
+	var response *node
+	for _, d := range item.find("responseDeclaration") {
+		if d.attr("identifier") == interaction.attr("responseIdentifier") {
+			response = d
+		}
+	}
+	correct := correctValues(response)
+
+	q.Text = blockText(body)
+	q.ImageID, q.AudioID = bodyMedia(body)
+	switch interaction.Name {
+	case "choiceInteraction":
+		if n := interaction.attr("maxChoices"); (n != "" && n != "1") || (response != nil && response.attr("cardinality") == "multiple") {
+			return q, "unsupported_interaction", "choiceInteraction maxChoices=" + n
+		}
+		q.Type = models.TypeMCQ
+		if hasClass(interaction, trueFalseClass) {
+			q.Type = models.TypeTrueFalse
+		}
+		for i, choice := range interaction.find("simpleChoice") {
+			opt := models.QuestionOption{Text: blockText(choice), IsCorrect: contains(correct, choice.attr("identifier"))}
+			if img := choice.find("img"); len(img) > 0 && localSrc(img[0].attr("src")) {
+				opt.ImageID = img[0].attr("src")
+			}
+			if opt.Text == "" && opt.ImageID == "" && q.Type == models.TypeTrueFalse {
+				opt.Text = []string{labels.True, labels.False}[min(i, 1)]
+			}
+			q.Options = append(q.Options, opt)
+		}
+	case "textEntryInteraction":
+		q.Type = models.TypeShortAnswer
+		if len(correct) > 0 {
+			q.CorrectAnswer = correct[0]
+		}
+	case "extendedTextInteraction":
+		q.Type = models.TypeEssay
+	default:
+		return q, "unsupported_interaction", interaction.Name
+	}
+
+	q.Points = DefaultPoints
+	if maxScore := outcomeDefault(item, "MAXSCORE"); maxScore > 0 {
+		q.Points = int(math.Round(maxScore))
+	} else if weight > 0 {
+		q.Points = int(math.Round(weight))
+	}
+
+	var explanations []string
+	for _, feedback := range item.find("modalFeedback") {
+		if t := blockText(feedback); t != "" {
+			explanations = append(explanations, t)
+		}
+	}
+	q.Explanation = strings.Join(explanations, "\n")
+	return q, "", ""
+}
+
+// bodyMedia finds the first image and audio of an item body outside its interaction, as
+// sources relative to the item; files elsewhere (http://...) are left out
+func bodyMedia(body *node) (image, audio string) {
+	walk(body, func(n *node) bool {
+		src := ""
+		switch n.Name {
+		case "img":
+			if src = n.attr("src"); image == "" && localSrc(src) {
+				image = src
+			}
+		case "object":
+			src = n.attr("data")
+			switch {
+			case !localSrc(src):
+			case strings.HasPrefix(n.attr("type"), "audio/") || audioExtensions[strings.ToLower(path.Ext(src))]:
+				if audio == "" {
+					audio = src
+				}
+			case strings.HasPrefix(n.attr("type"), "image/") && image == "":
+				image = src
+			}
+			return false
+		case "audio":
+			if src = n.attr("src"); src == "" {
+				if source := n.child("source"); source != nil {
+					src = source.attr("src")
+				}
+			}
+			if audio == "" && localSrc(src) {
+				audio = src
+			}
+			return false
+		}
+		return !strings.HasSuffix(n.Name, "Interaction")
+	})
+	return image, audio
+}
+
+var audioExtensions = map[string]bool{".mp3": true, ".wav": true, ".ogg": true, ".oga": true, ".m4a": true}
+
+// localSrc tells whether src is a file of the package rather than a URL
+func localSrc(src string) bool {
+	return src != "" && !strings.Contains(src, ":") && !strings.HasPrefix(src, "/")
+}
+
+// correctValues is the correct response, or the best-scoring key of its mapping
+func correctValues(response *node) []string {
+	if response == nil {
+		return nil
+	}
+	var values []string
+	if cr := response.child("correctResponse"); cr != nil {
+		for _, v := range cr.find("value") {
+			values = append(values, strings.TrimSpace(blockText(v)))
+		}
+	}
+	if len(values) > 0 {
+		return values
+	}
+	best, bestValue := "", 0.0
+	for _, entry := range response.find("mapEntry") {
+		if v, _ := strconv.ParseFloat(entry.attr("mappedValue"), 64); v > bestValue {
+			best, bestValue = entry.attr("mapKey"), v
+		}
+	}
+	if best != "" {
+		return []string{best}
+	}
+	return nil
+}
+
+func outcomeDefault(item *node, identifier string) float64 {
+	for _, d := range item.find("outcomeDeclaration") {
+		if d.attr("identifier") != identifier {
+			continue
+		}
+		if dv := d.child("defaultValue"); dv != nil {
+			if v := dv.child("value"); v != nil {
+				f, _ := strconv.ParseFloat(strings.TrimSpace(blockText(v)), 64)
+				return f
+			}
+		}
+	}
+	return 0
+}
+
+func hasClass(n *node, class string) bool {
+	for _, c := range strings.Fields(n.attr("class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// walk calls fn on every element below n in document order; fn returning false skips the
+// element's children
+func walk(n *node, fn func(*node) bool) {
+	for _, c := range n.Children {
+		if c.Name != "" && fn(c) {
+			walk(c, fn)
+		}
+	}
+}
+
+// blockElements start a new line of text
+var blockElements = map[string]bool{
+	"p": true, "div": true, "li": true, "pre": true, "blockquote": true, "tr": true, "prompt": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "contentBody": true,
+}
+
+// blockText renders the content of n as plain text, a line per block (<p>, <div>, <br>...);
+// whitespace collapses as in HTML. Interactions and feedback are left out, but the prompt of
+// a choice is kept and a text entry in running text reads "_____".
+func blockText(n *node) string {
+	var b strings.Builder
+	renderText(&b, n)
+	var out []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func renderText(b *strings.Builder, n *node) {
+	for _, c := range n.Children {
+		switch {
+		case c.Name == "":
+			b.WriteString(strings.Map(func(r rune) rune {
+				if r == '\n' || r == '\r' || r == '\t' {
+					return ' '
+				}
+				return r
+			}, c.Text))
+		case c.Name == "br":
+			b.WriteByte('\n')
+		case c.Name == "textEntryInteraction":
+			b.WriteString("_____")
+		case c.Name == "choiceInteraction":
+			if prompt := c.child("prompt"); prompt != nil {
+				b.WriteByte('\n')
+				renderText(b, prompt)
+				b.WriteByte('\n')
+			}
+		case c.Name == "math":
+			b.WriteString(mathText(c))
+		case strings.HasSuffix(c.Name, "Interaction"), strings.HasPrefix(c.Name, "feedback"),
+			c.Name == "modalFeedback", c.Name == "rubricBlock", c.Name == "object", c.Name == "audio", c.Name == "video":
+		case c.Name == "p" && onlyTextEntry(c):
+			// The answer box of a short answer question, not part of its text
+		case blockElements[c.Name]:
+			b.WriteByte('\n')
+			renderText(b, c)
+			b.WriteByte('\n')
+		default:
+			renderText(b, c)
+		}
+	}
+}
+
+// mathText is MathML as markup on one line, the way question texts hold it
+func mathText(n *node) string {
+	var flatten func(*node) *node
+	flatten = func(n *node) *node {
+		if n.Name == "" {
+			return text(strings.Join(strings.Fields(n.Text), " "))
+		}
+		out := &node{Name: n.Name, Attrs: n.Attrs}
+		for _, c := range n.Children {
+			if c.Name != "" || strings.TrimSpace(c.Text) != "" {
+				out.Children = append(out.Children, flatten(c))
+			}
+		}
+		return out
+	}
+	var b strings.Builder
+	flatten(n).write(&b, false)
+	return b.String()
+}
+
+// onlyTextEntry tells whether p holds a text entry and nothing else
+func onlyTextEntry(p *node) bool {
+	entry := false
+	for _, c := range p.Children {
+		switch {
+		case c.Name == "textEntryInteraction":
+			entry = true
+		case c.Name != "" || strings.TrimSpace(c.Text) != "":
+			return false
+		}
+	}
+	return entry
+}
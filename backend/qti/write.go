@@ -0,0 +1,283 @@
+package qti
+
+import (
+	"academic-suite-backend/models"
+	"archive/zip"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type itemRef struct {
+	id    string
+	href  string
+	files []string // media the item uses
+}
+
+// Write writes the quiz and its questions, in order, as a content package of version v.
+// media holds the files the questions' ImageID and AudioID (and their options' ImageID) refer
+// to, by ID; attachments missing from it are left out.
+func Write(w io.Writer, quiz models.Quiz, v Version, media map[string]File) error {
+	v3 := v == V30
+	zw := zip.NewWriter(w)
+
+	written := map[string]string{} // media ID -> path in the package
+	var mediaErr error
+	mediaPath := func(ref *itemRef, id string) string {
+		f, ok := media[id]
+		if id == "" || !ok {
+			return ""
+		}
+		href, done := written[id]
+		if !done {
+			href = "media/" + fileName(id, f.Name)
+			if err := writeFile(zw, href, f.Data); err != nil {
+				mediaErr = err
+				return ""
+			}
+			written[id] = href
+		}
+		if !contains(ref.files, href) {
+			ref.files = append(ref.files, href)
+		}
+		return href
+	}
+
+	refs := make([]itemRef, len(quiz.Questions))
+	for i, q := range quiz.Questions {
+		refs[i] = itemRef{id: fmt.Sprintf("Q%d", i+1), href: fmt.Sprintf("items/Q%d.xml", i+1)}
+		ref := &refs[i]
+		// Item files sit in items/, so their media paths climb one level
+		src := func(id string) (string, string) {
+			if href := mediaPath(ref, id); href != "" {
+				return "../" + href, media[id].ContentType
+			}
+			return "", ""
+		}
+		item := itemXML(q, ref.id, v3, src)
+		if mediaErr != nil {
+			return mediaErr
+		}
+		if err := writeFile(zw, ref.href, document(item, v3)); err != nil {
+			return err
+		}
+	}
+	if err := writeFile(zw, "assessment.xml", document(testXML(quiz, refs, v3), v3)); err != nil {
+		return err
+	}
+	if err := writeFile(zw, "imsmanifest.xml", document(manifestXML(refs, v3), false)); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeFile(zw *zip.Writer, name string, data []byte) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// fileName is the media file's name in the package, unique by media ID
+func fileName(id, name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if len(ext) > 6 || strings.ContainsAny(ext, `/\ "`) {
+		ext = ""
+	}
+	return strings.NewReplacer("/", "_", "\\", "_").Replace(id) + ext
+}
+
+// mediaSource gives the item-relative path and content type of a media ID, "" when absent
+type mediaSource func(id string) (src, contentType string)
+
+func itemXML(q models.Question, id string, v3 bool, media mediaSource) *node {
+	ns := ns21
+	if v3 {
+		ns = ns30
+	}
+	item := el("assessmentItem", "xmlns", ns, "identifier", id, "title", itemTitle(q.Text, id), "adaptive", "false", "timeDependent", "false")
+
+	body := el("itemBody").add(paragraphs(q.Text)...)
+	if src, _ := media(q.ImageID); src != "" {
+		body.add(el("p").add(el("img", "src", src, "alt", "")))
+	}
+	if src, contentType := media(q.AudioID); src != "" {
+		if contentType == "" {
+			contentType = "audio/mpeg"
+		}
+		body.add(el("p").add(el("object", "data", src, "type", contentType)))
+	}
+	response := el("responseDeclaration", "identifier", "RESPONSE", "cardinality", "single", "baseType", "string")
+	var match *node
+	switch q.Type {
+	case models.TypeMCQ, models.TypeTrueFalse:
+		response = el("responseDeclaration", "identifier", "RESPONSE", "cardinality", "single", "baseType", "identifier")
+		attrs := []string{"responseIdentifier", "RESPONSE", "shuffle", "false", "maxChoices", "1"}
+		if q.Type == models.TypeTrueFalse {
+			attrs = append(attrs, "class", trueFalseClass)
+		}
+		interaction := el("choiceInteraction", attrs...)
+		for i, opt := range q.Options {
+			choice := choiceID(i)
+			simpleChoice := el("simpleChoice", "identifier", choice)
+			if opt.Text != "" {
+				simpleChoice.add(lines(opt.Text)...)
+			}
+			if src, _ := media(opt.ImageID); src != "" {
+				simpleChoice.add(el("img", "src", src, "alt", ""))
+			}
+			interaction.add(simpleChoice)
+			if opt.IsCorrect && len(response.Children) == 0 {
+				response.add(el("correctResponse").add(el("value").add(text(choice))))
+			}
+		}
+		body.add(interaction)
+		match = el("match").add(el("variable", "identifier", "RESPONSE"), el("correct", "identifier", "RESPONSE"))
+	case models.TypeShortAnswer:
+		if q.CorrectAnswer != "" {
+			response.add(el("correctResponse").add(el("value").add(text(q.CorrectAnswer))))
+		}
+		body.add(el("p").add(el("textEntryInteraction", "responseIdentifier", "RESPONSE",
+			"expectedLength", strconv.Itoa(max(20, utf8.RuneCountInString(q.CorrectAnswer))))))
+		match = el("stringMatch", "caseSensitive", "false").add(el("variable", "identifier", "RESPONSE"), el("correct", "identifier", "RESPONSE"))
+	default:
+		// Essays are scored by hand: no key, no response processing
+		body.add(el("extendedTextInteraction", "responseIdentifier", "RESPONSE"))
+	}
+
+	item.add(response,
+		outcome("SCORE", "float", "0"),
+		outcome("MAXSCORE", "float", strconv.Itoa(q.Points)))
+	if q.Explanation != "" {
+		item.add(el("outcomeDeclaration", "identifier", "FEEDBACK", "cardinality", "single", "baseType", "identifier"))
+	}
+	item.add(body)
+	if match != nil {
+		// Full points for the correct response
+		item.add(el("responseProcessing").add(el("responseCondition").add(el("responseIf").add(
+			match,
+			el("setOutcomeValue", "identifier", "SCORE").add(el("variable", "identifier", "MAXSCORE")),
+		))))
+	}
+	if q.Explanation != "" {
+		// FEEDBACK is never set, so with showHide="hide" the explanation always shows
+		feedback := el("modalFeedback", "outcomeIdentifier", "FEEDBACK", "identifier", "EXPLANATION", "showHide", "hide")
+		if v3 {
+			feedback.add(el("contentBody").add(paragraphs(q.Explanation)...))
+		} else {
+			feedback.add(paragraphs(q.Explanation)...)
+		}
+		item.add(feedback)
+	}
+	return item
+}
+
+func outcome(id, baseType, value string) *node {
+	return el("outcomeDeclaration", "identifier", id, "cardinality", "single", "baseType", baseType).
+		add(el("defaultValue").add(el("value").add(text(value))))
+}
+
+func choiceID(i int) string {
+	return fmt.Sprintf("C%d", i+1)
+}
+
+// paragraphs is one <p> per line of s
+func paragraphs(s string) []*node {
+	var out []*node
+	for _, line := range strings.Split(s, "\n") {
+		out = append(out, el("p").add(richText(line)...))
+	}
+	return out
+}
+
+// lines is s with its line breaks as <br/>
+func lines(s string) []*node {
+	var out []*node
+	for i, line := range strings.Split(s, "\n") {
+		if i > 0 {
+			out = append(out, el("br"))
+		}
+		out = append(out, richText(line)...)
+	}
+	return out
+}
+
+var mathMarkup = regexp.MustCompile(`(?s)<math[\s>].*?</math>`)
+
+// richText is a line of text with its inline MathML as elements; markup that does not parse
+// stays text
+func richText(line string) []*node {
+	var out []*node
+	last := 0
+	for _, loc := range mathMarkup.FindAllStringIndex(line, -1) {
+		math, err := parseTree(strings.NewReader(line[loc[0]:loc[1]]))
+		if err != nil || math.Name != "math" {
+			continue
+		}
+		if loc[0] > last {
+			out = append(out, text(line[last:loc[0]]))
+		}
+		out = append(out, math)
+		last = loc[1]
+	}
+	if last < len(line) || len(out) == 0 {
+		out = append(out, text(line[last:]))
+	}
+	return out
+}
+
+// itemTitle is the start of the question's first line; platforms list items by it
+func itemTitle(s, fallback string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	if line == "" {
+		return fallback
+	}
+	if r := []rune(line); len(r) > 60 {
+		return string(r[:60]) + "…"
+	}
+	return line
+}
+
+func testXML(quiz models.Quiz, refs []itemRef, v3 bool) *node {
+	ns := ns21
+	if v3 {
+		ns = ns30
+	}
+	section := el("assessmentSection", "identifier", "SECTION", "title", quiz.Title, "visible", "true")
+	for _, ref := range refs {
+		section.add(el("assessmentItemRef", "identifier", ref.id, "href", ref.href))
+	}
+	return el("assessmentTest", "xmlns", ns, "identifier", "TEST", "title", quiz.Title).add(
+		el("testPart", "identifier", "PART", "navigationMode", "nonlinear", "submissionMode", "simultaneous").add(section))
+}
+
+func manifestXML(refs []itemRef, v3 bool) *node {
+	ns, schema, schemaVersion, version := nsManifest21, "QTIv2.1 Package", "1.0.0", "v2p1"
+	if v3 {
+		ns, schema, schemaVersion, version = nsManifest30, "QTI Package", "3.0.0", "v3p0"
+	}
+
+	test := el("resource", "identifier", "TEST", "type", "imsqti_test_xml"+version, "href", "assessment.xml").
+		add(el("file", "href", "assessment.xml"))
+	resources := el("resources").add(test)
+	for _, ref := range refs {
+		test.add(el("dependency", "identifierref", ref.id))
+		resource := el("resource", "identifier", ref.id, "type", "imsqti_item_xml"+version, "href", ref.href).
+			add(el("file", "href", ref.href))
+		for _, f := range ref.files {
+			resource.add(el("file", "href", f))
+		}
+		resources.add(resource)
+	}
+	return el("manifest", "xmlns", ns, "identifier", "MANIFEST").add(
+		el("metadata").add(el("schema").add(text(schema)), el("schemaversion").add(text(schemaVersion))),
+		el("organizations"),
+		resources,
+	)
+}
@@ -0,0 +1,196 @@
+package qti
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// node is an element or, when Name is empty, a run of text. QTI 3.0 renamed every element and
+// attribute ("choiceInteraction" became "qti-choice-interaction", "maxChoices" "max-choices");
+// parsed trees use the QTI 2 names so one reader handles both versions.
+type node struct {
+	Name     string
+	Attrs    []xml.Attr
+	Children []*node
+	Text     string
+}
+
+// htmlElements keep their name in both versions (QTI 3 only prefixes its own elements)
+var htmlElements = map[string]bool{
+	"p": true, "div": true, "span": true, "br": true, "img": true, "strong": true, "em": true,
+	"b": true, "i": true, "u": true, "sub": true, "sup": true, "ul": true, "ol": true, "li": true,
+	"table": true, "tr": true, "td": true, "th": true, "pre": true, "code": true, "blockquote": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "object": true, "math": true,
+}
+
+func el(name string, attrs ...string) *node {
+	n := &node{Name: name}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		n.Attrs = append(n.Attrs, xml.Attr{Name: xml.Name{Local: attrs[i]}, Value: attrs[i+1]})
+	}
+	return n
+}
+
+func text(s string) *node { return &node{Text: s} }
+
+func (n *node) add(children ...*node) *node {
+	n.Children = append(n.Children, children...)
+	return n
+}
+
+func (n *node) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (n *node) child(name string) *node {
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// find returns the elements named name anywhere below n, in document order
+func (n *node) find(name string) []*node {
+	var out []*node
+	for _, c := range n.Children {
+		if c.Name == name {
+			out = append(out, c)
+		}
+		out = append(out, c.find(name)...)
+	}
+	return out
+}
+
+// parseTree reads an XML document, normalizing QTI 3 names to QTI 2 ones
+func parseTree(r io.Reader) (*node, error) {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+	root := &node{}
+	stack := []*node{root}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		top := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &node{Name: qti2Name(t.Name.Local)}
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" || a.Name.Local == "xmlns" {
+					continue
+				}
+				n.Attrs = append(n.Attrs, xml.Attr{Name: xml.Name{Local: camel(a.Name.Local)}, Value: a.Value})
+			}
+			top.Children = append(top.Children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			top.Children = append(top.Children, text(string(t)))
+		}
+	}
+	for _, c := range root.Children {
+		if c.Name != "" {
+			return c, nil
+		}
+	}
+	return nil, io.ErrUnexpectedEOF
+}
+
+func qti2Name(name string) string {
+	if strings.HasPrefix(name, "qti-") {
+		return camel(strings.TrimPrefix(name, "qti-"))
+	}
+	return name
+}
+
+// camel turns "max-choices" into "maxChoices"
+func camel(s string) string {
+	parts := strings.Split(s, "-")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// kebab turns "maxChoices" into "max-choices"
+func kebab(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				b.WriteByte('-')
+			}
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// write serializes n. With v3, QTI elements and their attributes take their 3.0 names; HTML
+// and attributes holding ":" (xmlns:xsi, xml:lang) are written as is. Manifests are the
+// same in both versions and are written with v3 false.
+func (n *node) write(w *strings.Builder, v3 bool) {
+	if n.Name == "" {
+		xml.EscapeText(w, []byte(n.Text))
+		return
+	}
+	name := n.Name
+	if name == "math" {
+		// MathML keeps its names in QTI 3 and needs its namespace in both versions
+		v3 = false
+		if n.attr("xmlns") == "" {
+			n = &node{Name: n.Name, Attrs: append([]xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: nsMathML}}, n.Attrs...), Children: n.Children}
+		}
+	}
+	qtiElement := v3 && !htmlElements[name]
+	if qtiElement {
+		name = "qti-" + kebab(name)
+	}
+	w.WriteString("<" + name)
+	for _, a := range n.Attrs {
+		attr := a.Name.Local
+		if qtiElement && !strings.Contains(attr, ":") && attr != "xmlns" {
+			attr = kebab(attr)
+		}
+		w.WriteString(" " + attr + `="`)
+		xml.EscapeText(w, []byte(a.Value))
+		w.WriteString(`"`)
+	}
+	if len(n.Children) == 0 {
+		w.WriteString("/>")
+		return
+	}
+	w.WriteString(">")
+	for _, c := range n.Children {
+		c.write(w, v3)
+	}
+	w.WriteString("</" + name + ">")
+}
+
+// document is n as an XML file
+func document(n *node, v3 bool) []byte {
+	var w strings.Builder
+	w.WriteString(xml.Header)
+	n.write(&w, v3)
+	w.WriteString("\n")
+	return []byte(w.String())
+}
@@ -0,0 +1,43 @@
+// Package repository wraps queries that need to behave the same on every supported
+// database driver (Postgres and SQLite). Repositories take the *gorm.DB to use, so they
+// work with database.DB as well as inside a transaction.
+package repository
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type Repositories struct {
+	Users *UserRepository
+}
+
+func New(db *gorm.DB) *Repositories {
+	return &Repositories{
+		Users: NewUserRepository(db),
+	}
+}
+
+// escapeLike escapes the LIKE wildcards so user input is matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// ContainsFold is a scope matching rows where any of the columns contains term, ignoring case.
+// Uses LOWER(...) LIKE instead of Postgres-only ILIKE. Columns must be trusted identifiers.
+func ContainsFold(term string, columns ...string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if term == "" || len(columns) == 0 {
+			return db
+		}
+		pattern := "%" + escapeLike(strings.ToLower(term)) + "%"
+		conds := make([]string, len(columns))
+		args := make([]interface{}, len(columns))
+		for i, col := range columns {
+			conds[i] = "LOWER(" + col + `) LIKE ? ESCAPE '\'`
+			args[i] = pattern
+		}
+		return db.Where("("+strings.Join(conds, " OR ")+")", args...)
+	}
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"academic-suite-backend/models"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDBs opens an in-memory SQLite database, plus Postgres when TEST_POSTGRES_DSN is set
+// (its users table is dropped and recreated)
+func testDBs(t *testing.T) map[string]*gorm.DB {
+	t.Helper()
+	config := &gorm.Config{Logger: logger.Discard}
+	dbs := map[string]*gorm.DB{}
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), config)
+	if err != nil {
+		t.Fatalf("sqlite: %v", err)
+	}
+	dbs["sqlite"] = db
+
+	if dsn := os.Getenv("TEST_POSTGRES_DSN"); dsn != "" {
+		db, err := gorm.Open(postgres.Open(dsn), config)
+		if err != nil {
+			t.Fatalf("postgres: %v", err)
+		}
+		db.Migrator().DropTable(&models.User{})
+		dbs["postgres"] = db
+	}
+
+	for name, db := range dbs {
+		if err := db.AutoMigrate(&models.User{}); err != nil {
+			t.Fatalf("%s: migrate: %v", name, err)
+		}
+		users := []models.User{
+			{ID: "u1", Name: "Siti Rahayu", Email: "SITI@example.com", Role: models.RoleStudent},
+			{ID: "u2", Name: "Budi 100% Hadir", Email: "budi@example.com", Role: models.RoleStudent},
+			{ID: "u3", Name: "Dewi_Lestari", Email: "dewi@example.com", Role: models.RoleTeacher},
+			{ID: "u4", Name: "Dewi Anggraini", Email: "anggi@example.com", Role: models.RoleStudent},
+		}
+		if err := db.Create(&users).Error; err != nil {
+			t.Fatalf("%s: seed: %v", name, err)
+		}
+	}
+	return dbs
+}
+
+func matchingIDs(t *testing.T, db *gorm.DB, term string, columns ...string) []string {
+	t.Helper()
+	var ids []string
+	if err := db.Model(&models.User{}).Scopes(ContainsFold(term, columns...)).Pluck("id", &ids).Error; err != nil {
+		t.Fatalf("%q: %v", term, err)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestContainsFold(t *testing.T) {
+	cases := []struct {
+		term string
+		want []string
+	}{
+		{"siti", []string{"u1"}},               // email in upper case
+		{"DEWI", []string{"u3", "u4"}},         // name and email
+		{"100%", []string{"u2"}},               // % is literal
+		{"_", []string{"u3"}},                  // so is _
+		{"i_l", []string{"u3"}},                // not "any character"
+		{"", []string{"u1", "u2", "u3", "u4"}}, // no filter
+		{"tidak ada", []string{}},
+	}
+	for name, db := range testDBs(t) {
+		for _, c := range cases {
+			got := matchingIDs(t, db, c.term, "name", "email")
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("%s: %q = %v, want %v", name, c.term, got, c.want)
+			}
+		}
+	}
+}
+
+func TestUserRepositoryList(t *testing.T) {
+	for name, db := range testDBs(t) {
+		users, total, err := NewUserRepository(db).List(UserFilter{Role: string(models.RoleStudent), Search: "DEWI", Limit: 10})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if total != 1 || len(users) != 1 || users[0].ID != "u4" {
+			t.Errorf("%s: list = %d %v", name, total, users)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"academic-suite-backend/models"
+
+	"gorm.io/gorm"
+)
+
+type UserRepository struct {
+	db *gorm.DB
+}
+
+func NewUserRepository(db *gorm.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+type UserFilter struct {
+	InstitutionID string
+	Role          string
+	Search        string // name or email, case-insensitive
+	Offset        int
+	Limit         int
+}
+
+// List returns one page of users (newest first) and the total matching the filter
+func (r *UserRepository) List(f UserFilter) ([]models.User, int64, error) {
+	query := r.db.Model(&models.User{})
+	if f.InstitutionID != "" {
+		query = query.Where("institution_id = ?", f.InstitutionID)
+	}
+	if f.Role != "" {
+		query = query.Where("role = ?", f.Role)
+	}
+	query = query.Scopes(ContainsFold(f.Search, "name", "email"))
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	err := query.Offset(f.Offset).Limit(f.Limit).Order("created_at desc").Find(&users).Error
+	return users, total, err
+}
+
+func (r *UserRepository) FindByID(id string) (*models.User, error) {
+	var user models.User
+	if err := r.db.First(&user, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) FindByEmail(email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) Create(user *models.User) error {
+	return r.db.Create(user).Error
+}
+
+func (r *UserRepository) Save(user *models.User) error {
+	return r.db.Save(user).Error
+}
+
+func (r *UserRepository) UpdatePassword(id, hashedPassword string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("password", hashedPassword).Error
+}
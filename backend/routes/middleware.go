@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/models"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
@@ -12,7 +14,7 @@ var jwtSecret = []byte("secret")
 func AuthMiddleware(c *fiber.Ctx) error {
 	authHeader := c.Get("Authorization")
 	if authHeader == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+		return apperr.Unauthorized("unauthorized")
 	}
 
 	tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
@@ -22,12 +24,27 @@ func AuthMiddleware(c *fiber.Ctx) error {
 	})
 
 	if err != nil || !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid Token"})
+		return apperr.Unauthorized("invalid_token")
 	}
 
 	claims := token.Claims.(jwt.MapClaims)
 	c.Locals("userId", claims["userId"])
 	c.Locals("role", claims["role"])
+	c.Locals("locale", claims["locale"]) // the user's language preference, may be empty
 
 	return c.Next()
 }
+
+// RequireRole rejects requests whose token role is not one of the given roles.
+// Must run after AuthMiddleware.
+func RequireRole(roles ...models.UserRole) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role, _ := c.Locals("role").(string)
+		for _, r := range roles {
+			if models.UserRole(role) == r {
+				return c.Next()
+			}
+		}
+		return apperr.Forbidden("forbidden")
+	}
+}
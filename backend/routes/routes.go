@@ -2,78 +2,116 @@ package routes
 
 import (
 	"academic-suite-backend/handlers"
+	"academic-suite-backend/models"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupRoutes(app *fiber.App) {
+func SetupRoutes(app *fiber.App, svc *handlers.Services) {
 	api := app.Group("/api")
 
 	// Auth
-	api.Post("/auth/login", handlers.Login)
-	api.Post("/auth/forgot-password", handlers.ForgotPassword)
-	api.Post("/auth/reset-password", handlers.ResetPassword)
+	api.Post("/auth/login", svc.Auth.Login)
+	api.Post("/auth/forgot-password", svc.Auth.ForgotPassword)
+	api.Post("/auth/reset-password", svc.Auth.ResetPassword)
 
 	// Protected
 	api.Use(AuthMiddleware)
 
-	api.Get("/auth/profile", handlers.GetProfile)
+	api.Get("/auth/profile", svc.Auth.GetProfile)
 
 	// Users
-	api.Get("/users", handlers.GetUsers)
-	api.Post("/users", handlers.CreateUser)
-	api.Put("/users/:id", handlers.UpdateUser)
+	api.Get("/users", svc.Users.GetUsers)
+	api.Post("/users", svc.Users.CreateUser)
+	api.Put("/users/:id", svc.Users.UpdateUser)
 
 	// Quizzes
-	api.Get("/quizzes", handlers.GetQuizzes)
-	api.Get("/quizzes/:id", handlers.GetQuiz)
-	api.Post("/quizzes", handlers.CreateQuiz)
-	api.Put("/quizzes/:id", handlers.UpdateQuiz)
+	api.Get("/quizzes", svc.Quizzes.GetQuizzes)
+	api.Get("/quizzes/:id", svc.Quizzes.GetQuiz)
+	api.Post("/quizzes", svc.Quizzes.CreateQuiz)
+	api.Put("/quizzes/:id", svc.Quizzes.UpdateQuiz)
+	api.Get("/quizzes/:id/export", svc.Quizzes.ExportQuiz) // ?format=gift|aiken|qti
+
+	// Media (question images and audio)
+	api.Post("/media", svc.Media.UploadMedia)
+	api.Get("/media/:id", svc.Media.GetMedia)
 
 	// Institutions
-	api.Get("/institutions", handlers.GetInstitutions)
-	api.Post("/institutions", handlers.CreateInstitution)
+	api.Get("/institutions", svc.Institutions.GetInstitutions)
+	api.Post("/institutions", svc.Institutions.CreateInstitution)
+	api.Put("/institutions/:id", RequireRole(models.RoleAdmin), svc.Institutions.UpdateInstitution)
 
 	// Subjects
-	api.Get("/subjects", handlers.GetSubjects)
-	api.Get("/subjects/:id", handlers.GetSubject)
-	api.Post("/subjects", handlers.CreateSubject)
-	api.Put("/subjects/:id", handlers.UpdateSubject)
-	api.Delete("/subjects/:id", handlers.DeleteSubject)
+	api.Get("/subjects", svc.Subjects.GetSubjects)
+	api.Get("/subjects/:id", svc.Subjects.GetSubject)
+	api.Post("/subjects", svc.Subjects.CreateSubject)
+	api.Put("/subjects/:id", svc.Subjects.UpdateSubject)
+	api.Delete("/subjects/:id", svc.Subjects.DeleteSubject)
 
 	// Batches
-	api.Get("/batches", handlers.GetBatches)
-	api.Post("/batches", handlers.CreateBatch)
-	api.Put("/batches/:id", handlers.UpdateBatch)
-	api.Put("/batches/:id/status", handlers.UpdateBatchStatus)
-	api.Get("/batches/:id/live", handlers.GetBatchLiveStatus) // New
+	api.Get("/batches", svc.Batches.GetBatches)
+	api.Post("/batches", svc.Batches.CreateBatch)
+	api.Put("/batches/:id", svc.Batches.UpdateBatch)
+	api.Put("/batches/:id/status", svc.Batches.UpdateBatchStatus)
+	api.Get("/batches/:id/live", svc.Batches.GetBatchLiveStatus) // New
+	api.Post("/batches/:id/makeup", RequireRole(models.RoleAdmin, models.RoleTeacher), svc.Batches.CreateMakeupBatch)
+	api.Post("/batches/:id/waitlist", svc.Batches.JoinWaitlist)
+	api.Put("/batches/:id/waitlist", RequireRole(models.RoleAdmin, models.RoleTeacher), svc.Batches.ReorderWaitlist)
+	api.Delete("/batches/:id/waitlist/:studentId", svc.Batches.LeaveWaitlist)
+	api.Delete("/batches/:id/participants/:studentId", RequireRole(models.RoleAdmin, models.RoleTeacher), svc.Batches.RemoveParticipant)
 
 	// Attempts
 	attempts := api.Group("/attempts")
-	attempts.Get("/", handlers.GetAttempts)
-	attempts.Post("/start", handlers.StartAttempt)
-	attempts.Post("/:id/answers", handlers.SaveAnswer)
-	attempts.Post("/:id/submit", handlers.SubmitAttempt) // Now redundant? No, keeps compatible.
-	attempts.Post("/:id/log", handlers.LogAttemptEvent)
-	attempts.Post("/:id/pause", handlers.PauseAttempt)
-	attempts.Post("/:id/resume", handlers.ResumeAttempt)
-	attempts.Post("/:id/force-submit", handlers.ForceSubmitAttempt)
-	attempts.Post("/:id/ping", handlers.PingAttempt)
-	attempts.Get("/:id/time", handlers.GetServerTime)
+	attempts.Get("/", svc.Attempts.GetAttempts)
+	attempts.Post("/start", svc.Attempts.StartAttempt)
+	attempts.Post("/:id/answers", svc.Attempts.SaveAnswer)
+	attempts.Post("/:id/submit", svc.Attempts.SubmitAttempt) // Now redundant? No, keeps compatible.
+	attempts.Post("/:id/log", svc.Attempts.LogAttemptEvent)
+	attempts.Post("/:id/pause", svc.Attempts.PauseAttempt)
+	attempts.Post("/:id/resume", svc.Attempts.ResumeAttempt)
+	attempts.Post("/:id/force-submit", svc.Attempts.ForceSubmitAttempt)
+	attempts.Post("/:id/ping", svc.Attempts.PingAttempt)
+	attempts.Get("/:id/time", svc.Attempts.GetServerTime)
+	attempts.Post("/:id/break", svc.Attempts.BreakAttempt)
+	attempts.Post("/:id/reset", RequireRole(models.RoleAdmin), svc.Attempts.ResetAttempt)
+	attempts.Post("/:id/reopen", RequireRole(models.RoleAdmin), svc.Attempts.ReopenAttempt)
+
+	// Accommodations (extra time, breaks)
+	api.Get("/accommodations", RequireRole(models.RoleAdmin, models.RoleTeacher), svc.Accommodations.GetAccommodations)
+	api.Post("/accommodations", RequireRole(models.RoleAdmin, models.RoleTeacher), svc.Accommodations.SaveAccommodation)
+	api.Delete("/accommodations/:id", RequireRole(models.RoleAdmin, models.RoleTeacher), svc.Accommodations.DeleteAccommodation)
 
 	// Reports
-	api.Get("/reports/batch", handlers.GetBatchReport)
-	api.Get("/reports/logs", handlers.GetEventLogs)
-	api.Get("/export/batch/:id", handlers.ExportBatchReport) // Export route
+	api.Get("/reports/batch", svc.Reports.GetBatchReport)
+	api.Get("/reports/items", svc.Reports.GetItemAnalysis)
+	api.Get("/reports/reliability", svc.Reports.GetReliability)
+	api.Get("/reports/compare", svc.Reports.CompareCohorts)
+	api.Get("/reports/logs", svc.Reports.GetEventLogs)
+	api.Get("/reports/answers", svc.Reports.GetAnswers)
+	api.Get("/export/batch/:id", svc.Reports.ExportBatchReport) // Export route
+	api.Get("/export/batch/:id/matrix", RequireRole(models.RoleAdmin, models.RoleTeacher), svc.Reports.ExportAnswerMatrix)
+	api.Get("/export/batch/:id/pdf", RequireRole(models.RoleAdmin, models.RoleTeacher), svc.Reports.ExportBatchReportPDF)
+	api.Get("/export/batch/:id/documents", RequireRole(models.RoleAdmin, models.RoleTeacher), svc.Reports.ExportBatchDocuments) // zip: report, slips, certificates
+	api.Get("/export/attempts/:id/slip", svc.Reports.ExportResultSlip)
+	// Streamed row exports (?format=csv|ndjson|xlsx)
+	api.Get("/export/batch/:id/results", RequireRole(models.RoleAdmin, models.RoleTeacher), svc.Reports.ExportBatchResults)
+	api.Get("/export/batch/:id/answers", RequireRole(models.RoleAdmin, models.RoleTeacher), svc.Reports.ExportBatchAnswers)
+	api.Get("/export/logs", RequireRole(models.RoleAdmin, models.RoleTeacher), svc.Reports.ExportEventLogs)
+
+	// Gradebook
+	api.Get("/gradebook", svc.Gradebook.GetGradebook)
+	api.Get("/gradebook/export", svc.Gradebook.ExportGradebook)
 
 	// Import
-	api.Post("/import/users", handlers.ImportUsers)
-	api.Post("/import/questions/:quizId", handlers.ImportQuestions)
+	api.Get("/import/users/template", RequireRole(models.RoleAdmin), svc.Imports.GetUserImportTemplate)
+	api.Post("/import/users", RequireRole(models.RoleAdmin), svc.Imports.ImportUsers)
+	api.Post("/import/questions/:quizId", RequireRole(models.RoleAdmin, models.RoleTeacher), svc.Imports.ImportQuestions)
+	api.Post("/import/quizzes", RequireRole(models.RoleAdmin, models.RoleTeacher), svc.Imports.ImportQuiz) // QTI package -> new draft quiz
 
 	// Classes
-	api.Get("/classes", handlers.GetClasses)
-	api.Get("/classes/:id", handlers.GetClass)
-	api.Post("/classes", handlers.CreateClass)
-	api.Put("/classes/:id", handlers.UpdateClass)
-	api.Delete("/classes/:id", handlers.DeleteClass)
+	api.Get("/classes", svc.Classes.GetClasses)
+	api.Get("/classes/:id", svc.Classes.GetClass)
+	api.Post("/classes", svc.Classes.CreateClass)
+	api.Put("/classes/:id", svc.Classes.UpdateClass)
+	api.Delete("/classes/:id", svc.Classes.DeleteClass)
 }
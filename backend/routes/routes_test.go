@@ -0,0 +1,43 @@
+package routes
+
+import (
+	"academic-suite-backend/apperr"
+	"academic-suite-backend/handlers"
+	"academic-suite-backend/models"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Staff-only routes turn a student away before any handler runs, so empty services will do
+func TestStaffRoutesRejectStudents(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: apperr.Handler})
+	SetupRoutes(app, &handlers.Services{})
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"userId": "student-1", "role": string(models.RoleStudent),
+	}).SignedString(jwtSecret)
+
+	for _, route := range []struct{ method, path string }{
+		{"POST", "/api/batches/batch-1/makeup"},
+		{"PUT", "/api/batches/batch-1/waitlist"},
+		{"DELETE", "/api/batches/batch-1/participants/student-2"},
+		{"GET", "/api/export/batch/batch-1/matrix"},
+		{"GET", "/api/export/batch/batch-1/pdf"},
+		{"GET", "/api/export/batch/batch-1/documents"},
+		{"GET", "/api/export/batch/batch-1/results"},
+		{"GET", "/api/export/batch/batch-1/answers"},
+		{"GET", "/api/export/logs"},
+	} {
+		req := httptest.NewRequest(route.method, route.path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s %s: %v", route.method, route.path, err)
+		}
+		if resp.StatusCode != fiber.StatusForbidden {
+			t.Errorf("%s %s: status %d, want 403", route.method, route.path, resp.StatusCode)
+		}
+	}
+}
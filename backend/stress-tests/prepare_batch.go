@@ -3,10 +3,12 @@ package main
 import (
 	"academic-suite-backend/database"
 	"academic-suite-backend/models"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
+	"time"
+
+	"gorm.io/gorm/clause"
 )
 
 func main() {
@@ -26,13 +28,9 @@ func main() {
 		log.Fatalf("Batch not found: %v", err)
 	}
 
-	log.Printf("Found Batch: %s (Current Participants: %d bytes)", batch.Name, len(batch.AllowedParticipants))
-
 	// Get existing participants
 	var participants []string
-	if batch.AllowedParticipants != "" {
-		json.Unmarshal([]byte(batch.AllowedParticipants), &participants)
-	}
+	database.DB.Model(&models.BatchParticipant{}).Where("batch_id = ?", batch.ID).Pluck("student_id", &participants)
 
 	// Add dummy students
 	existingSet := make(map[string]bool)
@@ -40,23 +38,20 @@ func main() {
 		existingSet[p] = true
 	}
 
+	log.Printf("Found Batch: %s (Current Participants: %d)", batch.Name, len(participants))
+
 	addedCount := 0
 	for i := 1; i <= *count; i++ {
 		dummyID := fmt.Sprintf("student-dummy-%d", i)
 		if !existingSet[dummyID] {
-			participants = append(participants, dummyID)
+			row := models.BatchParticipant{BatchID: batch.ID, StudentID: dummyID, CreatedAt: time.Now()}
+			if err := database.DB.Omit(clause.Associations).Create(&row).Error; err != nil {
+				log.Fatalf("Failed to add participant %s: %v", dummyID, err)
+			}
 			existingSet[dummyID] = true
 			addedCount++
 		}
 	}
 
-	// Save back
-	newData, _ := json.Marshal(participants)
-	batch.AllowedParticipants = string(newData)
-
-	if err := database.DB.Save(&batch).Error; err != nil {
-		log.Fatalf("Failed to update batch: %v", err)
-	}
-
-	log.Printf("Successfully added %d dummy students. Total participants: %d", addedCount, len(participants))
+	log.Printf("Successfully added %d dummy students. Total participants: %d", addedCount, len(existingSet))
 }
@@ -0,0 +1,115 @@
+package tabular
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Delimiters tried when sniffing a CSV, in order of preference on a tie. Excel in
+// Indonesian (and most European) locales saves with ";" because "," is the decimal separator.
+var Delimiters = []rune{',', ';', '\t', '|'}
+
+// sniffLines is how many lines the delimiter guess looks at
+const sniffLines = 20
+
+func readCSV(data []byte) ([][]string, error) {
+	text, err := DecodeText(data)
+	if err != nil {
+		return nil, err
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	// Excel honours a leading "sep=;" line; so do we
+	var delim rune
+	if first, rest, _ := strings.Cut(text, "\n"); len(strings.TrimSpace(first)) == 5 && strings.HasPrefix(first, "sep=") {
+		delim = rune(first[4])
+		text = rest
+	}
+	if delim == 0 {
+		delim = sniffDelimiter(text)
+	}
+
+	r := newCSVReader(text, delim)
+	var rows [][]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i := range record {
+			record[i] = strings.TrimSpace(record[i])
+		}
+		rows = append(rows, trimRow(record))
+	}
+	return rows, nil
+}
+
+func newCSVReader(text string, delim rune) *csv.Reader {
+	r := csv.NewReader(strings.NewReader(text))
+	r.Comma = delim
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	return r
+}
+
+// DecodeText turns uploaded text into UTF-8. A BOM decides; without one, valid UTF-8 is kept and
+// anything else is read as Windows-1252, what Excel on Windows writes for "CSV".
+func DecodeText(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), nil
+	case hasUTF16BOM(data):
+		out, err := unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder().Bytes(data)
+		return string(out), err
+	case utf8.Valid(data):
+		return string(data), nil
+	}
+	out, err := charmap.Windows1252.NewDecoder().Bytes(data)
+	return string(out), err
+}
+
+func hasUTF16BOM(data []byte) bool {
+	return bytes.HasPrefix(data, []byte{0xFF, 0xFE}) || bytes.HasPrefix(data, []byte{0xFE, 0xFF})
+}
+
+// sniffDelimiter picks the delimiter that splits the first lines into the same number of
+// columns most often, preferring more columns; "," when nothing splits.
+func sniffDelimiter(text string) rune {
+	best, bestRows, bestCols := Delimiters[0], 0, 1
+	for _, d := range Delimiters {
+		r := newCSVReader(text, d)
+		var counts []int
+		for len(counts) < sniffLines {
+			record, err := r.Read()
+			if err != nil {
+				break
+			}
+			if len(trimRow(record)) == 0 {
+				continue
+			}
+			counts = append(counts, len(record))
+		}
+		if len(counts) == 0 || counts[0] < 2 {
+			continue
+		}
+		consistent := 0
+		for _, n := range counts {
+			if n == counts[0] {
+				consistent++
+			}
+		}
+		if consistent > bestRows || (consistent == bestRows && counts[0] > bestCols) {
+			best, bestRows, bestCols = d, consistent, counts[0]
+		}
+	}
+	return best
+}
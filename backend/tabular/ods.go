@@ -0,0 +1,169 @@
+package tabular
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// OpenDocument compresses runs of equal rows and cells with a repeat count; an empty sheet
+// typically ends in a row repeated ~1M times. Runs are expanded up to these limits only.
+const (
+	maxRepeatRows = 100000
+	maxRepeatCols = 1024
+)
+
+// A few kilobytes of XML can still ask for gigabytes of text through <text:s text:c="N"/> and
+// repeated cells; sheets beyond these limits are refused
+const (
+	maxSpaces    = 1024     // per <text:s/>
+	maxCellBytes = 32 << 20 // all decoded cells of the sheet
+)
+
+const (
+	nsTable  = "urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	nsText   = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+	nsOffice = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+)
+
+// readODS streams content.xml of an OpenDocument spreadsheet and returns its first table
+func readODS(data []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	var content *zip.File
+	for _, f := range zr.File {
+		if f.Name == "content.xml" {
+			content = f
+		}
+	}
+	if content == nil {
+		return nil, ErrUnsupported
+	}
+	rc, err := content.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var (
+		rows                  [][]string
+		row                   []string
+		cell                  strings.Builder
+		rowRepeat, cellRepeat int
+		inTable, inCell       bool
+		inComment             bool
+		paragraphs            int
+		decoded               int // bytes of cell text, counting repeats
+	)
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Space == nsTable && t.Name.Local == "table":
+				inTable = true
+			case !inTable:
+			case t.Name.Space == nsTable && t.Name.Local == "table-row":
+				row = nil
+				rowRepeat = repeatAttr(t, "number-rows-repeated")
+			case t.Name.Space == nsTable && (t.Name.Local == "table-cell" || t.Name.Local == "covered-table-cell"):
+				inCell = true
+				cell.Reset()
+				paragraphs = 0
+				cellRepeat = repeatAttr(t, "number-columns-repeated")
+			case inCell && t.Name.Space == nsOffice && t.Name.Local == "annotation":
+				inComment = true
+			case inComment:
+			case inCell && t.Name.Space == nsText && t.Name.Local == "p":
+				if paragraphs > 0 {
+					cell.WriteByte('\n')
+				}
+				paragraphs++
+			case inCell && t.Name.Space == nsText && t.Name.Local == "s":
+				n := repeatAttr(t, "c")
+				if n > maxSpaces || decoded+cell.Len()+n > maxCellBytes {
+					return nil, ErrUnsupported
+				}
+				cell.WriteString(strings.Repeat(" ", n))
+			case inCell && t.Name.Space == nsText && t.Name.Local == "tab":
+				cell.WriteByte('\t')
+			case inCell && t.Name.Space == nsText && t.Name.Local == "line-break":
+				cell.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inCell && !inComment {
+				cell.Write(t)
+			}
+		case xml.EndElement:
+			if t.Name.Space == nsOffice && t.Name.Local == "annotation" {
+				inComment = false
+			}
+			if !inTable || t.Name.Space != nsTable {
+				continue
+			}
+			switch t.Name.Local {
+			case "table":
+				// Only the first sheet
+				return trimRows(rows), nil
+			case "table-cell", "covered-table-cell":
+				inCell = false
+				value := cell.String()
+				for i := 0; i < cellRepeat && len(row) < maxRepeatCols; i++ {
+					if decoded += len(value); decoded > maxCellBytes {
+						return nil, ErrUnsupported
+					}
+					row = append(row, value)
+				}
+			case "table-row":
+				row = trimRow(row)
+				size := 0
+				for _, value := range row {
+					size += len(value)
+				}
+				for i := 0; i < rowRepeat && len(rows) < maxRepeatRows; i++ {
+					// The first copy was counted cell by cell
+					if i > 0 {
+						if decoded += size; decoded > maxCellBytes {
+							return nil, ErrUnsupported
+						}
+					}
+					rows = append(rows, row)
+				}
+			}
+		}
+	}
+	return trimRows(rows), nil
+}
+
+// repeatAttr reads a table:/text: count attribute, 1 when absent
+func repeatAttr(e xml.StartElement, name string) int {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			if n, err := strconv.Atoi(a.Value); err == nil && n > 0 {
+				return n
+			}
+		}
+	}
+	return 1
+}
+
+// trimRows drops trailing blank rows
+func trimRows(rows [][]string) [][]string {
+	n := len(rows)
+	for n > 0 && len(rows[n-1]) == 0 {
+		n--
+	}
+	return rows[:n]
+}
@@ -0,0 +1,94 @@
+// Package tabular reads uploaded spreadsheets into rows of text cells, whatever program made them.
+// The format is detected from the content, not the file name: teachers rename files, and
+// Google Sheets, LibreOffice and older school systems all export something slightly different.
+package tabular
+
+import (
+	"academic-suite-backend/unzip"
+	"archive/zip"
+	"bytes"
+	"errors"
+)
+
+// Format is a spreadsheet file format
+type Format string
+
+const (
+	FormatXLSX Format = "xlsx"
+	FormatODS  Format = "ods"
+	FormatCSV  Format = "csv"
+)
+
+// ErrUnsupported means the file is not a spreadsheet this package can read (e.g. a legacy .xls or a PDF)
+var ErrUnsupported = errors.New("tabular: unsupported file format")
+
+const odsMimetype = "application/vnd.oasis.opendocument.spreadsheet"
+
+// Detect tells the format of data. Zip files are told apart by their contents; anything else
+// that looks like text is taken as CSV.
+func Detect(data []byte) (Format, error) {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return "", ErrUnsupported
+		}
+		for _, f := range zr.File {
+			switch f.Name {
+			case "xl/workbook.xml":
+				return FormatXLSX, nil
+			case "mimetype":
+				if mt, err := unzip.ReadFile(f, 1<<10); err == nil && bytes.HasPrefix(mt, []byte(odsMimetype)) {
+					return FormatODS, nil
+				}
+			}
+		}
+		return "", ErrUnsupported
+	}
+	if looksLikeText(data) {
+		return FormatCSV, nil
+	}
+	return "", ErrUnsupported
+}
+
+// Read returns the rows of the first sheet of data. Rows keep their position (blank rows stay
+// as empty rows) but trailing blank cells are dropped, as excelize does.
+func Read(data []byte) ([][]string, Format, error) {
+	format, err := Detect(data)
+	if err != nil {
+		return nil, "", err
+	}
+	var rows [][]string
+	switch format {
+	case FormatXLSX:
+		rows, err = readXLSX(data)
+	case FormatODS:
+		rows, err = readODS(data)
+	default:
+		rows, err = readCSV(data)
+	}
+	return rows, format, err
+}
+
+// looksLikeText rejects binary files: NUL bytes only appear in text as UTF-16
+func looksLikeText(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	if hasUTF16BOM(data) {
+		return true
+	}
+	sample := data
+	if len(sample) > 4096 {
+		sample = sample[:4096]
+	}
+	return bytes.IndexByte(sample, 0) < 0
+}
+
+// trimRow drops trailing blank cells
+func trimRow(row []string) []string {
+	n := len(row)
+	for n > 0 && row[n-1] == "" {
+		n--
+	}
+	return row[:n]
+}
@@ -0,0 +1,148 @@
+package tabular
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+var want = [][]string{
+	{"Nama", "Email", "Kelas"},
+	{"Budi Śantoso", "budi@example.com", "7A"},
+	{"Ani", "ani@example.com"},
+}
+
+func assertRows(t *testing.T, data []byte, format Format) {
+	t.Helper()
+	rows, got, err := Read(data)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if got != format {
+		t.Errorf("format = %q, want %q", got, format)
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %q", rows)
+	}
+}
+
+func TestReadCSVDelimiters(t *testing.T) {
+	for name, text := range map[string]string{
+		"comma":     "Nama,Email,Kelas\r\nBudi Śantoso,budi@example.com,7A\r\nAni,ani@example.com,\r\n",
+		"semicolon": "Nama;Email;Kelas\nBudi Śantoso;budi@example.com;7A\nAni;ani@example.com;\n",
+		"tab":       "Nama\tEmail\tKelas\nBudi Śantoso\tbudi@example.com\t7A\nAni\tani@example.com\t\n",
+		"excel sep": "sep=|\nNama|Email|Kelas\nBudi Śantoso|budi@example.com|7A\nAni|ani@example.com|\n",
+		"quoted":    "Nama;Email;Kelas\n\"Budi Śantoso\";\"budi@example.com\";7A\nAni;ani@example.com;\n",
+	} {
+		t.Run(name, func(t *testing.T) { assertRows(t, []byte(text), FormatCSV) })
+	}
+}
+
+func TestReadCSVEncodings(t *testing.T) {
+	text := "Nama;Email;Kelas\nBudi Śantoso;budi@example.com;7A\nAni;ani@example.com;\n"
+
+	t.Run("utf-8 bom", func(t *testing.T) {
+		assertRows(t, append([]byte{0xEF, 0xBB, 0xBF}, text...), FormatCSV)
+	})
+	t.Run("utf-16", func(t *testing.T) {
+		data, _ := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().Bytes([]byte(text))
+		assertRows(t, data, FormatCSV)
+	})
+
+	// Windows-1252 has no "Ś"; check a name it does have
+	data, _ := charmap.Windows1252.NewEncoder().Bytes([]byte("Nama;Kota\nJosé;Bogotá\n"))
+	rows, _, err := Read(data)
+	if err != nil || len(rows) != 2 || rows[1][0] != "José" || rows[1][1] != "Bogotá" {
+		t.Errorf("windows-1252: rows %q err %v", rows, err)
+	}
+}
+
+func TestReadXLSX(t *testing.T) {
+	f := excelize.NewFile()
+	for i, row := range want {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		f.SetSheetRow("Sheet1", cell, &row)
+	}
+	var buf bytes.Buffer
+	f.Write(&buf)
+	f.Close()
+	assertRows(t, buf.Bytes(), FormatXLSX)
+}
+
+func TestReadODS(t *testing.T) {
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+<office:body><office:spreadsheet>
+<table:table table:name="Users">
+<table:table-row><table:table-cell><text:p>Nama</text:p></table:table-cell><table:table-cell><text:p>Email</text:p></table:table-cell><table:table-cell><text:p>Kelas</text:p></table:table-cell><table:table-cell table:number-columns-repeated="1021"/></table:table-row>
+<table:table-row><table:table-cell><office:annotation><text:p>catatan</text:p></office:annotation><text:p>Budi<text:s/><text:span>Śantoso</text:span></text:p></table:table-cell><table:table-cell><text:p>budi@example.com</text:p></table:table-cell><table:table-cell office:value-type="string"><text:p>7A</text:p></table:table-cell></table:table-row>
+<table:table-row><table:table-cell><text:p>Ani</text:p></table:table-cell><table:table-cell><text:p>ani@example.com</text:p></table:table-cell><table:table-cell table:number-columns-repeated="2"/></table:table-row>
+<table:table-row table:number-rows-repeated="1048573"><table:table-cell table:number-columns-repeated="1024"/></table:table-row>
+</table:table>
+<table:table table:name="Other"><table:table-row><table:table-cell><text:p>x</text:p></table:table-cell></table:table-row></table:table>
+</office:spreadsheet></office:body></office:document-content>`
+
+	assertRows(t, odsOf(content), FormatODS)
+}
+
+// Space runs and repeated cells are small in the file but huge once expanded
+func TestReadODSRefusesExpansionBombs(t *testing.T) {
+	sheet := func(rows string) string {
+		return `<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+<office:body><office:spreadsheet><table:table>` + rows + `</table:table></office:spreadsheet></office:body></office:document-content>`
+	}
+	spaces := strings.Repeat(`<text:s text:c="1024"/>`, 200)
+	for name, content := range map[string]string{
+		"space run": sheet(`<table:table-row><table:table-cell><text:p>a<text:s text:c="100000000"/></text:p></table:table-cell></table:table-row>`),
+		"repeated cells": sheet(`<table:table-row table:number-rows-repeated="1000"><table:table-cell table:number-columns-repeated="1024"><text:p>` +
+			spaces + `x</text:p></table:table-cell></table:table-row>`),
+	} {
+		if _, _, err := Read(odsOf(content)); err != ErrUnsupported {
+			t.Errorf("%s: err = %v, want ErrUnsupported", name, err)
+		}
+	}
+}
+
+func odsOf(content string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	w.Write([]byte(odsMimetype))
+	w, _ = zw.Create("content.xml")
+	w.Write([]byte(content))
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestDetectRejectsBinaries(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"xls":   {0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1, 0, 0},
+		"empty": {},
+		"zip":   zipWith("word/document.xml"),
+		// a mimetype entry too long to be one is not unpacked
+		"mimetype": zipWith("mimetype", odsMimetype+strings.Repeat(" ", 2<<10)),
+	} {
+		if _, err := Detect(data); err != ErrUnsupported {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
+
+func zipWith(name string, content ...string) []byte {
+	data := "<x/>"
+	if len(content) > 0 {
+		data = content[0]
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create(name)
+	w.Write([]byte(data))
+	zw.Close()
+	return buf.Bytes()
+}
@@ -0,0 +1,16 @@
+package tabular
+
+import (
+	"bytes"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func readXLSX(data []byte) ([][]string, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.GetRows(f.GetSheetName(0))
+}
@@ -0,0 +1,57 @@
+// Package unzip reads entries of uploaded zip files (QTI packages, spreadsheets) within size
+// limits. The sizes a zip declares are not trusted, so a small upload cannot unpack into gigabytes.
+package unzip
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+)
+
+var (
+	// ErrTooLarge means an entry is larger than the limit it was read with
+	ErrTooLarge = errors.New("unzip: entry too large")
+	// ErrArchiveTooLarge means the entries read so far exceed the budget of the whole archive
+	ErrArchiveTooLarge = errors.New("unzip: archive too large")
+)
+
+// ReadFile reads an entry of at most limit bytes
+func ReadFile(f *zip.File, limit int64) ([]byte, error) {
+	if f.UncompressedSize64 > uint64(limit) {
+		return nil, ErrTooLarge
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err == nil && int64(len(data)) > limit {
+		return nil, ErrTooLarge
+	}
+	return data, err
+}
+
+// Budget caps the bytes read from one archive across all its entries
+type Budget struct {
+	left int64
+}
+
+func NewBudget(size int64) *Budget {
+	return &Budget{left: size}
+}
+
+// ReadFile reads an entry of at most limit bytes out of what is left of the budget
+func (b *Budget) ReadFile(f *zip.File, limit int64) ([]byte, error) {
+	if limit <= b.left {
+		data, err := ReadFile(f, limit)
+		b.left -= int64(len(data))
+		return data, err
+	}
+	data, err := ReadFile(f, b.left)
+	if errors.Is(err, ErrTooLarge) {
+		return nil, ErrArchiveTooLarge
+	}
+	b.left -= int64(len(data))
+	return data, err
+}
@@ -0,0 +1,68 @@
+package unzip
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"testing"
+)
+
+func zipOf(t *testing.T, sizes ...int) []*zip.File {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i, size := range sizes {
+		w, _ := zw.Create(string(rune('a' + i)))
+		w.Write(make([]byte, size))
+	}
+	zw.Close()
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr.File
+}
+
+func TestReadFileLimit(t *testing.T) {
+	files := zipOf(t, 100, 101)
+	if data, err := ReadFile(files[0], 100); err != nil || len(data) != 100 {
+		t.Errorf("at the limit: %d bytes, err %v", len(data), err)
+	}
+	if _, err := ReadFile(files[1], 100); err != ErrTooLarge {
+		t.Errorf("over the limit: err %v", err)
+	}
+}
+
+// An entry that understates its size is not read past the limit
+func TestReadFileDoesNotTrustTheHeader(t *testing.T) {
+	var body bytes.Buffer
+	fw, _ := flate.NewWriter(&body, flate.BestCompression)
+	fw.Write(make([]byte, 1000))
+	fw.Close()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.CreateRaw(&zip.FileHeader{Name: "small", Method: zip.Deflate,
+		CompressedSize64: uint64(body.Len()), UncompressedSize64: 10})
+	w.Write(body.Bytes())
+	zw.Close()
+
+	zr, _ := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if data, err := ReadFile(zr.File[0], 100); err == nil || len(data) > 0 {
+		t.Errorf("read %d bytes, err %v", len(data), err)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	files := zipOf(t, 60, 60, 30)
+	b := NewBudget(100)
+	if _, err := b.ReadFile(files[0], 80); err != nil {
+		t.Fatalf("first entry: %v", err)
+	}
+	if _, err := b.ReadFile(files[1], 80); err != ErrArchiveTooLarge {
+		t.Errorf("second entry: err %v, want ErrArchiveTooLarge", err)
+	}
+	if data, err := b.ReadFile(files[2], 80); err != nil || len(data) != 30 {
+		t.Errorf("third entry: %d bytes, err %v", len(data), err)
+	}
+}
@@ -0,0 +1,185 @@
+// Package validation checks request bodies before handlers use them. Rules are declared with
+// `validate:"..."` struct tags (go-playground/validator); rules the tags cannot express, such as
+// "an MCQ has exactly one correct option", come from the DTO's own Validate method.
+//
+// Failures are reported per field, keyed by the JSON path the client sent ("questions[1].points"),
+// with the rule that failed and a message from the i18n catalog (validation.<rule>).
+package validation
+
+import (
+	"academic-suite-backend/i18n"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError is one failed rule. Message is filled in for the request's language when rendered.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// Errors is the list of failed rules of one request
+type Errors []FieldError
+
+// Add records that field failed rule (with an optional parameter, e.g. the minimum)
+func (e *Errors) Add(field, rule string, param ...string) {
+	fe := FieldError{Field: field, Rule: rule}
+	if len(param) > 0 {
+		fe.Param = param[0]
+	}
+	*e = append(*e, fe)
+}
+
+// Prefix returns e with every field placed under parent, for rules checked on a nested value
+func (e Errors) Prefix(parent string) Errors {
+	out := make(Errors, len(e))
+	for i, fe := range e {
+		fe.Field = parent + "." + fe.Field
+		out[i] = fe
+	}
+	return out
+}
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.Field + ": " + fe.Rule
+	}
+	return "validation failed: " + strings.Join(parts, ", ")
+}
+
+// Localize fills in the messages for locale l. It satisfies apperr.LocalizedDetails.
+func (e Errors) Localize(l i18n.Locale) interface{} {
+	out := make(Errors, len(e))
+	for i, fe := range e {
+		if fe.Param != "" {
+			fe.Message = l.T("validation."+fe.Rule, fe.Param)
+		} else {
+			fe.Message = l.T("validation." + fe.Rule)
+		}
+		out[i] = fe
+	}
+	return out
+}
+
+// Validator is implemented by request DTOs with domain rules beyond their struct tags.
+// Validate runs after the tags and only adds to what they found.
+type Validator interface {
+	Validate() Errors
+}
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	// Report JSON names; embedded structs are flattened like encoding/json does
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		if f.Anonymous {
+			return embeddedName
+		}
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	// Student numbers; both accept "" so clearing an optional number passes
+	v.RegisterValidation("digits", func(fl validator.FieldLevel) bool {
+		return isDigits(fl.Field().String())
+	})
+	v.RegisterValidation("nisn", func(fl validator.FieldLevel) bool {
+		s := fl.Field().String()
+		return s == "" || (len(s) == 10 && isDigits(s))
+	})
+	return v
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+const embeddedName = "~"
+
+// Struct checks v (a struct, a pointer to one, or a list of them) and returns the failed
+// rules, nil if none
+func Struct(v interface{}) Errors {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() == reflect.Slice {
+		var errs Errors
+		for i := 0; i < rv.Len(); i++ {
+			for _, fe := range Struct(rv.Index(i).Addr().Interface()) {
+				fe.Field = fmt.Sprintf("[%d].%s", i, fe.Field)
+				errs = append(errs, fe)
+			}
+		}
+		return errs
+	}
+
+	var errs Errors
+	if err := validate.Struct(v); err != nil {
+		var ves validator.ValidationErrors
+		if !errors.As(err, &ves) {
+			// Not a struct: a programming error, not the client's
+			panic(fmt.Sprintf("validation: %v", err))
+		}
+		for _, fe := range ves {
+			errs = append(errs, FieldError{
+				Field: fieldPath(fe.Namespace()),
+				Rule:  ruleName(fe),
+				Param: fe.Param(),
+			})
+		}
+	}
+	if dv, ok := v.(Validator); ok {
+		errs = append(errs, dv.Validate()...)
+	}
+	return errs
+}
+
+// fieldPath drops the struct name and embedded structs from "CreateReq.~.name"
+func fieldPath(namespace string) string {
+	parts := strings.Split(namespace, ".")
+	if len(parts) > 1 {
+		parts = parts[1:]
+	}
+	kept := parts[:0]
+	for _, p := range parts {
+		if p != embeddedName {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, ".")
+}
+
+// ruleName is the failed tag, with size rules told apart for text, lists and numbers
+// ("min_length", "min_items", "min") since they read differently
+func ruleName(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "min", "max", "len":
+		switch fe.Kind() {
+		case reflect.String:
+			return fe.Tag() + "_length"
+		case reflect.Slice, reflect.Map, reflect.Array:
+			return fe.Tag() + "_items"
+		}
+	}
+	return fe.Tag()
+}
+
+// Field is a single failed rule, for checks made outside Struct (e.g. parsing a schedule)
+func Field(field, rule string, param ...string) Errors {
+	var errs Errors
+	errs.Add(field, rule, param...)
+	return errs
+}
@@ -0,0 +1,84 @@
+package validation
+
+import (
+	"academic-suite-backend/i18n"
+	"fmt"
+	"testing"
+)
+
+type option struct {
+	Text string `json:"text" validate:"required"`
+}
+
+type base struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type request struct {
+	base
+	Password string   `json:"password" validate:"required,min=6"`
+	Points   int      `json:"points" validate:"gt=0"`
+	Tags     []string `json:"tags" validate:"min=1"`
+	Options  []option `json:"options" validate:"dive"`
+	Ignored  string   `json:"-"`
+}
+
+func (r request) Validate() Errors {
+	var errs Errors
+	if len(r.Options) > 0 && r.Options[0].Text == "same" {
+		errs.Add("options", "unique")
+	}
+	return errs
+}
+
+func rules(errs Errors) map[string]string {
+	out := make(map[string]string)
+	for _, fe := range errs {
+		out[fe.Field] = fe.Rule
+	}
+	return out
+}
+
+func TestStructReportsJSONPaths(t *testing.T) {
+	errs := Struct(&request{Password: "abc", Options: []option{{Text: "same"}, {}}})
+	want := map[string]string{
+		"name":            "required", // from the embedded struct, flattened like encoding/json
+		"password":        "min_length",
+		"points":          "gt",
+		"tags":            "min_items",
+		"options[1].text": "required",
+		"options":         "unique", // from Validate
+	}
+	if got := rules(errs); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("errors = %v, want %v", got, want)
+	}
+}
+
+func TestStructValid(t *testing.T) {
+	if errs := Struct(&request{base: base{Name: "a"}, Password: "secret", Points: 1, Tags: []string{"x"}}); errs != nil {
+		t.Errorf("unexpected errors %v", errs)
+	}
+}
+
+func TestStructChecksEachElementOfAList(t *testing.T) {
+	list := []option{{Text: "a"}, {}}
+	if got := rules(Struct(&list)); fmt.Sprint(got) != "map[[1].text:required]" {
+		t.Errorf("errors = %v", got)
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	errs := Errors{{Field: "password", Rule: "min_length", Param: "6"}, {Field: "name", Rule: "required"}}
+
+	en := errs.Localize(i18n.English).(Errors)
+	if en[0].Message != "Must be at least 6 characters" || en[1].Message != "Required" {
+		t.Errorf("en = %+v", en)
+	}
+	id := errs.Localize(i18n.Indonesian).(Errors)
+	if id[0].Message != "Minimal 6 karakter" {
+		t.Errorf("id = %+v", id)
+	}
+	if errs[0].Message != "" {
+		t.Error("Localize changed the original errors")
+	}
+}
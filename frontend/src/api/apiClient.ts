@@ -1,8 +1,10 @@
 import axios, { AxiosError } from 'axios';
 import {
     User, AuthTokens, Institution, Subject,
-    Quiz, ExamBatch, Attempt, Answer, EventLog, BatchReport, BatchStatus, Class
+    Quiz, ExamBatch, Attempt, Answer, EventLog, BatchReport, BatchStatus, Class, ItemAnalysis, Reliability, GradebookStudent, CohortComparison,
+    Page, AnswerRow, ExportFormat, ApiErrorResponse, FieldError, UserImportReport, UserImportOptions, Media
 } from '@/types';
+import i18n from '@/i18n';
 
 // Configuration
 
@@ -29,6 +31,10 @@ apiClient.interceptors.request.use((config) => {
     } catch (e) {
         console.error('Error attaching token', e);
     }
+    // Error messages and exported files follow the UI language
+    if (i18n.language) {
+        config.headers['Accept-Language'] = i18n.language;
+    }
     return config;
 });
 
@@ -57,10 +63,27 @@ apiClient.interceptors.response.use(
     }
 );
 
+// Error thrown by the API helpers; code is the backend's stable error code
+export class ApiError extends Error {
+    constructor(message: string, public code?: string, public details?: unknown, public requestId?: string) {
+        super(message);
+        this.name = 'ApiError';
+    }
+
+    // Field-level failures of a validation_failed error, empty otherwise
+    get fieldErrors(): FieldError[] {
+        return this.code === 'validation_failed' && Array.isArray(this.details) ? this.details as FieldError[] : [];
+    }
+}
+
 // Helper to handle axios errors
 const handlegetError = (error: unknown) => {
     if (axios.isAxiosError(error)) {
-        throw new Error(error.response?.data?.error || error.message);
+        const body: ApiErrorResponse | undefined = error.response?.data;
+        if (body?.error?.code) {
+            throw new ApiError(body.error.message, body.error.code, body.error.details, body.error.requestId);
+        }
+        throw new ApiError(error.message);
     }
     throw error;
 };
@@ -252,6 +275,18 @@ export const quizApi = {
         }
     },
 
+    // Questions as a Moodle GIFT or Aiken text file (Aiken only holds multiple choice),
+    // or the quiz as an IMS QTI 2.1/3.0 package (.zip)
+    exportQuestions: async (id: string, format: 'gift' | 'aiken' | 'qti' = 'gift', version: '2.1' | '3.0' = '2.1'): Promise<Blob> => {
+        try {
+            const params = format === 'qti' ? { format, version } : { format };
+            const response = await apiClient.get(`/quizzes/${id}/export`, { params, responseType: 'blob' });
+            return response.data;
+        } catch (error) {
+            throw handlegetError(error);
+        }
+    },
+
     delete: async (id: string): Promise<void> => {
         throw new Error("Delete Quiz not implemented in backend yet");
     }
@@ -476,6 +511,17 @@ export const userApi = {
 };
 
 // Report API (Mocked for now as backend doesn't implement reports yet)
+export interface EventLogQuery {
+    batchId?: string;
+    attemptId?: string;
+    userId?: string;
+    eventType?: string; // comma separated
+    from?: string; // RFC3339
+    to?: string;
+    limit?: number;
+    cursor?: string;
+}
+
 export const reportApi = {
     getBatchReport: async (batchId: string): Promise<BatchReport> => {
         try {
@@ -486,14 +532,82 @@ export const reportApi = {
         }
     },
 
-    getEventLogs: async (batchId?: string): Promise<EventLog[]> => {
+    // Per-question statistics for a batch (with its makeups) or for every batch of a quiz
+    getItemAnalysis: async (params: { batchId?: string; quizId?: string }): Promise<ItemAnalysis> => {
         try {
-            const query = batchId ? `?batchId=${batchId}` : '';
-            const response = await apiClient.get(`/reports/logs${query}`);
+            const response = await apiClient.get('/reports/items', { params });
+            return response.data;
+        } catch (error) {
+            throw handlegetError(error);
+        }
+    },
+
+    // KR-20/KR-21, Cronbach's alpha, SEM and score histogram
+    getReliability: async (params: { batchId?: string; quizId?: string; bins?: number }): Promise<Reliability> => {
+        try {
+            const response = await apiClient.get('/reports/reliability', { params });
+            return response.data;
+        } catch (error) {
+            throw handlegetError(error);
+        }
+    },
+
+    // Compare classes sitting the same quiz, by batch IDs or by quiz
+    compareCohorts: async (params: { batchIds?: string[]; quizId?: string }): Promise<CohortComparison> => {
+        try {
+            const response = await apiClient.get('/reports/compare', {
+                params: { batchIds: params.batchIds?.join(','), quizId: params.quizId }
+            });
+            return response.data;
+        } catch (error) {
+            throw handlegetError(error);
+        }
+    },
+
+    getEventLogs: async (query: EventLogQuery = {}): Promise<Page<EventLog>> => {
+        try {
+            const response = await apiClient.get('/reports/logs', { params: query });
             return response.data;
         } catch (error) {
             console.error("Failed to fetch event logs", error);
-            return [];
+            return { items: [], nextCursor: '' };
+        }
+    },
+
+    getAnswers: async (params: { batchId: string; studentId?: string; questionId?: string; limit?: number; cursor?: string }): Promise<Page<AnswerRow>> => {
+        try {
+            const response = await apiClient.get('/reports/answers', { params });
+            return response.data;
+        } catch (error) {
+            throw handlegetError(error);
+        }
+    },
+
+    // Streamed exports: every attempt / every saved answer of a batch, or the filtered event logs
+    exportBatchResults: async (batchId: string, format: ExportFormat = 'csv'): Promise<Blob> => {
+        try {
+            const response = await apiClient.get(`/export/batch/${batchId}/results`, { params: { format }, responseType: 'blob' });
+            return response.data;
+        } catch (error) {
+            throw handlegetError(error);
+        }
+    },
+
+    exportBatchAnswers: async (batchId: string, format: ExportFormat = 'csv'): Promise<Blob> => {
+        try {
+            const response = await apiClient.get(`/export/batch/${batchId}/answers`, { params: { format }, responseType: 'blob' });
+            return response.data;
+        } catch (error) {
+            throw handlegetError(error);
+        }
+    },
+
+    exportEventLogs: async (query: Omit<EventLogQuery, 'limit' | 'cursor'>, format: ExportFormat = 'csv'): Promise<Blob> => {
+        try {
+            const response = await apiClient.get('/export/logs', { params: { ...query, format }, responseType: 'blob' });
+            return response.data;
+        } catch (error) {
+            throw handlegetError(error);
         }
     },
 
@@ -506,24 +620,155 @@ export const reportApi = {
         } catch (error) {
             throw handlegetError(error);
         }
+    },
+
+    // Workbook with one row per student and columns per question, plus statistics and answer key sheets
+    exportAnswerMatrix: async (batchId: string): Promise<Blob> => {
+        try {
+            const response = await apiClient.get(`/export/batch/${batchId}/matrix`, { responseType: 'blob' });
+            return response.data;
+        } catch (error) {
+            throw handlegetError(error);
+        }
+    },
+
+    // Printable PDF report of a batch
+    exportBatchReportPdf: async (batchId: string): Promise<Blob> => {
+        try {
+            const response = await apiClient.get(`/export/batch/${batchId}/pdf`, { responseType: 'blob' });
+            return response.data;
+        } catch (error) {
+            throw handlegetError(error);
+        }
+    },
+
+    // Zip with the PDF report, a result slip per student and certificates for passing students
+    exportBatchDocuments: async (batchId: string): Promise<Blob> => {
+        try {
+            const response = await apiClient.get(`/export/batch/${batchId}/documents`, { responseType: 'blob' });
+            return response.data;
+        } catch (error) {
+            throw handlegetError(error);
+        }
+    },
+
+    exportResultSlip: async (attemptId: string): Promise<Blob> => {
+        try {
+            const response = await apiClient.get(`/export/attempts/${attemptId}/slip`, { responseType: 'blob' });
+            return response.data;
+        } catch (error) {
+            throw handlegetError(error);
+        }
+    }
+}
+
+export interface GradebookParams {
+    studentId?: string;
+    classId?: string;
+    subjectId?: string;
+}
+
+export const gradebookApi = {
+    get: async (params: GradebookParams): Promise<GradebookStudent[]> => {
+        try {
+            const response = await apiClient.get('/gradebook', { params });
+            return response.data;
+        } catch (error) {
+            throw handlegetError(error);
+        }
+    },
+
+    export: async (params: GradebookParams): Promise<Blob> => {
+        try {
+            const response = await apiClient.get('/gradebook/export', { params, responseType: 'blob' });
+            return response.data;
+        } catch (error) {
+            throw handlegetError(error);
+        }
     }
 }
 
 export const importApi = {
-    importUsers: async (file: File) => {
+    // Preview with dryRun, then call again without it to commit (all rows or none).
+    // A commit with failing rows throws ApiError 'import_has_errors' with the report as details.
+    importUsers: async (file: File, options: UserImportOptions = {}): Promise<UserImportReport> => {
         const formData = new FormData();
         formData.append('file', file);
-        const response = await apiClient.post('/import/users', formData, {
+        try {
+            const response = await apiClient.post('/import/users', formData, {
+                params: options,
+                headers: { 'Content-Type': 'multipart/form-data' }
+            });
+            return response.data;
+        } catch (error) {
+            throw handlegetError(error);
+        }
+    },
+    // The rows back as .xlsx with a status column and failed cells highlighted
+    importUsersResult: async (file: File, options: UserImportOptions = {}): Promise<Blob> => {
+        const formData = new FormData();
+        formData.append('file', file);
+        try {
+            const response = await apiClient.post('/import/users', formData, {
+                params: { ...options, format: 'xlsx' },
+                headers: { 'Content-Type': 'multipart/form-data' },
+                responseType: 'blob'
+            });
+            return response.data;
+        } catch (error) {
+            throw handlegetError(error);
+        }
+    },
+    downloadUserTemplate: async (): Promise<Blob> => {
+        try {
+            const response = await apiClient.get('/import/users/template', { responseType: 'blob' });
+            return response.data;
+        } catch (error) {
+            throw handlegetError(error);
+        }
+    },
+    // Spreadsheet, Moodle GIFT (.gift/.txt), Aiken (.txt) or QTI (.zip); the format is detected unless given
+    importQuestions: async (quizId: string, file: File, format: 'auto' | 'spreadsheet' | 'gift' | 'aiken' | 'qti' = 'auto') => {
+        const formData = new FormData();
+        formData.append('file', file);
+        const response = await apiClient.post(`/import/questions/${quizId}`, formData, {
+            params: { format },
             headers: { 'Content-Type': 'multipart/form-data' }
         });
         return response.data;
     },
-    importQuestions: async (quizId: string, file: File) => {
+    // A QTI package (.zip) as a new draft quiz; returns { quiz, errors, successCount }
+    importQuiz: async (file: File) => {
         const formData = new FormData();
         formData.append('file', file);
-        const response = await apiClient.post(`/import/questions/${quizId}`, formData, {
+        const response = await apiClient.post('/import/quizzes', formData, {
             headers: { 'Content-Type': 'multipart/form-data' }
         });
         return response.data;
     }
 }
+
+export const mediaApi = {
+    // Images (PNG, JPEG, GIF, WebP) and audio (MP3, WAV, Ogg, M4A) up to 20 MB
+    upload: async (file: File): Promise<Media> => {
+        const formData = new FormData();
+        formData.append('file', file);
+        try {
+            const response = await apiClient.post('/media', formData, {
+                headers: { 'Content-Type': 'multipart/form-data' }
+            });
+            return response.data;
+        } catch (error) {
+            throw handlegetError(error);
+        }
+    },
+    // Media needs the token, so <img>/<audio> get it through URL.createObjectURL(blob)
+    get: async (id: string): Promise<Blob> => {
+        try {
+            const response = await apiClient.get(`/media/${id}`, { responseType: 'blob' });
+            return response.data;
+        } catch (error) {
+            throw handlegetError(error);
+        }
+    }
+};
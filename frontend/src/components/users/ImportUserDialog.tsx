@@ -1,7 +1,9 @@
 import { useState, useRef } from 'react';
+import { useTranslation } from 'react-i18next';
 import { Button } from '@/components/ui/button';
 import { Input } from '@/components/ui/input';
 import { Label } from '@/components/ui/label';
+import { Checkbox } from '@/components/ui/checkbox';
 import {
     Dialog,
     DialogContent,
@@ -13,8 +15,8 @@ import {
 import { Alert, AlertDescription } from '@/components/ui/alert';
 import { Upload, FileSpreadsheet, AlertCircle, CheckCircle2, Download, Loader2 } from 'lucide-react';
 import { useToast } from '@/hooks/use-toast';
-import { importApi } from '@/api/apiClient';
-import * as XLSX from 'xlsx';
+import { importApi, ApiError } from '@/api/apiClient';
+import { UserImportReport } from '@/types';
 
 interface ImportUserDialogProps {
     open: boolean;
@@ -22,119 +24,124 @@ interface ImportUserDialogProps {
     onSuccess: () => void;
 }
 
+const saveBlob = (blob: Blob, filename: string) => {
+    const url = window.URL.createObjectURL(blob);
+    const a = document.createElement('a');
+    a.href = url;
+    a.download = filename;
+    document.body.appendChild(a);
+    a.click();
+    document.body.removeChild(a);
+    window.URL.revokeObjectURL(url);
+};
+
+// Two steps: "Check" previews every row (nothing is written), "Import" commits them in one go
 export function ImportUserDialog({ open, onOpenChange, onSuccess }: ImportUserDialogProps) {
+    const { t } = useTranslation();
     const { toast } = useToast();
     const fileInputRef = useRef<HTMLInputElement>(null);
     const [file, setFile] = useState<File | null>(null);
-    const [isUploading, setIsUploading] = useState(false);
-    const [errors, setErrors] = useState<string[]>([]);
-    const [successCount, setSuccessCount] = useState<number | null>(null);
+    const [upsert, setUpsert] = useState(false);
+    const [isBusy, setIsBusy] = useState(false);
+    const [preview, setPreview] = useState<UserImportReport | null>(null);
+    const [error, setError] = useState<string | null>(null);
+
+    const reset = () => {
+        setPreview(null);
+        setError(null);
+    };
 
     const handleFileChange = (e: React.ChangeEvent<HTMLInputElement>) => {
         const selectedFile = e.target.files?.[0];
         if (selectedFile) {
             setFile(selectedFile);
-            setErrors([]);
-            setSuccessCount(null);
+            reset();
         }
     };
 
-    const handleDownloadTemplate = () => {
-        const template = [
-            {
-                Name: 'John Doe',
-                Email: 'john@example.com',
-                Password: 'securepassword123',
-                Role: 'student', // student, teacher, admin
-                InstitutionID: 'inst-1' // Optional
-            },
-            {
-                Name: 'Jane Smith',
-                Email: 'jane@example.com',
-                Password: 'password123',
-                Role: 'teacher',
-                InstitutionID: 'inst-1'
-            }
-        ];
-
-        const ws = XLSX.utils.json_to_sheet(template);
-        const wb = XLSX.utils.book_new();
-        XLSX.utils.book_append_sheet(wb, ws, 'Users');
-        XLSX.writeFile(wb, 'template_users.xlsx');
+    const handleDownloadTemplate = async () => {
+        try {
+            saveBlob(await importApi.downloadUserTemplate(), 'template-users.xlsx');
+        } catch (err) {
+            toast({ title: t('users.import.failed'), description: (err as Error).message, variant: 'destructive' });
+        }
     };
 
-    const handleImport = async () => {
+    const handleDownloadResult = async () => {
         if (!file) return;
-
-        setIsUploading(true);
-        setErrors([]);
-
         try {
-            const result = await importApi.importUsers(file);
-
-            if (result.errors && result.errors.length > 0) {
-                setErrors(result.errors);
-            }
+            saveBlob(await importApi.importUsersResult(file, { dryRun: true, upsert }), 'import-users-result.xlsx');
+        } catch (err) {
+            toast({ title: t('users.import.failed'), description: (err as Error).message, variant: 'destructive' });
+        }
+    };
 
-            if (result.successCount !== undefined) {
-                setSuccessCount(result.successCount);
-                if (result.successCount > 0) {
-                    toast({
-                        title: "Import Success",
-                        description: `Successfully imported ${result.successCount} users.`,
-                    });
-                    onSuccess();
-                }
-            }
+    const handleCheck = async () => {
+        if (!file) return;
+        setIsBusy(true);
+        reset();
+        try {
+            setPreview(await importApi.importUsers(file, { dryRun: true, upsert }));
+        } catch (err) {
+            setError((err as Error).message);
+        } finally {
+            setIsBusy(false);
+        }
+    };
 
-            if ((!result.errors || result.errors.length === 0)) {
-                onOpenChange(false);
-                setFile(null);
+    const handleImport = async () => {
+        if (!file) return;
+        setIsBusy(true);
+        try {
+            const result = await importApi.importUsers(file, { upsert });
+            toast({ title: t('users.import.success', { created: result.created, updated: result.updated }) });
+            onSuccess();
+            onOpenChange(false);
+            setFile(null);
+            reset();
+        } catch (err) {
+            // Rows changed since the check: show the new report
+            if (err instanceof ApiError && err.code === 'import_has_errors') {
+                setPreview(err.details as UserImportReport);
+            } else {
+                setError((err as Error).message);
             }
-
-        } catch (error: any) {
-            setErrors([error.message || "An unexpected error occurred"]);
-            toast({
-                title: "Import Failed",
-                description: "Failed to import users.",
-                variant: "destructive"
-            });
+            toast({ title: t('users.import.failed'), variant: 'destructive' });
         } finally {
-            setIsUploading(false);
+            setIsBusy(false);
         }
     };
 
+    const failedRows = preview?.rows.filter(r => r.action === 'error') ?? [];
+    const canImport = preview !== null && preview.failed === 0 && preview.total > 0;
+
     return (
         <Dialog open={open} onOpenChange={onOpenChange}>
-            <DialogContent className="sm:max-w-[500px]">
+            <DialogContent className="sm:max-w-[560px]">
                 <DialogHeader>
                     <DialogTitle className="flex items-center gap-2">
                         <FileSpreadsheet className="h-5 w-5" />
-                        Import Users from Excel
+                        {t('users.import.title')}
                     </DialogTitle>
-                    <DialogDescription>
-                        Upload an Excel file (.xlsx) to bulk import users.
-                    </DialogDescription>
+                    <DialogDescription>{t('users.import.desc')}</DialogDescription>
                 </DialogHeader>
 
                 <div className="py-4 space-y-6">
                     {/* Download Template */}
                     <div className="flex items-center justify-between p-4 bg-muted/50 rounded-lg">
                         <div>
-                            <h4 className="font-medium text-foreground">Template</h4>
-                            <p className="text-sm text-muted-foreground">
-                                Download proper format
-                            </p>
+                            <h4 className="font-medium text-foreground">{t('users.import.template')}</h4>
+                            <p className="text-sm text-muted-foreground">{t('users.import.template_desc')}</p>
                         </div>
                         <Button variant="outline" size="sm" onClick={handleDownloadTemplate}>
                             <Download className="h-3 w-3 mr-2" />
-                            Download
+                            {t('users.import.download')}
                         </Button>
                     </div>
 
                     {/* File Upload */}
                     <div className="space-y-2">
-                        <Label>Select File</Label>
+                        <Label>{t('users.import.select_file')}</Label>
                         <div
                             className={`border-2 border-dashed rounded-lg p-8 text-center cursor-pointer transition-colors ${file ? 'border-primary bg-primary/5' : 'border-border hover:border-primary/50'
                                 }`}
@@ -143,76 +150,97 @@ export function ImportUserDialog({ open, onOpenChange, onSuccess }: ImportUserDi
                             <Upload className={`h-10 w-10 mx-auto mb-4 ${file ? 'text-primary' : 'text-muted-foreground'}`} />
                             {file ? (
                                 <div>
-                                    <p className="text-foreground font-medium truncate max-w-[300px] mx-auto">
-                                        {file.name}
-                                    </p>
-                                    <p className="text-xs text-muted-foreground mt-1">
-                                        Click to change file
-                                    </p>
+                                    <p className="text-foreground font-medium truncate max-w-[300px] mx-auto">{file.name}</p>
+                                    <p className="text-xs text-muted-foreground mt-1">{t('users.import.click_change')}</p>
                                 </div>
                             ) : (
                                 <>
-                                    <p className="text-foreground font-medium">
-                                        Click to upload or drag & drop
-                                    </p>
-                                    <p className="text-sm text-muted-foreground mt-1">
-                                        .xlsx or .xls
-                                    </p>
+                                    <p className="text-foreground font-medium">{t('users.import.click_upload')}</p>
+                                    <p className="text-sm text-muted-foreground mt-1">.xlsx, .ods, .csv</p>
                                 </>
                             )}
                             <Input
                                 ref={fileInputRef}
                                 type="file"
-                                accept=".xlsx,.xls"
+                                accept=".xlsx,.ods,.csv"
                                 className="hidden"
                                 onChange={handleFileChange}
                             />
                         </div>
                     </div>
 
-                    {/* Status/Errors */}
-                    {errors.length > 0 && (
-                        <Alert variant="destructive" className="max-h-[150px] overflow-y-auto">
+                    <div className="flex items-center space-x-2">
+                        <Checkbox
+                            id="importUpsert"
+                            checked={upsert}
+                            onCheckedChange={(checked) => { setUpsert(checked as boolean); reset(); }}
+                        />
+                        <Label htmlFor="importUpsert" className="font-normal">{t('users.import.upsert')}</Label>
+                    </div>
+
+                    {error && (
+                        <Alert variant="destructive">
                             <AlertCircle className="h-4 w-4" />
-                            <AlertDescription>
-                                <p className="font-semibold mb-1">Errors ({errors.length}):</p>
-                                <ul className="list-disc list-inside text-xs space-y-1">
-                                    {errors.map((error, i) => (
-                                        <li key={i}>{error}</li>
-                                    ))}
-                                </ul>
-                            </AlertDescription>
+                            <AlertDescription>{error}</AlertDescription>
                         </Alert>
                     )}
 
-                    {successCount !== null && (
-                        <Alert className="border-green-500 bg-green-50 dark:bg-green-900/10 dark:border-green-900">
-                            <CheckCircle2 className="h-4 w-4 text-green-600 dark:text-green-400" />
-                            <AlertDescription className="text-green-600 dark:text-green-400">
-                                Processed with {successCount} successful imports.
+                    {preview && (
+                        <Alert
+                            variant={failedRows.length > 0 ? 'destructive' : 'default'}
+                            className={failedRows.length > 0 ? 'max-h-[200px] overflow-y-auto' : 'border-green-500 bg-green-50 dark:bg-green-900/10 dark:border-green-900'}
+                        >
+                            {failedRows.length > 0 ? <AlertCircle className="h-4 w-4" /> : <CheckCircle2 className="h-4 w-4 text-green-600 dark:text-green-400" />}
+                            <AlertDescription>
+                                <p className="font-semibold mb-1">{t('users.import.summary', preview)}</p>
+                                {failedRows.length > 0 && (
+                                    <>
+                                        <p className="text-xs mb-1">{t('users.import.fix_errors')}</p>
+                                        <ul className="list-disc list-inside text-xs space-y-1">
+                                            {failedRows.map(row => (
+                                                <li key={row.row}>
+                                                    {t('users.import.row', { row: row.row })}: {row.errors?.map(e => `${e.field}: ${e.message}`).join('; ')}
+                                                </li>
+                                            ))}
+                                        </ul>
+                                        <Button variant="link" size="sm" className="px-0" onClick={handleDownloadResult}>
+                                            <Download className="h-3 w-3 mr-1" />
+                                            {t('users.import.download_result')}
+                                        </Button>
+                                    </>
+                                )}
                             </AlertDescription>
                         </Alert>
                     )}
-
                 </div>
 
                 <DialogFooter>
                     <Button variant="outline" onClick={() => onOpenChange(false)}>
-                        Close
-                    </Button>
-                    <Button
-                        onClick={handleImport}
-                        disabled={!file || isUploading}
-                    >
-                        {isUploading ? (
-                            <>
-                                <Loader2 className="mr-2 h-4 w-4 animate-spin" />
-                                Importing...
-                            </>
-                        ) : (
-                            'Import Users'
-                        )}
+                        {t('users.import.close')}
                     </Button>
+                    {canImport ? (
+                        <Button onClick={handleImport} disabled={isBusy}>
+                            {isBusy ? (
+                                <>
+                                    <Loader2 className="mr-2 h-4 w-4 animate-spin" />
+                                    {t('users.import.importing')}
+                                </>
+                            ) : (
+                                t('users.import.import', { count: preview.total })
+                            )}
+                        </Button>
+                    ) : (
+                        <Button onClick={handleCheck} disabled={!file || isBusy}>
+                            {isBusy ? (
+                                <>
+                                    <Loader2 className="mr-2 h-4 w-4 animate-spin" />
+                                    {t('users.import.checking')}
+                                </>
+                            ) : (
+                                t('users.import.check')
+                            )}
+                        </Button>
+                    )}
                 </DialogFooter>
             </DialogContent>
         </Dialog>
@@ -110,7 +110,7 @@ export default function BatchDetailsPage() {
         setName(batchData.name);
         setSelectedQuizId(batchData.quizId);
         setSelectedClassId(batchData.classId || '');
-        setStartTime(batchData.startTime.slice(0, 16)); // Format for datetime-local
+        setStartTime(batchData.startTime.slice(0, 16)); // Format for datetime-local (already in the batch timezone)
         setEndTime(batchData.endTime.slice(0, 16));
         setToken(batchData.token);
         setTimeLimit(batchData.duration || 0);
@@ -132,16 +132,9 @@ export default function BatchDetailsPage() {
   const fetchLogs = async (studentId: string) => {
     setLoadingLogs(true);
     try {
-      // Ideally we filter by batchId AND studentId at backend, but for now we might fetch all batch logs and filter client side
-      // OR assume our apiClient.getEventLogs supports filtering.
-      // Based on current backend implementation: api.Get("/reports/logs", handlers.GetEventLogs)
-      // We need to check if backend supports studentId filter.
-      // If not, we fetch all for batch and filter here.
-      // Let's assume we can pass batchId. 
-      const allLogs = await reportApi.getEventLogs(batchId);
-      // Filter by studentId
-      const studentLogs = allLogs.filter(l => l.studentId === studentId);
-      setLogs(studentLogs.sort((a, b) => new Date(b.createdAt).getTime() - new Date(a.createdAt).getTime()));
+      // Filtered by student on the backend, newest first
+      const page = await reportApi.getEventLogs({ batchId, userId: studentId, limit: 1000 });
+      setLogs(page.items);
     } catch (error) {
       toast({ title: t('batches.log_load_error'), variant: "destructive" });
     } finally {
@@ -199,8 +192,9 @@ export default function BatchDetailsPage() {
         name,
         quizId: selectedQuizId,
         classId: selectedClassId,
-        startTime: new Date(startTime).toISOString(),
-        endTime: new Date(endTime).toISOString(),
+        // Wall-clock times, the backend reads them in the batch's institution timezone
+        startTime,
+        endTime,
         token,
         timeLimit,
         settings: {
@@ -84,9 +84,8 @@ export default function ReportsPage() {
   const fetchLogs = async (studentId: string) => {
     setLoadingLogs(true);
     try {
-      const allLogs = await reportApi.getEventLogs(selectedBatchId);
-      const studentLogs = allLogs.filter(l => l.studentId === studentId);
-      setLogs(studentLogs.sort((a, b) => new Date(b.createdAt).getTime() - new Date(a.createdAt).getTime()));
+      const page = await reportApi.getEventLogs({ batchId: selectedBatchId, userId: studentId, limit: 1000 });
+      setLogs(page.items);
     } catch (error) {
       toast({ title: t('reports.toast.load_fail'), variant: "destructive" });
     } finally {
@@ -8,6 +8,9 @@ export interface User {
   role: UserRole;
   institutionId: string;
   avatarUrl?: string;
+  locale?: 'id' | 'en' | ''; // saved language for errors and exports; empty follows the browser
+  nis?: string; // school student number
+  nisn?: string; // national student number (10 digits)
   createdAt: string;
 }
 
@@ -23,6 +26,7 @@ export interface Institution {
   name: string;
   type: 'school' | 'university';
   address: string;
+  timezone?: string; // IANA name, e.g. Asia/Jakarta
   createdAt: string;
 }
 
@@ -57,6 +61,7 @@ export interface Subject {
   teacherId?: string; // Deprecated
   teacherIds?: string[];
   teachers?: User[]; // Resolved teacher objects
+  gradeWeights?: GradeWeights; // percent per exam type for the gradebook
 }
 
 export interface Class {
@@ -74,7 +79,8 @@ export type ExamType = 'daily_quiz' | 'midterm' | 'final' | 'practice';
 
 export interface QuestionOption {
   id: string;
-  text: string;
+  text: string; // may be empty for a picture choice
+  imageId?: string; // Media of kind image
   isCorrect: boolean;
 }
 
@@ -82,12 +88,29 @@ export interface Question {
   id: string;
   quizId: string;
   type: QuestionType;
-  text: string;
+  text: string; // may hold LaTeX (\( \), $$ $$) and inline MathML (<math>)
+  imageId?: string; // Media of kind image
+  audioId?: string; // Media of kind audio, e.g. a listening section
   points: number;
   options?: QuestionOption[];
   correctAnswer?: string;
   explanation?: string;
   orderIndex: number;
+  topic?: string; // groups questions in report breakdowns
+}
+
+// Uploaded image or audio, read back through mediaApi.get
+export type MediaKind = 'image' | 'audio';
+
+export interface Media {
+  id: string;
+  institutionId: string;
+  kind: MediaKind;
+  filename: string;
+  contentType: string;
+  size: number;
+  uploadedBy: string;
+  createdAt: string;
 }
 
 export interface Quiz {
@@ -120,6 +143,7 @@ export interface ExamBatch {
   startTime: string;
   endTime: string;
   duration: number; // in minutes
+  timezone?: string; // zone the schedule was entered in; startTime/endTime carry its offset
   status: BatchStatus;
   allowedParticipants: string[]; // student IDs
   waitlist: string[];
@@ -192,6 +216,29 @@ export interface EventLog {
   createdAt: string;
 }
 
+// Cursor-paginated list; pass nextCursor back as cursor, empty on the last page
+export interface Page<T> {
+  items: T[];
+  nextCursor: string;
+}
+
+export interface AnswerRow {
+  attemptId: string;
+  batchId: string;
+  studentId: string;
+  studentName: string;
+  questionId: string;
+  questionOrder: number;
+  questionText: string;
+  selectedOptionId: string;
+  optionText: string;
+  textAnswer: string;
+  isCorrect: boolean | null; // null when no option was chosen
+  answeredAt: string;
+}
+
+export type ExportFormat = 'csv' | 'ndjson' | 'xlsx';
+
 // Report Types
 export interface AttemptReport {
   attemptId: string;
@@ -207,6 +254,7 @@ export interface AttemptReport {
 
 export interface BatchReport {
   batchId: string;
+  batchName: string;
   batchType: BatchType;
   quizTitle: string;
   totalParticipants: number;
@@ -216,3 +264,191 @@ export interface BatchReport {
   lowestScore: number;
   attempts: AttemptReport[];
 }
+
+export interface ItemOptionStats {
+  optionId: string;
+  isKey: boolean;
+  count: number;
+  proportion: number;
+  upperCount: number;
+  lowerCount: number;
+}
+
+export interface ItemStats {
+  itemId: string;
+  questionId: string;
+  number: number;
+  text: string;
+  type: QuestionType;
+  points: number;
+  examinees: number;
+  answered: number;
+  blank: number;
+  blankRate: number;
+  correct: number;
+  difficulty: number | null; // p-value, null for unscored (essay) questions
+  discrimination: number | null; // upper 27% minus lower 27%
+  pointBiserial: number | null; // item-rest correlation (total without this question)
+  options: ItemOptionStats[];
+  optionLabels: string[];
+}
+
+// Gradebook Types
+export interface GradeWeights {
+  dailyQuiz: number;
+  midterm: number;
+  final: number;
+}
+
+export interface GradebookQuiz {
+  quizId: string;
+  title: string;
+  examType: ExamType;
+  attemptId: string;
+  batchId: string;
+  score: number;
+  totalPoints: number;
+  percentage: number;
+  passingScore: number;
+  passed: boolean;
+  submittedAt: string | null;
+}
+
+export interface GradebookComponent {
+  examType: ExamType;
+  weight: number;
+  average: number | null;
+  quizzes: GradebookQuiz[];
+}
+
+export interface GradebookSubject {
+  subjectId: string;
+  subjectName: string;
+  subjectCode: string;
+  gradeWeights: GradeWeights;
+  components: GradebookComponent[];
+  finalGrade: number | null;
+  passedCount: number;
+  failedCount: number;
+}
+
+export interface GradebookStudent {
+  studentId: string;
+  studentName: string;
+  subjects: GradebookSubject[];
+}
+
+export interface ScoreSummary {
+  count: number;
+  mean: number;
+  median: number;
+  stdDev: number;
+  min: number;
+  max: number;
+  percentiles: Record<string, number>; // p10, p25, p50, p75, p90
+}
+
+export interface TopicBreakdown {
+  topic: string; // "" for questions without a topic
+  items: number;
+  maxPoints: number;
+  meanPercentage: number;
+}
+
+export interface CohortGroup {
+  key: string;
+  classId: string;
+  className: string;
+  batchIds: string[];
+  summary: ScoreSummary;
+  passCount: number;
+  passRate: number; // 0-1
+  topics: TopicBreakdown[];
+}
+
+export interface CohortComparison {
+  quizId: string;
+  quizTitle: string;
+  passingScore: number;
+  groups: CohortGroup[];
+  overall: CohortGroup;
+}
+
+export interface HistogramBin {
+  from: number; // percent of max score
+  to: number;
+  count: number;
+}
+
+export interface Reliability {
+  quizId: string;
+  quizTitle: string;
+  examType: ExamType;
+  batchIds: string[];
+  examinees: number;
+  items: number;
+  maxScore: number;
+  mean: number;
+  stdDev: number;
+  min: number;
+  max: number;
+  kr20: number | null;
+  kr21: number | null;
+  cronbachAlpha: number | null;
+  sem: number | null;
+  histogram: HistogramBin[];
+}
+
+export interface ItemAnalysis {
+  quizId: string;
+  quizTitle: string;
+  batchIds: string[];
+  examinees: number;
+  groupSize: number;
+  items: ItemStats[];
+}
+
+// Envelope of every error response from the API
+// User import (POST /import/users): one row of the preview or result
+export interface UserImportRow {
+  row: number; // line in the sheet, header is 1
+  name: string;
+  email: string;
+  action: 'create' | 'update' | 'error';
+  userId?: string;
+  classId?: string;
+  errors?: FieldError[];
+}
+
+export interface UserImportReport {
+  dryRun: boolean;
+  upsert: boolean;
+  committed: boolean;
+  total: number;
+  created: number;
+  updated: number;
+  failed: number;
+  rows: UserImportRow[];
+}
+
+export interface UserImportOptions {
+  dryRun?: boolean;
+  upsert?: boolean;
+}
+
+// One entry of details when code is validation_failed
+export interface FieldError {
+  field: string; // JSON path, e.g. "questions[0].options"
+  rule: string;
+  param?: string;
+  message: string;
+}
+
+export interface ApiErrorResponse {
+  error: {
+    code: string;
+    message: string;
+    details?: unknown;
+    requestId?: string;
+  };
+}